@@ -0,0 +1,232 @@
+package bus
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+)
+
+// BankedMemory is a bank switching device for a MappedBus. It holds more memory than fits in its address range,
+// divided into banks, and its address range is divided into windows, each of which shows one bank at a time. This is
+// how NES mappers, the C64's PLA and homebrew MMUs give a 6502 more than 64KB.
+//
+// The bank shown in each window is selected from Go with Select, or by the program through the control registers
+// returned by Registers, which can be mapped anywhere on the bus. Banks are counted in units of the window's size, so
+// a window of $2000 bytes showing bank 3 shows bytes $6000-$7FFF of the memory. Windows can have different sizes, and
+// the memory can hold any number of banks.
+type BankedMemory struct {
+	// OnWrite, if not nil, is called for each write to banked ROM with the offset written and the data. Many NES
+	// mappers take their bank numbers from writes to their ROM's addresses, and this lets them be modelled.
+	OnWrite func(addr uint16, data byte)
+
+	id       string
+	memory   []byte
+	readOnly bool
+	windows  []Window
+}
+
+// Window is one of the windows of a BankedMemory.
+type Window struct {
+	Offset uint16 // The offset of the window from the start of the device's mapping
+	Size   int    // The size of the window, and of the banks shown in it
+	Bank   int    // The bank shown in the window
+	Banks  int    // The number of banks of this size that the memory holds
+}
+
+// NewBankedRAM creates banked RAM of the given size, with windows of the given sizes laid out one after another.
+// The id identifies the device in snapshots, and must be 4 characters. Window i initially shows bank i (or bank 0
+// if the memory is too small), and the RAM is zero-initialized.
+func NewBankedRAM(id string, size int, windowSizes ...int) (*BankedMemory, error) {
+	return newBankedMemory(id, make([]byte, size), false, windowSizes)
+}
+
+// NewBankedROM creates banked ROM holding an image, with windows of the given sizes laid out one after another, as
+// NewBankedRAM does. Writes are ignored, apart from being passed to OnWrite.
+func NewBankedROM(id string, image []byte, windowSizes ...int) (*BankedMemory, error) {
+	return newBankedMemory(id, image, true, windowSizes)
+}
+
+// LoadBankedROM creates banked ROM holding the contents of an image file.
+func LoadBankedROM(path string, id string, windowSizes ...int) (*BankedMemory, error) {
+	image, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m, err := NewBankedROM(id, image, windowSizes...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+func newBankedMemory(id string, memory []byte, readOnly bool, windowSizes []int) (*BankedMemory, error) {
+	if len(id) != 4 {
+		return nil, fmt.Errorf("banked memory ID %q is not 4 characters", id)
+	}
+	if len(windowSizes) == 0 {
+		return nil, fmt.Errorf("banked memory %q has no windows", id)
+	}
+	m := &BankedMemory{id: id, memory: memory, readOnly: readOnly}
+	offset := 0
+	for i, size := range windowSizes {
+		switch {
+		case size <= 0:
+			return nil, fmt.Errorf("banked memory %q: window %d has a size of %d", id, i, size)
+		case offset+size > 0x10000:
+			return nil, fmt.Errorf("banked memory %q: the windows are larger than 64KB", id)
+		case len(memory) < size || len(memory)%size != 0:
+			return nil, fmt.Errorf("banked memory %q: the memory (%d bytes) is not a whole number of window %d's "+
+				"banks (%d bytes)", id, len(memory), i, size)
+		}
+		banks := len(memory) / size
+		m.windows = append(m.windows, Window{Offset: uint16(offset), Size: size, Bank: i % banks, Banks: banks})
+		offset += size
+	}
+	return m, nil
+}
+
+// Size returns the total size of the windows, which is the size of the device's address range.
+func (m *BankedMemory) Size() int {
+	last := m.windows[len(m.windows)-1]
+	return int(last.Offset) + last.Size
+}
+
+// Mapping returns a mapping that attaches the device at start, covering its windows.
+func (m *BankedMemory) Mapping(name string, start uint16) Mapping {
+	return Mapping{Name: name, Start: start, End: start + uint16(m.Size()-1), Device: m}
+}
+
+// Windows returns the windows, in address order, with the bank each one shows.
+func (m *BankedMemory) Windows() []Window {
+	return append([]Window(nil), m.windows...)
+}
+
+// Bank returns the bank shown in a window.
+func (m *BankedMemory) Bank(window int) int {
+	return m.windows[window].Bank
+}
+
+// Select shows a bank in a window. Bank numbers wrap around at the number of banks, as the unused high bits of a
+// real bank register are ignored.
+func (m *BankedMemory) Select(window, bank int) {
+	w := &m.windows[window]
+	w.Bank = bank % w.Banks
+	if w.Bank < 0 {
+		w.Bank += w.Banks
+	}
+}
+
+// locate returns the index into memory of an offset within the device. Offsets past the last window are mirrored.
+func (m *BankedMemory) locate(addr uint16) int {
+	offset := int(addr) % m.Size()
+	for i := range m.windows {
+		w := &m.windows[i]
+		if offset < int(w.Offset)+w.Size {
+			return w.Bank*w.Size + offset - int(w.Offset)
+		}
+	}
+	panic("unreachable")
+}
+
+// Read returns the byte at the given offset from the bank shown in its window.
+func (m *BankedMemory) Read(addr uint16) byte {
+	return m.memory[m.locate(addr)]
+}
+
+// Write stores a byte at the given offset in the bank shown in its window. Banked ROM ignores the write, and passes
+// it to OnWrite.
+func (m *BankedMemory) Write(addr uint16, data byte) {
+	if m.readOnly {
+		if m.OnWrite != nil {
+			m.OnWrite(addr, data)
+		}
+		return
+	}
+	m.memory[m.locate(addr)] = data
+}
+
+// Poke stores a byte at the given offset in the bank shown in its window, even if the memory is ROM, without calling
+// OnWrite.
+func (m *BankedMemory) Poke(addr uint16, data byte) {
+	m.memory[m.locate(addr)] = data
+}
+
+// Registers returns the control registers, a device with one register for each window, in address order. Writing a
+// bank number to a register selects that bank in its window (see Select), and reading it returns the bank shown.
+// Map it where the program expects to find the registers, for example
+//
+//	b.Map(bus.Mapping{Name: "MMU", Start: 0xFF00, End: 0xFF01, Device: banked.Registers()})
+func (m *BankedMemory) Registers() Bus {
+	return bankRegisters{m}
+}
+
+// bankRegisters is the device returned by BankedMemory.Registers.
+type bankRegisters struct {
+	m *BankedMemory
+}
+
+func (r bankRegisters) Read(addr uint16) byte {
+	return byte(r.m.windows[int(addr)%len(r.m.windows)].Bank)
+}
+
+func (r bankRegisters) Write(addr uint16, data byte) {
+	r.m.Select(int(addr)%len(r.m.windows), int(data))
+}
+
+// SnapshotID returns the ID of the device's section in a snapshot (see the snapshot package).
+func (m *BankedMemory) SnapshotID() string {
+	return m.id
+}
+
+// bankedHeader starts the device's section in a snapshot. It is followed by the bank shown in each window (as
+// uint32s) and the contents of the memory, including banked ROM.
+type bankedHeader struct {
+	Windows uint16
+	Size    uint32
+}
+
+// SaveSnapshot writes the bank shown in each window and the complete contents of the memory to w.
+func (m *BankedMemory) SaveSnapshot(w io.Writer) error {
+	banks := make([]uint32, len(m.windows))
+	for i, window := range m.windows {
+		banks[i] = uint32(window.Bank)
+	}
+	for _, v := range []any{bankedHeader{uint16(len(m.windows)), uint32(len(m.memory))}, banks, m.memory} {
+		if err := binary.Write(w, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadSnapshot restores the banks and the contents of the memory from r. The snapshot must have been taken from a
+// device with the same windows and memory size.
+func (m *BankedMemory) LoadSnapshot(r io.Reader) error {
+	var h bankedHeader
+	if err := binary.Read(r, binary.LittleEndian, &h); err != nil {
+		return err
+	}
+	if int(h.Windows) != len(m.windows) || int(h.Size) != len(m.memory) {
+		return fmt.Errorf("the snapshot has %d windows and %d bytes of memory, but the device has %d and %d",
+			h.Windows, h.Size, len(m.windows), len(m.memory))
+	}
+	banks := make([]uint32, len(m.windows))
+	if err := binary.Read(r, binary.LittleEndian, banks); err != nil {
+		return err
+	}
+	memory := make([]byte, len(m.memory))
+	if _, err := io.ReadFull(r, memory); err != nil {
+		return err
+	}
+	for i, bank := range banks {
+		if int(bank) >= m.windows[i].Banks {
+			return fmt.Errorf("the snapshot shows bank %d in window %d, which has %d banks", bank, i, m.windows[i].Banks)
+		}
+	}
+	for i, bank := range banks {
+		m.windows[i].Bank = int(bank)
+	}
+	copy(m.memory, memory)
+	return nil
+}
@@ -0,0 +1,125 @@
+package bus_test
+
+import (
+	"testing"
+
+	"github.com/ukdave/6502_emulator/bus"
+)
+
+// newBankedBus returns a bus with 64KB of banked RAM in a fixed $4000 window at $8000 and a switched $2000 window
+// at $C000, and its registers at $FF00
+func newBankedBus(t *testing.T) (*bus.MappedBus, *bus.BankedMemory) {
+	banked, err := bus.NewBankedRAM("BANK", 0x10000, 0x4000, 0x2000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := bus.NewMappedBus()
+	for _, m := range []bus.Mapping{
+		banked.Mapping("Banked", 0x8000),
+		{Name: "MMU", Start: 0xFF00, End: 0xFF01, Device: banked.Registers()},
+	} {
+		if err := b.Map(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return b, banked
+}
+
+func TestBankedMemory_Windows(t *testing.T) {
+	_, banked := newBankedBus(t)
+	expected := []bus.Window{{Offset: 0x0000, Size: 0x4000, Bank: 0, Banks: 4}, {Offset: 0x4000, Size: 0x2000, Bank: 1, Banks: 8}}
+	for i, w := range banked.Windows() {
+		if w != expected[i] {
+			t.Errorf("Window %d: expected %+v but got %+v", i, expected[i], w)
+		}
+	}
+	if m := banked.Mapping("Banked", 0x8000); m.End != 0xDFFF {
+		t.Errorf("The mapping should end at $DFFF, but got $%04X", m.End)
+	}
+}
+
+func TestBankedMemory_Select(t *testing.T) {
+	b, banked := newBankedBus(t)
+	for bank := range 8 {
+		banked.Select(1, bank)
+		b.Write(0xC000, byte(0x10+bank))
+	}
+
+	for bank := range 8 {
+		banked.Select(1, bank)
+		if got := b.Read(0xC000); got != byte(0x10+bank) {
+			t.Errorf("Bank %d: expected %v but got %v", bank, 0x10+bank, got)
+		}
+	}
+
+	// Bank 2 of the $2000 window is the start of bank 1 of the $4000 window
+	banked.Select(0, 1)
+	if got := b.Read(0x8000); got != 0x12 {
+		t.Errorf("Expected the windows to share the memory, but got %v", got)
+	}
+
+	banked.Select(1, 9)
+	if got := banked.Bank(1); got != 1 {
+		t.Errorf("Bank numbers should wrap around, but got bank %d", got)
+	}
+}
+
+func TestBankedMemory_Registers(t *testing.T) {
+	b, banked := newBankedBus(t)
+	b.Write(0xFF01, 0x05)
+	if got := banked.Bank(1); got != 5 {
+		t.Errorf("Writing the register should select bank 5, but got %d", got)
+	}
+	if got := b.Read(0xFF01); got != 0x05 {
+		t.Errorf("Reading the register should return bank 5, but got %v", got)
+	}
+	b.Write(0xFF00, 0x03)
+	if got := b.Read(0xFF00); got != 0x03 {
+		t.Errorf("Reading the register should return bank 3, but got %v", got)
+	}
+}
+
+func TestBankedMemory_ROM(t *testing.T) {
+	image := make([]byte, 0x8000)
+	for i := range 4 {
+		image[i*0x2000] = byte(i)
+	}
+	rom, err := bus.NewBankedROM("PRG ", image, 0x2000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var writes []byte
+	rom.OnWrite = func(addr uint16, data byte) {
+		writes = append(writes, data)
+		rom.Select(0, int(data))
+	}
+
+	rom.Write(0x1234, 0x03)
+	if got := rom.Read(0x0000); got != 0x03 {
+		t.Errorf("The write should select bank 3 through OnWrite, but got %v", got)
+	}
+	if len(writes) != 1 || image[0x1234] != 0x00 {
+		t.Errorf("The write should not change the ROM")
+	}
+
+	rom.Poke(0x0001, 0x42)
+	if got := rom.Read(0x0001); got != 0x42 || len(writes) != 1 {
+		t.Errorf("The poke should change bank 3 without calling OnWrite, but got %v", got)
+	}
+}
+
+func TestBankedMemory_Errors(t *testing.T) {
+	for name, create := range map[string]func() (*bus.BankedMemory, error){
+		"a long ID":          func() (*bus.BankedMemory, error) { return bus.NewBankedRAM("BANKS", 0x4000, 0x2000) },
+		"no windows":         func() (*bus.BankedMemory, error) { return bus.NewBankedRAM("BANK", 0x4000) },
+		"a window too large": func() (*bus.BankedMemory, error) { return bus.NewBankedRAM("BANK", 0x4000, 0x8000) },
+		"partial banks":      func() (*bus.BankedMemory, error) { return bus.NewBankedRAM("BANK", 0x5000, 0x2000) },
+		"windows over 64KB": func() (*bus.BankedMemory, error) {
+			return bus.NewBankedRAM("BANK", 0x20000, 0x8000, 0x8000, 0x8000)
+		},
+	} {
+		if _, err := create(); err == nil {
+			t.Errorf("Banked memory with %s should be refused", name)
+		}
+	}
+}
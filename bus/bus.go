@@ -8,3 +8,25 @@ type Bus interface {
 	Write(addr uint16, data byte)
 	Read(addr uint16) byte
 }
+
+// Peeker is implemented by devices whose registers have side effects when they are read, such as a VIA, where
+// reading a register can clear an interrupt. Peek returns what Read would, without the side effects, so that
+// debuggers and memory views can show the device without disturbing it.
+type Peeker interface {
+	Peek(addr uint16) byte
+}
+
+// Poker is implemented by devices whose writes do more than store a byte, such as a ROM that records refused
+// writes. Poke stores the byte without the side effects, so that a debugger or the CPU's rewind history can put a
+// byte back without the device treating it as a write by the program.
+type Poker interface {
+	Poke(addr uint16, data byte)
+}
+
+// LongBus is the 24-bit equivalent of Bus, used by processors such as the 65C816 that can address 16MB of memory.
+// Addresses are passed as uint32 values but only the low 24 bits are significant: the top byte is the bank number
+// and the low 16 bits are the address within that bank.
+type LongBus interface {
+	WriteLong(addr uint32, data byte)
+	ReadLong(addr uint32) byte
+}
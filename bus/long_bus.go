@@ -0,0 +1,67 @@
+package bus
+
+// WrappedBus adapts a 16-bit Bus so that it can be used where a LongBus is required.
+//
+// The bank byte of every address is ignored, so each of the 256 banks is a mirror of the same 64KB. This lets a
+// 24-bit processor running in its 6502 compatible mode share a bus (for example a SimpleBus) with the existing
+// 6502 tooling.
+type WrappedBus struct {
+	Bus
+}
+
+// NewWrappedBus creates a new WrappedBus around the given 16-bit bus.
+func NewWrappedBus(b Bus) *WrappedBus {
+	return &WrappedBus{Bus: b}
+}
+
+// WriteLong stores a single byte at the given 24-bit address. The bank byte is discarded.
+func (b *WrappedBus) WriteLong(addr uint32, data byte) {
+	b.Write(uint16(addr), data)
+}
+
+// ReadLong returns the byte stored at the given 24-bit address. The bank byte is discarded.
+func (b *WrappedBus) ReadLong(addr uint32) byte {
+	return b.Read(uint16(addr))
+}
+
+// SimpleLongBus is the 24-bit equivalent of SimpleBus: a flat 16MB address space backed entirely by RAM.
+//
+// Banks are allocated the first time they are written to, so a program that only touches a few banks does not pay
+// for the full 16MB. Reading from a bank that has never been written returns zero. SimpleLongBus also implements
+// Bus, in which case it behaves like a SimpleBus on bank zero.
+type SimpleLongBus struct {
+	banks [256]*[64 * 1024]byte
+}
+
+// NewSimpleLongBus creates a new SimpleLongBus instance with every byte set to zero.
+func NewSimpleLongBus() *SimpleLongBus {
+	return &SimpleLongBus{}
+}
+
+// WriteLong stores a single byte at the given 24-bit address.
+func (b *SimpleLongBus) WriteLong(addr uint32, data byte) {
+	bank := uint8(addr >> 16)
+	if b.banks[bank] == nil {
+		b.banks[bank] = new([64 * 1024]byte)
+	}
+	b.banks[bank][uint16(addr)] = data
+}
+
+// ReadLong returns the byte stored at the given 24-bit address.
+func (b *SimpleLongBus) ReadLong(addr uint32) byte {
+	bank := b.banks[uint8(addr>>16)]
+	if bank == nil {
+		return 0
+	}
+	return bank[uint16(addr)]
+}
+
+// Write stores a single byte at the given 16-bit address in bank zero.
+func (b *SimpleLongBus) Write(addr uint16, data byte) {
+	b.WriteLong(uint32(addr), data)
+}
+
+// Read returns the byte stored at the given 16-bit address in bank zero.
+func (b *SimpleLongBus) Read(addr uint16) byte {
+	return b.ReadLong(uint32(addr))
+}
@@ -0,0 +1,56 @@
+package bus_test
+
+import (
+	"testing"
+
+	"github.com/ukdave/6502_emulator/bus"
+)
+
+func TestWrappedBus(t *testing.T) {
+	simple := bus.NewSimpleBus()
+	wrapped := bus.NewWrappedBus(simple)
+
+	// Writes through the wrapper land in the underlying bus
+	wrapped.WriteLong(0x001234, 0x42)
+	if got := simple.Read(0x1234); got != 0x42 {
+		t.Errorf("At address 0x1234, expected %v but got %v", 0x42, got)
+	}
+
+	// Every bank mirrors the same 64KB
+	if got := wrapped.ReadLong(0x7F1234); got != 0x42 {
+		t.Errorf("At address 0x7F1234, expected %v but got %v", 0x42, got)
+	}
+}
+
+func TestSimpleLongBus(t *testing.T) {
+	bus := bus.NewSimpleLongBus()
+
+	testCases := []struct {
+		addr uint32
+		data byte
+	}{
+		{0x000000, 0x01},
+		{0x001000, 0x42},
+		{0x011000, 0x43},
+		{0xFFFFFF, 0x99},
+	}
+
+	for _, tc := range testCases {
+		bus.WriteLong(tc.addr, tc.data)
+	}
+	for _, tc := range testCases {
+		if readData := bus.ReadLong(tc.addr); readData != tc.data {
+			t.Errorf("At address 0x%X, expected %v but got %v", tc.addr, tc.data, readData)
+		}
+	}
+
+	// Unallocated banks read as zero
+	if readData := bus.ReadLong(0x800000); readData != 0x00 {
+		t.Errorf("At address 0x800000, expected 0 but got %v", readData)
+	}
+
+	// The 16-bit interface accesses bank zero
+	if readData := bus.Read(0x1000); readData != 0x42 {
+		t.Errorf("At address 0x1000, expected %v but got %v", 0x42, readData)
+	}
+}
@@ -0,0 +1,245 @@
+package bus
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+)
+
+// MappedBus is a Bus that routes each access to the device mapped at its address, as the address decoding logic of
+// a real system does.
+//
+// Any Bus can be mapped as a device (a SimpleBus makes a RAM device). A device sees addresses as offsets from the
+// start of its mapping, so the same device can be mapped anywhere, and a mapping with a Size smaller than its range
+// mirrors the device across the range. Where mappings overlap, the one with the highest Priority receives the
+// access, and of those with the same priority, the one mapped last. Accesses to addresses with no mapping are
+// handled by the bus's unmapped access policies.
+type MappedBus struct {
+	// UnmappedRead decides what reads from unmapped addresses return.
+	UnmappedRead UnmappedReadPolicy
+
+	// UnmappedValue is the value returned by unmapped reads under the UnmappedFixed policy.
+	UnmappedValue byte
+
+	// UnmappedWrite decides what happens to writes to unmapped addresses.
+	UnmappedWrite UnmappedWritePolicy
+
+	// Log receives a line for each write logged by the UnmappedLog policy. Nil means os.Stderr.
+	Log io.Writer
+
+	// OnFault, if not nil, is called for each write faulted by the UnmappedFault policy.
+	OnFault func(err *AccessError)
+
+	mappings []*Mapping     // In order of precedence, lowest first
+	table    [0x10000]uint8 // For each address, the index of its mapping plus one, or zero if it is unmapped
+	last     byte           // The last value on the data bus
+	err      *AccessError
+}
+
+// Mapping attaches a device to a range of addresses on a MappedBus.
+type Mapping struct {
+	Name     string // Identifies the mapping, for example "RAM" or "VIA"
+	Start    uint16 // First address of the range
+	End      uint16 // Last address of the range (inclusive)
+	Size     int    // If not zero, the size of the device, which is mirrored across the range
+	Priority int    // Decides which mapping receives accesses where mappings overlap
+	Device   Bus
+}
+
+// offset returns the address that the device sees for an address in the mapping's range.
+func (m *Mapping) offset(addr uint16) uint16 {
+	offset := addr - m.Start
+	if m.Size != 0 {
+		offset = uint16(int(offset) % m.Size)
+	}
+	return offset
+}
+
+// UnmappedReadPolicy decides what a MappedBus returns for reads from unmapped addresses.
+type UnmappedReadPolicy uint8
+
+const (
+	// UnmappedOpenBus returns the last value that was on the data bus, as most real systems do (the default).
+	UnmappedOpenBus UnmappedReadPolicy = iota
+
+	// UnmappedFixed returns UnmappedValue.
+	UnmappedFixed
+)
+
+// UnmappedWritePolicy decides what a MappedBus does with writes to unmapped addresses.
+type UnmappedWritePolicy uint8
+
+const (
+	// UnmappedIgnore discards the write (the default).
+	UnmappedIgnore UnmappedWritePolicy = iota
+
+	// UnmappedLog discards the write and logs it to Log.
+	UnmappedLog
+
+	// UnmappedFault discards the write, records it as an AccessError (see Err) and calls OnFault.
+	UnmappedFault
+)
+
+// AccessError describes an access that a bus or device refused.
+type AccessError struct {
+	Address uint16
+	Data    byte
+	Write   bool
+	Reason  string // Why the access was refused, for example "unmapped"
+}
+
+func (e *AccessError) Error() string {
+	if e.Write {
+		return fmt.Sprintf("write of $%02X to %s address $%04X", e.Data, e.Reason, e.Address)
+	}
+	return fmt.Sprintf("read from %s address $%04X", e.Reason, e.Address)
+}
+
+// NewMappedBus creates a new MappedBus with nothing mapped.
+func NewMappedBus() *MappedBus {
+	return &MappedBus{}
+}
+
+// Map attaches a device to the bus.
+func (b *MappedBus) Map(m Mapping) error {
+	switch {
+	case m.Device == nil:
+		return fmt.Errorf("mapping %q has no device", m.Name)
+	case m.End < m.Start:
+		return fmt.Errorf("mapping %q ends at $%04X, before it starts at $%04X", m.Name, m.End, m.Start)
+	case m.Size < 0:
+		return fmt.Errorf("mapping %q has a negative size", m.Name)
+	case len(b.mappings) == 255:
+		return fmt.Errorf("mapping %q: too many mappings", m.Name)
+	}
+	b.mappings = append(b.mappings, &m)
+	b.update()
+	return nil
+}
+
+// Unmap removes the mappings with the given name, and returns true if there were any.
+func (b *MappedBus) Unmap(name string) bool {
+	n := len(b.mappings)
+	b.mappings = slices.DeleteFunc(b.mappings, func(m *Mapping) bool { return m.Name == name })
+	b.update()
+	return len(b.mappings) != n
+}
+
+// Mappings returns the mappings, in order of precedence (lowest first).
+func (b *MappedBus) Mappings() []Mapping {
+	mappings := make([]Mapping, len(b.mappings))
+	for i, m := range b.mappings {
+		mappings[i] = *m
+	}
+	return mappings
+}
+
+// MappingAt returns the mapping that receives accesses to an address, and the offset the device sees.
+func (b *MappedBus) MappingAt(addr uint16) (Mapping, uint16, bool) {
+	index := b.table[addr]
+	if index == 0 {
+		return Mapping{}, 0, false
+	}
+	m := b.mappings[index-1]
+	return *m, m.offset(addr), true
+}
+
+// update sorts the mappings by priority and rebuilds the address table.
+func (b *MappedBus) update() {
+	slices.SortStableFunc(b.mappings, func(x, y *Mapping) int { return cmp.Compare(x.Priority, y.Priority) })
+	clear(b.table[:])
+	for i, m := range b.mappings {
+		for addr := int(m.Start); addr <= int(m.End); addr++ {
+			b.table[addr] = uint8(i + 1)
+		}
+	}
+}
+
+// Read returns the byte at the given address from the device mapped there.
+func (b *MappedBus) Read(addr uint16) byte {
+	index := b.table[addr]
+	if index == 0 {
+		if b.UnmappedRead == UnmappedFixed {
+			return b.UnmappedValue
+		}
+		return b.last
+	}
+	m := b.mappings[index-1]
+	b.last = m.Device.Read(m.offset(addr))
+	return b.last
+}
+
+// Peek returns the byte at the given address without any side effects: devices that implement Peeker are peeked
+// rather than read, and the open-bus value is left alone.
+func (b *MappedBus) Peek(addr uint16) byte {
+	index := b.table[addr]
+	if index == 0 {
+		if b.UnmappedRead == UnmappedFixed {
+			return b.UnmappedValue
+		}
+		return b.last
+	}
+	m := b.mappings[index-1]
+	if peeker, ok := m.Device.(Peeker); ok {
+		return peeker.Peek(m.offset(addr))
+	}
+	return m.Device.Read(m.offset(addr))
+}
+
+// Poke stores a byte at the given address without any side effects: devices that implement Poker are poked rather
+// than written, writes to unmapped addresses are dropped without applying UnmappedWrite, and the open-bus value is
+// left alone.
+func (b *MappedBus) Poke(addr uint16, data byte) {
+	index := b.table[addr]
+	if index == 0 {
+		return
+	}
+	m := b.mappings[index-1]
+	if poker, ok := m.Device.(Poker); ok {
+		poker.Poke(m.offset(addr), data)
+		return
+	}
+	m.Device.Write(m.offset(addr), data)
+}
+
+// Write sends a byte to the device mapped at the given address.
+func (b *MappedBus) Write(addr uint16, data byte) {
+	b.last = data
+	index := b.table[addr]
+	if index != 0 {
+		m := b.mappings[index-1]
+		m.Device.Write(m.offset(addr), data)
+		return
+	}
+
+	switch b.UnmappedWrite {
+	case UnmappedLog:
+		w := b.Log
+		if w == nil {
+			w = os.Stderr
+		}
+		fmt.Fprintf(w, "bus: ignored write of $%02X to unmapped address $%04X\n", data, addr)
+	case UnmappedFault:
+		b.fault(&AccessError{Address: addr, Data: data, Write: true, Reason: "unmapped"})
+	}
+}
+
+// fault records a refused access and calls OnFault.
+func (b *MappedBus) fault(err *AccessError) {
+	if b.err == nil {
+		b.err = err
+	}
+	if b.OnFault != nil {
+		b.OnFault(err)
+	}
+}
+
+// Err returns the first access that was faulted, or nil.
+func (b *MappedBus) Err() error {
+	if b.err == nil {
+		return nil
+	}
+	return b.err
+}
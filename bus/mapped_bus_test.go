@@ -0,0 +1,188 @@
+package bus_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ukdave/6502_emulator/bus"
+)
+
+// newNESBus returns a bus with 2KB of RAM mirrored across $0000-$1FFF, like the NES, and 32KB of RAM at $8000
+func newNESBus(t *testing.T) (*bus.MappedBus, *bus.SimpleBus, *bus.SimpleBus) {
+	b := bus.NewMappedBus()
+	ram, cartridge := bus.NewSimpleBus(), bus.NewSimpleBus()
+	for _, m := range []bus.Mapping{
+		{Name: "RAM", Start: 0x0000, End: 0x1FFF, Size: 0x0800, Device: ram},
+		{Name: "Cartridge", Start: 0x8000, End: 0xFFFF, Device: cartridge},
+	} {
+		if err := b.Map(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return b, ram, cartridge
+}
+
+func TestMappedBus_Routing(t *testing.T) {
+	b, ram, cartridge := newNESBus(t)
+
+	b.Write(0x1801, 0x42)
+	if got := ram.Read(0x0001); got != 0x42 {
+		t.Errorf("The write to $1801 should reach RAM offset $0001, but got %v", got)
+	}
+	for _, addr := range []uint16{0x0001, 0x0801, 0x1001, 0x1801} {
+		if got := b.Read(addr); got != 0x42 {
+			t.Errorf("At mirrored address 0x%X, expected %v but got %v", addr, 0x42, got)
+		}
+	}
+
+	b.Write(0x8123, 0x99)
+	if got := cartridge.Read(0x0123); got != 0x99 {
+		t.Errorf("The write to $8123 should reach cartridge offset $0123, but got %v", got)
+	}
+
+	m, offset, ok := b.MappingAt(0x1FFF)
+	if !ok || m.Name != "RAM" || offset != 0x07FF {
+		t.Errorf("$1FFF should be RAM offset $07FF, but got %q offset 0x%X", m.Name, offset)
+	}
+	if _, _, ok := b.MappingAt(0x4000); ok {
+		t.Errorf("$4000 should be unmapped")
+	}
+}
+
+func TestMappedBus_Priority(t *testing.T) {
+	b, _, _ := newNESBus(t)
+	io := bus.NewSimpleBus()
+	if err := b.Map(bus.Mapping{Name: "IO", Start: 0x1000, End: 0x100F, Priority: 1, Device: io}); err != nil {
+		t.Fatal(err)
+	}
+	low := bus.NewSimpleBus()
+	if err := b.Map(bus.Mapping{Name: "Low", Start: 0x0000, End: 0x1FFF, Priority: -1, Device: low}); err != nil {
+		t.Fatal(err)
+	}
+
+	b.Write(0x1005, 0x11)
+	if got := io.Read(0x0005); got != 0x11 {
+		t.Errorf("The higher priority mapping should receive the write, but got %v", got)
+	}
+	b.Write(0x1010, 0x22)
+	if got := b.Read(0x0010); got != 0x22 {
+		t.Errorf("Outside the IO range the RAM should receive the write, but got %v", got)
+	}
+	if got := low.Read(0x1010); got != 0x00 {
+		t.Errorf("The lower priority mapping should not receive the write, but got %v", got)
+	}
+
+	if !b.Unmap("IO") {
+		t.Errorf("Unmap should find the IO mapping")
+	}
+	if m, _, _ := b.MappingAt(0x1005); m.Name != "RAM" {
+		t.Errorf("Once IO is unmapped $1005 should be RAM, but got %q", m.Name)
+	}
+}
+
+func TestMappedBus_UnmappedReads(t *testing.T) {
+	b, _, _ := newNESBus(t)
+	b.Write(0x0000, 0x5A)
+	b.Read(0x0000)
+	if got := b.Read(0x4000); got != 0x5A {
+		t.Errorf("An open bus read should return the last value on the bus, %v, but got %v", 0x5A, got)
+	}
+
+	b.UnmappedRead, b.UnmappedValue = bus.UnmappedFixed, 0xFF
+	if got := b.Read(0x4000); got != 0xFF {
+		t.Errorf("A fixed unmapped read should return %v, but got %v", 0xFF, got)
+	}
+}
+
+// register is a device whose register is cleared by reading it, and which can be peeked without clearing it
+type register struct {
+	value byte
+}
+
+func (r *register) Read(addr uint16) byte {
+	value := r.value
+	r.value = 0
+	return value
+}
+
+func (r *register) Write(addr uint16, data byte) {
+	r.value = data
+}
+
+func (r *register) Peek(addr uint16) byte {
+	return r.value
+}
+
+func TestMappedBus_Peek(t *testing.T) {
+	b, _, _ := newNESBus(t)
+	reg := &register{}
+	if err := b.Map(bus.Mapping{Name: "Register", Start: 0x4000, End: 0x4000, Device: reg}); err != nil {
+		t.Fatal(err)
+	}
+	b.Write(0x0801, 0x42)
+	b.Write(0x4000, 0x99)
+	if got := b.Peek(0x0001); got != 0x42 {
+		t.Errorf("Peeking a device without Peek should read it, expected %v but got %v", 0x42, got)
+	}
+	if got := b.Peek(0x4000); got != 0x99 || reg.value != 0x99 {
+		t.Errorf("Peeking the register should not clear it, but got %v and left %v", got, reg.value)
+	}
+	if got := b.Peek(0x5000); got != 0x99 {
+		t.Errorf("Peeking an unmapped address should return the open bus value, %v, but got %v", 0x99, got)
+	}
+	if got := b.Read(0x4000); got != 0x99 || reg.value != 0 {
+		t.Errorf("Reading the register should clear it, but got %v and left %v", got, reg.value)
+	}
+}
+
+func TestMappedBus_Poke(t *testing.T) {
+	b, ram, _ := newNESBus(t)
+	b.UnmappedWrite, b.OnFault = bus.UnmappedFault, func(err *bus.AccessError) { t.Errorf("Unexpected fault %v", err) }
+	b.Poke(0x0801, 0x42)
+	if got := ram.Read(0x0001); got != 0x42 {
+		t.Errorf("Poking a device without Poke should write it, expected %v but got %v", 0x42, got)
+	}
+	b.Poke(0x4000, 0x99)
+	if b.Err() != nil {
+		t.Errorf("Poking an unmapped address should not be faulted")
+	}
+}
+
+func TestMappedBus_UnmappedWrites(t *testing.T) {
+	b, _, _ := newNESBus(t)
+	b.Write(0x4000, 0x01)
+	if b.Err() != nil {
+		t.Errorf("Ignored writes should not be faulted")
+	}
+
+	var log bytes.Buffer
+	b.UnmappedWrite, b.Log = bus.UnmappedLog, &log
+	b.Write(0x4001, 0x02)
+	if got := log.String(); got != "bus: ignored write of $02 to unmapped address $4001\n" {
+		t.Errorf("The write should be logged, but got %q", got)
+	}
+
+	var faults []*bus.AccessError
+	b.UnmappedWrite, b.OnFault = bus.UnmappedFault, func(err *bus.AccessError) { faults = append(faults, err) }
+	b.Write(0x4002, 0x03)
+	b.Write(0x4003, 0x04)
+	if len(faults) != 2 {
+		t.Errorf("Each write should be faulted, but got %d faults", len(faults))
+	}
+	if err := b.Err(); err == nil || err.Error() != "write of $03 to unmapped address $4002" {
+		t.Errorf("Err should return the first fault, but got %v", err)
+	}
+}
+
+func TestMappedBus_MapErrors(t *testing.T) {
+	b := bus.NewMappedBus()
+	if err := b.Map(bus.Mapping{Name: "RAM", Start: 0x2000, End: 0x1FFF, Device: bus.NewSimpleBus()}); err == nil {
+		t.Errorf("A mapping that ends before it starts should be refused")
+	}
+	if err := b.Map(bus.Mapping{Name: "RAM", Start: 0x0000, End: 0x1FFF}); err == nil {
+		t.Errorf("A mapping without a device should be refused")
+	}
+	if len(b.Mappings()) != 0 {
+		t.Errorf("Refused mappings should not be added")
+	}
+}
@@ -0,0 +1,144 @@
+package bus
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ROM is a read-only memory device for a MappedBus, holding an image that is usually loaded from a file.
+//
+// A ROM is created for a fixed address, and its Mapping attaches it there. Writes never change its contents: what
+// else happens to them is decided by its write policy.
+type ROM struct {
+	// Writes decides what happens to writes to the ROM.
+	Writes ROMWritePolicy
+
+	// Stop is called for each write refused under the ROMStop policy. It is usually the Fault method of the CPU, which
+	// halts the CPU and names the instruction that made the write.
+	Stop func(err error)
+
+	start      uint16
+	data       []byte
+	violations []*AccessError // The first maxViolations writes refused under ROMRecord or ROMStop
+	count      int            // The number of writes refused under ROMRecord or ROMStop
+}
+
+// maxViolations is the number of refused writes a ROM keeps, so that a runaway loop cannot use up the memory.
+const maxViolations = 256
+
+// ROMWritePolicy decides what a ROM does with writes.
+type ROMWritePolicy uint8
+
+const (
+	// ROMIgnore discards the write, as real ROM does (the default).
+	ROMIgnore ROMWritePolicy = iota
+
+	// ROMRecord discards the write and records it as a violation (see Violations).
+	ROMRecord
+
+	// ROMStop discards the write, records it as a violation and calls Stop.
+	ROMStop
+)
+
+var romWritePolicyNames = map[ROMWritePolicy]string{
+	ROMIgnore: "ignore",
+	ROMRecord: "record",
+	ROMStop:   "stop",
+}
+
+// String returns the short name of the policy, as accepted by ParseROMWritePolicy.
+func (p ROMWritePolicy) String() string {
+	if name, ok := romWritePolicyNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("ROMWritePolicy(%d)", uint8(p))
+}
+
+// ParseROMWritePolicy returns the policy with the given (case-insensitive) name.
+func ParseROMWritePolicy(name string) (ROMWritePolicy, error) {
+	for p, n := range romWritePolicyNames {
+		if strings.EqualFold(n, name) {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown ROM write policy %q", name)
+}
+
+// NewROM creates a ROM holding data, to be mapped at start. The data must fit between start and $FFFF.
+func NewROM(start uint16, data []byte) (*ROM, error) {
+	switch {
+	case len(data) == 0:
+		return nil, fmt.Errorf("ROM at $%04X is empty", start)
+	case int(start)+len(data) > 0x10000:
+		return nil, fmt.Errorf("ROM at $%04X is %d bytes, which runs past $FFFF", start, len(data))
+	}
+	return &ROM{start: start, data: data}, nil
+}
+
+// LoadROM creates a ROM holding the contents of an image file, to be mapped at start.
+func LoadROM(path string, start uint16) (*ROM, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rom, err := NewROM(start, data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return rom, nil
+}
+
+// Mapping returns a mapping that attaches the ROM at its address. Its priority is 1, so that it overlays a RAM
+// mapping with the default priority.
+func (r *ROM) Mapping(name string) Mapping {
+	return Mapping{Name: name, Start: r.start, End: r.start + uint16(len(r.data)-1), Priority: 1, Device: r}
+}
+
+// Start returns the address the ROM is mapped at.
+func (r *ROM) Start() uint16 {
+	return r.start
+}
+
+// Len returns the size of the ROM in bytes.
+func (r *ROM) Len() int {
+	return len(r.data)
+}
+
+// Read returns the byte at the given offset. The image is mirrored if the ROM is mapped across a larger range.
+func (r *ROM) Read(addr uint16) byte {
+	return r.data[int(addr)%len(r.data)]
+}
+
+// Write refuses the write, applying the write policy. The address in a violation is the one the ROM was created
+// for, so it is only the address the CPU wrote to if the ROM is not mirrored.
+func (r *ROM) Write(addr uint16, data byte) {
+	if r.Writes == ROMIgnore {
+		return
+	}
+	offset := uint16(int(addr) % len(r.data))
+	err := &AccessError{Address: r.start + offset, Data: data, Write: true, Reason: "read-only"}
+	r.count++
+	if len(r.violations) < maxViolations {
+		r.violations = append(r.violations, err)
+	}
+	if r.Writes == ROMStop && r.Stop != nil {
+		r.Stop(err)
+	}
+}
+
+// Poke stores a byte in the image at the given offset, bypassing the write policy.
+func (r *ROM) Poke(addr uint16, data byte) {
+	r.data[int(addr)%len(r.data)] = data
+}
+
+// Violations returns the writes that were refused under the ROMRecord or ROMStop policies, oldest first. Only the
+// first 256 are kept; ViolationCount returns the total.
+func (r *ROM) Violations() []*AccessError {
+	return r.violations
+}
+
+// ViolationCount returns the number of writes that were refused under the ROMRecord or ROMStop policies.
+func (r *ROM) ViolationCount() int {
+	return r.count
+}
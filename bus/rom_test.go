@@ -0,0 +1,125 @@
+package bus_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ukdave/6502_emulator/bus"
+)
+
+// newROMBus returns a bus with RAM across the whole address space and a 4 byte ROM at $C000
+func newROMBus(t *testing.T) (*bus.MappedBus, *bus.SimpleBus, *bus.ROM) {
+	rom, err := bus.NewROM(0xC000, []byte{0x11, 0x22, 0x33, 0x44})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, ram := bus.NewMappedBus(), bus.NewSimpleBus()
+	for _, m := range []bus.Mapping{{Name: "RAM", Start: 0x0000, End: 0xFFFF, Device: ram}, rom.Mapping("ROM")} {
+		if err := b.Map(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return b, ram, rom
+}
+
+func TestROM_Read(t *testing.T) {
+	b, _, _ := newROMBus(t)
+	b.Write(0xC004, 0x55)
+	for addr, expected := range map[uint16]byte{0xC000: 0x11, 0xC003: 0x44, 0xC004: 0x55} {
+		if got := b.Read(addr); got != expected {
+			t.Errorf("At address 0x%X, expected %v but got %v", addr, expected, got)
+		}
+	}
+}
+
+func TestROM_WritePolicies(t *testing.T) {
+	b, ram, rom := newROMBus(t)
+	b.Write(0xC001, 0x99)
+	if got := b.Read(0xC001); got != 0x22 {
+		t.Errorf("The write should not change the ROM, but got %v", got)
+	}
+	if got := ram.Read(0xC001); got != 0x00 {
+		t.Errorf("The write should not reach the RAM under the ROM, but got %v", got)
+	}
+	if rom.ViolationCount() != 0 {
+		t.Errorf("Ignored writes should not be recorded")
+	}
+
+	rom.Writes = bus.ROMRecord
+	b.Write(0xC002, 0x98)
+	if got := rom.Violations(); len(got) != 1 || got[0].Error() != "write of $98 to read-only address $C002" {
+		t.Errorf("The write should be recorded, but got %v", got)
+	}
+
+	var stopped []error
+	rom.Writes, rom.Stop = bus.ROMStop, func(err error) { stopped = append(stopped, err) }
+	b.Write(0xC003, 0x97)
+	if len(stopped) != 1 || stopped[0].Error() != "write of $97 to read-only address $C003" {
+		t.Errorf("Stop should be called with the write, but got %v", stopped)
+	}
+	if rom.ViolationCount() != 2 {
+		t.Errorf("Expected 2 violations but got %d", rom.ViolationCount())
+	}
+	if got := b.Read(0xC003); got != 0x44 {
+		t.Errorf("The write should not change the ROM, but got %v", got)
+	}
+}
+
+func TestROM_Poke(t *testing.T) {
+	b, _, rom := newROMBus(t)
+	stopped := 0
+	rom.Writes, rom.Stop = bus.ROMStop, func(error) { stopped++ }
+	b.Poke(0xC001, 0x99)
+	if got := b.Read(0xC001); got != 0x99 {
+		t.Errorf("The poke should change the ROM, but got %v", got)
+	}
+	if rom.ViolationCount() != 0 || stopped != 0 {
+		t.Errorf("The poke should not be treated as a write")
+	}
+}
+
+func TestROM_Violations(t *testing.T) {
+	_, _, rom := newROMBus(t)
+	rom.Writes = bus.ROMRecord
+	for i := range 1000 {
+		rom.Write(uint16(i%4), byte(i))
+	}
+	if rom.ViolationCount() != 1000 {
+		t.Errorf("Expected 1000 violations but got %d", rom.ViolationCount())
+	}
+	if got := len(rom.Violations()); got != 256 {
+		t.Errorf("Only the first 256 violations should be kept, but got %d", got)
+	}
+}
+
+func TestLoadROM(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rom.bin")
+	if err := os.WriteFile(path, []byte{0xEA, 0xEA, 0x60}, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rom, err := bus.LoadROM(path, 0xFFFD)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := rom.Mapping("BIOS"); m.Start != 0xFFFD || m.End != 0xFFFF || m.Priority != 1 {
+		t.Errorf("The mapping should cover $FFFD-$FFFF with priority 1, but got %+v", m)
+	}
+
+	if _, err := bus.LoadROM(path, 0xFFFE); err == nil {
+		t.Errorf("A ROM that runs past $FFFF should be refused")
+	}
+	if _, err := bus.NewROM(0x8000, nil); err == nil {
+		t.Errorf("An empty ROM should be refused")
+	}
+}
+
+func TestParseROMWritePolicy(t *testing.T) {
+	policy, err := bus.ParseROMWritePolicy("Stop")
+	if err != nil || policy != bus.ROMStop {
+		t.Errorf("Expected ROMStop but got %v, %v", policy, err)
+	}
+	if _, err := bus.ParseROMWritePolicy("panic"); err == nil {
+		t.Errorf("An unknown policy should be refused")
+	}
+}
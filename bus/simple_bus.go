@@ -1,10 +1,12 @@
 package bus
 
+import "io"
+
 // SimpleBus is a minimal concrete implementation of the Bus interface.
 //
 // It models a flat 64KB address space backed entirely by RAM, which is sufficient for basic CPU emulation
-// and testing. In a more complete emulator, the bus would be responsible for routing reads and writes to
-// different devices (RAM, ROM, PPU, I/O registers, etc.) based on the address.
+// and testing. To route reads and writes to different devices (RAM, ROM, I/O registers, etc.) based on the
+// address, use a MappedBus instead, with a SimpleBus mapped as its RAM.
 type SimpleBus struct {
 	ram [64 * 1024]byte // Fake RAM (64KB)
 }
@@ -18,16 +20,33 @@ func NewSimpleBus() *SimpleBus {
 
 // Write stores a single byte at the given 16-bit address.
 //
-// In this simple implementation, the address maps directly to RAM. Future bus implementations may intercept
-// or redirect writes to memory-mapped devices instead.
+// In this simple implementation, the address maps directly to RAM. A MappedBus routes writes to memory-mapped
+// devices instead.
 func (b *SimpleBus) Write(addr uint16, data byte) {
 	b.ram[addr] = data
 }
 
 // Read returns the byte stored at the given 16-bit address.
 //
-// As with Write, this currently performs a direct RAM access. More advanced buses may return data from ROM
-// or peripheral devices depending on the address range.
+// As with Write, this performs a direct RAM access. A MappedBus returns data from ROM or peripheral devices
+// depending on the address range.
 func (b *SimpleBus) Read(addr uint16) byte {
 	return b.ram[addr]
 }
+
+// SnapshotID returns the ID of the RAM's section in a snapshot (see the snapshot package).
+func (b *SimpleBus) SnapshotID() string {
+	return "RAM "
+}
+
+// SaveSnapshot writes the complete contents of the RAM to w.
+func (b *SimpleBus) SaveSnapshot(w io.Writer) error {
+	_, err := w.Write(b.ram[:])
+	return err
+}
+
+// LoadSnapshot replaces the contents of the RAM with the 64KB read from r.
+func (b *SimpleBus) LoadSnapshot(r io.Reader) error {
+	_, err := io.ReadFull(r, b.ram[:])
+	return err
+}
@@ -0,0 +1,25 @@
+// Package cputest sets up CPUs running small programs, for the tests of the packages that use the processor package.
+package cputest
+
+import (
+	"github.com/ukdave/6502_emulator/bus"
+	"github.com/ukdave/6502_emulator/processor"
+)
+
+// New creates a CPU with 64KB of RAM and the given program at addr, with the Program Counter pointing at it. The RAM
+// is returned too, for tests that set up more of memory.
+func New(variant processor.Variant, addr uint16, program ...byte) (*processor.CPU, *bus.SimpleBus) {
+	ram := bus.NewSimpleBus()
+	cpu := processor.NewCPUWithVariant(ram, variant)
+	Load(cpu, ram, addr, program...)
+	return cpu, ram
+}
+
+// Load writes a program to the bus at addr and points the CPU's Program Counter at it. The program is written
+// directly to the bus, not through the CPU, so it is not seen by hooks or rewind history.
+func Load(cpu *processor.CPU, b bus.Bus, addr uint16, program ...byte) {
+	for i, data := range program {
+		b.Write(addr+uint16(i), data)
+	}
+	cpu.PC = addr
+}
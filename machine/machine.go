@@ -0,0 +1,200 @@
+// Package machine advances a CPU and the devices around it in step with each other.
+//
+// A Machine owns a CPU and a set of clocked devices (anything that implements Clockable, such as timers, serial ports
+// or video chips). Each device is ticked in step with the CPU's TotalCycles, at its own rate relative to the CPU
+// clock (see Divider), and callbacks can be scheduled for any cycle count. Running the machine rather than the CPU
+// advances everything together, so a program and its devices behave the same way every time, whether they are run
+// from the TUI or headless.
+//
+// The machine runs the CPU in chunks that end on the next cycle a device is ticked or an event is due, so the CPU
+// keeps its fast paths (see processor.CPU.RunCycles) when the devices are slow or idle.
+package machine
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/ukdave/6502_emulator/processor"
+)
+
+// Clockable is implemented by devices that advance in time with the CPU.
+type Clockable interface {
+	// Tick advances the device by one cycle of its own clock.
+	Tick()
+}
+
+// Divider relates a device's clock to the CPU's: the device is ticked Ticks times every Cycles CPU cycles. The ticks
+// are spread as evenly as possible, and always fall at the end of a CPU cycle.
+type Divider struct {
+	Ticks  uint64
+	Cycles uint64
+}
+
+// CPUClock ticks a device once every CPU cycle, as for a device clocked by the CPU's φ2 output.
+var CPUClock = Divider{Ticks: 1, Cycles: 1}
+
+// DivideBy ticks a device once every n CPU cycles.
+func DivideBy(n uint64) Divider {
+	return Divider{Ticks: 1, Cycles: n}
+}
+
+// MultiplyBy ticks a device n times every CPU cycle, for devices with a faster clock than the CPU's (such as the NES
+// PPU, which runs at 3 times the speed of its CPU).
+func MultiplyBy(n uint64) Divider {
+	return Divider{Ticks: n, Cycles: 1}
+}
+
+// String returns the divider as a ratio of device ticks to CPU cycles, for example "1:4".
+func (d Divider) String() string {
+	return fmt.Sprintf("%d:%d", d.Ticks, d.Cycles)
+}
+
+// device is a Clockable attached to a machine.
+type device struct {
+	clockable Clockable
+	divider   Divider
+	phase     uint64 // Ticks owed, in units of 1/Cycles of a tick
+}
+
+// due returns the number of cycles until the device's next tick (at least 1).
+func (d *device) due() uint64 {
+	need := d.divider.Cycles - d.phase
+	return max(1, (need+d.divider.Ticks-1)/d.divider.Ticks)
+}
+
+// advance ticks the device for the given number of cycles.
+func (d *device) advance(cycles uint64) {
+	owed := d.phase + cycles*d.divider.Ticks
+	d.phase = owed % d.divider.Cycles
+	for range owed / d.divider.Cycles {
+		d.clockable.Tick()
+	}
+}
+
+// Machine runs a CPU together with its clocked devices and scheduled events.
+type Machine struct {
+	CPU *processor.CPU
+
+	devices []*device
+	synced  uint64 // The cycle count the devices have been ticked up to
+	events  eventQueue
+	seq     uint64
+}
+
+// New creates a machine for a CPU, with no devices attached.
+func New(cpu *processor.CPU) *Machine {
+	return &Machine{CPU: cpu, synced: cpu.TotalCycles}
+}
+
+// Attach adds a device, ticked at the rate given by the divider from the next CPU cycle. Devices are ticked in the
+// order they were attached.
+func (m *Machine) Attach(clockable Clockable, divider Divider) error {
+	if divider.Ticks == 0 || divider.Cycles == 0 {
+		return fmt.Errorf("invalid clock divider %v", divider)
+	}
+	m.sync()
+	m.devices = append(m.devices, &device{clockable: clockable, divider: divider})
+	return nil
+}
+
+// Detach removes a device, and returns true if it was attached.
+func (m *Machine) Detach(clockable Clockable) bool {
+	m.sync()
+	n := len(m.devices)
+	m.devices = slices.DeleteFunc(m.devices, func(d *device) bool { return d.clockable == clockable })
+	return len(m.devices) != n
+}
+
+// sync ticks the devices for the cycles the CPU has run since they were last ticked, for example by a caller
+// clocking the CPU directly. The ticks are all made at once, so this is only exact if no device was due.
+func (m *Machine) sync() {
+	if m.CPU.TotalCycles < m.synced {
+		// The CPU was rewound or restored from a snapshot: time starts again from here
+		m.synced = m.CPU.TotalCycles
+	}
+	if elapsed := m.CPU.TotalCycles - m.synced; elapsed > 0 {
+		for _, d := range m.devices {
+			d.advance(elapsed)
+		}
+		m.synced = m.CPU.TotalCycles
+	}
+}
+
+// next returns the cycle count at which the next device tick or event is due, or math.MaxUint64 if there are none.
+func (m *Machine) next() uint64 {
+	next := uint64(math.MaxUint64)
+	for _, d := range m.devices {
+		next = min(next, m.synced+d.due())
+	}
+	if len(m.events) > 0 {
+		next = min(next, max(m.events[0].at, m.synced+1))
+	}
+	return next
+}
+
+// Finished returns true if the CPU has stopped making progress for good. With no devices attached and no events
+// scheduled, that is whenever processor.CPU.HaltReason reports a reason. Otherwise a device or event can still
+// interrupt a CPU that is waiting in WAI or a self-loop, so only a halted CPU or a BRK through an unset vector has
+// finished.
+func (m *Machine) Finished() bool {
+	if len(m.devices) == 0 && len(m.events) == 0 {
+		return m.CPU.HaltReason() != processor.HaltNone
+	}
+	return m.CPU.Halted() || m.CPU.HaltReason() == processor.HaltBRKZeroVector
+}
+
+// Clock runs a single CPU cycle, then ticks the devices and runs the events due on that cycle.
+func (m *Machine) Clock() {
+	m.sync()
+	m.CPU.Clock()
+	m.sync()
+	m.runEvents()
+}
+
+// Step runs the machine until the CPU's current instruction (or interrupt sequence) has completed, as
+// processor.CPU.Step does, and returns the number of cycles run. If the CPU is halted, waiting for an interrupt or
+// stalled, Step runs a single cycle, so the devices still advance.
+func (m *Machine) Step() uint64 {
+	start := m.CPU.TotalCycles
+	m.Clock()
+	for m.CPU.Cycles() > 0 && !m.CPU.Stalled() {
+		m.Clock()
+	}
+	return m.CPU.TotalCycles - start
+}
+
+// RunCycles runs the machine for exactly n CPU cycles and returns n.
+func (m *Machine) RunCycles(n uint64) uint64 {
+	m.sync()
+	target := m.CPU.TotalCycles + n
+	for m.CPU.TotalCycles < target {
+		m.CPU.RunCycles(min(target, m.next()) - m.CPU.TotalCycles)
+		m.sync()
+		m.runEvents()
+	}
+	return n
+}
+
+// RunUntil runs the machine until stop returns true, the CPU halts or maxCycles CPU cycles have been run (0 means no
+// limit), as processor.CPU.RunUntil does, and returns the number of cycles run. A CPU waiting for an interrupt keeps
+// running, so that a device can wake it.
+func (m *Machine) RunUntil(stop func(*processor.CPU) bool, maxCycles uint64) uint64 {
+	m.sync()
+	start := m.CPU.TotalCycles
+	target := uint64(math.MaxUint64)
+	if maxCycles != 0 {
+		target = start + maxCycles
+	}
+	for m.CPU.TotalCycles < target {
+		chunk := min(target, m.next()) - m.CPU.TotalCycles
+		ran := m.CPU.RunUntil(stop, chunk)
+		m.sync()
+		m.runEvents()
+		if ran < chunk {
+			// The CPU stopped or halted before the end of the chunk
+			break
+		}
+	}
+	return m.CPU.TotalCycles - start
+}
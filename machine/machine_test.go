@@ -0,0 +1,164 @@
+package machine_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ukdave/6502_emulator/internal/cputest"
+	"github.com/ukdave/6502_emulator/machine"
+	"github.com/ukdave/6502_emulator/processor"
+)
+
+// counter records the CPU cycle count at each of its ticks
+type counter struct {
+	cpu   *processor.CPU
+	ticks []uint64
+}
+
+func (c *counter) Tick() {
+	c.ticks = append(c.ticks, c.cpu.TotalCycles)
+}
+
+// newMachine returns a machine for a 65C02 running the program at $8000, with the IRQ vector pointing at $9000
+func newMachine(program ...byte) *machine.Machine {
+	cpu, ram := cputest.New(processor.Variant65C02, 0x8000, program...)
+	ram.Write(0xFFFE, 0x00)
+	ram.Write(0xFFFF, 0x90)
+	return machine.New(cpu)
+}
+
+// loop is a program that increments X forever
+var loop = []byte{0xE8, 0x4C, 0x00, 0x80} // INX; JMP $8000
+
+func TestMachine_Dividers(t *testing.T) {
+	dividers := []machine.Divider{machine.CPUClock, machine.DivideBy(4), machine.MultiplyBy(3), {Ticks: 2, Cycles: 3}}
+	run := func(run func(m *machine.Machine)) [][]uint64 {
+		m := newMachine(loop...)
+		counters := make([]*counter, len(dividers))
+		for i, d := range dividers {
+			counters[i] = &counter{cpu: m.CPU}
+			assert.NoError(t, m.Attach(counters[i], d))
+		}
+		run(m)
+		ticks := make([][]uint64, len(counters))
+		for i, c := range counters {
+			ticks[i] = c.ticks
+		}
+		return ticks
+	}
+
+	clocked := run(func(m *machine.Machine) {
+		for range 12 {
+			m.Clock()
+		}
+	})
+	assert.Len(t, clocked[0], 12, "A device on the CPU clock should be ticked every cycle")
+	assert.Equal(t, []uint64{4, 8, 12}, clocked[1], "A device divided by 4 should be ticked every 4th cycle")
+	assert.Len(t, clocked[2], 36, "A device multiplied by 3 should be ticked 3 times a cycle")
+	assert.Equal(t, []uint64{2, 3, 5, 6, 8, 9, 11, 12}, clocked[3], "A 2:3 device should be ticked twice every 3 cycles")
+
+	assert.Equal(t, clocked, run(func(m *machine.Machine) { m.RunCycles(12) }),
+		"RunCycles should tick the devices on the same cycles as Clock")
+	assert.Equal(t, clocked, run(func(m *machine.Machine) {
+		m.RunUntil(func(*processor.CPU) bool { return false }, 12)
+	}), "RunUntil should tick the devices on the same cycles as Clock")
+}
+
+func TestMachine_AttachDetach(t *testing.T) {
+	m := newMachine(loop...)
+	c := &counter{cpu: m.CPU}
+	assert.Error(t, m.Attach(c, machine.Divider{Ticks: 1}), "A divider with no cycles should be refused")
+
+	m.RunCycles(10)
+	assert.NoError(t, m.Attach(c, machine.DivideBy(5)))
+	m.RunCycles(10)
+	assert.Equal(t, []uint64{15, 20}, c.ticks, "The device should be ticked from when it was attached")
+
+	assert.True(t, m.Detach(c))
+	assert.False(t, m.Detach(c), "The device should already be detached")
+	m.RunCycles(10)
+	assert.Len(t, c.ticks, 2, "A detached device should not be ticked")
+}
+
+func TestMachine_Events(t *testing.T) {
+	m := newMachine(loop...)
+	var fired []string
+	record := func(name string) func() {
+		return func() { fired = append(fired, fmt.Sprintf("%s@%d", name, m.CPU.TotalCycles)) }
+	}
+	m.Schedule(5, record("b"))
+	first := m.Schedule(3, record("a"))
+	m.Schedule(5, record("c"))
+	cancelled := m.Schedule(4, record("x"))
+	assert.True(t, m.Cancel(cancelled))
+	assert.False(t, m.Cancel(cancelled), "The event should already be cancelled")
+
+	m.RunCycles(6)
+	assert.Equal(t, []string{"a@3", "b@5", "c@5"}, fired, "Events should run in order, on the cycle they are due")
+	assert.False(t, first.Pending(), "The event should have run")
+	assert.False(t, m.Cancel(first), "An event that has run cannot be cancelled")
+
+	fired = nil
+	m.Schedule(2, record("late"))
+	m.Clock()
+	assert.Equal(t, []string{"late@7"}, fired, "An event that is already due should run after the next cycle")
+}
+
+func TestMachine_RepeatingEvent(t *testing.T) {
+	m := newMachine(loop...)
+	var at []uint64
+	var repeat func()
+	repeat = func() {
+		at = append(at, m.CPU.TotalCycles)
+		m.ScheduleIn(100, repeat)
+	}
+	m.Schedule(100, repeat)
+	m.RunUntil(func(*processor.CPU) bool { return false }, 1000)
+	assert.Equal(t, []uint64{100, 200, 300, 400, 500, 600, 700, 800, 900, 1000}, at)
+}
+
+func TestMachine_WakeFromWAI(t *testing.T) {
+	m := newMachine(
+		0x58, // 8000 CLI
+		0xCB, // 8001 WAI
+		0xDB, // 8002 STP
+	)
+	m.CPU.Write(0x9000, 0xA9) // 9000 LDA #$42
+	m.CPU.Write(0x9001, 0x42)
+	m.CPU.Write(0x9002, 0xDB) // 9002 STP
+	m.Schedule(500, func() { m.CPU.AssertIRQ(1) })
+
+	m.RunUntil(func(*processor.CPU) bool { return false }, 10000)
+	assert.True(t, m.CPU.Halted(), "The CPU should have stopped in the interrupt handler")
+	assert.Equal(t, uint8(0x42), m.CPU.A, "The interrupt handler should have run")
+	assert.Greater(t, m.CPU.TotalCycles, uint64(500), "The CPU should have waited for the interrupt")
+}
+
+func TestMachine_Step(t *testing.T) {
+	m := newMachine(loop...)
+	c := &counter{cpu: m.CPU}
+	assert.NoError(t, m.Attach(c, machine.CPUClock))
+	assert.Equal(t, uint64(2), m.Step(), "INX should take 2 cycles")
+	assert.Equal(t, uint64(3), m.Step(), "JMP should take 3 cycles")
+	assert.Equal(t, []uint64{1, 2, 3, 4, 5}, c.ticks, "The device should be ticked on each cycle of the instructions")
+}
+
+func TestMachine_Finished(t *testing.T) {
+	m := newMachine(0x4C, 0x00, 0x80) // JMP *
+	m.Step()
+	assert.Equal(t, processor.HaltSelfLoop, m.CPU.HaltReason())
+	assert.True(t, m.Finished(), "Nothing can interrupt the self-loop")
+
+	event := m.ScheduleIn(100, func() {})
+	assert.False(t, m.Finished(), "A scheduled event could interrupt the self-loop")
+	m.Cancel(event)
+	assert.NoError(t, m.Attach(&counter{cpu: m.CPU}, machine.CPUClock))
+	assert.False(t, m.Finished(), "A device could interrupt the self-loop")
+
+	m.CPU.Write(0x8000, 0xDB) // STP
+	m.CPU.PC = 0x8000
+	m.Step()
+	assert.True(t, m.Finished(), "A stopped CPU has finished")
+}
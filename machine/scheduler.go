@@ -0,0 +1,91 @@
+package machine
+
+import "container/heap"
+
+// Event is a callback scheduled to run at a cycle count (see Machine.Schedule).
+type Event struct {
+	at    uint64
+	seq   uint64 // Orders events scheduled for the same cycle
+	fn    func()
+	index int // The event's index in the queue, or -1 once it has run or been cancelled
+}
+
+// At returns the cycle count the event is scheduled for.
+func (e *Event) At() uint64 {
+	return e.at
+}
+
+// Pending returns true if the event has neither run nor been cancelled.
+func (e *Event) Pending() bool {
+	return e.index >= 0
+}
+
+// eventQueue is a heap of events, earliest first, and in the order they were scheduled for the same cycle.
+type eventQueue []*Event
+
+func (q eventQueue) Len() int { return len(q) }
+
+func (q eventQueue) Less(i, j int) bool {
+	if q[i].at != q[j].at {
+		return q[i].at < q[j].at
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q eventQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *eventQueue) Push(x any) {
+	e := x.(*Event)
+	e.index = len(*q)
+	*q = append(*q, e)
+}
+
+func (q *eventQueue) Pop() any {
+	old := *q
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	e.index = -1
+	return e
+}
+
+// Schedule arranges for fn to be called once the CPU's TotalCycles reaches at, after any devices have been ticked
+// for that cycle. Events for the same cycle run in the order they were scheduled. An event for a cycle that has
+// already been reached runs at the end of the next cycle. fn may schedule further events, for example to repeat
+// itself.
+//
+// Events are not saved in snapshots: devices that schedule them should schedule them again from their own state
+// once it has been loaded.
+func (m *Machine) Schedule(at uint64, fn func()) *Event {
+	m.seq++
+	e := &Event{at: at, seq: m.seq, fn: fn}
+	heap.Push(&m.events, e)
+	return e
+}
+
+// ScheduleIn arranges for fn to be called the given number of cycles from now (see Schedule).
+func (m *Machine) ScheduleIn(cycles uint64, fn func()) *Event {
+	return m.Schedule(m.CPU.TotalCycles+cycles, fn)
+}
+
+// Cancel stops an event from running, and returns true if it had not already run or been cancelled.
+func (m *Machine) Cancel(e *Event) bool {
+	if !e.Pending() {
+		return false
+	}
+	heap.Remove(&m.events, e.index)
+	e.index = -1
+	return true
+}
+
+// runEvents runs the events that are due.
+func (m *Machine) runEvents() {
+	for len(m.events) > 0 && m.events[0].at <= m.CPU.TotalCycles {
+		e := heap.Pop(&m.events).(*Event)
+		e.fn()
+	}
+}
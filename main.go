@@ -1,12 +1,21 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/ukdave/6502_emulator/bus"
+	"github.com/ukdave/6502_emulator/machine"
 	"github.com/ukdave/6502_emulator/processor"
+	"github.com/ukdave/6502_emulator/snapshot"
+	"github.com/ukdave/6502_emulator/testrom"
+	"github.com/ukdave/6502_emulator/trace"
 	"github.com/ukdave/6502_emulator/tui"
+	"github.com/ukdave/6502_emulator/via"
 
 	tea "charm.land/bubbletea/v2"
 	flags "github.com/jessevdk/go-flags"
@@ -15,6 +24,36 @@ import (
 var opts struct {
 	StartAddress   uint16 `short:"s" long:"start" description:"Start address to load the binary file into memory" default:"0x8000"`
 	RunDelayMillis int    `short:"r" long:"runDelayMills" description:"Run delay in milliseconds" default:"100"`
+	Variant        string `short:"c" long:"cpu" description:"CPU variant to emulate" choice:"2a03" choice:"nmos" choice:"65c02" choice:"r65c02" default:"2a03"`
+	CycleAccurate  bool   `long:"cycle-accurate" description:"Perform each bus access on the cycle the real CPU does (NMOS variants only)"`
+	Unimplemented  string `long:"unimplemented" description:"What to do with opcodes outside the documented instruction set" choice:"execute" choice:"nop" choice:"trap" choice:"brk" default:"execute"`
+	Snapshot       string `long:"snapshot" description:"Snapshot file written and read by the TUI's save and load keys" default:"6502_emulator.snap"`
+	LoadSnapshot   bool   `long:"load-snapshot" description:"Start from the state saved in the snapshot file"`
+	History        int    `long:"history" description:"Number of instructions the TUI can step back through" default:"10000"`
+	Headless       bool   `long:"headless" description:"Run without the TUI until the CPU halts, then print its state"`
+	MaxCycles      uint64 `long:"max-cycles" description:"Stop a headless run after this many cycles (0 for no limit)" default:"0"`
+	JIT            bool   `long:"jit" description:"Run headless with the basic block JIT"`
+	TestROM        string `long:"test-rom" description:"Run binary_file as one of Klaus Dormann's test programs, or as nestest.nes, and report whether it passed" choice:"functional" choice:"decimal" choice:"nestest"`
+	Golden         string `long:"golden" description:"With --test-rom nestest, compare the trace with this log (nestest.log) instead of printing it"`
+
+	ROM        []string `long:"rom" description:"Attach a ROM image at an address, e.g. basic.bin@C000 (may be repeated)"`
+	ProgramROM bool     `long:"program-rom" description:"Load binary_file as a ROM at the start address instead of into RAM"`
+	ROMWrites  string   `long:"rom-writes" description:"What to do with writes to ROM: ignore them, record them and report them on exit, or stop the CPU" choice:"ignore" choice:"record" choice:"stop" default:"ignore"`
+
+	BankedROM     []string `long:"banked-rom" description:"Map a ROM image larger than its windows at an address, in windows of the given sizes, e.g. game.prg@8000:4000,4000 (may be repeated)"`
+	BankedRAM     []string `long:"banked-ram" description:"Map banked RAM of the given size at an address, in windows of the given sizes, e.g. 20000@4000:4000 (may be repeated)"`
+	BankRegisters string   `long:"bank-registers" description:"Map the bank registers, one for each window of the banked ROM and RAM in order, from this address"`
+
+	VIA []string `long:"via" description:"Map a 6522 VIA at this address, clocked by the CPU and wired to its IRQ input, e.g. 6000 (may be repeated)"`
+
+	Trace         string   `long:"trace" description:"Write a trace of the instructions executed to this file"`
+	TraceFormat   string   `long:"trace-format" description:"Format of the trace" choice:"text" choice:"csv" choice:"json" default:"text"`
+	TraceRange    []string `long:"trace-range" description:"Only trace instructions in this address range, e.g. 8000-80FF (may be repeated)"`
+	TraceMnemonic []string `long:"trace-mnemonic" description:"Only trace this instruction, e.g. JSR (may be repeated)"`
+	TraceStart    string   `long:"trace-start" description:"Start tracing when PC reaches this address"`
+	TraceStop     string   `long:"trace-stop" description:"Stop tracing when PC reaches this address"`
+	TraceCount    int      `long:"trace-count" description:"Stop tracing after this many instructions"`
+	TraceRing     int      `long:"trace-ring" description:"Only write the last N instructions, when the CPU halts or the emulator exits"`
 
 	Args struct {
 		BinaryPath string `positional-arg-name:"binary_file" description:"Path to the binary file to load into memory"`
@@ -30,21 +69,388 @@ func main() {
 		os.Exit(1)
 	}
 
+	variant, err := processor.ParseVariant(opts.Variant)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	policy, err := processor.ParseUnimplementedPolicy(opts.Unimplemented)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	if opts.TestROM != "" {
+		cpu, ram, _ := newCPU("", opts.StartAddress, variant, opts.CycleAccurate, nil)
+		cpu.SetUnimplementedPolicy(policy)
+		cpu.SetJIT(opts.JIT)
+		if opts.TestROM == "nestest" {
+			os.Exit(runNestest(opts.Args.BinaryPath, opts.Golden, cpu, ram, opts.MaxCycles))
+		}
+		os.Exit(runTestROM(testROMs[opts.TestROM], opts.Args.BinaryPath, cpu, ram, opts.MaxCycles))
+	}
+
+	roms, err := loadROMs()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	banked, mappings, err := loadBanked()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	for i, rom := range roms {
+		mappings = append(mappings, rom.Mapping(fmt.Sprintf("ROM %d", i+1)))
+	}
+	vias, viaMappings, err := newVIAs()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	mappings = append(mappings, viaMappings...)
+	binaryPath := opts.Args.BinaryPath
+	if opts.ProgramROM {
+		binaryPath = ""
+	}
+	cpu, ram, mappedBus := newCPU(binaryPath, opts.StartAddress, variant, opts.CycleAccurate, mappings)
+	cpu.SetUnimplementedPolicy(policy)
+	for _, rom := range roms {
+		rom.Stop = cpu.Fault
+	}
+	sections := []snapshot.Section{cpu, ram}
+	for _, b := range banked {
+		sections = append(sections, b)
+	}
+	for _, v := range vias {
+		sections = append(sections, v)
+	}
+	if opts.LoadSnapshot {
+		if err := snapshot.LoadFile(opts.Snapshot, sections...); err != nil {
+			fmt.Printf("Failed to load snapshot: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	mach := machine.New(cpu)
+	for i, v := range vias {
+		v.ConnectIRQ(cpu, processor.IRQSource(i+1)) // Source 0 is the TUI's IRQ key
+		if err := mach.Attach(v, machine.CPUClock); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+	closeTrace, err := startTrace(cpu)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if opts.Headless {
+		cpu.SetJIT(opts.JIT)
+		code := runHeadless(mach, opts.MaxCycles)
+		if err := closeTrace(); err != nil {
+			fmt.Printf("Failed to write trace: %v\n", err)
+		}
+		reportROMWrites(roms)
+		os.Exit(code)
+	}
+
 	// Create and start the TUI program
-	p := tea.NewProgram(initialModel(opts.Args.BinaryPath, opts.StartAddress, opts.RunDelayMillis))
-	if _, err := p.Run(); err != nil {
+	cpu.EnableHistory(opts.History)
+	model := tui.NewModel(mach, opts.RunDelayMillis, opts.Snapshot, sections...)
+	if mappedBus != nil {
+		model.SetMappedBus(mappedBus)
+	}
+	p := tea.NewProgram(model)
+	_, err = p.Run()
+	if err := closeTrace(); err != nil {
+		fmt.Printf("Failed to write trace: %v\n", err)
+	}
+	reportROMWrites(roms)
+	if err != nil {
 		fmt.Printf("Alas, there's been an error: %v", err)
 		os.Exit(1)
 	}
 }
 
-func initialModel(binaryPath string, startAddress uint16, runDelayMillis int) *tui.Model {
+// loadROMs loads the ROM images given by --rom, and binary_file if --program-rom was given, applying the
+// --rom-writes policy to each.
+func loadROMs() ([]*bus.ROM, error) {
+	policy, err := bus.ParseROMWritePolicy(opts.ROMWrites)
+	if err != nil {
+		return nil, err
+	}
+	specs := opts.ROM
+	if opts.ProgramROM {
+		specs = append(specs, fmt.Sprintf("%s@%04X", opts.Args.BinaryPath, opts.StartAddress))
+	}
+	var roms []*bus.ROM
+	for _, spec := range specs {
+		path, at, found := strings.Cut(spec, "@")
+		if !found {
+			return nil, fmt.Errorf("invalid ROM %q: expected file@address", spec)
+		}
+		start, err := trace.ParseAddress(at)
+		if err != nil {
+			return nil, err
+		}
+		rom, err := bus.LoadROM(path, start)
+		if err != nil {
+			return nil, err
+		}
+		rom.Writes = policy
+		roms = append(roms, rom)
+	}
+	return roms, nil
+}
+
+// loadBanked creates the banked ROM and RAM given by --banked-rom and --banked-ram, and returns them with the
+// mappings that attach them and, if --bank-registers was given, their registers.
+func loadBanked() ([]*bus.BankedMemory, []bus.Mapping, error) {
+	var banked []*bus.BankedMemory
+	var mappings []bus.Mapping
+	for i, spec := range append(opts.BankedROM, opts.BankedRAM...) {
+		source, windows, found := strings.Cut(spec, ":")
+		source, at, found2 := strings.Cut(source, "@")
+		if !found || !found2 {
+			return nil, nil, fmt.Errorf("invalid banked memory %q: expected source@address:size,...", spec)
+		}
+		var sizes []int
+		for s := range strings.SplitSeq(windows, ",") {
+			size, err := strconv.ParseUint(s, 16, 17)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid window size %q", s)
+			}
+			sizes = append(sizes, int(size))
+		}
+		start, err := trace.ParseAddress(at)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		id := fmt.Sprintf("BNK%d", i+1)
+		var b *bus.BankedMemory
+		if i < len(opts.BankedROM) {
+			b, err = bus.LoadBankedROM(source, id, sizes...)
+		} else {
+			var size uint64
+			if size, err = strconv.ParseUint(source, 16, 32); err != nil {
+				return nil, nil, fmt.Errorf("invalid banked RAM size %q", source)
+			}
+			b, err = bus.NewBankedRAM(id, int(size), sizes...)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		mapping := b.Mapping(fmt.Sprintf("Banked %d", i+1), start)
+		mapping.Priority = 1
+		banked = append(banked, b)
+		mappings = append(mappings, mapping)
+	}
+
+	if opts.BankRegisters != "" {
+		addr, err := trace.ParseAddress(opts.BankRegisters)
+		if err != nil {
+			return nil, nil, err
+		}
+		for i, b := range banked {
+			end := addr + uint16(len(b.Windows())-1)
+			mappings = append(mappings, bus.Mapping{Name: fmt.Sprintf("Bank registers %d", i+1), Start: addr, End: end,
+				Priority: 2, Device: b.Registers()})
+			addr = end + 1
+		}
+	}
+	return banked, mappings, nil
+}
+
+// newVIAs creates the VIAs given by --via, and returns them with the mappings that attach them.
+func newVIAs() ([]*via.VIA, []bus.Mapping, error) {
+	var vias []*via.VIA
+	var mappings []bus.Mapping
+	for i, at := range opts.VIA {
+		start, err := trace.ParseAddress(at)
+		if err != nil {
+			return nil, nil, err
+		}
+		v, err := via.New(fmt.Sprintf("VIA%d", i+1))
+		if err != nil {
+			return nil, nil, err
+		}
+		vias = append(vias, v)
+		mappings = append(mappings, bus.Mapping{Name: fmt.Sprintf("VIA %d", i+1), Start: start, End: start + 0x0F,
+			Priority: 2, Device: v})
+	}
+	return vias, mappings, nil
+}
+
+// reportROMWrites prints the writes to ROM recorded by the --rom-writes record policy.
+func reportROMWrites(roms []*bus.ROM) {
+	for _, rom := range roms {
+		if rom.Writes != bus.ROMRecord || rom.ViolationCount() == 0 {
+			continue
+		}
+		fmt.Printf("%d writes to the ROM at $%04X were refused\n", rom.ViolationCount(), rom.Start())
+		for i, err := range rom.Violations() {
+			if i == 10 {
+				fmt.Println("  ...")
+				break
+			}
+			fmt.Printf("  %v\n", err)
+		}
+	}
+}
+
+// startTrace attaches a tracer to the CPU if --trace was given. It returns a function that writes out the rest of
+// the trace and closes the file.
+func startTrace(cpu *processor.CPU) (func() error, error) {
+	if opts.Trace == "" {
+		return func() error { return nil }, nil
+	}
+	options := trace.Options{Mnemonics: opts.TraceMnemonic, StopAfter: opts.TraceCount, Ring: opts.TraceRing}
+	var err error
+	if options.Format, err = trace.ParseFormat(opts.TraceFormat); err != nil {
+		return nil, err
+	}
+	for _, s := range opts.TraceRange {
+		r, err := trace.ParseRange(s)
+		if err != nil {
+			return nil, err
+		}
+		options.Ranges = append(options.Ranges, r)
+	}
+	for _, trigger := range []struct {
+		value string
+		addr  **uint16
+	}{{opts.TraceStart, &options.StartAt}, {opts.TraceStop, &options.StopAt}} {
+		if trigger.value != "" {
+			addr, err := trace.ParseAddress(trigger.value)
+			if err != nil {
+				return nil, err
+			}
+			*trigger.addr = &addr
+		}
+	}
+
+	file, err := os.Create(opts.Trace)
+	if err != nil {
+		return nil, err
+	}
+	tracer := trace.New(file, options)
+	tracer.Attach(cpu)
+	return func() error {
+		tracer.Detach(cpu)
+		return errors.Join(tracer.Flush(), file.Close())
+	}, nil
+}
+
+// runHeadless runs the machine until the CPU halts (or the cycle limit is reached), prints its final state and
+// returns the exit code: 0 if the CPU halted, 2 if the cycle limit was reached first and 3 if an unimplemented opcode
+// was trapped or a write to ROM stopped the CPU. If devices can interrupt the CPU, a WAI or a self-loop only waits
+// for the next interrupt, so the run continues until the CPU stops for good (see machine.Machine.Finished) or the
+// cycle limit is reached.
+func runHeadless(mach *machine.Machine, maxCycles uint64) int {
+	cpu := mach.CPU
+	halted := func(*processor.CPU) bool { return mach.Finished() }
+	mach.RunUntil(halted, maxCycles)
+	if !halted(cpu) {
+		fmt.Printf("Cycle limit reached at $%04X after %d cycles\n", cpu.PC, cpu.TotalCycles)
+		printState(cpu)
+		return 2
+	}
+	if err := cpu.Err(); err != nil {
+		fmt.Printf("Trapped %v after %d cycles\n", err, cpu.TotalCycles)
+		printState(cpu)
+		return 3
+	}
+	fmt.Printf("Halted (%s) at $%04X after %d cycles\n", cpu.HaltReason(), cpu.PC, cpu.TotalCycles)
+	printState(cpu)
+	return 0
+}
+
+// testROMs are the test programs that can be run with --test-rom
+var testROMs = map[string]testrom.Test{
+	"functional": testrom.DormannFunctional,
+	"decimal":    testrom.DormannDecimal,
+}
+
+// runTestROM runs a test program and prints the result. It returns the exit code: 0 if the program passed, 1 if it
+// failed and 2 if it could not be run to the end (see testrom.Test.Run).
+func runTestROM(test testrom.Test, binaryPath string, cpu *processor.CPU, ram *bus.SimpleBus, maxCycles uint64) int {
+	program, err := os.ReadFile(binaryPath)
+	if err != nil {
+		fmt.Printf("Failed to read binary file: %v\n", err)
+		return 1
+	}
+	result, err := test.Run(cpu, ram, program, maxCycles)
+	if err != nil {
+		fmt.Println(err)
+		printState(cpu)
+		return 2
+	}
+	fmt.Println(result)
+	if !result.Passed {
+		printState(cpu)
+		return 1
+	}
+	return 0
+}
+
+// runNestest runs nestest.nes, printing its trace or comparing it with a golden log, and prints the result. It
+// returns the exit code: 0 if nestest passed (and the trace matched the golden log), 1 if it didn't and 2 if it
+// could not be run.
+func runNestest(binaryPath, goldenPath string, cpu *processor.CPU, ram *bus.SimpleBus, maxCycles uint64) int {
+	image, err := os.ReadFile(binaryPath)
+	if err != nil {
+		fmt.Printf("Failed to read binary file: %v\n", err)
+		return 2
+	}
+	if err := testrom.LoadNestest(ram, image); err != nil {
+		fmt.Println(err)
+		return 2
+	}
+
+	var trace io.Writer = os.Stdout
+	var golden io.Reader
+	if goldenPath != "" {
+		file, err := os.Open(goldenPath)
+		if err != nil {
+			fmt.Printf("Failed to read golden log: %v\n", err)
+			return 2
+		}
+		defer file.Close()
+		trace, golden = nil, file
+	} else if maxCycles == 0 {
+		fmt.Println("nestest needs --golden or --max-cycles to know when to stop")
+		return 2
+	}
+
+	result, err := testrom.RunNestest(cpu, trace, golden, maxCycles)
+	if err != nil {
+		fmt.Println(err)
+		return 2
+	}
+	fmt.Println(result)
+	if result.Divergence != nil || result.Official != 0 || result.Unofficial != 0 {
+		return 1
+	}
+	return 0
+}
+
+// printState prints the CPU registers
+func printState(cpu *processor.CPU) {
+	fmt.Printf("A:$%02X X:$%02X Y:$%02X SP:$%02X P:%08b\n", cpu.A, cpu.X, cpu.Y, cpu.SP, cpu.Status)
+}
+
+// newCPU creates a CPU with 64KB of RAM, loading binary_file into it. If any device mappings are given, they are
+// mapped over the RAM on a MappedBus, which is returned along with the RAM (for snapshots and test programs).
+func newCPU(binaryPath string, startAddress uint16, variant processor.Variant, cycleAccurate bool, mappings []bus.Mapping) (*processor.CPU, *bus.SimpleBus, *bus.MappedBus) {
 	// Create a new bus
-	bus := bus.NewSimpleBus()
+	ram := bus.NewSimpleBus()
 
-	// Set the value of the reset vector to startAddress. This is where our program will start
-	bus.Write(0xFFFC, uint8(startAddress&0xFF))
-	bus.Write(0xFFFD, uint8((startAddress>>8)&0xFF))
+	// Set the value of the reset vector to startAddress. This is where our program will start (unless a ROM
+	// provides its own vector)
+	ram.Write(0xFFFC, uint8(startAddress&0xFF))
+	ram.Write(0xFFFD, uint8((startAddress>>8)&0xFF))
 
 	// If a binary path was provided, load that file into memory at startAddress
 	if binaryPath != "" {
@@ -54,11 +460,30 @@ func initialModel(binaryPath string, startAddress uint16, runDelayMillis int) *t
 			os.Exit(1)
 		}
 		for i, b := range binFile {
-			bus.Write(startAddress+uint16(i), b)
+			ram.Write(startAddress+uint16(i), b)
+		}
+	}
+
+	// Map the devices over the RAM. The CPU uses the RAM directly if there are none, which is faster and lets the
+	// JIT run.
+	var cpuBus bus.Bus = ram
+	var mapped *bus.MappedBus
+	if len(mappings) > 0 {
+		mapped = bus.NewMappedBus()
+		for _, m := range append([]bus.Mapping{{Name: "RAM", Start: 0x0000, End: 0xFFFF, Device: ram}}, mappings...) {
+			if err := mapped.Map(m); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
 		}
+		cpuBus = mapped
 	}
 
 	// Create a new CPU
-	cpu := processor.NewCPU(bus)
-	return tui.NewModel(cpu, runDelayMillis)
+	cpu := processor.NewCPUWithVariant(cpuBus, variant)
+	if err := cpu.SetCycleAccurate(cycleAccurate); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	return cpu, ram, mapped
 }
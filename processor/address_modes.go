@@ -1,5 +1,7 @@
 package processor
 
+import "fmt"
+
 // The 6502 has a 16-bit address space ranging from 0x0000 to 0xFFFF. The upper byte of an address is commonly
 // referred to as the "page", while the lower byte represents the offset within that page. This divides memory
 // into 256 pages of 256 bytes each.
@@ -9,12 +11,86 @@ package processor
 // the instruction report whether an extra cycle is possible. If both indicate true, one additional clock cycle
 // is added.
 
-type AddressModeFunc func(*CPU) AddressInfo
-
 type AddressInfo struct {
-	Address       uint16
-	PageChanged   bool
-	IsAccumulator bool
+	Address         uint16
+	PageChanged     bool
+	IsAccumulator   bool
+	IsImmediate     bool
+	RelativeAddress uint16 // Branch target of the ZPR address mode
+}
+
+// Mode identifies an address mode in the opcode tables. Each mode is implemented by the function of the same
+// name (ModeABS by ABS, and so on), which resolve calls directly rather than through a function pointer.
+type Mode uint8
+
+const (
+	ModeIMP Mode = iota
+	ModeACC
+	ModeIMM
+	ModeZP0
+	ModeZPX
+	ModeZPY
+	ModeREL
+	ModeABS
+	ModeABX
+	ModeABY
+	ModeIND
+	ModeINDX
+	ModeINDY
+	ModeZPI
+	ModeIAX
+	ModeZPR
+)
+
+var modeNames = [...]string{
+	ModeIMP: "IMP", ModeACC: "ACC", ModeIMM: "IMM", ModeZP0: "ZP0", ModeZPX: "ZPX", ModeZPY: "ZPY",
+	ModeREL: "REL", ModeABS: "ABS", ModeABX: "ABX", ModeABY: "ABY", ModeIND: "IND", ModeINDX: "INDX",
+	ModeINDY: "INDY", ModeZPI: "ZPI", ModeIAX: "IAX", ModeZPR: "ZPR",
+}
+
+// String returns the short name of the address mode (for example "ABX").
+func (m Mode) String() string {
+	if int(m) < len(modeNames) {
+		return modeNames[m]
+	}
+	return fmt.Sprintf("Mode(%d)", uint8(m))
+}
+
+// resolve works out the address information for the current instruction using the given address mode.
+func (c *CPU) resolve(mode Mode) AddressInfo {
+	switch mode {
+	case ModeACC:
+		return ACC(c)
+	case ModeIMM:
+		return IMM(c)
+	case ModeZP0:
+		return ZP0(c)
+	case ModeZPX:
+		return ZPX(c)
+	case ModeZPY:
+		return ZPY(c)
+	case ModeREL:
+		return REL(c)
+	case ModeABS:
+		return ABS(c)
+	case ModeABX:
+		return ABX(c)
+	case ModeABY:
+		return ABY(c)
+	case ModeIND:
+		return IND(c)
+	case ModeINDX:
+		return INDX(c)
+	case ModeINDY:
+		return INDY(c)
+	case ModeZPI:
+		return ZPI(c)
+	case ModeIAX:
+		return IAX(c)
+	case ModeZPR:
+		return ZPR(c)
+	}
+	return IMP(c)
 }
 
 // ACC implements "Accumulator" address mode.
@@ -28,13 +104,13 @@ func ACC(cpu *CPU) AddressInfo {
 // The operand is the byte immediately following the opcode and is treated as a literal value rather than a
 // memory address.
 func IMM(cpu *CPU) AddressInfo {
-	return AddressInfo{Address: cpu.PC + 1}
+	return AddressInfo{Address: cpu.PC + 1, IsImmediate: true}
 }
 
 // ABS implements "Absolute" address mode.
 // A full 16-bit address is read from the instruction operands and used directly.
 func ABS(cpu *CPU) AddressInfo {
-	return AddressInfo{Address: cpu.Read16(cpu.PC + 1)}
+	return AddressInfo{Address: cpu.read16(cpu.PC+1, AccessOperand)}
 }
 
 // ABX implements "Absolute with X Offset" address mode.
@@ -42,7 +118,7 @@ func ABS(cpu *CPU) AddressInfo {
 // value of the X Register is then added to form the effective address. Some instructions require an additional
 // clock cycle if this addition causes a page boundary to be crossed.
 func ABX(cpu *CPU) AddressInfo {
-	addr := cpu.Read16(cpu.PC + 1)
+	addr := cpu.read16(cpu.PC+1, AccessOperand)
 	addr += uint16(cpu.X)
 	pageChanged := pagesDiffer(addr-uint16(cpu.X), addr)
 	return AddressInfo{Address: addr, PageChanged: pageChanged}
@@ -53,7 +129,7 @@ func ABX(cpu *CPU) AddressInfo {
 // then the value of the Y Resister is added to form the effective address. Some instructions require an additional
 // clock cycle if this addition causes a page boundary to be crossed.
 func ABY(cpu *CPU) AddressInfo {
-	addr := cpu.Read16(cpu.PC + 1)
+	addr := cpu.read16(cpu.PC+1, AccessOperand)
 	addr += uint16(cpu.Y)
 	pageChanged := pagesDiffer(addr-uint16(cpu.Y), addr)
 	return AddressInfo{Address: addr, PageChanged: pageChanged}
@@ -63,7 +139,7 @@ func ABY(cpu *CPU) AddressInfo {
 // The operand is an 8-bit address that implicitly refers to a location within page zero (0x0000–0x00FF). This
 // addressing mode saves program bytes by only requiring one byte instead of two.
 func ZP0(cpu *CPU) AddressInfo {
-	return AddressInfo{Address: uint16(cpu.Read(cpu.PC + 1))}
+	return AddressInfo{Address: uint16(cpu.read(cpu.PC+1, AccessOperand))}
 }
 
 // ZPX implements "Zero Page with X Offset" address mode.
@@ -71,7 +147,7 @@ func ZP0(cpu *CPU) AddressInfo {
 // value of the X Register is then added to form the effective address. Any wrapping of the result occurs within
 // page zero so the final address will always be in the range 0x0000–0x00FF.
 func ZPX(cpu *CPU) AddressInfo {
-	addr := uint16(cpu.Read(cpu.PC+1)+cpu.X) & 0x00FF
+	addr := uint16(cpu.read(cpu.PC+1, AccessOperand)+cpu.X) & 0x00FF
 	return AddressInfo{Address: addr}
 }
 
@@ -80,7 +156,7 @@ func ZPX(cpu *CPU) AddressInfo {
 // the value of the Y Resister is added to form the effective address. Any wrapping of the result occurs within
 // page zero so the final address will always be in the range 0x0000–0x00FF.
 func ZPY(cpu *CPU) AddressInfo {
-	addr := uint16(cpu.Read(cpu.PC+1)+cpu.Y) & 0x00FF
+	addr := uint16(cpu.read(cpu.PC+1, AccessOperand)+cpu.Y) & 0x00FF
 	return AddressInfo{Address: addr}
 }
 
@@ -97,7 +173,7 @@ func IMP(cpu *CPU) AddressInfo {
 // possible to branch to any address in the full address space. If a page boundary is crossed then two additional
 // clock cycles will be required, but only if the branch is taken.
 func REL(cpu *CPU) AddressInfo {
-	offset := cpu.Read(cpu.PC + 1)
+	offset := cpu.read(cpu.PC+1, AccessOperand)
 	baseAddr := cpu.PC + 2
 	addr := baseAddr + uint16(offset)
 	if offset >= 0x80 {
@@ -112,12 +188,13 @@ func REL(cpu *CPU) AddressInfo {
 // IND implements "Absolute Indirect" address mode.
 // A 16-bit pointer is read from the instruction operands and used to fetch the final address. Due to a hardware
 // bug in the original 6502, if the pointer’s low byte is 0xFF, the high byte of the target address is read from
-// the beginning of the same page instead of the next page (i.e. the address wraps within the page).
+// the beginning of the same page instead of the next page (i.e. the address wraps within the page). The CMOS
+// variants fix this bug.
 func IND(cpu *CPU) AddressInfo {
-	ptr := cpu.Read16(cpu.PC + 1)
+	ptr := cpu.read16(cpu.PC+1, AccessOperand)
 
 	var addr uint16
-	if ptr&0x00FF == 0x00FF {
+	if ptr&0x00FF == 0x00FF && !cpu.variant.isCMOS() {
 		// Simulate page boundary hardware bug
 		lo := uint16(cpu.Read(ptr))
 		hi := uint16(cpu.Read(ptr & 0xFF00))
@@ -135,7 +212,7 @@ func IND(cpu *CPU) AddressInfo {
 // A zero-page (8-bit) base address is read from the instruction operand, then the X register is added to it with
 // zero-page wraparound. The result is used as a pointer to fetch the final 16-bit address.
 func INDX(cpu *CPU) AddressInfo {
-	ptr := uint16((cpu.Read(cpu.PC+1) + cpu.X) & 0x00FF)
+	ptr := uint16((cpu.read(cpu.PC+1, AccessOperand) + cpu.X) & 0x00FF)
 	lo := uint16(cpu.Read(ptr))
 	hi := uint16(cpu.Read((ptr + 1) & 0xFF))
 	addr := (hi << 8) | lo
@@ -147,7 +224,7 @@ func INDX(cpu *CPU) AddressInfo {
 // address. The Y register is added to form the final address. Some instructions require an additional clock cycle
 // if this addition crosses a page boundary.
 func INDY(cpu *CPU) AddressInfo {
-	ptr := uint16(cpu.Read(cpu.PC + 1))
+	ptr := uint16(cpu.read(cpu.PC+1, AccessOperand))
 	lo := uint16(cpu.Read(ptr))
 	hi := uint16(cpu.Read((ptr + 1) & 0xFF))
 	baseAddr := (hi << 8) | lo
@@ -155,3 +232,40 @@ func INDY(cpu *CPU) AddressInfo {
 	pageChanged := pagesDiffer(baseAddr, addr)
 	return AddressInfo{Address: addr, PageChanged: pageChanged}
 }
+
+// The remaining address modes were added by the CMOS 65C02.
+
+// ZPI implements "Zero Page Indirect" address mode.
+// A zero-page (8-bit) address is read from the instruction operand and used as a pointer to fetch the final
+// 16-bit address. This is the same as INDY without the Y offset.
+func ZPI(cpu *CPU) AddressInfo {
+	ptr := uint16(cpu.read(cpu.PC+1, AccessOperand))
+	lo := uint16(cpu.Read(ptr))
+	hi := uint16(cpu.Read((ptr + 1) & 0xFF))
+	addr := (hi << 8) | lo
+	return AddressInfo{Address: addr}
+}
+
+// IAX implements "Absolute Indexed Indirect" address mode.
+// A 16-bit base address is read from the instruction operands and the X register is added to it. The result is
+// used as a pointer to fetch the final 16-bit address. It is only used by JMP, to implement jump tables.
+func IAX(cpu *CPU) AddressInfo {
+	ptr := cpu.read16(cpu.PC+1, AccessOperand) + uint16(cpu.X)
+	return AddressInfo{Address: cpu.Read16(ptr)}
+}
+
+// ZPR implements "Zero Page Relative" address mode.
+// The instruction has two operands: an 8-bit zero page address followed by a signed 8-bit branch offset. It is
+// only used by the BBR and BBS instructions, which test a bit in zero page and branch on the result. The branch
+// target is returned in RelativeAddress, and PageChanged reports whether the branch would cross a page boundary.
+func ZPR(cpu *CPU) AddressInfo {
+	addr := uint16(cpu.read(cpu.PC+1, AccessOperand))
+	offset := cpu.read(cpu.PC+2, AccessOperand)
+	baseAddr := cpu.PC + 3
+	target := baseAddr + uint16(offset)
+	if offset >= 0x80 {
+		target -= 0x100
+	}
+	pageChanged := pagesDiffer(baseAddr, target)
+	return AddressInfo{Address: addr, PageChanged: pageChanged, RelativeAddress: target}
+}
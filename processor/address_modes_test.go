@@ -372,6 +372,28 @@ func (suite *AddressModesSuite) TestIND_PageBug() {
 	assert.False(suite.T(), result.IsAccumulator, "Expected IsAccumulator to be false")
 }
 
+func (suite *AddressModesSuite) TestIND_NoPageBugOn65C02() {
+	suite.cpu = processor.NewCPUWithVariant(suite.bus, processor.Variant65C02)
+
+	// Write instruction "JMP $12FF" into memory at address 0x0000
+	suite.bus.Write(0x0000, 0x6C) // opcode
+	suite.bus.Write(0x0001, 0xFF) // lo byte
+	suite.bus.Write(0x0002, 0x12) // hi byte
+
+	// Write final target address 0x5678 into memory at address 0x12FF, crossing into the next page
+	suite.bus.Write(0x12FF, 0x78) // lo byte
+	suite.bus.Write(0x1300, 0x56) // hi byte
+	suite.bus.Write(0x1200, 0x99) // where the NMOS page wrapping bug would read the hi byte from
+
+	// Set the Program Counter to the start of the instruction
+	suite.cpu.PC = 0x0000
+
+	// Use indirect addressing mode to get the final address
+	result := processor.IND(suite.cpu)
+
+	assert.Equal(suite.T(), uint16(0x5678), result.Address, "Expected address to be 0x5678")
+}
+
 func (suite *AddressModesSuite) TestINDX() {
 	// Write instruction "LDA ($40,X)" into memory at address 0x2000
 	suite.bus.Write(0x2000, 0xA1) // opcode
@@ -455,3 +477,60 @@ func (suite *AddressModesSuite) TestINDY_PageWrapping() {
 	assert.True(suite.T(), result.PageChanged, "Expected pageChanged to be true")
 	assert.False(suite.T(), result.IsAccumulator, "Expected IsAccumulator to be false")
 }
+
+func (suite *AddressModesSuite) TestZPI() {
+	// Write instruction "LDA ($40)" into memory at address 0x2000
+	suite.bus.Write(0x2000, 0xB2) // opcode
+	suite.bus.Write(0x2001, 0x40) // operand
+
+	// Write the target address (0x1278) to the zero page
+	suite.bus.Write(0x0040, 0x78)
+	suite.bus.Write(0x0041, 0x12)
+
+	// Set the Program Counter to the start of the instruction
+	suite.cpu.PC = 0x2000
+
+	// Use zero page indirect addressing mode to get the final address
+	result := processor.ZPI(suite.cpu)
+
+	assert.Equal(suite.T(), uint16(0x1278), result.Address, "Expected address to be 0x1278")
+	assert.False(suite.T(), result.PageChanged, "Expected pageChanged to be be false")
+}
+
+func (suite *AddressModesSuite) TestIAX() {
+	// Write instruction "JMP ($1230,X)" into memory at address 0x2000
+	suite.bus.Write(0x2000, 0x7C) // opcode
+	suite.bus.Write(0x2001, 0x30) // lo byte
+	suite.bus.Write(0x2002, 0x12) // hi byte
+
+	// Write the target address (0x5678) into the jump table at 0x1230 + X
+	suite.bus.Write(0x1234, 0x78)
+	suite.bus.Write(0x1235, 0x56)
+
+	// Set the Program Counter to the start of the instruction
+	suite.cpu.PC = 0x2000
+	suite.cpu.X = 0x04
+
+	// Use absolute indexed indirect addressing mode to get the final address
+	result := processor.IAX(suite.cpu)
+
+	assert.Equal(suite.T(), uint16(0x5678), result.Address, "Expected address to be 0x5678")
+	assert.False(suite.T(), result.PageChanged, "Expected pageChanged to be be false")
+}
+
+func (suite *AddressModesSuite) TestZPR() {
+	// Write instruction "BBR0 $40,$05" into memory at address 0x20F0
+	suite.bus.Write(0x20F0, 0x0F) // opcode
+	suite.bus.Write(0x20F1, 0x40) // zero page address
+	suite.bus.Write(0x20F2, 0x10) // branch offset
+
+	// Set the Program Counter to the start of the instruction
+	suite.cpu.PC = 0x20F0
+
+	// Use zero page relative addressing mode to get the address and branch target
+	result := processor.ZPR(suite.cpu)
+
+	assert.Equal(suite.T(), uint16(0x0040), result.Address, "Expected address to be 0x0040")
+	assert.Equal(suite.T(), uint16(0x2103), result.RelativeAddress, "Expected branch target to be 0x2103")
+	assert.True(suite.T(), result.PageChanged, "Expected pageChanged to be be true")
+}
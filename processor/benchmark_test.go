@@ -0,0 +1,63 @@
+package processor_test
+
+import (
+	"testing"
+
+	"github.com/ukdave/6502_emulator/processor"
+)
+
+// reportMHz reports the emulated clock speed achieved by a benchmark
+func reportMHz(b *testing.B, cpu *processor.CPU) {
+	b.ReportMetric(float64(cpu.TotalCycles)/b.Elapsed().Seconds()/1e6, "MHz")
+}
+
+func BenchmarkClock(b *testing.B) {
+	cpu := newCPU(processor.VariantNMOS, benchmarkProgram...)
+	for b.Loop() {
+		cpu.Clock()
+	}
+	reportMHz(b, cpu)
+}
+
+func BenchmarkClock_CycleAccurate(b *testing.B) {
+	cpu := newCPU(processor.VariantNMOS, benchmarkProgram...)
+	if err := cpu.SetCycleAccurate(true); err != nil {
+		b.Fatal(err)
+	}
+	for b.Loop() {
+		cpu.Clock()
+	}
+	reportMHz(b, cpu)
+}
+
+func BenchmarkStep(b *testing.B) {
+	cpu := newCPU(processor.VariantNMOS, benchmarkProgram...)
+	for b.Loop() {
+		cpu.Step()
+	}
+	reportMHz(b, cpu)
+}
+
+func BenchmarkRunCycles(b *testing.B) {
+	cpu := newCPU(processor.VariantNMOS, benchmarkProgram...)
+	for b.Loop() {
+		cpu.RunCycles(1000)
+	}
+	reportMHz(b, cpu)
+}
+
+func BenchmarkRunCycles_JIT(b *testing.B) {
+	cpu := newCPU(processor.VariantNMOS, benchmarkProgram...)
+	cpu.SetJIT(true)
+	for b.Loop() {
+		cpu.RunCycles(1000)
+	}
+	reportMHz(b, cpu)
+}
+
+func BenchmarkDisassembleOperation(b *testing.B) {
+	cpu := newCPU(processor.VariantNMOS, benchmarkProgram...)
+	for b.Loop() {
+		cpu.DisassembleOperation(0x8002)
+	}
+}
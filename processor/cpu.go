@@ -19,7 +19,7 @@ const (
 	C Flag = (1 << 0) // Carry Bit
 	Z Flag = (1 << 1) // Zero
 	I Flag = (1 << 2) // Disable Interrupts
-	D Flag = (1 << 3) // Decimal Mode (unused in this implementation)
+	D Flag = (1 << 3) // Decimal Mode (ignored by the 2A03 variant)
 	B Flag = (1 << 4) // Break
 	U Flag = (1 << 5) // Unused
 	V Flag = (1 << 6) // Overflow
@@ -27,7 +27,14 @@ const (
 )
 
 type CPU struct {
-	bus bus.Bus
+	bus          bus.Bus
+	ram          *bus.SimpleBus // The bus, if it is a SimpleBus, so that it can be accessed without an interface call
+	peeker       bus.Peeker     // The bus, if it can be read without side effects (see Peek)
+	poker        bus.Poker      // The bus, if it can be written without side effects (see poke)
+	variant      Variant
+	operations   *[256]Operation
+	instructions *InstructionSet
+	decoded      [256]decodedOp // Each opcode as it is executed under the unimplemented opcode policy (see decodeOpcodes)
 
 	// CPU Core registers, exported for ease of access by external inspectors. This is all the 6502 has.
 	A      byte   // Accumulator Register
@@ -39,42 +46,124 @@ type CPU struct {
 
 	TotalCycles uint64 // Total number of cycles executed
 	cycles      uint8
+
+	// MagicConstant is ORed with the accumulator by the unstable XAA and LXA instructions. The value differs between
+	// individual chips; see DefaultMagicConstant.
+	MagicConstant byte
+
+	halted     bool       // Set by a JAM or STP instruction; cleared by Reset
+	waiting    bool       // Set by a WAI instruction; cleared by an interrupt or Reset
+	haltReason HaltReason // Why the CPU has stopped making progress (see halt.go)
+	err        error      // The error that halted the CPU, if any
+
+	// Unimplemented opcode handling (see unimplemented.go)
+	unimplementedPolicy  UnimplementedPolicy
+	unimplementedHandler UnimplementedHandlerFunc
+
+	// Cycle accurate execution state (see cycle_accurate.go)
+	cycleAccurate bool
+	micro         microState
+	latched       bool // While set, Read returns latch without accessing the bus
+	latch         byte // The operand already read from the bus by the current instruction
+
+	// Interrupt state (see interrupts.go)
+	irqLines         uint32 // One bit for each IRQSource asserting the IRQ line
+	nmiLine          bool   // The level of the NMI line
+	nmiPending       bool   // Set when the NMI line is asserted; cleared when the NMI sequence starts
+	pollMask         bool   // The I flag as seen by the interrupt poll of the current instruction
+	pendingInterrupt uint16 // The vector of an interrupt to start at the next instruction boundary, or 0
+	interruptVector  uint16 // The vector of the BRK, IRQ, NMI or reset sequence in progress, or 0
+
+	// External pins (see pins.go)
+	notReady     bool // The RDY pin is released
+	soLine       bool // The level of the SO pin
+	resetLine    bool // The level of the RESET pin
+	pendingReset bool // The reset sequence starts on the next cycle
+
+	// Reporting for Step (see step.go)
+	last         StepResult  // The instruction or sequence started most recently
+	accessLog    []BusAccess // Bus accesses are appended while non-nil
+	accessBuffer []BusAccess // The storage accessLog reuses on each Step
+
+	history *history // Rewind history, or nil if disabled (see history.go)
+	jit     *jit     // Basic block cache, or nil if disabled (see jit.go)
+
+	hooks    []*Hooks   // Execution hooks, or nil if there are none (see hooks.go)
+	dataKind AccessKind // How Read reports accesses to hooks: AccessOperand while an immediate operand is being read
 }
 
-// NewCPU creates a new CPU instance.
+// NewCPU creates a new CPU instance emulating the 2A03 variant (decimal mode disabled).
 func NewCPU(bus bus.Bus) *CPU {
-	c := &CPU{bus: bus}
-	c.Reset()
+	return NewCPUWithVariant(bus, Variant2A03)
+}
+
+// NewCPUWithVariant creates a new CPU instance emulating the given member of the 6502 family.
+func NewCPUWithVariant(b bus.Bus, variant Variant) *CPU {
+	c := &CPU{bus: b, variant: variant, operations: variant.operations(),
+		instructions: variant.InstructionSet(), MagicConstant: DefaultMagicConstant}
+	c.ram, _ = b.(*bus.SimpleBus)
+	c.peeker, _ = b.(bus.Peeker)
+	c.poker, _ = b.(bus.Poker)
+	c.decodeOpcodes()
+	c.PowerOn()
 	return c
 }
 
-// Reset resets the CPU to its initial powerup state.
-func (c *CPU) Reset() {
+// Variant returns the member of the 6502 family that this CPU emulates.
+func (c *CPU) Variant() Variant {
+	return c.variant
+}
+
+// PowerOn puts the CPU into its initial powerup state, as if the reset sequence had just completed. Every
+// register is initialised and the cycle count starts again from zero.
+func (c *CPU) PowerOn() {
+	c.abort()
 	c.A = 0x00
 	c.X = 0x00
 	c.Y = 0x00
 	c.SP = 0xFD
-	c.PC = c.ResetVector()
+	c.PC = c.read16(resetVector, AccessVector)
 	c.Status = 0x24 // Clear all flags except U and I
 	c.TotalCycles = 0
+	c.clearHistory()
+	c.FlushJIT()
 }
 
-// ResetVector returns the 16-bit address read from the 6502 reset vector ($FFFC–$FFFD), which is loaded into
-// the program counter on reset.
+// Reset performs a warm reset, as if the RESET pin had been pulsed. The current instruction is abandoned and the
+// 7-cycle reset sequence runs on the following clock cycles (see pins.go).
+func (c *CPU) Reset() {
+	c.SetReset(true)
+	c.SetReset(false)
+}
+
+// Halted returns true if the CPU has stopped executing instructions (for example after a JAM or STP instruction).
+// A halted CPU still counts clock cycles but does nothing else until it is reset.
+func (c *CPU) Halted() bool {
+	return c.halted
+}
+
+// Waiting returns true if the CPU is paused by a WAI instruction. It resumes when an interrupt is signalled.
+func (c *CPU) Waiting() bool {
+	return c.waiting
+}
+
+// ResetVector returns the 16-bit address held in the 6502 reset vector ($FFFC–$FFFD), which is loaded into
+// the program counter on reset. The vector is peeked (see Peek), so it can be inspected at any time.
 func (c *CPU) ResetVector() uint16 {
-	return c.Read16(0xFFFC)
+	return c.peek16(resetVector)
 }
 
-// IRQVector returns the 16-bit address read from the 6502 IRQ/BRK vector ($FFFE–$FFFF), which is loaded into
-// the program counter on an IRQ or BRK.
+// IRQVector returns the 16-bit address held in the 6502 IRQ/BRK vector ($FFFE–$FFFF), which is loaded into
+// the program counter on an IRQ or BRK. The vector is peeked (see Peek), so it can be inspected at any time.
 func (c *CPU) IRQVector() uint16 {
-	return c.Read16(0xFFFE)
+	return c.peek16(irqVector)
 }
 
-// NMIVector returns the 16-bit address read from the 6502 NMI vector ($FFFA–$FFFB), which is loaded into
-// the program counter on a non-maskable interrupt.
+// NMIVector returns the 16-bit address held in the 6502 NMI vector ($FFFA–$FFFB), which is loaded into
+// the program counter on a non-maskable interrupt. The vector is peeked (see Peek), so it can be inspected at any
+// time.
 func (c *CPU) NMIVector() uint16 {
-	return c.Read16(0xFFFA)
+	return c.peek16(nmiVector)
 }
 
 // Clock advances the CPU by a single clock cycle.
@@ -83,19 +172,68 @@ func (c *CPU) NMIVector() uint16 {
 // portion of the instruction via internal micro-operations. This emulator executes the full instruction atomically,
 // but still models timing by tracking the number of cycles the instruction consumes. Each call to Clock decrements
 // the remaining cycle count, and when it reaches zero the instruction is considered complete.
+//
+// When cycle accurate execution is enabled (see SetCycleAccurate) each call instead performs the single bus access
+// that the real processor makes on that cycle.
 func (c *CPU) Clock() {
+	c.recordInstruction()
+	if c.cycleAccurate {
+		c.clockCycleAccurate()
+		return
+	}
+
 	c.TotalCycles++
+	if c.stalled() {
+		return
+	}
+	if c.pendingReset {
+		c.reset()
+		return
+	}
 	if c.cycles > 0 {
+		if c.interruptVector != 0 {
+			c.hijack()
+		} else if c.cycles == 2 {
+			// The next cycle is the penultimate cycle of the instruction
+			c.pollInterrupts(c.pollMask)
+		}
 		c.cycles--
+		if c.cycles == 0 && c.interruptVector != 0 && c.hooks != nil {
+			c.sequenceDone(c.interruptVector, c.last.decoded != nil)
+		}
+		return
+	}
+	c.interruptVector = 0
+	if !c.wake() {
+		return
+	}
+	if c.pendingInterrupt != 0 {
+		c.interrupt()
 		return
 	}
 
-	opcode := c.Read(c.PC)
-	op := operations[opcode]
+	pc := c.PC
+	opcode := c.read(c.PC, AccessOpcode)
+	d := &c.decoded[opcode]
+	if c.hooks != nil {
+		c.beforeInstruction(pc, opcode)
+	}
 
 	// Get the address information/operand using the appropriate address mode for this operation.
 	// Note that not all instructions require an operand (e.g. NOP, INX, CLC).
-	addressInfo := op.AddressMode(c)
+	c.execute(pc, opcode, d, c.resolve(d.op.AddressMode))
+	if c.hooks != nil {
+		c.afterInstruction(pc, opcode)
+	}
+}
+
+// execute runs the instruction at pc once its opcode has been fetched and decoded, and its address resolved. It is
+// shared by Clock and the JIT (see jit.go) so that both give the same results.
+func (c *CPU) execute(pc uint16, opcode byte, d *decodedOp, addressInfo AddressInfo) {
+	op := &d.op
+	masked := c.GetFlag(I)
+	c.haltReason = HaltNone
+	c.last.started(pc, opcode, d, addressInfo.Address)
 
 	// Increment the Program Counter (PC) by the size of this operation. We do this *before* executing the
 	// instruction as some instructions may alter PC directly (e.g. branch instructions).
@@ -105,7 +243,11 @@ func (c *CPU) Clock() {
 	c.cycles = op.Cycles
 
 	// Perform operation
+	if addressInfo.IsImmediate {
+		c.dataKind = AccessOperand
+	}
 	extraCycle := op.Instruction(c, addressInfo)
+	c.dataKind = AccessData
 
 	// Several addressing modes have the potential to require an additional clock cycle if they cross a page
 	// boundary. This is combined with several instructions that enable this additional clock cycle. If both
@@ -120,56 +262,130 @@ func (c *CPU) Clock() {
 
 	// Decrement the number of cycles remaining for this instruction
 	c.cycles--
-}
+	c.checkProgress(pc, d.brk)
 
-// IRQ performs an Interrupt Request (IRQ) sequence.
-// The current Program Counter and status flags are pushed onto the stack and then we jump to the address
-// stored in the IRQ vector.
-func (c *CPU) IRQ() {
-	// Only run if the Disable Interrupts flag is clear
-	if !c.GetFlag(I) {
-		c.Push16(c.PC)
-		c.Push(c.Status &^ 0x10) // 0x10 clears the Break flag to 1 (but only in the value pushed to the stack)
-		c.SetFlag(I, true)       // Set the "Interrupt Disable" flag
-		c.PC = c.IRQVector()
-		c.cycles += 7
+	// Work out the I flag the interrupt poll will see, and poll now if this is a 2-cycle instruction
+	if !d.delaysMask {
+		masked = c.GetFlag(I)
+	}
+	c.pollMask = masked
+	if d.brk {
+		c.interruptVector = irqVector // BRK
+	} else if c.cycles <= 1 {
+		c.pollInterrupts(c.pollMask)
 	}
 }
 
-// NMI performs a Non-Maskable Interrupt (NMI) sequence.
-// The current Program Counter and status flags are pushed onto the stack and then we jump to the address
-// stored in the NMI vector.
-func (c *CPU) NMI() {
-	c.Push16(c.PC)
-	c.Push(c.Status &^ 0x10) // 0x10 clears the Break flag to 1 (but only in the value pushed to the stack)
-	c.SetFlag(I, true)       // Set the "Interrupt Disable" flag
-	c.PC = c.NMIVector()
-	c.cycles += 7
+// enterInterrupt updates the status flags on entry to an interrupt handler (IRQ, NMI or BRK). Interrupts are
+// disabled, and the CMOS variants also clear decimal mode so that the handler starts in a known state.
+func (c *CPU) enterInterrupt() {
+	c.SetFlag(I, true) // Set the "Interrupt Disable" flag
+	if c.variant.isCMOS() {
+		c.SetFlag(D, false)
+	}
 }
 
-// Read reads an 8-bit value from the bus at the specified address.
+// Read reads an 8-bit value from the bus at the specified address. It is the CPU's own bus access, as made by the
+// instructions, so it is reported to hooks and by Step; use Peek to inspect memory without disturbing either.
 func (c *CPU) Read(addr uint16) byte {
+	return c.read(addr, c.dataKind)
+}
+
+// read reads an 8-bit value from the bus, reporting it to any hooks as the given kind of access.
+func (c *CPU) read(addr uint16, kind AccessKind) byte {
+	if c.latched {
+		return c.latch
+	}
+	var data byte
+	if c.ram != nil {
+		data = c.ram.Read(addr)
+	} else {
+		data = c.bus.Read(addr)
+	}
+	if c.accessLog != nil {
+		c.accessLog = append(c.accessLog, BusAccess{Address: addr, Data: data})
+	}
+	if c.hooks != nil {
+		c.accessed(BusAccess{Address: addr, Data: data}, kind)
+	}
+	return data
+}
+
+// Peek returns the 8-bit value at the specified address without the CPU accessing the bus: it is not reported to
+// hooks or by Step, and devices that implement bus.Peeker do not see it. It is intended for debuggers and other
+// tools that inspect memory while the CPU runs.
+func (c *CPU) Peek(addr uint16) byte {
+	switch {
+	case c.ram != nil:
+		return c.ram.Read(addr)
+	case c.peeker != nil:
+		return c.peeker.Peek(addr)
+	}
 	return c.bus.Read(addr)
 }
 
+// peek16 peeks at a 16-bit value stored least significant byte first (little endian).
+func (c *CPU) peek16(addr uint16) uint16 {
+	return uint16(c.Peek(addr+1))<<8 | uint16(c.Peek(addr))
+}
+
+// poke stores an 8-bit value at the specified address without the CPU accessing the bus, the counterpart of Peek.
+// Devices that implement bus.Poker do not treat it as a write.
+func (c *CPU) poke(addr uint16, data byte) {
+	switch {
+	case c.ram != nil:
+		c.ram.Write(addr, data)
+	case c.poker != nil:
+		c.poker.Poke(addr, data)
+	default:
+		c.bus.Write(addr, data)
+	}
+}
+
 // Read16 reads a 16-bit value from the bus at the specified address.
 // The value is assumed to be stored least significant byte first (little endian).
 func (c *CPU) Read16(addr uint16) uint16 {
-	lo := uint16(c.Read(addr))
-	hi := uint16(c.Read(addr + 1))
+	return c.read16(addr, c.dataKind)
+}
+
+// read16 reads a 16-bit value from the bus, reporting it to any hooks as the given kind of access.
+func (c *CPU) read16(addr uint16, kind AccessKind) uint16 {
+	lo := uint16(c.read(addr, kind))
+	hi := uint16(c.read(addr+1, kind))
 	return (hi << 8) | lo
 }
 
 // Write writes an 8-bit value to the bus at ths specified address.
 func (c *CPU) Write(addr uint16, data byte) {
-	c.bus.Write(addr, data)
+	c.write(addr, data, AccessData)
+}
+
+// write writes an 8-bit value to the bus, reporting it to any hooks as the given kind of access.
+func (c *CPU) write(addr uint16, data byte, kind AccessKind) {
+	if c.accessLog != nil {
+		c.accessLog = append(c.accessLog, BusAccess{Address: addr, Data: data, Write: true})
+	}
+	if c.hooks != nil {
+		c.accessed(BusAccess{Address: addr, Data: data, Write: true}, kind)
+	}
+	if c.history != nil {
+		c.recordWrite(addr)
+	}
+	if c.jit != nil {
+		c.jit.written(addr)
+	}
+	if c.ram != nil {
+		c.ram.Write(addr, data)
+	} else {
+		c.bus.Write(addr, data)
+	}
 }
 
 // Write16 writes a 16-bit value to the bus at the specified address.
 // The value is written least significant byte first (little endian).
 func (c *CPU) Write16(addr uint16, data uint16) {
-	c.bus.Write(addr, uint8(data&0xFF))
-	c.bus.Write(addr+1, uint8(data>>8))
+	c.write(addr, uint8(data&0xFF), AccessData)
+	c.write(addr+1, uint8(data>>8), AccessData)
 }
 
 // GetFlag returns the value of a specific bit of the status register.
@@ -208,7 +424,7 @@ func (c *CPU) Cycles() uint8 {
 func (c *CPU) Push(value uint8) {
 	// Remember that the stack is stored in page 1 (so we need to add 0x100 to the value of the stack pointer).
 	// Also, the stack pointer starts at 0xFD after a reset and grows down, so we need to decrement it after pushing.
-	c.Write(0x100|uint16(c.SP), value)
+	c.write(0x100|uint16(c.SP), value, AccessStack)
 	c.SP--
 }
 
@@ -224,7 +440,7 @@ func (c *CPU) Push16(value uint16) {
 // Pop pops an 8-bit value off the stack.
 func (c *CPU) Pop() uint8 {
 	c.SP++
-	return c.Read(0x100 | uint16(c.SP))
+	return c.read(0x100|uint16(c.SP), AccessStack)
 }
 
 // Pop16 pops a 16-bit value off the stack.
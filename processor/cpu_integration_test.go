@@ -9,8 +9,8 @@ import (
 )
 
 // TestIntegration is a simple integration test that loads a basic program into memory and executes it.
-// Only a few instructions and address modes are tested here, but combined with the unit tests it's good
-// enough for now. For a full test we'd probably want to run something like nestest.
+// Only a few instructions and address modes are tested here. The full test programs, nestest and Klaus Dormann's
+// functional and decimal tests, are run by the testrom package when their binaries are available.
 // https://www.nesdev.org/wiki/Emulator_tests
 func TestIntegration(t *testing.T) {
 	// Create a new bus
@@ -44,26 +44,15 @@ func TestIntegration(t *testing.T) {
 	// Create new CPU
 	cpu := processor.NewCPU(bus)
 
-	// Clock the CPU until the Program Counter equals 0x0000 indicating that our program has finished.
+	// Step the CPU until the Program Counter equals 0x0000 indicating that our program has finished.
 	// This works because the memory is zeroed-out when it is initialised. This means that the next
 	// instruction after the end of our program will be interpreted as a BRK (interrupt). This will cause
 	// the Program Counter to be set to the memory address stored in the IRQ Vector (0xFFFE) which will
 	// be 0x0000. In case of a bug in the emulator we will also stop if we clock the CPU more than 1,000 times.
 	totalClockCycles := 0
 	clockCycleLimit := 1000
-	for {
-		// Step through one instruction
-		for {
-			cpu.Clock()
-			totalClockCycles++
-			if cpu.Cycles() == 0 || totalClockCycles > clockCycleLimit {
-				break
-			}
-		}
-		// Break if Program Counter == 0x0000
-		if cpu.PC == 0x0000 || totalClockCycles > clockCycleLimit {
-			break
-		}
+	for cpu.PC != 0x0000 && totalClockCycles <= clockCycleLimit {
+		totalClockCycles += int(cpu.Step().Cycles)
 	}
 
 	assert.Equal(t, uint16(0x0000), cpu.PC, "Expected Program Counter to be 0x0000")
@@ -1,6 +1,8 @@
 package processor_test
 
 import (
+	"reflect"
+	"runtime"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -26,6 +28,34 @@ func TestNewCPU(t *testing.T) {
 	assert.Equal(t, uint8(0b00100100), cpu.Status, "Status Flags should be 0b00100100")
 }
 
+func TestNewCPU_DefaultVariant(t *testing.T) {
+	cpu := processor.NewCPU(bus.NewSimpleBus())
+
+	assert.Equal(t, processor.Variant2A03, cpu.Variant(), "Default variant should be 2A03")
+}
+
+func TestNewCPUWithVariant(t *testing.T) {
+	cpu := processor.NewCPUWithVariant(bus.NewSimpleBus(), processor.VariantNMOS)
+
+	assert.Equal(t, processor.VariantNMOS, cpu.Variant(), "Variant should be NMOS")
+	assert.Equal(t, uint8(0xFD), cpu.SP, "Stack Pointer should be 0xFD")
+	assert.Equal(t, uint8(0b00100100), cpu.Status, "Status Flags should be 0b00100100")
+}
+
+func TestParseVariant(t *testing.T) {
+	variant, err := processor.ParseVariant("nmos")
+	assert.NoError(t, err)
+	assert.Equal(t, processor.VariantNMOS, variant)
+	assert.Equal(t, "NMOS", variant.String())
+
+	variant, err = processor.ParseVariant("2A03")
+	assert.NoError(t, err)
+	assert.Equal(t, processor.Variant2A03, variant)
+
+	_, err = processor.ParseVariant("z80")
+	assert.Error(t, err)
+}
+
 func TestResetVector(t *testing.T) {
 	// Write value (0x1234) for PC to 0xFFFC
 	bus := bus.NewSimpleBus()
@@ -90,75 +120,6 @@ func TestClock(t *testing.T) {
 	assert.Equal(t, uint8(0), cpu.Cycles(), "Expected Cycles to be 0")
 }
 
-func TestIRQ_Enabled(t *testing.T) {
-	bus := bus.NewSimpleBus()
-	cpu := processor.NewCPU(bus)
-
-	// Set the Program Counter to a known value
-	cpu.PC = 0x2000
-
-	// Write a 16-bit value to memory to 0xFFFE
-	cpu.Write16(0xFFFE, 0x1005)
-
-	// Enable interrupts
-	cpu.SetFlag(processor.I, false)
-
-	// Read current status flags
-	statusFlags := cpu.Status
-
-	// Trigger IRQ
-	cpu.IRQ()
-
-	assert.Equal(t, uint16(0x1005), cpu.PC, "Expected PC to be 0x1005")
-	assert.Equal(t, true, cpu.GetFlag(processor.I), "Disable Interrupt flag should be true")
-	assert.Equal(t, uint8(0xFA), cpu.SP, "Expected stack pointer to be 0xFA (3 bytes pushed)")
-	assert.Equal(t, statusFlags&^0x10, cpu.Pop(), "Expected stack to contain status flags with B clear")
-	assert.Equal(t, uint16(0x2000), cpu.Pop16(), "Expected stack contain original PC value (0x2000)")
-}
-
-func TestIRQ_Disabled(t *testing.T) {
-	bus := bus.NewSimpleBus()
-	cpu := processor.NewCPU(bus)
-
-	// Set the Program Counter to a known value
-	cpu.PC = 0x2000
-
-	// Write a 16-bit value to memory to 0xFFFE
-	cpu.Write16(0xFFFE, 0x1005)
-
-	// Disable interrupts
-	cpu.SetFlag(processor.I, true)
-
-	// Trigger IRQ
-	cpu.IRQ()
-
-	assert.Equal(t, uint16(0x2000), cpu.PC, "Expected PC to be 0x2000")
-	assert.Equal(t, uint8(0xFD), cpu.SP, "Expected stack pointer to be 0xFD (nothing pushed)")
-}
-
-func TestNMI(t *testing.T) {
-	bus := bus.NewSimpleBus()
-	cpu := processor.NewCPU(bus)
-
-	// Set the Program Counter to a known value
-	cpu.PC = 0x2000
-
-	// Write a 16-bit value to memory to 0xFFFA
-	cpu.Write16(0xFFFA, 0x1005)
-
-	// Read current status flags
-	statusFlags := cpu.Status
-
-	// Trigger NMI
-	cpu.NMI()
-
-	assert.Equal(t, uint16(0x1005), cpu.PC, "Expected PC to be 0x1005")
-	assert.Equal(t, true, cpu.GetFlag(processor.I), "Disable Interrupt flag should be true")
-	assert.Equal(t, uint8(0xFA), cpu.SP, "Expected stack pointer to be 0xFA (3 bytes pushed)")
-	assert.Equal(t, statusFlags&^0x10, cpu.Pop(), "Expected stack to contain status flags with B clear")
-	assert.Equal(t, uint16(0x2000), cpu.Pop16(), "Expected stack contain original PC value (0x2000)")
-}
-
 func TestRead(t *testing.T) {
 	bus := bus.NewSimpleBus()
 	bus.Write(0x1234, 0xAB)
@@ -342,3 +303,20 @@ func TestPop16(t *testing.T) {
 	assert.Equal(t, uint16(0x1234), cpu.Pop16(), "Expected to pop 0x1234 off the stack")
 	assert.Equal(t, uint8(0xFD), cpu.SP, "Expected the Stack Pointer to be 0xFD")
 }
+
+func TestMnemonic(t *testing.T) {
+	for _, variant := range []processor.Variant{processor.Variant2A03, processor.Variant65C02, processor.VariantR65C02} {
+		cpu := processor.NewCPUWithVariant(bus.NewSimpleBus(), variant)
+		for opcode := 0; opcode < 256; opcode++ {
+			op := cpu.GetOperation(uint8(opcode))
+			assert.Equal(t, op.Name(), cpu.Mnemonic(uint8(opcode)), "Mnemonic of opcode 0x%02X should match its operation", opcode)
+			function := runtime.FuncForPC(reflect.ValueOf(op.Instruction).Pointer()).Name()
+			assert.Equal(t, "github.com/ukdave/6502_emulator/processor."+op.Mnemonic, function,
+				"Mnemonic of opcode 0x%02X should name its instruction function", opcode)
+		}
+	}
+	cpu := processor.NewCPU(bus.NewSimpleBus())
+	assert.Equal(t, "LDA", cpu.Mnemonic(0xBD))
+	assert.Equal(t, "ABX", cpu.GetOperation(0xBD).AddressModeName())
+	assert.Equal(t, "Mode(99)", processor.Mode(99).String())
+}
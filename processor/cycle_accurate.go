@@ -0,0 +1,628 @@
+package processor
+
+import "errors"
+
+// Cycle accurate execution.
+//
+// By default Clock executes a whole instruction on its first cycle, performing all of its bus accesses at once, and
+// then counts down the remaining cycles. That is fast and good enough for running programs, but memory-mapped
+// hardware that reacts to the timing of accesses (a register that is cleared when it is read, or a write that
+// acknowledges an interrupt) needs to see the same sequence of bus accesses as the real chip.
+//
+// In cycle accurate mode each call to Clock performs exactly the one bus read or write that the NMOS 6502 performs
+// on that cycle, including the dummy reads made while the chip works out an address and the dummy write made by
+// read-modify-write instructions (which write the unmodified value back before writing the result). The sequences
+// follow "64doc" by John West and Marko Mäkelä.
+//
+// The instruction logic itself is shared with the default mode. The sequencer performs the addressing and the
+// data read, then calls the instruction function with the data latched, so the instruction sees the value without
+// accessing the bus a second time.
+//
+// http://www.6502.org/tutorials/64doc.txt
+
+// accessKind classifies how an instruction uses its operand address.
+type accessKind uint8
+
+const (
+	accessNone  accessKind = iota // Implied, accumulator and control flow instructions
+	accessRead                    // Reads its operand (LDA, ADC, NOP zp, ...)
+	accessWrite                   // Writes its operand without reading it (STA, SAX, ...)
+	accessRMW                     // Reads, modifies and writes back its operand (ASL, INC, SLO, ...)
+)
+
+// microSequence identifies the cycles an opcode performs in cycle accurate mode. Most instructions follow the
+// sequence of their address mode and access kind; the stack and control flow instructions have their own.
+type microSequence uint8
+
+const (
+	sequenceAddressed microSequence = iota // Follows the address mode (see microStep)
+	sequenceBRK
+	sequenceRTI
+	sequenceRTS
+	sequenceJSR
+	sequenceJMP
+	sequencePush // PHA and PHP
+	sequencePull // PLA and PLP
+	sequenceJAM
+)
+
+// instructionSequences are the instructions with a sequence of their own. The sequence and access kind of each
+// opcode are looked up when the CPU decodes its opcodes (see decodeOpcodes), not while it runs.
+var instructionSequences = map[string]microSequence{
+	"BRK": sequenceBRK, "RTI": sequenceRTI, "RTS": sequenceRTS, "JSR": sequenceJSR, "JMP": sequenceJMP,
+	"PHA": sequencePush, "PHP": sequencePush, "PLA": sequencePull, "PLP": sequencePull, "JAM": sequenceJAM,
+}
+
+// instructionAccess is the access kind of each NMOS instruction that has an operand address.
+var instructionAccess = map[string]accessKind{
+	"LDA": accessRead, "LDX": accessRead, "LDY": accessRead, "EOR": accessRead, "AND": accessRead,
+	"ORA": accessRead, "ADC": accessRead, "SBC": accessRead, "CMP": accessRead, "CPX": accessRead,
+	"CPY": accessRead, "BIT": accessRead, "LAX": accessRead, "LAS": accessRead, "NOP": accessRead,
+	"ANC": accessRead, "ALR": accessRead, "ARR": accessRead, "SBX": accessRead, "XAA": accessRead,
+	"LXA": accessRead,
+
+	"STA": accessWrite, "STX": accessWrite, "STY": accessWrite, "SAX": accessWrite, "AHX": accessWrite,
+	"SHX": accessWrite, "SHY": accessWrite, "TAS": accessWrite,
+
+	"ASL": accessRMW, "LSR": accessRMW, "ROL": accessRMW, "ROR": accessRMW, "INC": accessRMW,
+	"DEC": accessRMW, "SLO": accessRMW, "RLA": accessRMW, "SRE": accessRMW, "RRA": accessRMW,
+	"DCP": accessRMW, "ISC": accessRMW,
+}
+
+// microState holds the progress of the instruction being executed in cycle accurate mode.
+type microState struct {
+	active      bool
+	op          Operation
+	sequence    microSequence
+	mode        Mode
+	kind        accessKind
+	step        uint8  // The cycle of the instruction being executed (the opcode fetch is cycle 1)
+	addr        uint16 // The address being assembled, and finally the effective address
+	ptr         uint16 // A pointer read from zero page or the instruction operands
+	pageChanged bool   // Indexing crossed a page boundary
+	data        byte   // The operand read from memory
+	vector      uint16 // The vector used by an interrupt sequence
+	interrupt   bool   // The sequence is a hardware interrupt rather than an instruction
+}
+
+// SetCycleAccurate enables or disables cycle accurate execution (see above). It is only supported by the NMOS
+// variants; an error is returned for the CMOS variants. The mode should only be changed between instructions.
+func (c *CPU) SetCycleAccurate(enabled bool) error {
+	if enabled && c.variant.isCMOS() {
+		return errors.New("cycle accurate execution is only supported by the NMOS variants")
+	}
+	c.cycleAccurate = enabled
+	c.micro = microState{}
+	return nil
+}
+
+// CycleAccurate returns true if cycle accurate execution is enabled.
+func (c *CPU) CycleAccurate() bool {
+	return c.cycleAccurate
+}
+
+// clockCycleAccurate advances the CPU by a single clock cycle in cycle accurate mode.
+func (c *CPU) clockCycleAccurate() {
+	c.TotalCycles++
+	if c.stalled() {
+		return
+	}
+	m := &c.micro
+
+	if c.pendingReset {
+		// Cycle 1 of the reset sequence
+		c.last = StepResult{PC: c.PC, Address: resetVector, Interrupt: InterruptReset}
+		c.read(c.PC, AccessOpcode)
+		*m = microState{active: true, step: 1, vector: resetVector, interrupt: true, op: Operation{Cycles: 7}}
+		c.pendingReset = false
+		c.cycles = 6
+		return
+	}
+
+	if !m.active {
+		if !c.wake() {
+			return
+		}
+		if c.pendingInterrupt != 0 {
+			// Cycle 1 of an interrupt sequence: the opcode is fetched but ignored, and PC is not incremented
+			c.last = StepResult{PC: c.PC, Address: c.pendingInterrupt, Interrupt: interruptFor(c.pendingInterrupt)}
+			c.read(c.PC, AccessOpcode)
+			*m = microState{active: true, step: 1, vector: c.pendingInterrupt, interrupt: true, op: Operation{Cycles: 7}}
+			if c.pendingInterrupt == nmiVector {
+				c.nmiPending = false
+			}
+			c.pendingInterrupt = 0
+			c.cycles = 6
+			return
+		}
+
+		pc := c.PC
+		opcode := c.read(c.PC, AccessOpcode)
+		c.PC++
+		d := &c.decoded[opcode]
+		c.last.started(pc, opcode, d, 0)
+		c.haltReason = HaltNone
+		*m = microState{active: true, step: 1, op: d.op, sequence: d.sequence, mode: d.op.AddressMode, kind: d.kind}
+		if d.sequence == sequenceBRK {
+			m.vector = irqVector
+		}
+		c.cycles = d.op.Cycles - 1
+		c.pollPenultimate()
+		if c.hooks != nil {
+			c.beforeInstruction(pc, opcode)
+		}
+		return
+	}
+
+	m.step++
+	if c.microStep() {
+		if !m.interrupt {
+			if m.mode != ModeIMP && m.mode != ModeACC {
+				c.last.Address = m.addr
+			}
+			c.checkProgress(c.last.PC, c.last.decoded.brk)
+		}
+		m.active = false
+		c.cycles = 0
+		if c.hooks != nil {
+			c.microDone()
+		}
+		return
+	}
+
+	// Keep an estimate of the remaining cycles, but never let it reach zero until the instruction has finished
+	c.cycles = uint8(max(1, int(m.op.Cycles)-int(m.step)))
+	c.pollPenultimate()
+}
+
+// microDone calls the hooks once the current instruction or sequence has finished.
+func (c *CPU) microDone() {
+	m := &c.micro
+	if !m.interrupt {
+		c.afterInstruction(c.last.PC, c.last.Opcode)
+	}
+	if m.vector != 0 {
+		c.sequenceDone(m.vector, !m.interrupt)
+	}
+}
+
+// pollPenultimate polls the interrupt lines if the cycle just performed may be the penultimate cycle of the
+// instruction. The poll is repeated if the instruction turns out to take longer (for example when indexing
+// crosses a page boundary), so the last poll is the one that counts. The BRK and interrupt sequences do not poll.
+func (c *CPU) pollPenultimate() {
+	m := &c.micro
+	if c.cycles == 1 && m.vector == 0 {
+		c.pollInterrupts(c.GetFlag(I))
+	}
+}
+
+// readLatched performs the data read for an instruction that reads its operand and then executes it.
+func (c *CPU) readLatched(addressInfo AddressInfo) {
+	c.micro.data = c.read(addressInfo.Address, ternary(addressInfo.IsImmediate, AccessOperand, AccessData))
+	c.executeLatched(addressInfo)
+}
+
+// executeLatched executes the current instruction with its data read satisfied from the latched value.
+func (c *CPU) executeLatched(addressInfo AddressInfo) {
+	c.latched = true
+	c.latch = c.micro.data
+	c.micro.op.Instruction(c, addressInfo)
+	c.latched = false
+}
+
+// microStep performs one cycle (from cycle 2 onwards) of the current instruction. It returns true when the
+// instruction is complete.
+func (c *CPU) microStep() bool {
+	m := &c.micro
+	if m.interrupt {
+		return c.microInterrupt()
+	}
+
+	switch m.sequence {
+	case sequenceBRK:
+		return c.microInterrupt()
+	case sequenceRTI, sequenceRTS:
+		return c.microReturn()
+	case sequenceJSR:
+		return c.microJSR()
+	case sequenceJMP:
+		return c.microJMP()
+	case sequencePush:
+		return c.microPush()
+	case sequencePull:
+		return c.microPull()
+	case sequenceJAM:
+		// The processor locks up after reading the next byte
+		c.read(c.PC, AccessOperand)
+		m.op.Instruction(c, AddressInfo{})
+		return true
+	}
+
+	switch m.mode {
+	case ModeIMP, ModeACC:
+		c.read(c.PC, AccessOperand) // Dummy read of the next byte
+		m.op.Instruction(c, AddressInfo{IsAccumulator: m.mode == ModeACC})
+		return true
+	case ModeIMM:
+		m.addr = c.PC
+		addressInfo := AddressInfo{Address: c.PC, IsImmediate: true}
+		c.PC++
+		c.readLatched(addressInfo)
+		return true
+	case ModeREL:
+		return c.microBranch()
+	}
+
+	// The remaining modes calculate an effective address and then hand over to the access sequence
+	return c.microAddress()
+}
+
+// accessSteps is the step on which each memory addressing mode first accesses its effective address, as
+// performed by microAddress, indexed by mode. It is zero for the modes that microAddress doesn't handle.
+var accessSteps = [ModeZPR + 1]uint8{
+	ModeZP0: 3, ModeZPX: 4, ModeZPY: 4, ModeABS: 4, ModeABX: 5, ModeABY: 5, ModeINDX: 6, ModeINDY: 6,
+}
+
+// nextCycleWrites returns true if the next cycle of the current instruction or sequence is a write cycle.
+func (c *CPU) nextCycleWrites() bool {
+	m := &c.micro
+	if !m.active {
+		return false // Opcode fetch
+	}
+	step := m.step + 1
+	if m.interrupt || m.sequence == sequenceBRK {
+		return step >= 3 && step <= 5 && m.vector != resetVector
+	}
+	switch m.sequence {
+	case sequenceJSR:
+		return step == 4 || step == 5
+	case sequencePush:
+		return step == 3
+	}
+
+	first := accessSteps[m.mode]
+	if first == 0 {
+		return false
+	}
+	switch m.kind {
+	case accessWrite:
+		return step == first
+	case accessRMW:
+		return step == first+1 || step == first+2
+	}
+	return false
+}
+
+// microAddress performs the addressing cycles for the memory addressing modes, followed by the data access. The
+// step numbers are those of the addressing mode; once the effective address is known the remaining cycles are
+// handled by microAccess.
+func (c *CPU) microAddress() bool {
+	m := &c.micro
+	switch m.mode {
+	case ModeZP0:
+		if m.step == 2 {
+			m.addr = uint16(c.read(c.PC, AccessOperand))
+			c.PC++
+			return false
+		}
+		return c.microAccess(3)
+
+	case ModeZPX, ModeZPY:
+		index := ternary(m.mode == ModeZPX, c.X, c.Y)
+		switch m.step {
+		case 2:
+			m.addr = uint16(c.read(c.PC, AccessOperand))
+			c.PC++
+			return false
+		case 3:
+			c.Read(m.addr) // Dummy read from the unindexed address
+			m.addr = uint16(byte(m.addr) + index)
+			return false
+		}
+		return c.microAccess(4)
+
+	case ModeABS:
+		switch m.step {
+		case 2:
+			m.addr = uint16(c.read(c.PC, AccessOperand))
+			c.PC++
+			return false
+		case 3:
+			m.addr |= uint16(c.read(c.PC, AccessOperand)) << 8
+			c.PC++
+			return false
+		}
+		return c.microAccess(4)
+
+	case ModeABX, ModeABY:
+		index := ternary(m.mode == ModeABX, c.X, c.Y)
+		switch m.step {
+		case 2:
+			m.addr = uint16(c.read(c.PC, AccessOperand))
+			c.PC++
+			return false
+		case 3:
+			m.addr |= uint16(c.read(c.PC, AccessOperand)) << 8
+			c.PC++
+			return false
+		}
+		return c.microIndexed(4, index)
+
+	case ModeINDX:
+		switch m.step {
+		case 2:
+			m.ptr = uint16(c.read(c.PC, AccessOperand))
+			c.PC++
+			return false
+		case 3:
+			c.Read(m.ptr) // Dummy read from the unindexed pointer
+			m.ptr = uint16(byte(m.ptr) + c.X)
+			return false
+		case 4:
+			m.addr = uint16(c.Read(m.ptr))
+			return false
+		case 5:
+			m.addr |= uint16(c.Read(uint16(byte(m.ptr)+1))) << 8
+			return false
+		}
+		return c.microAccess(6)
+
+	case ModeINDY:
+		switch m.step {
+		case 2:
+			m.ptr = uint16(c.read(c.PC, AccessOperand))
+			c.PC++
+			return false
+		case 3:
+			m.addr = uint16(c.Read(m.ptr))
+			return false
+		case 4:
+			m.addr |= uint16(c.Read(uint16(byte(m.ptr)+1))) << 8
+			return false
+		}
+		return c.microIndexed(5, c.Y)
+	}
+
+	// Not reachable for the NMOS opcode tables
+	return true
+}
+
+// microIndexed performs the cycles of an absolute indexed or indirect indexed access, starting at the given step
+// with m.addr holding the base address.
+//
+// The processor adds the index to the low byte of the address first and reads from the result before the high
+// byte is fixed up. A read instruction that does not cross a page boundary uses that read and finishes a cycle
+// early. Otherwise the read is a dummy read from the wrong page (or, if no page was crossed, from the right one).
+func (c *CPU) microIndexed(first uint8, index byte) bool {
+	m := &c.micro
+	if m.step == first {
+		base := m.addr
+		m.addr = base + uint16(index)
+		m.pageChanged = pagesDiffer(base, m.addr)
+		unfixed := base&0xFF00 | m.addr&0x00FF
+		if m.kind == accessRead && !m.pageChanged {
+			c.readLatched(AddressInfo{Address: m.addr})
+			return true
+		}
+		c.Read(unfixed) // Dummy read before the high byte is fixed
+		return false
+	}
+	return c.microAccess(first + 1)
+}
+
+// microAccess performs the data access cycles, starting at the given step with m.addr holding the effective
+// address. Reads and writes take a single cycle. Read-modify-write instructions read the value, write it back
+// unmodified while the ALU works on it, and then write the result.
+func (c *CPU) microAccess(first uint8) bool {
+	m := &c.micro
+	addressInfo := AddressInfo{Address: m.addr, PageChanged: m.pageChanged}
+	switch m.kind {
+	case accessRead:
+		c.readLatched(addressInfo)
+		return true
+	case accessWrite:
+		m.op.Instruction(c, addressInfo)
+		return true
+	case accessRMW:
+		switch m.step - first {
+		case 0:
+			m.data = c.Read(m.addr)
+			return false
+		case 1:
+			c.Write(m.addr, m.data) // Dummy write of the unmodified value
+			return false
+		}
+		c.executeLatched(addressInfo)
+		return true
+	}
+
+	// An instruction with an operand address that does not access it; execute it without touching the bus
+	m.op.Instruction(c, addressInfo)
+	return true
+}
+
+// microBranch performs the cycles of a conditional branch. The offset is read on cycle 2; if the branch is taken
+// cycle 3 adds it to the low byte of PC, and if that crosses a page boundary cycle 4 fixes the high byte.
+func (c *CPU) microBranch() bool {
+	m := &c.micro
+	switch m.step {
+	case 2:
+		offset := c.read(c.PC, AccessOperand)
+		c.PC++
+		m.addr = c.PC + uint16(int8(offset))
+
+		// Evaluate the condition by running the instruction against a copy of the PC. Branch instructions only
+		// touch the PC and the cycle count, both of which are restored.
+		pc, cycles := c.PC, c.cycles
+		m.op.Instruction(c, AddressInfo{Address: m.addr})
+		taken := c.PC != pc || c.cycles != cycles
+		c.PC, c.cycles = pc, cycles
+		return !taken
+	case 3:
+		c.read(c.PC, AccessOperand) // Dummy read of the next opcode
+		m.pageChanged = pagesDiffer(c.PC, m.addr)
+		c.PC = c.PC&0xFF00 | m.addr&0x00FF
+		if !m.pageChanged {
+			return true
+		}
+		m.op.Cycles++ // Keep the remaining cycle estimate in step
+		return false
+	}
+	c.read(c.PC, AccessOperand) // Dummy read from the wrong page
+	c.PC = m.addr
+	return true
+}
+
+// microInterrupt performs the cycles of BRK and of the IRQ, NMI and reset sequences. BRK reads (and skips) its
+// signature byte on cycle 2, whereas an interrupt performs a dummy read without incrementing PC.
+func (c *CPU) microInterrupt() bool {
+	m := &c.micro
+	if m.vector == resetVector && m.step >= 3 && m.step <= 5 {
+		// The reset sequence reads from the stack instead of writing to it
+		c.read(0x100|uint16(c.SP), AccessStack)
+		c.SP--
+		return false
+	}
+
+	switch m.step {
+	case 2:
+		c.read(c.PC, AccessOperand)
+		if !m.interrupt {
+			m.addr = c.PC // The signature byte
+			c.PC++
+		}
+	case 3:
+		c.Push(uint8(c.PC >> 8))
+	case 4:
+		c.Push(uint8(c.PC))
+	case 5:
+		if m.interrupt {
+			c.Push(c.Status &^ byte(B))
+		} else {
+			c.Push(c.Status | byte(B))
+		}
+		if m.vector == irqVector && c.nmiPending {
+			// The NMI hijacks the sequence
+			c.nmiPending = false
+			m.vector = nmiVector
+			c.hijacked()
+		}
+	case 6:
+		m.ptr = uint16(c.read(m.vector, AccessVector))
+		c.enterInterrupt()
+	case 7:
+		m.ptr |= uint16(c.read(m.vector+1, AccessVector)) << 8
+		c.PC = m.ptr
+		return true
+	}
+	return false
+}
+
+// microReturn performs the cycles of RTI and RTS. Both perform a dummy read of the next byte and of the top of the
+// stack before pulling. RTS finishes with a read of the return address while incrementing it.
+func (c *CPU) microReturn() bool {
+	m := &c.micro
+	rti := m.sequence == sequenceRTI
+	switch m.step {
+	case 2:
+		c.read(c.PC, AccessOperand)
+	case 3:
+		c.read(0x100|uint16(c.SP), AccessStack)
+	case 4:
+		if rti {
+			c.Status = c.Pop()
+			c.SetFlag(B, false)
+			c.SetFlag(U, true)
+		} else {
+			m.addr = uint16(c.Pop())
+		}
+	case 5:
+		if rti {
+			m.addr = uint16(c.Pop())
+		} else {
+			m.addr |= uint16(c.Pop()) << 8
+		}
+	case 6:
+		if rti {
+			m.addr |= uint16(c.Pop()) << 8
+			c.PC = m.addr
+		} else {
+			c.read(m.addr, AccessOperand)
+			c.PC = m.addr + 1
+		}
+		return true
+	}
+	return false
+}
+
+// microJSR performs the cycles of JSR. The high byte of the target is read last, after the return address (which
+// points at that byte) has been pushed.
+func (c *CPU) microJSR() bool {
+	m := &c.micro
+	switch m.step {
+	case 2:
+		m.addr = uint16(c.read(c.PC, AccessOperand))
+		c.PC++
+	case 3:
+		c.read(0x100|uint16(c.SP), AccessStack)
+	case 4:
+		c.Push(uint8(c.PC >> 8))
+	case 5:
+		c.Push(uint8(c.PC))
+	case 6:
+		m.addr |= uint16(c.read(c.PC, AccessOperand)) << 8
+		c.PC = m.addr
+		return true
+	}
+	return false
+}
+
+// microJMP performs the cycles of JMP absolute and JMP indirect, including the NMOS page wrapping bug.
+func (c *CPU) microJMP() bool {
+	m := &c.micro
+	switch m.step {
+	case 2:
+		m.ptr = uint16(c.read(c.PC, AccessOperand))
+		c.PC++
+		return false
+	case 3:
+		m.ptr |= uint16(c.read(c.PC, AccessOperand)) << 8
+		c.PC++
+		if m.mode == ModeABS {
+			m.addr = m.ptr
+			c.PC = m.addr
+			return true
+		}
+		return false
+	case 4:
+		m.addr = uint16(c.Read(m.ptr))
+		return false
+	}
+	m.addr |= uint16(c.Read(m.ptr&0xFF00|uint16(byte(m.ptr)+1))) << 8
+	c.PC = m.addr
+	return true
+}
+
+// microPush performs the cycles of PHA and PHP.
+func (c *CPU) microPush() bool {
+	if c.micro.step == 2 {
+		c.read(c.PC, AccessOperand)
+		return false
+	}
+	c.micro.op.Instruction(c, AddressInfo{})
+	return true
+}
+
+// microPull performs the cycles of PLA and PLP, which read the stack once before incrementing the stack pointer.
+func (c *CPU) microPull() bool {
+	switch c.micro.step {
+	case 2:
+		c.read(c.PC, AccessOperand)
+		return false
+	case 3:
+		c.read(0x100|uint16(c.SP), AccessStack)
+		return false
+	}
+	c.micro.op.Instruction(c, AddressInfo{})
+	return true
+}
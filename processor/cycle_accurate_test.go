@@ -0,0 +1,224 @@
+package processor_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/ukdave/6502_emulator/bus"
+	"github.com/ukdave/6502_emulator/internal/cputest"
+	"github.com/ukdave/6502_emulator/processor"
+)
+
+type CycleAccurateSuite struct {
+	cpuSuite
+}
+
+func TestCycleAccurateSuite(t *testing.T) {
+	suite.Run(t, new(CycleAccurateSuite))
+}
+
+func (suite *CycleAccurateSuite) SetupTest() {
+	suite.bus = bus.NewSimpleBus()
+	suite.cpu = processor.NewCPUWithVariant(suite.bus, processor.VariantNMOS)
+	assert.NoError(suite.T(), suite.cpu.SetCycleAccurate(true))
+}
+
+// step executes a single instruction, checking that each clock cycle makes exactly one bus access, and returns the
+// accesses made
+func (suite *CycleAccurateSuite) step() []processor.BusAccess {
+	result := suite.cpu.Step()
+	assert.Len(suite.T(), result.Accesses, int(result.Cycles), "Each cycle should make exactly one bus access")
+	return result.Accesses
+}
+
+func (suite *CycleAccurateSuite) TestSetCycleAccurate_CMOS() {
+	cpu := processor.NewCPUWithVariant(bus.NewSimpleBus(), processor.Variant65C02)
+	assert.Error(suite.T(), cpu.SetCycleAccurate(true), "CMOS variants should not support cycle accurate execution")
+	assert.False(suite.T(), cpu.CycleAccurate(), "Cycle accurate execution should be disabled")
+	assert.NoError(suite.T(), cpu.SetCycleAccurate(false))
+}
+
+func (suite *CycleAccurateSuite) TestImplied() {
+	suite.load(0xE8) // INX
+	assert.Equal(suite.T(), []processor.BusAccess{read(0x8000, 0xE8), read(0x8001, 0x00)}, suite.step())
+	assert.Equal(suite.T(), uint8(0x01), suite.cpu.X, "X should be 0x01")
+}
+
+func (suite *CycleAccurateSuite) TestImmediate() {
+	suite.load(0xA9, 0x42) // LDA #$42
+	assert.Equal(suite.T(), []processor.BusAccess{read(0x8000, 0xA9), read(0x8001, 0x42)}, suite.step())
+	assert.Equal(suite.T(), uint8(0x42), suite.cpu.A, "Accumulator should be 0x42")
+	assert.Equal(suite.T(), uint16(0x8002), suite.cpu.PC, "PC should be 0x8002")
+}
+
+func (suite *CycleAccurateSuite) TestZeroPageX() {
+	suite.load(0xB5, 0xF0) // LDA $F0,X
+	suite.cpu.X = 0x20
+	suite.bus.Write(0x0010, 0x42)
+	expected := []processor.BusAccess{read(0x8000, 0xB5), read(0x8001, 0xF0), read(0x00F0, 0x00), read(0x0010, 0x42)}
+	assert.Equal(suite.T(), expected, suite.step(), "Indexing should wrap within the zero page")
+	assert.Equal(suite.T(), uint8(0x42), suite.cpu.A, "Accumulator should be 0x42")
+}
+
+func (suite *CycleAccurateSuite) TestAbsoluteX_Read() {
+	suite.load(0xBD, 0x80, 0x20) // LDA $2080,X
+	suite.cpu.X = 0x01
+	suite.bus.Write(0x2081, 0x42)
+	expected := []processor.BusAccess{read(0x8000, 0xBD), read(0x8001, 0x80), read(0x8002, 0x20), read(0x2081, 0x42)}
+	assert.Equal(suite.T(), expected, suite.step())
+	assert.Equal(suite.T(), uint8(0x42), suite.cpu.A, "Accumulator should be 0x42")
+}
+
+func (suite *CycleAccurateSuite) TestAbsoluteX_ReadPageCrossed() {
+	suite.load(0xBD, 0x80, 0x20) // LDA $2080,X
+	suite.cpu.X = 0x90
+	suite.bus.Write(0x2010, 0x99)
+	suite.bus.Write(0x2110, 0x42)
+	expected := []processor.BusAccess{
+		read(0x8000, 0xBD), read(0x8001, 0x80), read(0x8002, 0x20),
+		read(0x2010, 0x99), // Dummy read before the high byte is fixed
+		read(0x2110, 0x42),
+	}
+	assert.Equal(suite.T(), expected, suite.step())
+	assert.Equal(suite.T(), uint8(0x42), suite.cpu.A, "Accumulator should be 0x42")
+}
+
+func (suite *CycleAccurateSuite) TestAbsoluteX_Write() {
+	suite.load(0x9D, 0x80, 0x20) // STA $2080,X
+	suite.cpu.A = 0x42
+	suite.cpu.X = 0x01
+	expected := []processor.BusAccess{
+		read(0x8000, 0x9D), read(0x8001, 0x80), read(0x8002, 0x20),
+		read(0x2081, 0x00), // Stores always take the extra cycle
+		write(0x2081, 0x42),
+	}
+	assert.Equal(suite.T(), expected, suite.step())
+}
+
+func (suite *CycleAccurateSuite) TestIndirectY_PageCrossed() {
+	suite.load(0xB1, 0x10) // LDA ($10),Y
+	suite.cpu.Y = 0x10
+	store16(suite.bus, 0x0010, 0x20F8)
+	suite.bus.Write(0x2108, 0x42)
+	expected := []processor.BusAccess{
+		read(0x8000, 0xB1), read(0x8001, 0x10), read(0x0010, 0xF8), read(0x0011, 0x20),
+		read(0x2008, 0x00), // Dummy read before the high byte is fixed
+		read(0x2108, 0x42),
+	}
+	assert.Equal(suite.T(), expected, suite.step())
+	assert.Equal(suite.T(), uint8(0x42), suite.cpu.A, "Accumulator should be 0x42")
+}
+
+func (suite *CycleAccurateSuite) TestReadModifyWrite() {
+	suite.load(0xE6, 0x10) // INC $10
+	suite.bus.Write(0x0010, 0x41)
+	expected := []processor.BusAccess{
+		read(0x8000, 0xE6), read(0x8001, 0x10), read(0x0010, 0x41),
+		write(0x0010, 0x41), // The unmodified value is written back first
+		write(0x0010, 0x42),
+	}
+	assert.Equal(suite.T(), expected, suite.step())
+}
+
+func (suite *CycleAccurateSuite) TestJSR_RTS() {
+	suite.load(0x20, 0x00, 0x90) // JSR $9000
+	suite.bus.Write(0x9000, 0x60)
+	expected := []processor.BusAccess{
+		read(0x8000, 0x20), read(0x8001, 0x00), read(0x01FD, 0x00),
+		write(0x01FD, 0x80), write(0x01FC, 0x02), read(0x8002, 0x90),
+	}
+	assert.Equal(suite.T(), expected, suite.step())
+	assert.Equal(suite.T(), uint16(0x9000), suite.cpu.PC, "PC should be 0x9000")
+
+	expected = []processor.BusAccess{
+		read(0x9000, 0x60), read(0x9001, 0x00), read(0x01FB, 0x00),
+		read(0x01FC, 0x02), read(0x01FD, 0x80), read(0x8002, 0x90),
+	}
+	assert.Equal(suite.T(), expected, suite.step())
+	assert.Equal(suite.T(), uint16(0x8003), suite.cpu.PC, "PC should be 0x8003")
+}
+
+func (suite *CycleAccurateSuite) TestBRK() {
+	suite.load(0x00, 0xFF) // BRK
+	store16(suite.bus, 0xFFFE, 0x9000)
+	suite.cpu.Status = 0x20
+	expected := []processor.BusAccess{
+		read(0x8000, 0x00), read(0x8001, 0xFF),
+		write(0x01FD, 0x80), write(0x01FC, 0x02), write(0x01FB, 0x30),
+		read(0xFFFE, 0x00), read(0xFFFF, 0x90),
+	}
+	assert.Equal(suite.T(), expected, suite.step())
+	assert.Equal(suite.T(), uint16(0x9000), suite.cpu.PC, "PC should be 0x9000")
+	assert.True(suite.T(), suite.cpu.GetFlag(processor.I), "Disable Interrupt flag should be true")
+}
+
+func (suite *CycleAccurateSuite) TestIRQ() {
+	suite.load(0xEA) // NOP
+	store16(suite.bus, 0xFFFE, 0x9000)
+	suite.cpu.Status = 0x20
+	suite.cpu.AssertIRQ(0)
+	assert.Equal(suite.T(), []processor.BusAccess{read(0x8000, 0xEA), read(0x8001, 0x00)}, suite.step())
+
+	// The interrupt is taken at the end of the NOP
+	expected := []processor.BusAccess{
+		read(0x8001, 0x00), read(0x8001, 0x00),
+		write(0x01FD, 0x80), write(0x01FC, 0x01), write(0x01FB, 0x20),
+		read(0xFFFE, 0x00), read(0xFFFF, 0x90),
+	}
+	assert.Equal(suite.T(), expected, suite.step())
+	assert.Equal(suite.T(), uint16(0x9000), suite.cpu.PC, "PC should be 0x9000")
+	assert.Equal(suite.T(), uint64(9), suite.cpu.TotalCycles, "The interrupt should take 7 cycles")
+}
+
+func (suite *CycleAccurateSuite) TestBranch() {
+	// Not taken
+	suite.load(0xD0, 0x10) // BNE $10
+	suite.cpu.SetFlag(processor.Z, true)
+	assert.Equal(suite.T(), []processor.BusAccess{read(0x8000, 0xD0), read(0x8001, 0x10)}, suite.step())
+	assert.Equal(suite.T(), uint16(0x8002), suite.cpu.PC, "PC should be 0x8002")
+
+	// Taken
+	suite.load(0xD0, 0x10) // BNE $10
+	suite.cpu.SetFlag(processor.Z, false)
+	assert.Equal(suite.T(), []processor.BusAccess{read(0x8000, 0xD0), read(0x8001, 0x10), read(0x8002, 0x00)}, suite.step())
+	assert.Equal(suite.T(), uint16(0x8012), suite.cpu.PC, "PC should be 0x8012")
+
+	// Taken across a page boundary
+	suite.load(0xD0, 0x80) // BNE $80
+	expected := []processor.BusAccess{read(0x8000, 0xD0), read(0x8001, 0x80), read(0x8002, 0x00), read(0x8082, 0x00)}
+	assert.Equal(suite.T(), expected, suite.step())
+	assert.Equal(suite.T(), uint16(0x7F82), suite.cpu.PC, "PC should be 0x7F82")
+}
+
+func (suite *CycleAccurateSuite) TestCyclesMatchDefaultMode() {
+	program := []byte{
+		0xA2, 0x0A, //         LDX #$0A
+		0xA0, 0x80, //         LDY #$80
+		0x96, 0x10, //         STX $10,Y
+		0xFE, 0x00, 0x20, //   INC $2000,X
+		0xB9, 0x90, 0x1F, //   LDA $1F90,Y
+		0x48,       //         PHA
+		0x68,       //         PLA
+		0xCA,       //         DEX
+		0xD0, 0xF4, //         BNE $F4
+		0x6C, 0x00, 0x30, //   JMP ($3000)
+	}
+	create := func(accurate bool) *processor.CPU {
+		cpu, b := cputest.New(processor.VariantNMOS, 0x8000, program...)
+		store16(b, 0x3000, 0x8000)
+		assert.NoError(suite.T(), cpu.SetCycleAccurate(accurate))
+		return cpu
+	}
+	fast, accurate := create(false), create(true)
+	for fast.TotalCycles < 1000 {
+		expected, actual := fast.Step(), accurate.Step()
+		assert.Equal(suite.T(), expected.PC, actual.PC, "Both modes should execute the same instruction")
+		assert.Equal(suite.T(), expected.Address, actual.Address, "Both modes should use the same address")
+		assert.Equal(suite.T(), expected.Cycles, actual.Cycles, "Both modes should take the same number of cycles")
+	}
+	assert.Equal(suite.T(), fast.A, accurate.A, "Both modes should end with the same accumulator")
+	assert.Equal(suite.T(), fast.X, accurate.X, "Both modes should end with the same X")
+	assert.Equal(suite.T(), fast.Status, accurate.Status, "Both modes should end with the same status")
+}
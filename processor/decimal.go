@@ -0,0 +1,136 @@
+package processor
+
+// Decimal (BCD) arithmetic.
+//
+// When the D flag is set, ADC and SBC treat each nibble of their operands as a decimal digit (0–9). The NMOS 6502
+// adjusts the result after each nibble of a binary add or subtract, which leaves the N, V and Z flags in a state
+// that is well defined but not particularly useful:
+//
+//   - ADC sets Z from the binary result, and N and V from the intermediate result after the low nibble has been
+//     adjusted but before the high nibble has been adjusted. Only C is valid.
+//   - SBC sets all of its flags from the binary result. Only the value in A is decimal adjusted.
+//
+// The CMOS variants fix this: N, V and Z all reflect the decimal result, at the cost of one extra clock cycle.
+//
+// The algorithms below follow Bruce Clark's "Decimal Mode" tutorial, which also describes the behaviour when the
+// operands are not valid BCD values.
+//
+// http://www.6502.org/tutorials/decimal_mode.html
+
+// decimalMode returns true if ADC and SBC should perform BCD arithmetic.
+func (c *CPU) decimalMode() bool {
+	return c.GetFlag(D) && c.variant.hasDecimalMode()
+}
+
+// add adds value and the carry flag to the accumulator, using BCD arithmetic when decimal mode is active.
+func (c *CPU) add(value byte) {
+	if c.decimalMode() && c.variant.isCMOS() {
+		c.addDecimalCMOS(value)
+	} else if c.decimalMode() {
+		c.addDecimal(value)
+	} else {
+		c.addBinary(value)
+	}
+}
+
+// subtract subtracts value and the inverted carry flag from the accumulator, using BCD arithmetic when decimal mode
+// is active.
+func (c *CPU) subtract(value byte) {
+	if c.decimalMode() && c.variant.isCMOS() {
+		c.subtractDecimalCMOS(value)
+	} else if c.decimalMode() {
+		c.subtractDecimal(value)
+	} else {
+		c.subtractBinary(value)
+	}
+}
+
+// addBinary adds value and the carry flag to the accumulator using binary arithmetic.
+func (c *CPU) addBinary(value byte) {
+	temp := uint16(c.A) + uint16(value) + ternary(c.GetFlag(C), uint16(1), uint16(0))
+	c.SetFlag(C, temp > 0xFF)
+	c.SetFlag(V, (c.A^value)&0x80 == 0 && (uint16(c.A)^temp)&0x80 != 0)
+	c.A = uint8(temp)
+	c.SetZN(c.A)
+}
+
+// subtractBinary subtracts value and the inverted carry flag from the accumulator using binary arithmetic.
+func (c *CPU) subtractBinary(value byte) {
+	temp := uint16(c.A) - uint16(value) - ternary(c.GetFlag(C), uint16(0), uint16(1))
+	c.SetFlag(C, temp <= 0xFF)
+	c.SetFlag(V, (c.A^value)&0x80 != 0 && (uint16(c.A)^temp)&0x80 != 0)
+	c.A = uint8(temp)
+	c.SetZN(c.A)
+}
+
+// addDecimal adds value and the carry flag to the accumulator using NMOS BCD arithmetic.
+func (c *CPU) addDecimal(value byte) {
+	carry := ternary(c.GetFlag(C), 1, 0)
+	binary := uint16(c.A) + uint16(value) + uint16(carry)
+
+	// Add the low nibbles, adjusting for a decimal carry into the high nibble
+	lo := int(c.A&0x0F) + int(value&0x0F) + carry
+	if lo >= 0x0A {
+		lo = ((lo + 0x06) & 0x0F) + 0x10
+	}
+
+	// Add the high nibbles. N and V come from this intermediate value, before the high nibble is adjusted.
+	result := int(c.A&0xF0) + int(value&0xF0) + lo
+	c.SetFlag(N, result&0x80 != 0)
+	c.SetFlag(V, (c.A^value)&0x80 == 0 && (int(c.A)^result)&0x80 != 0)
+	if result >= 0xA0 {
+		result += 0x60
+	}
+
+	c.SetFlag(C, result >= 0x100)
+	c.SetFlag(Z, binary&0xFF == 0)
+	c.A = uint8(result)
+}
+
+// subtractDecimal subtracts value and the inverted carry flag from the accumulator using NMOS BCD arithmetic.
+func (c *CPU) subtractDecimal(value byte) {
+	borrow := ternary(c.GetFlag(C), 0, 1)
+
+	// Subtract the low nibbles, adjusting for a decimal borrow from the high nibble
+	lo := int(c.A&0x0F) - int(value&0x0F) - borrow
+	if lo < 0 {
+		lo = ((lo - 0x06) & 0x0F) - 0x10
+	}
+
+	// Subtract the high nibbles and adjust
+	result := int(c.A&0xF0) - int(value&0xF0) + lo
+	if result < 0 {
+		result -= 0x60
+	}
+
+	// All flags are set exactly as they would be for a binary subtraction
+	c.subtractBinary(value)
+	c.A = uint8(result)
+}
+
+// addDecimalCMOS adds value and the carry flag to the accumulator using CMOS BCD arithmetic. The result is the same
+// as on the NMOS 6502, but the N and Z flags reflect the final (decimal) value and an extra cycle is taken.
+func (c *CPU) addDecimalCMOS(value byte) {
+	c.addDecimal(value)
+	c.SetZN(c.A)
+	c.cycles++
+}
+
+// subtractDecimalCMOS subtracts value and the inverted carry flag from the accumulator using CMOS BCD arithmetic.
+// C and V come from the binary subtraction, N and Z from the decimal result, and an extra cycle is taken.
+func (c *CPU) subtractDecimalCMOS(value byte) {
+	borrow := ternary(c.GetFlag(C), 0, 1)
+	lo := int(c.A&0x0F) - int(value&0x0F) - borrow
+	result := int(c.A) - int(value) - borrow
+	if result < 0 {
+		result -= 0x60
+	}
+	if lo < 0 {
+		result -= 0x06
+	}
+
+	c.subtractBinary(value)
+	c.A = uint8(result)
+	c.SetZN(c.A)
+	c.cycles++
+}
@@ -7,17 +7,18 @@ type DisassembledOperation struct {
 	Bytes       []byte
 	Operand     uint16
 	Operation   Operation
+	Instruction InstructionInfo
 	Disassembly string
 }
 
 // DisassembleOperation decodes an operation at the given address and returns a DisassembledOperation struct.
 func (c *CPU) DisassembleOperation(addr uint16) DisassembledOperation {
-	opcode := c.Read(addr)
-	op := c.GetOperation(opcode)
+	opcode := c.Peek(addr)
+	info := c.instructions.opcodes[opcode]
 
-	bytes := make([]byte, op.Size)
-	for i := 0; i < int(op.Size); i++ {
-		bytes[i] = c.Read(addr + uint16(i))
+	bytes := make([]byte, info.Size)
+	for i := 0; i < int(info.Size); i++ {
+		bytes[i] = c.Peek(addr + uint16(i))
 	}
 
 	operand := uint16(0)
@@ -27,47 +28,60 @@ func (c *CPU) DisassembleOperation(addr uint16) DisassembledOperation {
 
 	// Generate the disassembly string
 	var disassembly string
-	switch op.AddressModeName() {
-	case "ACC":
-		disassembly = fmt.Sprintf("%s A {%s}", op.Name(), op.AddressModeName())
-	case "IMM":
-		disassembly = fmt.Sprintf("%s #$%02X {%s}", op.Name(), uint8(operand), op.AddressModeName())
-	case "ABS":
-		disassembly = fmt.Sprintf("%s $%04X {%s}", op.Name(), operand, op.AddressModeName())
-	case "ABX":
-		disassembly = fmt.Sprintf("%s $%04X,X {%s}", op.Name(), operand, op.AddressModeName())
-	case "ABY":
-		disassembly = fmt.Sprintf("%s $%04X,Y {%s}", op.Name(), operand, op.AddressModeName())
-	case "ZP0":
-		disassembly = fmt.Sprintf("%s $%02X {%s}", op.Name(), uint8(operand), op.AddressModeName())
-	case "ZPX":
-		disassembly = fmt.Sprintf("%s $%02X,X {%s}", op.Name(), uint8(operand), op.AddressModeName())
-	case "ZPY":
-		disassembly = fmt.Sprintf("%s $%02X,Y {%s}", op.Name(), uint8(operand), op.AddressModeName())
-	case "IMP":
-		disassembly = fmt.Sprintf("%s {%s}", op.Name(), op.AddressModeName())
-	case "REL":
+	name, mode := info.Mnemonic, info.Mode.String()
+	switch info.Mode {
+	case ModeACC:
+		disassembly = fmt.Sprintf("%s A {%s}", name, mode)
+	case ModeIMM:
+		disassembly = fmt.Sprintf("%s #$%02X {%s}", name, uint8(operand), mode)
+	case ModeABS:
+		disassembly = fmt.Sprintf("%s $%04X {%s}", name, operand, mode)
+	case ModeABX:
+		disassembly = fmt.Sprintf("%s $%04X,X {%s}", name, operand, mode)
+	case ModeABY:
+		disassembly = fmt.Sprintf("%s $%04X,Y {%s}", name, operand, mode)
+	case ModeZP0:
+		disassembly = fmt.Sprintf("%s $%02X {%s}", name, uint8(operand), mode)
+	case ModeZPX:
+		disassembly = fmt.Sprintf("%s $%02X,X {%s}", name, uint8(operand), mode)
+	case ModeZPY:
+		disassembly = fmt.Sprintf("%s $%02X,Y {%s}", name, uint8(operand), mode)
+	case ModeIMP:
+		disassembly = fmt.Sprintf("%s {%s}", name, mode)
+	case ModeREL:
 		offset := uint8(operand)
 		// Calculate relative address from instruction address
-		targetAddr := addr + uint16(op.Size) + uint16(offset)
+		targetAddr := addr + uint16(info.Size) + uint16(offset)
 		if offset >= 0x80 {
 			targetAddr -= 0x100
 		}
-		disassembly = fmt.Sprintf("%s $%02X [$%04X] {%s}", op.Name(), offset, targetAddr, op.AddressModeName())
-	case "IND":
-		disassembly = fmt.Sprintf("%s ($%04X) {%s}", op.Name(), operand, op.AddressModeName())
-	case "INDX":
-		disassembly = fmt.Sprintf("%s ($%02X,X) {%s}", op.Name(), uint8(operand), op.AddressModeName())
-	case "INDY":
-		disassembly = fmt.Sprintf("%s ($%02X),Y {%s}", op.Name(), uint8(operand), op.AddressModeName())
+		disassembly = fmt.Sprintf("%s $%02X [$%04X] {%s}", name, offset, targetAddr, mode)
+	case ModeIND:
+		disassembly = fmt.Sprintf("%s ($%04X) {%s}", name, operand, mode)
+	case ModeINDX:
+		disassembly = fmt.Sprintf("%s ($%02X,X) {%s}", name, uint8(operand), mode)
+	case ModeINDY:
+		disassembly = fmt.Sprintf("%s ($%02X),Y {%s}", name, uint8(operand), mode)
+	case ModeZPI:
+		disassembly = fmt.Sprintf("%s ($%02X) {%s}", name, uint8(operand), mode)
+	case ModeIAX:
+		disassembly = fmt.Sprintf("%s ($%04X,X) {%s}", name, operand, mode)
+	case ModeZPR:
+		offset := uint8(operand >> 8)
+		targetAddr := addr + uint16(info.Size) + uint16(offset)
+		if offset >= 0x80 {
+			targetAddr -= 0x100
+		}
+		disassembly = fmt.Sprintf("%s $%02X,$%02X [$%04X] {%s}", name, uint8(operand), offset, targetAddr, mode)
 	default:
-		disassembly = fmt.Sprintf("%s {%s}", op.Name(), op.AddressModeName())
+		disassembly = fmt.Sprintf("%s {%s}", name, mode)
 	}
 
 	return DisassembledOperation{
 		Bytes:       bytes,
 		Operand:     operand,
-		Operation:   op,
+		Operation:   c.GetOperation(opcode),
+		Instruction: info,
 		Disassembly: disassembly,
 	}
 }
@@ -225,14 +225,82 @@ func (suite *DisassembleOperationSuite) TestDisassembleOperation_IndirectIndexed
 	assert.Equal(suite.T(), "LDA ($40),Y {INDY}", result.Disassembly, "Expected disassembly to be 'LDA ($40),Y {INDY}'")
 }
 
-func (suite *DisassembleOperationSuite) TestDisassembleOperation_IllegalOpcode() {
+func (suite *DisassembleOperationSuite) TestDisassembleOperation_UndocumentedOpcode() {
+	// LAX $42 (0xA7 0x42 at address 0x0000)
+	suite.bus.Write(0x0000, 0xA7)
+	suite.bus.Write(0x0001, 0x42)
+
+	result := suite.cpu.DisassembleOperation(0x0000)
+
+	assert.Equal(suite.T(), []byte{0xA7, 0x42}, result.Bytes, "Expected operand to be [0xA7, 0x42]")
+	assert.Equal(suite.T(), uint16(0x42), result.Operand, "Expected operand to be 0x42")
+	assert.Equal(suite.T(), "LAX", result.Operation.Name(), "Expected operation name to be LAX")
+	assert.Equal(suite.T(), "ZP0", result.Operation.AddressModeName(), "Expected address mode to be ZP0")
+	assert.Equal(suite.T(), "LAX $42 {ZP0}", result.Disassembly, "Expected disassembly to be 'LAX $42 {ZP0}'")
+}
+
+func (suite *DisassembleOperationSuite) TestDisassembleOperation_JamOpcode() {
 	suite.bus.Write(0x0000, 0x02)
 
 	result := suite.cpu.DisassembleOperation(0x0000)
 
 	assert.Equal(suite.T(), []byte{0x02}, result.Bytes, "Expected operand to be [0x02]")
 	assert.Equal(suite.T(), uint16(0x00), result.Operand, "Expected operand to be 0x00")
-	assert.Equal(suite.T(), "???", result.Operation.Name(), "Expected operation name to be ???")
+	assert.Equal(suite.T(), "JAM", result.Operation.Name(), "Expected operation name to be JAM")
 	assert.Equal(suite.T(), "IMP", result.Operation.AddressModeName(), "Expected address mode to be IMP")
-	assert.Equal(suite.T(), "??? {IMP}", result.Disassembly, "Expected disassembly to be '??? {IMP}'")
+	assert.Equal(suite.T(), "JAM {IMP}", result.Disassembly, "Expected disassembly to be 'JAM {IMP}'")
+}
+
+func (suite *DisassembleOperationSuite) TestDisassembleOperation_ZeroPageIndirect() {
+	suite.cpu = processor.NewCPUWithVariant(suite.bus, processor.Variant65C02)
+
+	// LDA ($40) (0xB2 0x40 at address 0x0000)
+	suite.bus.Write(0x0000, 0xB2)
+	suite.bus.Write(0x0001, 0x40)
+
+	result := suite.cpu.DisassembleOperation(0x0000)
+
+	assert.Equal(suite.T(), []byte{0xB2, 0x40}, result.Bytes, "Expected operand to be [0xB2, 0x40]")
+	assert.Equal(suite.T(), "LDA ($40) {ZPI}", result.Disassembly, "Expected disassembly to be 'LDA ($40) {ZPI}'")
+}
+
+func (suite *DisassembleOperationSuite) TestDisassembleOperation_AbsoluteIndexedIndirect() {
+	suite.cpu = processor.NewCPUWithVariant(suite.bus, processor.Variant65C02)
+
+	// JMP ($1234,X) (0x7C 0x34 0x12 at address 0x0000)
+	suite.bus.Write(0x0000, 0x7C)
+	suite.bus.Write(0x0001, 0x34)
+	suite.bus.Write(0x0002, 0x12)
+
+	result := suite.cpu.DisassembleOperation(0x0000)
+
+	assert.Equal(suite.T(), uint16(0x1234), result.Operand, "Expected operand to be 0x1234")
+	assert.Equal(suite.T(), "JMP ($1234,X) {IAX}", result.Disassembly, "Expected disassembly to be 'JMP ($1234,X) {IAX}'")
+}
+
+func (suite *DisassembleOperationSuite) TestDisassembleOperation_ZeroPageRelative() {
+	suite.cpu = processor.NewCPUWithVariant(suite.bus, processor.Variant65C02)
+
+	// BBS7 $40,$FD (0xFF 0x40 0xFD at address 0x2000)
+	suite.bus.Write(0x2000, 0xFF)
+	suite.bus.Write(0x2001, 0x40)
+	suite.bus.Write(0x2002, 0xFD)
+
+	result := suite.cpu.DisassembleOperation(0x2000)
+
+	assert.Equal(suite.T(), []byte{0xFF, 0x40, 0xFD}, result.Bytes, "Expected operand to be [0xFF, 0x40, 0xFD]")
+	assert.Equal(suite.T(), "BBS7", result.Operation.Name(), "Expected operation name to be BBS7")
+	assert.Equal(suite.T(), "BBS7 $40,$FD [$2000] {ZPR}", result.Disassembly, "Expected disassembly to be 'BBS7 $40,$FD [$2000] {ZPR}'")
+}
+
+func (suite *DisassembleOperationSuite) TestDisassembleOperation_FollowsVariant() {
+	// 0x80 is an undocumented 2-byte NOP on the NMOS 6502, but BRA on the 65C02
+	suite.bus.Write(0x0000, 0x80)
+	suite.bus.Write(0x0001, 0x02)
+
+	nmos := suite.cpu.DisassembleOperation(0x0000)
+	cmos := processor.NewCPUWithVariant(suite.bus, processor.Variant65C02).DisassembleOperation(0x0000)
+
+	assert.Equal(suite.T(), "NOP #$02 {IMM}", nmos.Disassembly)
+	assert.Equal(suite.T(), "BRA $02 [$0004] {REL}", cmos.Disassembly)
 }
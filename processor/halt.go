@@ -0,0 +1,93 @@
+package processor
+
+import "fmt"
+
+// HaltReason describes why the CPU has stopped making progress.
+//
+// JAM, STP and unimplemented opcodes halt the processor until it is reset. WAI pauses it until an interrupt is
+// signalled. The other reasons describe programs that have effectively finished even though the processor is
+// still running: a tight self-loop (such as "JMP *") only exits if an interrupt arrives, and a BRK through an
+// unset vector sends the processor off to address $0000. Callers that run a program to completion can stop as
+// soon as HaltReason returns anything other than HaltNone.
+type HaltReason uint8
+
+const (
+	HaltNone          HaltReason = iota
+	HaltJAM                      // A JAM opcode locked up the processor
+	HaltSTP                      // An STP instruction stopped the clock
+	HaltWAI                      // A WAI instruction is waiting for an interrupt
+	HaltSelfLoop                 // The last instruction jumped or branched to itself
+	HaltBRKZeroVector            // A BRK instruction jumped through an IRQ vector of $0000
+	HaltUnimplemented            // An unimplemented opcode was executed
+	HaltFault                    // A device refused an access and stopped the processor (see Fault)
+)
+
+var haltReasonNames = map[HaltReason]string{
+	HaltNone:          "none",
+	HaltJAM:           "JAM opcode",
+	HaltSTP:           "STP instruction",
+	HaltWAI:           "waiting for interrupt",
+	HaltSelfLoop:      "self-loop",
+	HaltBRKZeroVector: "BRK with unset vector",
+	HaltUnimplemented: "unimplemented opcode",
+	HaltFault:         "bus fault",
+}
+
+// String returns a short description of the halt reason.
+func (r HaltReason) String() string {
+	if name, ok := haltReasonNames[r]; ok {
+		return name
+	}
+	return fmt.Sprintf("HaltReason(%d)", uint8(r))
+}
+
+// HaltReason returns the reason the CPU has stopped making progress, or HaltNone if it is running normally.
+func (c *CPU) HaltReason() HaltReason {
+	return c.haltReason
+}
+
+// halt stops the processor until it is reset.
+func (c *CPU) halt(reason HaltReason) {
+	c.halted = true
+	c.haltReason = reason
+}
+
+// FaultError is reported by Err when Fault halts the CPU.
+type FaultError struct {
+	PC  uint16 // The address of the instruction that made the access
+	Err error  // The reason the access was refused
+}
+
+func (e *FaultError) Error() string {
+	return fmt.Sprintf("%v by the instruction at $%04X", e.Err, e.PC)
+}
+
+func (e *FaultError) Unwrap() error {
+	return e.Err
+}
+
+// Fault halts the CPU with HaltFault, as if it had executed a JAM, because a device refused an access made by the
+// current instruction. The instruction still completes. Err returns a FaultError wrapping err and naming the
+// instruction; if Fault is called more than once before a reset, the first error is kept. The error is not saved
+// in snapshots.
+//
+// Fault is meant to be called by devices on the bus, for example by a ROM whose write policy is ROMStop.
+func (c *CPU) Fault(err error) {
+	if c.haltReason != HaltFault {
+		c.err = &FaultError{PC: c.last.PC, Err: err}
+	}
+	c.halt(HaltFault)
+}
+
+// checkProgress updates the halt reason once an instruction starting at pc has completed. brk is true if the
+// instruction ran the BRK sequence.
+func (c *CPU) checkProgress(pc uint16, brk bool) {
+	switch {
+	case c.halted || c.waiting:
+		// The reason was set by the instruction
+	case c.PC == pc:
+		c.haltReason = HaltSelfLoop
+	case brk && c.PC == 0x0000:
+		c.haltReason = HaltBRKZeroVector
+	}
+}
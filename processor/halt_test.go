@@ -0,0 +1,121 @@
+package processor_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ukdave/6502_emulator/bus"
+	"github.com/ukdave/6502_emulator/processor"
+)
+
+func TestHaltReason_None(t *testing.T) {
+	cpu := newCPU(processor.Variant2A03, 0xEA) // NOP
+	cpu.Step()
+	assert.Equal(t, processor.HaltNone, cpu.HaltReason(), "Expected no halt reason")
+}
+
+func TestHaltReason_JAM(t *testing.T) {
+	cpu := newCPU(processor.VariantNMOS, 0x02) // JAM
+	cpu.Step()
+	assert.Equal(t, processor.HaltJAM, cpu.HaltReason(), "Expected the JAM to be reported")
+	assert.True(t, cpu.Halted(), "CPU should be halted")
+
+	cpu.Reset()
+	assert.Equal(t, processor.HaltNone, cpu.HaltReason(), "Expected the reset to clear the halt reason")
+}
+
+func TestHaltReason_STP(t *testing.T) {
+	cpu := newCPU(processor.Variant65C02, 0xDB) // STP
+	cpu.Step()
+	assert.Equal(t, processor.HaltSTP, cpu.HaltReason(), "Expected the STP to be reported")
+}
+
+func TestHaltReason_WAI(t *testing.T) {
+	cpu := newCPU(processor.Variant65C02, 0xCB, 0xEA) // WAI; NOP
+	cpu.SetFlag(processor.I, true)
+	cpu.Step()
+	assert.Equal(t, processor.HaltWAI, cpu.HaltReason(), "Expected the WAI to be reported")
+	assert.False(t, cpu.Halted(), "CPU should be waiting rather than halted")
+
+	cpu.AssertIRQ(0)
+	cpu.Step()
+	assert.Equal(t, processor.HaltNone, cpu.HaltReason(), "Expected the interrupt to clear the halt reason")
+}
+
+func TestHaltReason_SelfLoop(t *testing.T) {
+	for _, program := range [][]byte{
+		{0x4C, 0x00, 0x80}, // JMP $8000
+		{0xF0, 0xFE},       // BEQ *
+	} {
+		cpu := newCPU(processor.Variant2A03, program...)
+		cpu.SetFlag(processor.Z, true)
+		cpu.Step()
+		assert.Equal(t, processor.HaltSelfLoop, cpu.HaltReason(), "Expected the self-loop to be reported")
+		assert.False(t, cpu.Halted(), "CPU should still be running")
+	}
+
+	// A branch that is not taken carries on
+	cpu := newCPU(processor.Variant2A03, 0xF0, 0xFE) // BEQ *
+	cpu.Step()
+	assert.Equal(t, processor.HaltNone, cpu.HaltReason(), "Expected no halt reason")
+}
+
+func TestHaltReason_SelfLoop_CycleAccurate(t *testing.T) {
+	cpu := newCPU(processor.VariantNMOS, 0x4C, 0x00, 0x80) // JMP $8000
+	assert.NoError(t, cpu.SetCycleAccurate(true))
+	cpu.Step()
+	assert.Equal(t, processor.HaltSelfLoop, cpu.HaltReason(), "Expected the self-loop to be reported")
+}
+
+func TestHaltReason_BRKZeroVector(t *testing.T) {
+	cpu := newCPU(processor.Variant2A03, 0x00, 0x00) // BRK
+	cpu.Step()
+	assert.Equal(t, processor.HaltBRKZeroVector, cpu.HaltReason(), "Expected the BRK to be reported")
+
+	cpu = newCPU(processor.Variant2A03, 0x00, 0x00) // BRK
+	cpu.Write16(0xFFFE, 0x9000)
+	cpu.Step()
+	assert.Equal(t, processor.HaltNone, cpu.HaltReason(), "Expected no halt reason")
+}
+
+func TestHaltReason_Unimplemented(t *testing.T) {
+	cpu := newCPU(processor.Variant2A03)
+	processor.XXX(cpu, processor.AddressInfo{})
+	assert.Equal(t, processor.HaltUnimplemented, cpu.HaltReason(), "Expected the unimplemented opcode to be reported")
+	assert.True(t, cpu.Halted(), "CPU should be halted")
+}
+
+func TestHaltReason_Fault(t *testing.T) {
+	for _, cycleAccurate := range []bool{false, true} {
+		rom, err := bus.NewROM(0xC000, []byte{0x11})
+		assert.NoError(t, err)
+		ram, mapped := bus.NewSimpleBus(), bus.NewMappedBus()
+		assert.NoError(t, mapped.Map(bus.Mapping{Name: "RAM", Start: 0x0000, End: 0xFFFF, Device: ram}))
+		assert.NoError(t, mapped.Map(rom.Mapping("ROM")))
+		for i, b := range []byte{0xA9, 0x42, 0x8D, 0x00, 0xC0, 0xEA} { // LDA #$42; STA $C000; NOP
+			ram.Write(0x8000+uint16(i), b)
+		}
+		cpu := processor.NewCPUWithVariant(mapped, processor.VariantNMOS)
+		cpu.PC = 0x8000
+		assert.NoError(t, cpu.SetCycleAccurate(cycleAccurate))
+		rom.Writes, rom.Stop = bus.ROMStop, cpu.Fault
+
+		cpu.RunUntil(func(*processor.CPU) bool { return false }, 100)
+		assert.Equal(t, processor.HaltFault, cpu.HaltReason(), "Expected the fault to be reported")
+		assert.Equal(t, uint16(0x8005), cpu.PC, "The faulting instruction should complete")
+		assert.EqualError(t, cpu.Err(), "write of $42 to read-only address $C000 by the instruction at $8002")
+		var accessErr *bus.AccessError
+		assert.ErrorAs(t, cpu.Err(), &accessErr, "The fault should wrap the device's error")
+
+		cpu.Reset()
+		assert.NoError(t, cpu.Err(), "Expected the reset to clear the fault")
+	}
+}
+
+func TestHaltReason_String(t *testing.T) {
+	assert.Equal(t, "none", processor.HaltNone.String())
+	assert.Equal(t, "JAM opcode", processor.HaltJAM.String())
+	assert.Equal(t, "self-loop", processor.HaltSelfLoop.String())
+	assert.Equal(t, "HaltReason(99)", processor.HaltReason(99).String())
+}
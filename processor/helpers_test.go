@@ -0,0 +1,49 @@
+package processor_test
+
+import (
+	"github.com/stretchr/testify/suite"
+
+	"github.com/ukdave/6502_emulator/bus"
+	"github.com/ukdave/6502_emulator/internal/cputest"
+	"github.com/ukdave/6502_emulator/processor"
+)
+
+// newCPU creates a CPU with 64KB of RAM and the given program at 0x8000, with the Program Counter pointing at it
+func newCPU(variant processor.Variant, program ...byte) *processor.CPU {
+	cpu, _ := cputest.New(variant, 0x8000, program...)
+	return cpu
+}
+
+// cpuSuite holds the CPU and RAM of the test suites that run programs, which embed it
+type cpuSuite struct {
+	suite.Suite
+	bus *bus.SimpleBus
+	cpu *processor.CPU
+}
+
+// load writes a program at 0x8000 and points the Program Counter at it
+func (suite *cpuSuite) load(program ...byte) {
+	cputest.Load(suite.cpu, suite.bus, 0x8000, program...)
+}
+
+// handler writes an interrupt handler to the bus at addr and points the vector at it
+func handler(b bus.Bus, vector uint16, addr uint16, code ...byte) {
+	for i, data := range code {
+		b.Write(addr+uint16(i), data)
+	}
+	store16(b, vector, addr)
+}
+
+// store16 writes a 16-bit little endian value to the bus
+func store16(b bus.Bus, addr uint16, value uint16) {
+	b.Write(addr, byte(value))
+	b.Write(addr+1, byte(value>>8))
+}
+
+func read(addr uint16, data byte) processor.BusAccess {
+	return processor.BusAccess{Address: addr, Data: data}
+}
+
+func write(addr uint16, data byte) processor.BusAccess {
+	return processor.BusAccess{Address: addr, Data: data, Write: true}
+}
@@ -0,0 +1,124 @@
+package processor
+
+// Rewind history.
+//
+// When history is enabled the CPU keeps a bounded record of the most recent instructions, so that they can be undone
+// with StepBack and RewindCycles. Each entry holds the state of the CPU at the start of an instruction (or
+// interrupt sequence) and the previous value of each byte the CPU wrote while executing it. Memory use therefore
+// grows with the number of writes made rather than the size of the address space.
+//
+// Only writes made through the CPU are recorded: a device that changes its own state is not rewound. The previous
+// value of a byte is peeked from the bus before it is written (see CPU.Peek), and undoing the write pokes it back, so
+// that a bus implementing bus.Peeker and bus.Poker neither triggers a register's read side effects nor applies its
+// write policies (recording a ROM violation or logging an unmapped write again, say). A device that cannot be poked
+// is written to instead, so undoing a write to its registers has the same side effects as the program writing them
+// (restarting a timer, for example). Devices are only restored exactly by loading a snapshot.
+
+// historyEntry records an instruction so that it can be undone.
+type historyEntry struct {
+	state  cpuState       // The state of the CPU at the start of the instruction
+	writes []historyWrite // The bytes written by the instruction, in the order they were written
+}
+
+// historyWrite is a byte overwritten by an instruction.
+type historyWrite struct {
+	addr uint16
+	old  byte
+}
+
+// history is a ring buffer of the most recent instructions.
+type history struct {
+	entries []historyEntry
+	start   int // Index of the oldest entry
+	count   int // Number of entries in use
+}
+
+// EnableHistory keeps a record of up to depth instructions so that they can be undone. A depth of 0 disables the
+// history. Any existing history is discarded.
+func (c *CPU) EnableHistory(depth int) {
+	if depth <= 0 {
+		c.history = nil
+		return
+	}
+	c.history = &history{entries: make([]historyEntry, depth)}
+}
+
+// HistoryLen returns the number of instructions that can be undone.
+func (c *CPU) HistoryLen() int {
+	if c.history == nil {
+		return 0
+	}
+	return c.history.count
+}
+
+// StepBack undoes the most recent instruction (or interrupt sequence), restoring the CPU and the memory it wrote
+// to their state at the start of it. If the CPU is part way through an instruction, that instruction is undone. It
+// returns false if there is no history to undo. The memory is restored by poking it, so only devices that cannot be
+// poked see the bytes being put back as writes.
+func (c *CPU) StepBack() bool {
+	h := c.history
+	if h == nil || h.count == 0 {
+		return false
+	}
+	h.count--
+	entry := &h.entries[(h.start+h.count)%len(h.entries)]
+	for i := len(entry.writes) - 1; i >= 0; i-- {
+		if c.jit != nil {
+			c.jit.written(entry.writes[i].addr)
+		}
+		c.poke(entry.writes[i].addr, entry.writes[i].old)
+	}
+	c.setState(&entry.state)
+	return true
+}
+
+// RewindCycles undoes instructions until at least n cycles have been rewound or the history runs out, and returns
+// the number of cycles actually rewound. The CPU is always left at the start of an instruction, so more than n
+// cycles may be rewound.
+func (c *CPU) RewindCycles(n uint64) uint64 {
+	start := c.TotalCycles
+	for start-c.TotalCycles < n {
+		if !c.StepBack() {
+			break
+		}
+	}
+	return start - c.TotalCycles
+}
+
+// clearHistory discards the history, keeping its capacity.
+func (c *CPU) clearHistory() {
+	if c.history != nil {
+		c.history.start = 0
+		c.history.count = 0
+	}
+}
+
+// recordInstruction adds an entry to the history if an instruction or sequence is about to start. It is called at
+// the start of each clock cycle.
+func (c *CPU) recordInstruction() {
+	h := c.history
+	if h == nil || c.cycles != 0 || c.halted || c.waiting || c.stalled() {
+		return
+	}
+	var entry *historyEntry
+	if h.count == len(h.entries) {
+		// Reuse the oldest entry
+		entry = &h.entries[h.start]
+		h.start = (h.start + 1) % len(h.entries)
+	} else {
+		entry = &h.entries[(h.start+h.count)%len(h.entries)]
+		h.count++
+	}
+	entry.state = c.state()
+	entry.writes = entry.writes[:0]
+}
+
+// recordWrite records the byte at addr in the current history entry before it is overwritten.
+func (c *CPU) recordWrite(addr uint16) {
+	h := c.history
+	if h == nil || h.count == 0 {
+		return
+	}
+	entry := &h.entries[(h.start+h.count-1)%len(h.entries)]
+	entry.writes = append(entry.writes, historyWrite{addr: addr, old: c.Peek(addr)})
+}
@@ -0,0 +1,149 @@
+package processor_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ukdave/6502_emulator/bus"
+	"github.com/ukdave/6502_emulator/processor"
+)
+
+func TestStepBack(t *testing.T) {
+	for _, cycleAccurate := range []bool{false, true} {
+		cpu := newCPU(processor.VariantNMOS, 0xA9, 0x42, 0x85, 0x10, 0xE6, 0x10, 0x48) // LDA #$42; STA $10; INC $10; PHA
+		assert.NoError(t, cpu.SetCycleAccurate(cycleAccurate))
+		cpu.EnableHistory(10)
+		sp := cpu.SP
+
+		for range 4 {
+			cpu.Step()
+		}
+		assert.Equal(t, uint8(0x43), cpu.Read(0x0010), "Memory at 0x0010 should be 0x43")
+		assert.Equal(t, uint8(0x42), cpu.Read(0x0100+uint16(sp)), "A should be pushed")
+		assert.Equal(t, 4, cpu.HistoryLen(), "Four instructions should be recorded")
+
+		assert.True(t, cpu.StepBack())
+		assert.Equal(t, uint16(0x8006), cpu.PC, "PC should be back at the PHA")
+		assert.Equal(t, sp, cpu.SP, "SP should be restored")
+		assert.Equal(t, uint8(0x00), cpu.Read(0x0100+uint16(sp)), "The push should be undone")
+		assert.Equal(t, uint64(10), cpu.TotalCycles, "TotalCycles should be 10")
+
+		assert.True(t, cpu.StepBack())
+		assert.Equal(t, uint8(0x42), cpu.Read(0x0010), "The INC should be undone")
+
+		assert.True(t, cpu.StepBack())
+		assert.True(t, cpu.StepBack())
+		assert.Equal(t, uint16(0x8000), cpu.PC, "PC should be back at the start")
+		assert.Equal(t, uint8(0x00), cpu.A, "A should be 0x00")
+		assert.Equal(t, uint8(0x00), cpu.Read(0x0010), "Memory at 0x0010 should be 0x00")
+		assert.Equal(t, uint64(0), cpu.TotalCycles, "TotalCycles should be 0")
+		assert.False(t, cpu.StepBack(), "There should be nothing left to undo")
+
+		// Running forwards again gives the same result
+		for range 4 {
+			cpu.Step()
+		}
+		assert.Equal(t, uint8(0x43), cpu.Read(0x0010), "Memory at 0x0010 should be 0x43")
+		assert.Equal(t, uint64(13), cpu.TotalCycles, "TotalCycles should be 13")
+	}
+}
+
+func TestStepBack_PartlyExecuted(t *testing.T) {
+	cpu := newCPU(processor.VariantNMOS, 0xE6, 0x10) // INC $10
+	assert.NoError(t, cpu.SetCycleAccurate(true))
+	cpu.EnableHistory(10)
+
+	for range 4 {
+		cpu.Clock()
+	}
+	assert.NotZero(t, cpu.Cycles(), "The INC should still be running")
+	assert.True(t, cpu.StepBack())
+	assert.Equal(t, uint16(0x8000), cpu.PC, "PC should be back at the INC")
+	assert.Equal(t, uint8(0), cpu.Cycles(), "The CPU should be between instructions")
+	assert.Equal(t, uint64(0), cpu.TotalCycles, "TotalCycles should be 0")
+
+	cpu.Step()
+	assert.Equal(t, uint8(0x01), cpu.Read(0x0010), "The INC should run again from the start")
+}
+
+func TestStepBack_Bounded(t *testing.T) {
+	cpu := newCPU(processor.Variant2A03, 0xE8, 0xE8, 0xE8) // INX; INX; INX
+	cpu.EnableHistory(2)
+	for range 3 {
+		cpu.Step()
+	}
+	assert.Equal(t, 2, cpu.HistoryLen(), "Only two instructions should be kept")
+	assert.True(t, cpu.StepBack())
+	assert.True(t, cpu.StepBack())
+	assert.False(t, cpu.StepBack(), "The oldest instruction should have been dropped")
+	assert.Equal(t, uint8(1), cpu.X, "X should be 1")
+}
+
+func TestStepBack_Interrupt(t *testing.T) {
+	cpu := newCPU(processor.Variant2A03, 0xEA) // NOP
+	cpu.Write16(0xFFFE, 0x9000)
+	cpu.EnableHistory(10)
+	cpu.SetFlag(processor.I, false)
+	cpu.AssertIRQ(0)
+
+	cpu.Step()
+	cpu.Step()
+	assert.Equal(t, uint16(0x9000), cpu.PC, "The IRQ should be taken")
+	assert.True(t, cpu.StepBack())
+	assert.Equal(t, uint16(0x8001), cpu.PC, "PC should be back before the IRQ")
+	assert.False(t, cpu.GetFlag(processor.I), "I should be clear")
+}
+
+func TestRewindCycles(t *testing.T) {
+	cpu := newCPU(processor.Variant2A03, 0xE8, 0xE6, 0x10, 0xE8) // INX; INC $10; INX
+	cpu.EnableHistory(10)
+	for range 3 {
+		cpu.Step()
+	}
+	assert.Equal(t, uint64(9), cpu.TotalCycles, "TotalCycles should be 9")
+
+	assert.Equal(t, uint64(7), cpu.RewindCycles(3), "The INX and INC should be rewound")
+	assert.Equal(t, uint16(0x8001), cpu.PC, "PC should be back at the INC")
+	assert.Equal(t, uint64(2), cpu.RewindCycles(100), "Only the history available should be rewound")
+	assert.Equal(t, uint64(0), cpu.TotalCycles, "TotalCycles should be 0")
+}
+
+func TestStepBack_MappedBus(t *testing.T) {
+	rom, err := bus.NewROM(0xC000, []byte{0x11})
+	assert.NoError(t, err)
+	ram, mapped := bus.NewSimpleBus(), bus.NewMappedBus()
+	assert.NoError(t, mapped.Map(bus.Mapping{Name: "RAM", Start: 0x0000, End: 0xBFFF, Device: ram}))
+	assert.NoError(t, mapped.Map(rom.Mapping("ROM")))
+	for i, b := range []byte{0xA9, 0x42, 0x8D, 0x00, 0xC0, 0x8D, 0x00, 0xD0} { // LDA #$42; STA $C000; STA $D000
+		ram.Write(0x8000+uint16(i), b)
+	}
+	var log bytes.Buffer
+	mapped.UnmappedWrite, mapped.Log = bus.UnmappedLog, &log
+	cpu := processor.NewCPUWithVariant(mapped, processor.VariantNMOS)
+	cpu.PC = 0x8000
+	stopped := 0
+	rom.Writes, rom.Stop = bus.ROMStop, func(error) { stopped++ }
+	cpu.EnableHistory(10)
+
+	for range 3 {
+		cpu.Step()
+	}
+	for range 3 {
+		assert.True(t, cpu.StepBack())
+	}
+	assert.Equal(t, uint16(0x8000), cpu.PC, "PC should be back at the start")
+	assert.Equal(t, 1, rom.ViolationCount(), "Undoing the write to ROM should not be a violation")
+	assert.Equal(t, 1, stopped, "Undoing the write to ROM should not stop the CPU")
+	assert.Equal(t, uint8(0x11), mapped.Read(0xC000), "The ROM should be unchanged")
+	assert.Equal(t, 1, strings.Count(log.String(), "\n"), "Undoing the unmapped write should not log it")
+}
+
+func TestHistory_Disabled(t *testing.T) {
+	cpu := newCPU(processor.Variant2A03, 0xE8) // INX
+	cpu.Step()
+	assert.Equal(t, 0, cpu.HistoryLen(), "No history should be kept")
+	assert.False(t, cpu.StepBack(), "There should be nothing to undo")
+}
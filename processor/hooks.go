@@ -0,0 +1,135 @@
+package processor
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Execution hooks.
+//
+// Hooks let tools such as tracers, profilers and coverage collectors observe the CPU as it runs without changing
+// Clock. Any number of sets of hooks can be registered with AddHooks, and they are called in the order they were
+// added. With no hooks registered the cost is a nil check on each bus access and instruction.
+//
+// Only the accesses the CPU itself makes are reported: those made by instructions, interrupt and reset sequences,
+// and by calling Read and Write. Inspecting memory with Peek, the vector getters (ResetVector, IRQVector and
+// NMIVector) or DisassembleOperation does not access the bus through the CPU, so it is not reported.
+//
+// The JIT (see jit.go) is not used while hooks are registered, as it skips the opcode and operand fetches that the
+// hooks would otherwise see.
+
+// AccessKind identifies why the CPU accessed the bus.
+type AccessKind uint8
+
+const (
+	AccessData    AccessKind = iota // Data read or written by an instruction, including indirect addresses
+	AccessOpcode                    // An opcode fetch (including the ignored fetch that starts an interrupt sequence)
+	AccessOperand                   // An instruction operand, or a dummy read of the instruction stream
+	AccessStack                     // A push, pull or dummy read of the stack
+	AccessVector                    // A read of an interrupt or reset vector
+)
+
+var accessKindNames = map[AccessKind]string{
+	AccessData:    "data",
+	AccessOpcode:  "opcode",
+	AccessOperand: "operand",
+	AccessStack:   "stack",
+	AccessVector:  "vector",
+}
+
+// String returns the name of the access kind.
+func (k AccessKind) String() string {
+	if name, ok := accessKindNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("AccessKind(%d)", uint8(k))
+}
+
+// Hooks are callbacks made as the CPU runs. Any of them may be nil.
+type Hooks struct {
+	// BeforeInstruction is called once an opcode has been fetched, before the instruction executes.
+	BeforeInstruction func(cpu *CPU, pc uint16, opcode byte)
+
+	// AfterInstruction is called once an instruction has executed. Outside cycle accurate mode an instruction takes
+	// effect on its first cycle, so it is called then, before the remaining cycles of the instruction are counted.
+	AfterInstruction func(cpu *CPU, pc uint16, opcode byte)
+
+	// Access is called for every bus read and write made by the CPU, including those made by calling Read and Write,
+	// but not for Peek.
+	Access func(cpu *CPU, access BusAccess, kind AccessKind)
+
+	// Interrupt is called when an IRQ, NMI or BRK sequence has finished and PC holds the address of the handler. brk
+	// is true for a BRK, which is reported as InterruptIRQ as it shares the IRQ vector (or InterruptNMI if an NMI
+	// hijacked it).
+	Interrupt func(cpu *CPU, interrupt Interrupt, brk bool)
+
+	// Return is called when an RTI instruction has returned from an interrupt handler.
+	Return func(cpu *CPU)
+
+	// Reset is called when the reset sequence has finished and PC holds the reset vector.
+	Reset func(cpu *CPU)
+}
+
+// AddHooks registers a set of hooks.
+func (c *CPU) AddHooks(hooks *Hooks) {
+	c.hooks = append(c.hooks, hooks)
+}
+
+// RemoveHooks unregisters a set of hooks added by AddHooks.
+func (c *CPU) RemoveHooks(hooks *Hooks) {
+	c.hooks = slices.DeleteFunc(c.hooks, func(h *Hooks) bool { return h == hooks })
+	if len(c.hooks) == 0 {
+		c.hooks = nil
+	}
+}
+
+// EffectiveAddress returns the effective address of the instruction being executed, or of the last one if the CPU
+// is between instructions (see StepResult.Address). It is meant to be called from an AfterInstruction hook.
+func (c *CPU) EffectiveAddress() uint16 {
+	return c.last.Address
+}
+
+// beforeInstruction calls the BeforeInstruction hooks.
+func (c *CPU) beforeInstruction(pc uint16, opcode byte) {
+	for _, h := range c.hooks {
+		if h.BeforeInstruction != nil {
+			h.BeforeInstruction(c, pc, opcode)
+		}
+	}
+}
+
+// afterInstruction calls the AfterInstruction hooks, and the Return hooks if the instruction was an RTI.
+func (c *CPU) afterInstruction(pc uint16, opcode byte) {
+	rti := c.decoded[opcode].rti
+	for _, h := range c.hooks {
+		if h.AfterInstruction != nil {
+			h.AfterInstruction(c, pc, opcode)
+		}
+		if rti && h.Return != nil {
+			h.Return(c)
+		}
+	}
+}
+
+// accessed calls the Access hooks.
+func (c *CPU) accessed(access BusAccess, kind AccessKind) {
+	for _, h := range c.hooks {
+		if h.Access != nil {
+			h.Access(c, access, kind)
+		}
+	}
+}
+
+// sequenceDone calls the Interrupt or Reset hooks once a sequence using the given vector has finished. brk is true
+// if the sequence was started by a BRK instruction.
+func (c *CPU) sequenceDone(vector uint16, brk bool) {
+	for _, h := range c.hooks {
+		if vector == resetVector {
+			if h.Reset != nil {
+				h.Reset(c)
+			}
+		} else if h.Interrupt != nil {
+			h.Interrupt(c, interruptFor(vector), brk)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package processor_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ukdave/6502_emulator/processor"
+)
+
+// hookRecorder records every hook call as a line of text
+type hookRecorder struct {
+	calls []string
+}
+
+func (r *hookRecorder) hooks() *processor.Hooks {
+	return &processor.Hooks{
+		BeforeInstruction: func(cpu *processor.CPU, pc uint16, opcode byte) {
+			r.calls = append(r.calls, fmt.Sprintf("before $%04X $%02X", pc, opcode))
+		},
+		AfterInstruction: func(cpu *processor.CPU, pc uint16, opcode byte) {
+			r.calls = append(r.calls, fmt.Sprintf("after $%04X $%02X", pc, opcode))
+		},
+		Access: func(cpu *processor.CPU, access processor.BusAccess, kind processor.AccessKind) {
+			r.calls = append(r.calls, fmt.Sprintf("%s %s", access, kind))
+		},
+		Interrupt: func(cpu *processor.CPU, interrupt processor.Interrupt, brk bool) {
+			r.calls = append(r.calls, fmt.Sprintf("interrupt %s brk=%t PC=$%04X", interrupt, brk, cpu.PC))
+		},
+		Return: func(cpu *processor.CPU) {
+			r.calls = append(r.calls, fmt.Sprintf("return PC=$%04X", cpu.PC))
+		},
+		Reset: func(cpu *processor.CPU) {
+			r.calls = append(r.calls, fmt.Sprintf("reset PC=$%04X", cpu.PC))
+		},
+	}
+}
+
+func TestHooks_Instructions(t *testing.T) {
+	for _, cycleAccurate := range []bool{false, true} {
+		cpu := newCPU(processor.VariantNMOS, 0xA9, 0x42, 0x85, 0x10, 0x48) // LDA #$42; STA $10; PHA
+		assert.NoError(t, cpu.SetCycleAccurate(cycleAccurate))
+		recorder := &hookRecorder{}
+		cpu.AddHooks(recorder.hooks())
+
+		cpu.Step()
+		cpu.Step()
+		expected := []string{
+			"R $8000 = $A9 opcode",
+			"before $8000 $A9",
+			"R $8001 = $42 operand",
+			"after $8000 $A9",
+			"R $8002 = $85 opcode",
+			"before $8002 $85",
+			"R $8003 = $10 operand",
+			"W $0010 = $42 data",
+			"after $8002 $85",
+		}
+		assert.Equal(t, expected, recorder.calls, "Hook calls should match (cycle accurate: %t)", cycleAccurate)
+
+		recorder.calls = nil
+		cpu.Step()
+		assert.Contains(t, recorder.calls, "W $01FD = $42 stack", "The push should be a stack access")
+	}
+}
+
+func TestHooks_Interrupts(t *testing.T) {
+	for _, cycleAccurate := range []bool{false, true} {
+		cpu := newCPU(processor.VariantNMOS, 0xEA, 0x00, 0x00) // NOP; BRK
+		cpu.Write16(0xFFFE, 0x9000)
+		cpu.Write16(0xFFFC, 0x8000)
+		cpu.Write(0x9000, 0x40) // RTI
+		assert.NoError(t, cpu.SetCycleAccurate(cycleAccurate))
+		cpu.SetFlag(processor.I, false)
+		recorder := &hookRecorder{}
+		cpu.AddHooks(recorder.hooks())
+
+		// IRQ
+		cpu.AssertIRQ(0)
+		cpu.Step()
+		cpu.ReleaseIRQ(0)
+		cpu.Step()
+		cpu.Step()
+		assert.Contains(t, recorder.calls, "R $FFFE = $00 vector", "The vector read should be reported")
+		assert.Contains(t, recorder.calls, "interrupt IRQ brk=false PC=$9000", "The IRQ should be reported")
+		assert.Contains(t, recorder.calls, "return PC=$8001", "The RTI should be reported")
+
+		// BRK
+		recorder.calls = nil
+		cpu.Step()
+		cpu.Step()
+		assert.Contains(t, recorder.calls, "interrupt IRQ brk=true PC=$9000", "The BRK should be reported")
+
+		// Reset
+		recorder.calls = nil
+		cpu.Reset()
+		cpu.Step()
+		assert.Equal(t, "reset PC=$8000", recorder.calls[len(recorder.calls)-1], "The reset should be reported")
+	}
+}
+
+func TestHooks_ReadWrite16(t *testing.T) {
+	cpu := newCPU(processor.VariantNMOS, 0xEA) // NOP
+	recorder := &hookRecorder{}
+	cpu.AddHooks(recorder.hooks())
+	cpu.Write16(0x0200, 0x1234)
+	assert.Equal(t, uint16(0x1234), cpu.Read16(0x0200))
+	expected := []string{
+		"W $0200 = $34 data",
+		"W $0201 = $12 data",
+		"R $0200 = $34 data",
+		"R $0201 = $12 data",
+	}
+	assert.Equal(t, expected, recorder.calls, "Both bytes should be reported")
+}
+
+func TestHooks_Peek(t *testing.T) {
+	cpu := newCPU(processor.VariantNMOS, 0xEA) // NOP
+	recorder := &hookRecorder{}
+	cpu.AddHooks(recorder.hooks())
+	assert.Equal(t, byte(0xEA), cpu.Peek(0x8000))
+	cpu.ResetVector()
+	cpu.IRQVector()
+	cpu.NMIVector()
+	cpu.DisassembleOperation(0x8000)
+	assert.Empty(t, recorder.calls, "Inspecting memory should not be reported")
+}
+
+func TestRemoveHooks(t *testing.T) {
+	cpu := newCPU(processor.VariantNMOS, 0xEA, 0xEA) // NOP; NOP
+	first, second := &hookRecorder{}, &hookRecorder{}
+	firstHooks := first.hooks()
+	cpu.AddHooks(firstHooks)
+	cpu.AddHooks(second.hooks())
+
+	cpu.Step()
+	cpu.RemoveHooks(firstHooks)
+	cpu.Step()
+	assert.Len(t, first.calls, 3, "The first hooks should only see the first NOP")
+	assert.Len(t, second.calls, 6, "The second hooks should see both NOPs")
+}
+
+func TestHooks_DisableJIT(t *testing.T) {
+	cpu := newCPU(processor.VariantNMOS, 0xE8, 0x4C, 0x00, 0x80) // INX; JMP $8000
+	cpu.SetJIT(true)
+	instructions := 0
+	cpu.AddHooks(&processor.Hooks{
+		BeforeInstruction: func(cpu *processor.CPU, pc uint16, opcode byte) { instructions++ },
+	})
+
+	cpu.RunCycles(50)
+	assert.Equal(t, 20, instructions, "Every instruction should be seen")
+}
+
+func TestAccessKind_String(t *testing.T) {
+	assert.Equal(t, "opcode", processor.AccessOpcode.String())
+	assert.Equal(t, "AccessKind(9)", processor.AccessKind(9).String())
+}
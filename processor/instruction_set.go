@@ -0,0 +1,170 @@
+package processor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Instruction set metadata.
+//
+// An InstructionSet describes every opcode of a variant: its mnemonic, address mode, size and base cycle count, the
+// status flags it reads and writes, and the conditions under which it takes extra cycles. It is built from the
+// opcode tables when the package is initialised, and is what the CPU, the disassembler and the JIT use to name and
+// classify opcodes, so that tools such as assemblers can rely on it describing exactly what the CPU executes.
+
+// Penalty is a set of conditions under which an instruction takes more than its base number of cycles.
+type Penalty uint8
+
+const (
+	// PenaltyPageCross adds a cycle when an indexed address is on a different page to the base address.
+	PenaltyPageCross Penalty = 1 << iota
+	// PenaltyBranch adds a cycle when the branch is taken, and another when the target is on a different page.
+	PenaltyBranch
+	// PenaltyDecimal adds a cycle in decimal mode (ADC and SBC on the CMOS variants).
+	PenaltyDecimal
+)
+
+var penaltyNames = []string{"page-cross", "branch", "decimal"}
+
+// String returns the names of the conditions separated by "|", or "none".
+func (p Penalty) String() string {
+	var names []string
+	for i, name := range penaltyNames {
+		if p&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, "|")
+}
+
+// String returns the letters of the flags in the set, in the order they appear in the status register (for example
+// "NZC"), or "-" if the set is empty.
+func (f Flag) String() string {
+	var b strings.Builder
+	for i, letter := range "NVUBDIZC" {
+		if f&(0x80>>i) != 0 {
+			b.WriteRune(letter)
+		}
+	}
+	if b.Len() == 0 {
+		return "-"
+	}
+	return b.String()
+}
+
+// InstructionInfo describes a single opcode.
+type InstructionInfo struct {
+	Opcode       byte
+	Mnemonic     string
+	Mode         Mode
+	Size         uint8 // Length of the instruction in bytes, including the opcode
+	Cycles       uint8 // Number of cycles taken, before any Penalty
+	Penalty      Penalty
+	FlagsRead    Flag // Status flags that affect the result
+	FlagsWritten Flag // Status flags that may be changed
+	Undocumented bool // Outside the documented instruction set of the variant (see UnimplementedPolicy)
+}
+
+// String returns a one line description of the opcode, for example "$BD LDA ABX 3 bytes 4 cycles".
+func (i InstructionInfo) String() string {
+	return fmt.Sprintf("$%02X %s %s %d bytes %d cycles", i.Opcode, i.Mnemonic, i.Mode, i.Size, i.Cycles)
+}
+
+// InstructionSet is the set of opcodes executed by a variant.
+type InstructionSet struct {
+	variant Variant
+	opcodes [256]InstructionInfo
+	byName  map[instructionKey]byte
+}
+
+// instructionKey identifies an instruction by its mnemonic and address mode.
+type instructionKey struct {
+	mnemonic string
+	mode     Mode
+}
+
+// Variant returns the variant described by the instruction set.
+func (s *InstructionSet) Variant() Variant {
+	return s.variant
+}
+
+// Opcode returns the description of an opcode.
+func (s *InstructionSet) Opcode(opcode byte) InstructionInfo {
+	return s.opcodes[opcode]
+}
+
+// Find returns the opcode of the instruction with the given (case-insensitive) mnemonic and address mode. Where
+// more than one opcode matches, the documented one is returned, or else the lowest.
+func (s *InstructionSet) Find(mnemonic string, mode Mode) (InstructionInfo, bool) {
+	opcode, ok := s.byName[instructionKey{strings.ToUpper(mnemonic), mode}]
+	if !ok {
+		return InstructionInfo{}, false
+	}
+	return s.opcodes[opcode], true
+}
+
+// Instructions returns the description of every opcode, in opcode order.
+func (s *InstructionSet) Instructions() []InstructionInfo {
+	return append([]InstructionInfo(nil), s.opcodes[:]...)
+}
+
+// InstructionSet returns the instruction set of the variant.
+func (v Variant) InstructionSet() *InstructionSet {
+	switch v {
+	case Variant65C02:
+		return wdc65c02InstructionSet
+	case VariantR65C02:
+		return rockwellInstructionSet
+	case VariantNMOS:
+		return nmosInstructionSet
+	default:
+		return ricoh2A03InstructionSet
+	}
+}
+
+// InstructionSet returns the instruction set executed by this CPU.
+func (c *CPU) InstructionSet() *InstructionSet {
+	return c.instructions
+}
+
+var (
+	ricoh2A03InstructionSet = newInstructionSet(Variant2A03)
+	nmosInstructionSet      = newInstructionSet(VariantNMOS)
+	wdc65c02InstructionSet  = newInstructionSet(Variant65C02)
+	rockwellInstructionSet  = newInstructionSet(VariantR65C02)
+)
+
+// newInstructionSet describes the opcode table of a variant.
+func newInstructionSet(variant Variant) *InstructionSet {
+	s := &InstructionSet{variant: variant, byName: map[instructionKey]byte{}}
+	for opcode, op := range variant.operations() {
+		name := op.Name()
+		info := InstructionInfo{
+			Opcode:       byte(opcode),
+			Mnemonic:     name,
+			Mode:         op.AddressMode,
+			Size:         op.Size,
+			Cycles:       op.Cycles,
+			Penalty:      op.Penalty,
+			FlagsRead:    op.FlagsRead,
+			FlagsWritten: op.FlagsWritten,
+			Undocumented: isUndocumented(byte(opcode), name),
+		}
+		if !variant.hasDecimalMode() {
+			info.FlagsRead &^= D
+		}
+		s.opcodes[opcode] = info
+
+		key := instructionKey{name, op.AddressMode}
+		if existing, ok := s.byName[key]; !ok || (s.opcodes[existing].Undocumented && !info.Undocumented) {
+			s.byName[key] = byte(opcode)
+		}
+	}
+	return s
+}
+
+// allFlags are the flags that PLP and RTI restore (B and U are not stored in the status register).
+const allFlags = N | V | D | I | Z | C
@@ -0,0 +1,202 @@
+package processor_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ukdave/6502_emulator/bus"
+	"github.com/ukdave/6502_emulator/internal/cputest"
+	"github.com/ukdave/6502_emulator/processor"
+)
+
+func TestInstructionSet_Opcode(t *testing.T) {
+	info := processor.VariantNMOS.InstructionSet().Opcode(0xBD)
+	assert.Equal(t, processor.InstructionInfo{
+		Opcode: 0xBD, Mnemonic: "LDA", Mode: processor.ModeABX, Size: 3, Cycles: 4,
+		Penalty: processor.PenaltyPageCross, FlagsWritten: processor.N | processor.Z,
+	}, info)
+	assert.Equal(t, "$BD LDA ABX 3 bytes 4 cycles", info.String())
+
+	info = processor.VariantNMOS.InstructionSet().Opcode(0x61)
+	assert.Equal(t, "ADC", info.Mnemonic, "Mnemonic should be ADC")
+	assert.Equal(t, processor.C|processor.D, info.FlagsRead, "ADC should read C and D")
+	assert.Equal(t, processor.N|processor.V|processor.Z|processor.C, info.FlagsWritten, "ADC should write NVZC")
+	assert.Equal(t, processor.C, processor.Variant2A03.InstructionSet().Opcode(0x61).FlagsRead,
+		"ADC on the 2A03 should not read D")
+	assert.Equal(t, processor.PenaltyDecimal, processor.Variant65C02.InstructionSet().Opcode(0x69).Penalty,
+		"ADC on the 65C02 should take a decimal penalty")
+
+	assert.Equal(t, processor.Z, processor.Variant65C02.InstructionSet().Opcode(0x89).FlagsWritten,
+		"BIT #imm should only write Z")
+	assert.Equal(t, processor.PenaltyBranch, processor.VariantNMOS.InstructionSet().Opcode(0xD0).Penalty,
+		"BNE should take a branch penalty")
+	assert.True(t, processor.VariantNMOS.InstructionSet().Opcode(0x02).Undocumented, "JAM should be undocumented")
+	assert.False(t, processor.VariantNMOS.InstructionSet().Opcode(0xEA).Undocumented, "NOP should be documented")
+}
+
+func TestInstructionSet_Find(t *testing.T) {
+	nmos := processor.VariantNMOS.InstructionSet()
+	tests := []struct {
+		mnemonic string
+		mode     processor.Mode
+		opcode   byte
+	}{
+		{"LDA", processor.ModeIMM, 0xA9},
+		{"lda", processor.ModeINDY, 0xB1},
+		{"NOP", processor.ModeIMP, 0xEA},
+		{"SBC", processor.ModeIMM, 0xE9},
+		{"JMP", processor.ModeIND, 0x6C},
+		{"LAX", processor.ModeZP0, 0xA7},
+	}
+	for _, tt := range tests {
+		info, ok := nmos.Find(tt.mnemonic, tt.mode)
+		assert.True(t, ok, "%s %s should be found", tt.mnemonic, tt.mode)
+		assert.Equal(t, tt.opcode, info.Opcode, "%s %s should be $%02X", tt.mnemonic, tt.mode, tt.opcode)
+	}
+
+	_, ok := nmos.Find("BRA", processor.ModeREL)
+	assert.False(t, ok, "BRA should not exist on the NMOS 6502")
+	info, ok := processor.Variant65C02.InstructionSet().Find("BRA", processor.ModeREL)
+	assert.True(t, ok, "BRA should exist on the 65C02")
+	assert.Equal(t, byte(0x80), info.Opcode, "BRA should be $80")
+	_, ok = processor.VariantR65C02.InstructionSet().Find("WAI", processor.ModeIMP)
+	assert.False(t, ok, "WAI should not exist on the R65C02")
+}
+
+func TestInstructionSet_Instructions(t *testing.T) {
+	for _, variant := range jitVariants {
+		set := processor.NewCPUWithVariant(bus.NewSimpleBus(), variant).InstructionSet()
+		assert.Equal(t, variant, set.Variant(), "Variant should be %s", variant)
+		for opcode, info := range set.Instructions() {
+			assert.Equal(t, byte(opcode), info.Opcode, "Instructions should be in opcode order")
+			found, ok := set.Find(info.Mnemonic, info.Mode)
+			assert.True(t, ok, "%s should be found", info)
+			assert.Equal(t, info.Mnemonic, found.Mnemonic, "%s should find the same mnemonic", info)
+		}
+	}
+}
+
+// TestInstructionSet_MatchesCPU checks the size, cycles and page crossing penalty of every opcode against what the
+// CPU actually does.
+func TestInstructionSet_MatchesCPU(t *testing.T) {
+	for _, variant := range jitVariants {
+		for _, info := range variant.InstructionSet().Instructions() {
+			if info.Penalty&processor.PenaltyBranch != 0 || info.Mnemonic == "JAM" || info.Mnemonic == "STP" ||
+				info.Mnemonic == "WAI" {
+				continue
+			}
+			for _, crossPage := range []bool{false, true} {
+				// Each indexed mode reads its base address $10FF from the operand or from a pointer at $0020
+				cpu := newCPU(variant, info.Opcode, 0xFF, 0x10)
+				if info.Mode == processor.ModeINDY || info.Mode == processor.ModeZPI {
+					cpu.Write(0x8001, 0x20)
+					cpu.Write16(0x0020, 0x10FF)
+				}
+				if crossPage {
+					cpu.X, cpu.Y = 1, 1
+				}
+				result := cpu.Step()
+
+				cycles := info.Cycles
+				if crossPage && info.Penalty&processor.PenaltyPageCross != 0 {
+					cycles++
+				}
+				assert.Equal(t, cycles, result.Cycles, "%s on %s (page crossed: %t)", info, variant, crossPage)
+				if info.Penalty == 0 && !isJump(info.Mnemonic) {
+					assert.Equal(t, 0x8000+uint16(info.Size), cpu.PC, "%s on %s should be %d bytes", info, variant, info.Size)
+				}
+			}
+		}
+	}
+}
+
+func TestInstructionSet_PenaltyMatchesCPU(t *testing.T) {
+	runs := []struct {
+		variant       processor.Variant
+		cycleAccurate bool
+	}{
+		{processor.Variant2A03, false}, {processor.Variant2A03, true}, {processor.VariantNMOS, false},
+		{processor.VariantNMOS, true}, {processor.Variant65C02, false}, {processor.VariantR65C02, false},
+	}
+	for _, run := range runs {
+		for _, info := range run.variant.InstructionSet().Instructions() {
+			observed := observePenalty(t, run.variant, run.cycleAccurate, info)
+			assert.Equal(t, info.Penalty, observed, "%s on %s (cycle accurate: %t)", info, run.variant, run.cycleAccurate)
+		}
+	}
+}
+
+// observePenalty runs an instruction with and without a page crossing, with and without decimal mode, and with the
+// other flags (and the bits tested by BBR and BBS) all clear and all set, and returns the conditions under which it
+// took more than its base number of cycles.
+func observePenalty(t *testing.T, variant processor.Variant, cycleAccurate bool,
+	info processor.InstructionInfo) processor.Penalty {
+	branch := info.Mode == processor.ModeREL || info.Mode == processor.ModeZPR
+	// extra returns the number of cycles taken over the base count
+	extra := func(crossPage, decimal, flags bool) uint8 {
+		// Branches go forward within the page. Other indexed modes read their base address $10FF from the operand
+		// or from a pointer at $0020.
+		cpu, ram := cputest.New(variant, 0x8000, info.Opcode, 0xFF, 0x10)
+		assert.NoError(t, cpu.SetCycleAccurate(cycleAccurate))
+		switch info.Mode {
+		case processor.ModeREL:
+			ram.Write(0x8001, 0x02)
+		case processor.ModeZPR:
+			ram.Write(0x8001, 0x30)
+			ram.Write(0x8002, 0x02)
+		case processor.ModeINDY, processor.ModeZPI:
+			ram.Write(0x8001, 0x20)
+			store16(ram, 0x0020, 0x10FF)
+		}
+		cpu.Status = 0x20
+		if flags {
+			cpu.Status = 0xFF &^ byte(processor.D)
+			ram.Write(0x0030, 0xFF)
+		}
+		cpu.SetFlag(processor.D, decimal)
+		if crossPage {
+			cpu.X, cpu.Y = 1, 1
+		}
+		return cpu.Step().Cycles - info.Cycles
+	}
+
+	var observed processor.Penalty
+	for _, flags := range []bool{false, true} {
+		for _, decimal := range []bool{false, true} {
+			if extra(true, decimal, flags) > extra(false, decimal, flags) && !branch {
+				observed |= processor.PenaltyPageCross
+			}
+		}
+		for _, crossPage := range []bool{false, true} {
+			if extra(crossPage, true, flags) > extra(crossPage, false, flags) {
+				observed |= processor.PenaltyDecimal
+			}
+			if extra(crossPage, false, flags) > 0 && branch {
+				observed |= processor.PenaltyBranch
+			}
+		}
+	}
+	return observed
+}
+
+// isJump returns true for the instructions that set PC.
+func isJump(mnemonic string) bool {
+	switch mnemonic {
+	case "JMP", "JSR", "RTS", "RTI", "BRK":
+		return true
+	}
+	return false
+}
+
+func TestPenalty_String(t *testing.T) {
+	assert.Equal(t, "none", processor.Penalty(0).String())
+	assert.Equal(t, "page-cross", processor.PenaltyPageCross.String())
+	assert.Equal(t, "page-cross|decimal", (processor.PenaltyPageCross | processor.PenaltyDecimal).String())
+}
+
+func TestFlag_String(t *testing.T) {
+	assert.Equal(t, "-", processor.Flag(0).String())
+	assert.Equal(t, "NZC", (processor.C | processor.Z | processor.N).String())
+	assert.Equal(t, "NVUBDIZC", processor.Flag(0xFF).String())
+}
@@ -125,48 +125,56 @@ func TYA(cpu *CPU, addressInfo AddressInfo) bool {
 // ADC - Add with Carry
 // Function:  A = A + memory + C
 // Flags Out: C, Z, V, N
+//
+// When the D flag is set (and the CPU variant supports decimal mode) the addition is performed in BCD instead.
 func ADC(cpu *CPU, addressInfo AddressInfo) bool {
-	value := cpu.Read(addressInfo.Address)
-	temp := uint16(cpu.A) + uint16(value) + ternary(cpu.GetFlag(C), uint16(1), uint16(0))
-	cpu.SetFlag(C, temp > 0xFF)
-	cpu.SetFlag(V, (cpu.A^value)&0x80 == 0 && (uint16(cpu.A)^temp)&0x80 != 0)
-	cpu.A = uint8(temp)
-	cpu.SetZN(cpu.A)
+	cpu.add(cpu.Read(addressInfo.Address))
 	return true
 }
 
 // SBC - Subtract with Carry
 // Function: A = A - memory - !C
 // Flags Out: C, Z, V, N
+//
+// When the D flag is set (and the CPU variant supports decimal mode) the subtraction is performed in BCD instead.
 func SBC(cpu *CPU, addressInfo AddressInfo) bool {
-	value := cpu.Read(addressInfo.Address)
-	temp := uint16(cpu.A) - uint16(value) - ternary(cpu.GetFlag(C), uint16(0), uint16(1))
-	cpu.SetFlag(C, temp <= 0xFF)
-	cpu.SetFlag(V, (cpu.A^value)&0x80 != 0 && (uint16(cpu.A)^temp)&0x80 != 0)
-	cpu.A = uint8(temp)
-	cpu.SetZN(cpu.A)
+	cpu.subtract(cpu.Read(addressInfo.Address))
 	return true
 }
 
 // INC - Increment Memory
 // Function: memory = memory + 1
 // Flags Out: Z, N
+//
+// The CMOS variants add an accumulator form (INC A).
 func INC(cpu *CPU, addressInfo AddressInfo) bool {
-	value := cpu.Read(addressInfo.Address)
-	value++
-	cpu.Write(addressInfo.Address, value)
-	cpu.SetZN(value)
+	if addressInfo.IsAccumulator {
+		cpu.A++
+		cpu.SetZN(cpu.A)
+	} else {
+		value := cpu.Read(addressInfo.Address)
+		value++
+		cpu.Write(addressInfo.Address, value)
+		cpu.SetZN(value)
+	}
 	return false
 }
 
 // DEC - Decrement Memory
 // Function: memory = memory - 1
 // Flags Out: Z, N
+//
+// The CMOS variants add an accumulator form (DEC A).
 func DEC(cpu *CPU, addressInfo AddressInfo) bool {
-	value := cpu.Read(addressInfo.Address)
-	value--
-	cpu.Write(addressInfo.Address, value)
-	cpu.SetZN(value)
+	if addressInfo.IsAccumulator {
+		cpu.A--
+		cpu.SetZN(cpu.A)
+	} else {
+		value := cpu.Read(addressInfo.Address)
+		value--
+		cpu.Write(addressInfo.Address, value)
+		cpu.SetZN(value)
+	}
 	return false
 }
 
@@ -209,6 +217,9 @@ func DEY(cpu *CPU, addressInfo AddressInfo) bool {
 //
 // Shift Instructions
 //
+// On the CMOS variants the absolute X indexed forms of the shift instructions only take an extra cycle when a page
+// boundary is crossed, so they report that an extra cycle is possible. The NMOS forms always take 7 cycles.
+//
 
 // ASL - Arithmetic Shift Left
 // Function: value = value << 1
@@ -225,7 +236,7 @@ func ASL(cpu *CPU, addressInfo AddressInfo) bool {
 		cpu.Write(addressInfo.Address, value)
 		cpu.SetZN(value)
 	}
-	return false
+	return cpu.variant.isCMOS()
 }
 
 // LSR - Logical Shift Right
@@ -243,7 +254,7 @@ func LSR(cpu *CPU, addressInfo AddressInfo) bool {
 		cpu.Write(addressInfo.Address, value)
 		cpu.SetZN(value)
 	}
-	return false
+	return cpu.variant.isCMOS()
 }
 
 // ROL - Rotate Left
@@ -263,7 +274,7 @@ func ROL(cpu *CPU, addressInfo AddressInfo) bool {
 		cpu.Write(addressInfo.Address, value)
 		cpu.SetZN(value)
 	}
-	return false
+	return cpu.variant.isCMOS()
 }
 
 // ROR - Rotate Right
@@ -283,7 +294,7 @@ func ROR(cpu *CPU, addressInfo AddressInfo) bool {
 		cpu.Write(addressInfo.Address, value)
 		cpu.SetZN(value)
 	}
-	return false
+	return cpu.variant.isCMOS()
 }
 
 //
@@ -320,12 +331,17 @@ func EOR(cpu *CPU, addressInfo AddressInfo) bool {
 // BIT - Bit Test
 // Function: A & memory
 // Flags Out: Z, V, N
+//
+// The immediate form (CMOS variants only) has no memory location to test, so it only sets Z. The CMOS indexed forms
+// take an extra cycle if a page boundary is crossed.
 func BIT(cpu *CPU, addressInfo AddressInfo) bool {
 	value := cpu.Read(addressInfo.Address)
 	cpu.SetFlag(Z, (cpu.A&value) == 0x00)
-	cpu.SetFlag(N, value&0x80 != 0)
-	cpu.SetFlag(V, value&0x40 != 0)
-	return false
+	if !addressInfo.IsImmediate {
+		cpu.SetFlag(N, value&0x80 != 0)
+		cpu.SetFlag(V, value&0x40 != 0)
+	}
+	return cpu.variant.isCMOS()
 }
 
 //
@@ -492,11 +508,14 @@ func RTS(cpu *CPU, addressInfo AddressInfo) bool {
 //	push PC + 2 low byte to stack
 //	push NV11DIZC flags to stack
 //	PC = ($FFFE)
+//
+// BRK is 2 bytes long: the byte after the opcode is a padding (or signature) byte, which the return address skips.
 func BRK(cpu *CPU, addressInfo AddressInfo) bool {
 	cpu.Push16(cpu.PC)
 	cpu.Push(cpu.Status | 0x10) // 0x10 sets the Break flag to 1 (but only in the value pushed to the stack)
-	cpu.SetFlag(I, true)        // Set the "Interrupt Disable" flag
-	cpu.PC = cpu.IRQVector()    // Read a value from 0xFFFE and use this as the memory address to jump to
+	cpu.enterInterrupt()        // Set the "Interrupt Disable" flag (and clear D on CMOS variants)
+	// Read a value from 0xFFFE and use this as the memory address to jump to
+	cpu.PC = cpu.read16(irqVector, AccessVector)
 	return false
 }
 
@@ -646,11 +665,18 @@ func CLV(cpu *CPU, addressInfo AddressInfo) bool {
 //
 
 // NOP - No operation
+//
+// The undocumented multi-byte NOPs still read their operand from memory, so the absolute X indexed forms take an
+// extra cycle when a page boundary is crossed just like a load.
 func NOP(cpu *CPU, addressInfo AddressInfo) bool {
-	return false
+	return true
 }
 
-// XXX captures illegal opcodes
+// XXX captures illegal opcodes. None of the standard opcode tables use it, but the UnimplementedTrap policy
+// executes it in place of unimplemented opcodes. The CPU halts with PC pointing at the opcode so that runaway code
+// is noticed.
 func XXX(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.PC = cpu.last.PC
+	cpu.trap()
 	return false
 }
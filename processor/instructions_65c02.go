@@ -0,0 +1,353 @@
+package processor
+
+// The CMOS 65C02 adds the following instructions to the NMOS instruction set.
+//
+// Branch:     BRA, BBR0-7, BBS0-7
+// Stack:      PHX, PHY, PLX, PLY
+// Store:      STZ
+// Bitwise:    TRB, TSB, RMB0-7, SMB0-7
+// Other:      WAI, STP
+//
+// It also adds new addressing modes to existing instructions: INC A and DEC A, BIT with immediate and indexed
+// operands, JMP (abs,X), and "zero page indirect" for the ALU instructions. The bit instructions (RMB, SMB, BBR and
+// BBS) were introduced by Rockwell and later adopted by WDC, and WAI and STP are only found on WDC parts.
+//
+// http://www.6502.org/tutorials/65c02opcodes.html
+
+//
+// Branch Instructions
+//
+
+// BRA - Branch Always
+// Function = PC = PC + 2 + memory (signed)
+func BRA(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.addBranchCycles(addressInfo)
+	cpu.PC = addressInfo.Address
+	return false
+}
+
+//
+// Stack Instructions
+//
+
+// PHX - Push X
+// Function:
+//
+//	($0100 + SP) = X
+//	SP = SP - 1
+func PHX(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.Push(cpu.X)
+	return false
+}
+
+// PHY - Push Y
+// Function:
+//
+//	($0100 + SP) = Y
+//	SP = SP - 1
+func PHY(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.Push(cpu.Y)
+	return false
+}
+
+// PLX - Pull X
+// Function:
+//
+//	SP = SP + 1
+//	X = ($0100 + SP)
+//
+// Flags Out: Z, N
+func PLX(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.X = cpu.Pop()
+	cpu.SetZN(cpu.X)
+	return false
+}
+
+// PLY - Pull Y
+// Function:
+//
+//	SP = SP + 1
+//	Y = ($0100 + SP)
+//
+// Flags Out: Z, N
+func PLY(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.Y = cpu.Pop()
+	cpu.SetZN(cpu.Y)
+	return false
+}
+
+//
+// Store Instructions
+//
+
+// STZ - Store Zero
+// Function:  memory = 0
+// Flags Out: None
+func STZ(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.Write(addressInfo.Address, 0x00)
+	return false
+}
+
+//
+// Bitwise Instructions
+//
+
+// TRB - Test and Reset Bits
+// Function:  memory = memory & ^A
+// Flags Out: Z (set from A & memory)
+func TRB(cpu *CPU, addressInfo AddressInfo) bool {
+	value := cpu.Read(addressInfo.Address)
+	cpu.SetFlag(Z, (cpu.A&value) == 0x00)
+	cpu.Write(addressInfo.Address, value&^cpu.A)
+	return false
+}
+
+// TSB - Test and Set Bits
+// Function:  memory = memory | A
+// Flags Out: Z (set from A & memory)
+func TSB(cpu *CPU, addressInfo AddressInfo) bool {
+	value := cpu.Read(addressInfo.Address)
+	cpu.SetFlag(Z, (cpu.A&value) == 0x00)
+	cpu.Write(addressInfo.Address, value|cpu.A)
+	return false
+}
+
+// The RMB, SMB, BBR and BBS instructions encode the bit number in the opcode, so there are eight versions of each.
+// They all share the helpers at the end of this section.
+
+// RMB0 - Reset Memory Bit 0
+// Function:  memory = memory & ^(1 << 0)
+func RMB0(cpu *CPU, addressInfo AddressInfo) bool {
+	return cpu.resetMemoryBit(addressInfo, 0)
+}
+
+// RMB1 - Reset Memory Bit 1
+// Function:  memory = memory & ^(1 << 1)
+func RMB1(cpu *CPU, addressInfo AddressInfo) bool {
+	return cpu.resetMemoryBit(addressInfo, 1)
+}
+
+// RMB2 - Reset Memory Bit 2
+// Function:  memory = memory & ^(1 << 2)
+func RMB2(cpu *CPU, addressInfo AddressInfo) bool {
+	return cpu.resetMemoryBit(addressInfo, 2)
+}
+
+// RMB3 - Reset Memory Bit 3
+// Function:  memory = memory & ^(1 << 3)
+func RMB3(cpu *CPU, addressInfo AddressInfo) bool {
+	return cpu.resetMemoryBit(addressInfo, 3)
+}
+
+// RMB4 - Reset Memory Bit 4
+// Function:  memory = memory & ^(1 << 4)
+func RMB4(cpu *CPU, addressInfo AddressInfo) bool {
+	return cpu.resetMemoryBit(addressInfo, 4)
+}
+
+// RMB5 - Reset Memory Bit 5
+// Function:  memory = memory & ^(1 << 5)
+func RMB5(cpu *CPU, addressInfo AddressInfo) bool {
+	return cpu.resetMemoryBit(addressInfo, 5)
+}
+
+// RMB6 - Reset Memory Bit 6
+// Function:  memory = memory & ^(1 << 6)
+func RMB6(cpu *CPU, addressInfo AddressInfo) bool {
+	return cpu.resetMemoryBit(addressInfo, 6)
+}
+
+// RMB7 - Reset Memory Bit 7
+// Function:  memory = memory & ^(1 << 7)
+func RMB7(cpu *CPU, addressInfo AddressInfo) bool {
+	return cpu.resetMemoryBit(addressInfo, 7)
+}
+
+// SMB0 - Set Memory Bit 0
+// Function:  memory = memory | (1 << 0)
+func SMB0(cpu *CPU, addressInfo AddressInfo) bool {
+	return cpu.setMemoryBit(addressInfo, 0)
+}
+
+// SMB1 - Set Memory Bit 1
+// Function:  memory = memory | (1 << 1)
+func SMB1(cpu *CPU, addressInfo AddressInfo) bool {
+	return cpu.setMemoryBit(addressInfo, 1)
+}
+
+// SMB2 - Set Memory Bit 2
+// Function:  memory = memory | (1 << 2)
+func SMB2(cpu *CPU, addressInfo AddressInfo) bool {
+	return cpu.setMemoryBit(addressInfo, 2)
+}
+
+// SMB3 - Set Memory Bit 3
+// Function:  memory = memory | (1 << 3)
+func SMB3(cpu *CPU, addressInfo AddressInfo) bool {
+	return cpu.setMemoryBit(addressInfo, 3)
+}
+
+// SMB4 - Set Memory Bit 4
+// Function:  memory = memory | (1 << 4)
+func SMB4(cpu *CPU, addressInfo AddressInfo) bool {
+	return cpu.setMemoryBit(addressInfo, 4)
+}
+
+// SMB5 - Set Memory Bit 5
+// Function:  memory = memory | (1 << 5)
+func SMB5(cpu *CPU, addressInfo AddressInfo) bool {
+	return cpu.setMemoryBit(addressInfo, 5)
+}
+
+// SMB6 - Set Memory Bit 6
+// Function:  memory = memory | (1 << 6)
+func SMB6(cpu *CPU, addressInfo AddressInfo) bool {
+	return cpu.setMemoryBit(addressInfo, 6)
+}
+
+// SMB7 - Set Memory Bit 7
+// Function:  memory = memory | (1 << 7)
+func SMB7(cpu *CPU, addressInfo AddressInfo) bool {
+	return cpu.setMemoryBit(addressInfo, 7)
+}
+
+// BBR0 - Branch on Bit 0 Reset
+// Function = if memory & (1 << 0) == 0 then PC = PC + 3 + offset (signed)
+func BBR0(cpu *CPU, addressInfo AddressInfo) bool {
+	return cpu.branchOnBit(addressInfo, 0, false)
+}
+
+// BBR1 - Branch on Bit 1 Reset
+// Function = if memory & (1 << 1) == 0 then PC = PC + 3 + offset (signed)
+func BBR1(cpu *CPU, addressInfo AddressInfo) bool {
+	return cpu.branchOnBit(addressInfo, 1, false)
+}
+
+// BBR2 - Branch on Bit 2 Reset
+// Function = if memory & (1 << 2) == 0 then PC = PC + 3 + offset (signed)
+func BBR2(cpu *CPU, addressInfo AddressInfo) bool {
+	return cpu.branchOnBit(addressInfo, 2, false)
+}
+
+// BBR3 - Branch on Bit 3 Reset
+// Function = if memory & (1 << 3) == 0 then PC = PC + 3 + offset (signed)
+func BBR3(cpu *CPU, addressInfo AddressInfo) bool {
+	return cpu.branchOnBit(addressInfo, 3, false)
+}
+
+// BBR4 - Branch on Bit 4 Reset
+// Function = if memory & (1 << 4) == 0 then PC = PC + 3 + offset (signed)
+func BBR4(cpu *CPU, addressInfo AddressInfo) bool {
+	return cpu.branchOnBit(addressInfo, 4, false)
+}
+
+// BBR5 - Branch on Bit 5 Reset
+// Function = if memory & (1 << 5) == 0 then PC = PC + 3 + offset (signed)
+func BBR5(cpu *CPU, addressInfo AddressInfo) bool {
+	return cpu.branchOnBit(addressInfo, 5, false)
+}
+
+// BBR6 - Branch on Bit 6 Reset
+// Function = if memory & (1 << 6) == 0 then PC = PC + 3 + offset (signed)
+func BBR6(cpu *CPU, addressInfo AddressInfo) bool {
+	return cpu.branchOnBit(addressInfo, 6, false)
+}
+
+// BBR7 - Branch on Bit 7 Reset
+// Function = if memory & (1 << 7) == 0 then PC = PC + 3 + offset (signed)
+func BBR7(cpu *CPU, addressInfo AddressInfo) bool {
+	return cpu.branchOnBit(addressInfo, 7, false)
+}
+
+// BBS0 - Branch on Bit 0 Set
+// Function = if memory & (1 << 0) != 0 then PC = PC + 3 + offset (signed)
+func BBS0(cpu *CPU, addressInfo AddressInfo) bool {
+	return cpu.branchOnBit(addressInfo, 0, true)
+}
+
+// BBS1 - Branch on Bit 1 Set
+// Function = if memory & (1 << 1) != 0 then PC = PC + 3 + offset (signed)
+func BBS1(cpu *CPU, addressInfo AddressInfo) bool {
+	return cpu.branchOnBit(addressInfo, 1, true)
+}
+
+// BBS2 - Branch on Bit 2 Set
+// Function = if memory & (1 << 2) != 0 then PC = PC + 3 + offset (signed)
+func BBS2(cpu *CPU, addressInfo AddressInfo) bool {
+	return cpu.branchOnBit(addressInfo, 2, true)
+}
+
+// BBS3 - Branch on Bit 3 Set
+// Function = if memory & (1 << 3) != 0 then PC = PC + 3 + offset (signed)
+func BBS3(cpu *CPU, addressInfo AddressInfo) bool {
+	return cpu.branchOnBit(addressInfo, 3, true)
+}
+
+// BBS4 - Branch on Bit 4 Set
+// Function = if memory & (1 << 4) != 0 then PC = PC + 3 + offset (signed)
+func BBS4(cpu *CPU, addressInfo AddressInfo) bool {
+	return cpu.branchOnBit(addressInfo, 4, true)
+}
+
+// BBS5 - Branch on Bit 5 Set
+// Function = if memory & (1 << 5) != 0 then PC = PC + 3 + offset (signed)
+func BBS5(cpu *CPU, addressInfo AddressInfo) bool {
+	return cpu.branchOnBit(addressInfo, 5, true)
+}
+
+// BBS6 - Branch on Bit 6 Set
+// Function = if memory & (1 << 6) != 0 then PC = PC + 3 + offset (signed)
+func BBS6(cpu *CPU, addressInfo AddressInfo) bool {
+	return cpu.branchOnBit(addressInfo, 6, true)
+}
+
+// BBS7 - Branch on Bit 7 Set
+// Function = if memory & (1 << 7) != 0 then PC = PC + 3 + offset (signed)
+func BBS7(cpu *CPU, addressInfo AddressInfo) bool {
+	return cpu.branchOnBit(addressInfo, 7, true)
+}
+
+func (c *CPU) resetMemoryBit(addressInfo AddressInfo, bit uint) bool {
+	value := c.Read(addressInfo.Address)
+	c.Write(addressInfo.Address, value&^(1<<bit))
+	return false
+}
+
+func (c *CPU) setMemoryBit(addressInfo AddressInfo, bit uint) bool {
+	value := c.Read(addressInfo.Address)
+	c.Write(addressInfo.Address, value|(1<<bit))
+	return false
+}
+
+func (c *CPU) branchOnBit(addressInfo AddressInfo, bit uint, set bool) bool {
+	value := c.Read(addressInfo.Address)
+	if (value&(1<<bit) != 0) == set {
+		c.addBranchCycles(addressInfo)
+		c.PC = addressInfo.RelativeAddress
+	}
+	return false
+}
+
+//
+// Other Instructions
+//
+
+// WAI - Wait for Interrupt
+//
+// The processor stops executing instructions until an IRQ or NMI is signalled. An IRQ wakes the processor even
+// when interrupts are disabled, in which case execution simply continues with the next instruction.
+func WAI(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.waiting = true
+	cpu.haltReason = HaltWAI
+	return false
+}
+
+// STP - Stop the Clock
+//
+// The processor stops executing instructions until it is reset. The Program Counter is left pointing at the STP
+// opcode.
+func STP(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.PC--
+	cpu.halt(HaltSTP)
+	return false
+}
@@ -0,0 +1,285 @@
+package processor_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/ukdave/6502_emulator/bus"
+	"github.com/ukdave/6502_emulator/processor"
+)
+
+type CMOSInstructionsSuite struct {
+	cpuSuite
+}
+
+func TestCMOSInstructionsSuite(t *testing.T) {
+	suite.Run(t, new(CMOSInstructionsSuite))
+}
+
+func (suite *CMOSInstructionsSuite) SetupTest() {
+	suite.bus = bus.NewSimpleBus()
+	suite.cpu = processor.NewCPUWithVariant(suite.bus, processor.Variant65C02)
+}
+
+//
+// Branch Instructions
+//
+
+func (suite *CMOSInstructionsSuite) TestBRA() {
+	suite.cpu.PC = 0x1000
+
+	extraCycle := processor.BRA(suite.cpu, processor.AddressInfo{Address: 0x1050})
+
+	assert.Equal(suite.T(), uint16(0x1050), suite.cpu.PC, "Expected PC to be 0x1050")
+	assert.Equal(suite.T(), uint8(1), suite.cpu.Cycles(), "Expected cycles to be 1")
+	assert.False(suite.T(), extraCycle, "Expected extraCycle to be false")
+}
+
+func (suite *CMOSInstructionsSuite) TestBBR_Taken() {
+	suite.bus.Write(0x0040, 0b11111110)
+	suite.cpu.PC = 0x1000
+
+	extraCycle := processor.BBR0(suite.cpu, processor.AddressInfo{Address: 0x0040, RelativeAddress: 0x1050})
+
+	assert.Equal(suite.T(), uint16(0x1050), suite.cpu.PC, "Expected PC to be 0x1050")
+	assert.Equal(suite.T(), uint8(1), suite.cpu.Cycles(), "Expected cycles to be 1")
+	assert.False(suite.T(), extraCycle, "Expected extraCycle to be false")
+}
+
+func (suite *CMOSInstructionsSuite) TestBBR_NotTaken() {
+	suite.bus.Write(0x0040, 0b00000001)
+	suite.cpu.PC = 0x1000
+
+	processor.BBR0(suite.cpu, processor.AddressInfo{Address: 0x0040, RelativeAddress: 0x1050})
+
+	assert.Equal(suite.T(), uint16(0x1000), suite.cpu.PC, "Expected PC to be 0x1000")
+	assert.Equal(suite.T(), uint8(0), suite.cpu.Cycles(), "Expected cycles to be 0")
+}
+
+func (suite *CMOSInstructionsSuite) TestBBS_Taken() {
+	suite.bus.Write(0x0040, 0b10000000)
+	suite.cpu.PC = 0x1000
+
+	processor.BBS7(suite.cpu, processor.AddressInfo{Address: 0x0040, RelativeAddress: 0x2000, PageChanged: true})
+
+	assert.Equal(suite.T(), uint16(0x2000), suite.cpu.PC, "Expected PC to be 0x2000")
+	assert.Equal(suite.T(), uint8(2), suite.cpu.Cycles(), "Expected cycles to be 2")
+}
+
+//
+// Stack Instructions
+//
+
+func (suite *CMOSInstructionsSuite) TestPHX_PLX() {
+	suite.cpu.X = 0x80
+	processor.PHX(suite.cpu, processor.AddressInfo{})
+	assert.Equal(suite.T(), uint8(0x80), suite.bus.Read(0x01FD), "Expected X to be pushed onto the stack")
+
+	suite.cpu.X = 0x00
+	processor.PLX(suite.cpu, processor.AddressInfo{})
+	assert.Equal(suite.T(), uint8(0x80), suite.cpu.X, "X Register should be 0x80")
+	assert.True(suite.T(), suite.cpu.GetFlag(processor.N), "Negative flag should be set")
+	assert.Equal(suite.T(), uint8(0xFD), suite.cpu.SP, "Expected the Stack Pointer to be 0xFD")
+}
+
+func (suite *CMOSInstructionsSuite) TestPHY_PLY() {
+	suite.cpu.Y = 0x00
+	processor.PHY(suite.cpu, processor.AddressInfo{})
+	assert.Equal(suite.T(), uint8(0x00), suite.bus.Read(0x01FD), "Expected Y to be pushed onto the stack")
+
+	suite.cpu.Y = 0x12
+	processor.PLY(suite.cpu, processor.AddressInfo{})
+	assert.Equal(suite.T(), uint8(0x00), suite.cpu.Y, "Y Register should be 0x00")
+	assert.True(suite.T(), suite.cpu.GetFlag(processor.Z), "Zero flag should be set")
+}
+
+//
+// Store Instructions
+//
+
+func (suite *CMOSInstructionsSuite) TestSTZ() {
+	suite.bus.Write(0x2000, 0x12)
+
+	processor.STZ(suite.cpu, processor.AddressInfo{Address: 0x2000})
+
+	assert.Equal(suite.T(), uint8(0x00), suite.bus.Read(0x2000), "Memory at 0x2000 should be 0x00")
+}
+
+//
+// Bitwise Instructions
+//
+
+func (suite *CMOSInstructionsSuite) TestTRB() {
+	suite.bus.Write(0x2000, 0b11110000)
+	suite.cpu.A = 0b00111100
+
+	processor.TRB(suite.cpu, processor.AddressInfo{Address: 0x2000})
+
+	assert.Equal(suite.T(), uint8(0b11000000), suite.bus.Read(0x2000), "Expected bits in A to be cleared")
+	assert.False(suite.T(), suite.cpu.GetFlag(processor.Z), "Zero flag should be false")
+}
+
+func (suite *CMOSInstructionsSuite) TestTSB() {
+	suite.bus.Write(0x2000, 0b11000000)
+	suite.cpu.A = 0b00001100
+
+	processor.TSB(suite.cpu, processor.AddressInfo{Address: 0x2000})
+
+	assert.Equal(suite.T(), uint8(0b11001100), suite.bus.Read(0x2000), "Expected bits in A to be set")
+	assert.True(suite.T(), suite.cpu.GetFlag(processor.Z), "Zero flag should be true")
+}
+
+func (suite *CMOSInstructionsSuite) TestRMB() {
+	suite.bus.Write(0x0040, 0xFF)
+
+	processor.RMB3(suite.cpu, processor.AddressInfo{Address: 0x0040})
+
+	assert.Equal(suite.T(), uint8(0b11110111), suite.bus.Read(0x0040), "Expected bit 3 to be cleared")
+}
+
+func (suite *CMOSInstructionsSuite) TestSMB() {
+	suite.bus.Write(0x0040, 0x00)
+
+	processor.SMB5(suite.cpu, processor.AddressInfo{Address: 0x0040})
+
+	assert.Equal(suite.T(), uint8(0b00100000), suite.bus.Read(0x0040), "Expected bit 5 to be set")
+}
+
+func (suite *CMOSInstructionsSuite) TestBIT_Immediate() {
+	suite.bus.Write(0x2000, 0xC0)
+	suite.cpu.A = 0x01
+	suite.cpu.SetFlag(processor.N, false)
+	suite.cpu.SetFlag(processor.V, false)
+
+	processor.BIT(suite.cpu, processor.AddressInfo{Address: 0x2000, IsImmediate: true})
+
+	assert.True(suite.T(), suite.cpu.GetFlag(processor.Z), "Zero flag should be set")
+	assert.False(suite.T(), suite.cpu.GetFlag(processor.N), "Negative flag should not be affected")
+	assert.False(suite.T(), suite.cpu.GetFlag(processor.V), "Overflow flag should not be affected")
+}
+
+//
+// Arithmetic Instructions
+//
+
+func (suite *CMOSInstructionsSuite) TestINC_Accumulator() {
+	suite.cpu.A = 0xFF
+
+	processor.INC(suite.cpu, processor.AddressInfo{IsAccumulator: true})
+
+	assert.Equal(suite.T(), uint8(0x00), suite.cpu.A, "Accumulator should be 0x00")
+	assert.True(suite.T(), suite.cpu.GetFlag(processor.Z), "Zero flag should be set")
+}
+
+func (suite *CMOSInstructionsSuite) TestDEC_Accumulator() {
+	suite.cpu.A = 0x00
+
+	processor.DEC(suite.cpu, processor.AddressInfo{IsAccumulator: true})
+
+	assert.Equal(suite.T(), uint8(0xFF), suite.cpu.A, "Accumulator should be 0xFF")
+	assert.True(suite.T(), suite.cpu.GetFlag(processor.N), "Negative flag should be set")
+}
+
+func (suite *CMOSInstructionsSuite) TestADC_Decimal() {
+	suite.bus.Write(0x2000, 0x01)
+	suite.cpu.A = 0x99
+	suite.cpu.SetFlag(processor.C, false)
+	suite.cpu.SetFlag(processor.D, true)
+
+	processor.ADC(suite.cpu, processor.AddressInfo{Address: 0x2000})
+
+	// Unlike the NMOS 6502, N and Z reflect the decimal result, and an extra cycle is taken
+	assert.Equal(suite.T(), uint8(0x00), suite.cpu.A, "Accumulator should be 0x00")
+	assert.True(suite.T(), suite.cpu.GetFlag(processor.C), "Carry flag should be set")
+	assert.True(suite.T(), suite.cpu.GetFlag(processor.Z), "Zero flag should be set")
+	assert.False(suite.T(), suite.cpu.GetFlag(processor.N), "Negative flag should be false")
+	assert.Equal(suite.T(), uint8(1), suite.cpu.Cycles(), "Expected an extra cycle")
+}
+
+func (suite *CMOSInstructionsSuite) TestSBC_Decimal() {
+	suite.bus.Write(0x2000, 0x01)
+	suite.cpu.A = 0x00
+	suite.cpu.SetFlag(processor.C, true)
+	suite.cpu.SetFlag(processor.D, true)
+
+	processor.SBC(suite.cpu, processor.AddressInfo{Address: 0x2000})
+
+	assert.Equal(suite.T(), uint8(0x99), suite.cpu.A, "Accumulator should be 0x99")
+	assert.False(suite.T(), suite.cpu.GetFlag(processor.C), "Carry flag should be cleared (borrow)")
+	assert.False(suite.T(), suite.cpu.GetFlag(processor.Z), "Zero flag should be false")
+	assert.True(suite.T(), suite.cpu.GetFlag(processor.N), "Negative flag should be set")
+	assert.Equal(suite.T(), uint8(1), suite.cpu.Cycles(), "Expected an extra cycle")
+}
+
+//
+// Other Instructions
+//
+
+func (suite *CMOSInstructionsSuite) TestWAI() {
+	// WAI; INX
+	suite.load(0xCB, 0xE8)
+	suite.cpu.SetFlag(processor.I, true)
+
+	for range 10 {
+		suite.cpu.Clock()
+	}
+	assert.True(suite.T(), suite.cpu.Waiting(), "CPU should be waiting")
+	assert.Equal(suite.T(), uint16(0x8001), suite.cpu.PC, "Expected PC to point after WAI")
+
+	// A masked IRQ wakes the CPU, which carries on with the next instruction
+	suite.cpu.AssertIRQ(0)
+	suite.cpu.Clock()
+	suite.cpu.Clock()
+	assert.False(suite.T(), suite.cpu.Waiting(), "CPU should no longer be waiting")
+	assert.Equal(suite.T(), uint8(0x01), suite.cpu.X, "Expected INX to have been executed")
+}
+
+func (suite *CMOSInstructionsSuite) TestSTP() {
+	// STP; INX
+	suite.load(0xDB, 0xE8)
+
+	for range 10 {
+		suite.cpu.Clock()
+	}
+
+	assert.True(suite.T(), suite.cpu.Halted(), "CPU should be halted")
+	assert.Equal(suite.T(), uint16(0x8000), suite.cpu.PC, "Expected PC to point at STP")
+	assert.Equal(suite.T(), uint8(0x00), suite.cpu.X, "Expected INX not to have been executed")
+}
+
+func (suite *CMOSInstructionsSuite) TestRockwell_NoWAIOrSTP() {
+	suite.cpu = processor.NewCPUWithVariant(suite.bus, processor.VariantR65C02)
+	// STP; INX (STP is a 1 byte, 1 cycle NOP on the Rockwell part)
+	suite.load(0xDB, 0xE8)
+
+	suite.cpu.Clock()
+	suite.cpu.Clock()
+	suite.cpu.Clock()
+
+	assert.False(suite.T(), suite.cpu.Halted(), "CPU should not be halted")
+	assert.Equal(suite.T(), uint8(0x01), suite.cpu.X, "Expected INX to have been executed")
+}
+
+func (suite *CMOSInstructionsSuite) TestBRK_ClearsDecimal() {
+	suite.cpu.SetFlag(processor.D, true)
+
+	processor.BRK(suite.cpu, processor.AddressInfo{})
+
+	assert.False(suite.T(), suite.cpu.GetFlag(processor.D), "Decimal flag should be cleared")
+	assert.True(suite.T(), suite.cpu.GetFlag(processor.I), "Disable Interrupt flag should be set")
+}
+
+func (suite *CMOSInstructionsSuite) TestASL_AbsoluteX_Timing() {
+	// ASL $2000,X takes 6 cycles on the 65C02 (7 on the NMOS 6502), plus 1 if a page boundary is crossed
+	suite.load(0x1E, 0x00, 0x20)
+	suite.cpu.X = 0x01
+	suite.cpu.Clock()
+	assert.Equal(suite.T(), uint8(5), suite.cpu.Cycles(), "Expected 5 cycles remaining")
+	suite.cpu.Step()
+
+	suite.load(0x1E, 0xFF, 0x20)
+	suite.cpu.Clock()
+	assert.Equal(suite.T(), uint8(6), suite.cpu.Cycles(), "Expected 6 cycles remaining")
+}
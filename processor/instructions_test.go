@@ -569,6 +569,95 @@ func (suite *InstructionsSuite) TestSBC_Overflow() {
 	assert.True(suite.T(), extraCycle, "Expected extraCycle to be true")
 }
 
+func (suite *InstructionsSuite) TestADC_DecimalIgnoredOn2A03() {
+	suite.bus.Write(0x2000, 0x27)
+
+	suite.cpu.A = 0x15
+	suite.cpu.SetFlag(processor.C, false)
+	suite.cpu.SetFlag(processor.D, true)
+
+	processor.ADC(suite.cpu, processor.AddressInfo{Address: 0x2000})
+
+	assert.Equal(suite.T(), uint8(0x3C), suite.cpu.A, "Accumulator should be 0x3C (binary addition)")
+}
+
+func (suite *InstructionsSuite) TestADC_Decimal() {
+	// Use a CPU variant which supports decimal mode
+	suite.cpu = processor.NewCPUWithVariant(suite.bus, processor.VariantNMOS)
+	suite.bus.Write(0x2000, 0x27)
+
+	suite.cpu.A = 0x15
+	suite.cpu.SetFlag(processor.C, false)
+	suite.cpu.SetFlag(processor.D, true)
+
+	extraCycle := processor.ADC(suite.cpu, processor.AddressInfo{Address: 0x2000})
+
+	assert.Equal(suite.T(), uint8(0x42), suite.cpu.A, "Accumulator should be 0x42")
+	assert.False(suite.T(), suite.cpu.GetFlag(processor.C), "Carry flag should be false")
+	assert.False(suite.T(), suite.cpu.GetFlag(processor.V), "Overflow flag should be false")
+	assert.False(suite.T(), suite.cpu.GetFlag(processor.Z), "Zero flag should be false")
+	assert.False(suite.T(), suite.cpu.GetFlag(processor.N), "Negative flag should be false")
+	assert.True(suite.T(), extraCycle, "Expected extraCycle to be true")
+}
+
+func (suite *InstructionsSuite) TestADC_DecimalCarryOut() {
+	// Use a CPU variant which supports decimal mode
+	suite.cpu = processor.NewCPUWithVariant(suite.bus, processor.VariantNMOS)
+	suite.bus.Write(0x2000, 0x01)
+
+	suite.cpu.A = 0x99
+	suite.cpu.SetFlag(processor.C, false)
+	suite.cpu.SetFlag(processor.D, true)
+
+	processor.ADC(suite.cpu, processor.AddressInfo{Address: 0x2000})
+
+	// 99 + 01 = 100, so A wraps to 00 with carry set. On the NMOS 6502 Z comes from the binary result ($9A) and N
+	// comes from the intermediate result ($A0), so neither reflects the value in A.
+	assert.Equal(suite.T(), uint8(0x00), suite.cpu.A, "Accumulator should be 0x00")
+	assert.True(suite.T(), suite.cpu.GetFlag(processor.C), "Carry flag should be set")
+	assert.False(suite.T(), suite.cpu.GetFlag(processor.V), "Overflow flag should be false")
+	assert.False(suite.T(), suite.cpu.GetFlag(processor.Z), "Zero flag should be false")
+	assert.True(suite.T(), suite.cpu.GetFlag(processor.N), "Negative flag should be set")
+}
+
+func (suite *InstructionsSuite) TestSBC_Decimal() {
+	// Use a CPU variant which supports decimal mode
+	suite.cpu = processor.NewCPUWithVariant(suite.bus, processor.VariantNMOS)
+	suite.bus.Write(0x2000, 0x15)
+
+	suite.cpu.A = 0x42
+	suite.cpu.SetFlag(processor.C, true)
+	suite.cpu.SetFlag(processor.D, true)
+
+	extraCycle := processor.SBC(suite.cpu, processor.AddressInfo{Address: 0x2000})
+
+	assert.Equal(suite.T(), uint8(0x27), suite.cpu.A, "Accumulator should be 0x27")
+	assert.True(suite.T(), suite.cpu.GetFlag(processor.C), "Carry flag should be set")
+	assert.False(suite.T(), suite.cpu.GetFlag(processor.V), "Overflow flag should be false")
+	assert.False(suite.T(), suite.cpu.GetFlag(processor.Z), "Zero flag should be false")
+	assert.False(suite.T(), suite.cpu.GetFlag(processor.N), "Negative flag should be false")
+	assert.True(suite.T(), extraCycle, "Expected extraCycle to be true")
+}
+
+func (suite *InstructionsSuite) TestSBC_DecimalBorrow() {
+	// Use a CPU variant which supports decimal mode
+	suite.cpu = processor.NewCPUWithVariant(suite.bus, processor.VariantNMOS)
+	suite.bus.Write(0x2000, 0x01)
+
+	suite.cpu.A = 0x00
+	suite.cpu.SetFlag(processor.C, true)
+	suite.cpu.SetFlag(processor.D, true)
+
+	processor.SBC(suite.cpu, processor.AddressInfo{Address: 0x2000})
+
+	// 00 - 01 = 99 with a borrow. The flags are those of the binary result ($FF).
+	assert.Equal(suite.T(), uint8(0x99), suite.cpu.A, "Accumulator should be 0x99")
+	assert.False(suite.T(), suite.cpu.GetFlag(processor.C), "Carry flag should be cleared (borrow)")
+	assert.False(suite.T(), suite.cpu.GetFlag(processor.V), "Overflow flag should be false")
+	assert.False(suite.T(), suite.cpu.GetFlag(processor.Z), "Zero flag should be false")
+	assert.True(suite.T(), suite.cpu.GetFlag(processor.N), "Negative flag should be set")
+}
+
 func (suite *InstructionsSuite) TestINC() {
 	// Write a value to memory at address 0x2000
 	suite.bus.Write(0x2000, 0x05)
@@ -2233,7 +2322,7 @@ func (suite *InstructionsSuite) TestNOP() {
 	// Execute NOP instruction
 	extraCycle := processor.NOP(suite.cpu, processor.AddressInfo{})
 
-	assert.False(suite.T(), extraCycle, "Expected extraCycle to be false")
+	assert.True(suite.T(), extraCycle, "Expected extraCycle to be true")
 }
 
 func (suite *InstructionsSuite) TestXXX() {
@@ -0,0 +1,291 @@
+package processor
+
+// The NMOS 6502 has 105 opcodes that were never documented by MOS. They are a side effect of the way the
+// instruction decoder works: most of them activate the logic for two documented instructions at once.
+//
+// Combined:   SLO, RLA, SRE, RRA, SAX, LAX, DCP, ISC
+// Immediate:  ANC, ALR, ARR, SBX
+// Unstable:   XAA, LXA, AHX, SHX, SHY, TAS, LAS
+// Other:      JAM, and a number of single and multi-byte NOPs
+//
+// The names follow the conventions used by the NESdev wiki and most modern assemblers.
+//
+// https://www.nesdev.org/wiki/CPU_unofficial_opcodes
+// https://www.masswerk.at/nowgobang/2021/6502-illegal-opcodes
+
+// DefaultMagicConstant is the default value of CPU.MagicConstant. It matches the behaviour of most NMOS chips and
+// is the value assumed by the SingleStepTests test suite.
+const DefaultMagicConstant = 0xEE
+
+//
+// Combined Instructions
+//
+
+// SLO - Arithmetic Shift Left then Bitwise OR
+// Function:  memory = memory << 1, A = A | memory
+// Flags Out: C, Z, N
+func SLO(cpu *CPU, addressInfo AddressInfo) bool {
+	value := cpu.Read(addressInfo.Address)
+	cpu.SetFlag(C, (value&0x80) != 0)
+	value <<= 1
+	cpu.Write(addressInfo.Address, value)
+	cpu.A |= value
+	cpu.SetZN(cpu.A)
+	return false
+}
+
+// RLA - Rotate Left then Bitwise AND
+// Function:  memory = memory << 1 through C, A = A & memory
+// Flags Out: C, Z, N
+func RLA(cpu *CPU, addressInfo AddressInfo) bool {
+	c := cpu.GetFlag(C)
+	value := cpu.Read(addressInfo.Address)
+	cpu.SetFlag(C, (value&0x80) != 0)
+	value = (value << 1) | (ternary(c, byte(1), byte(0)))
+	cpu.Write(addressInfo.Address, value)
+	cpu.A &= value
+	cpu.SetZN(cpu.A)
+	return false
+}
+
+// SRE - Logical Shift Right then Bitwise XOR
+// Function:  memory = memory >> 1, A = A ^ memory
+// Flags Out: C, Z, N
+func SRE(cpu *CPU, addressInfo AddressInfo) bool {
+	value := cpu.Read(addressInfo.Address)
+	cpu.SetFlag(C, (value&0x01) != 0)
+	value >>= 1
+	cpu.Write(addressInfo.Address, value)
+	cpu.A ^= value
+	cpu.SetZN(cpu.A)
+	return false
+}
+
+// RRA - Rotate Right then Add with Carry
+// Function:  memory = memory >> 1 through C, A = A + memory + C
+// Flags Out: C, Z, V, N
+//
+// The carry shifted out of memory by the rotate is the carry added by the ADC. Decimal mode is honoured.
+func RRA(cpu *CPU, addressInfo AddressInfo) bool {
+	c := cpu.GetFlag(C)
+	value := cpu.Read(addressInfo.Address)
+	cpu.SetFlag(C, (value&0x01) != 0)
+	value = (value >> 1) | ((ternary(c, byte(1), byte(0))) << 7)
+	cpu.Write(addressInfo.Address, value)
+	cpu.add(value)
+	return false
+}
+
+// SAX - Store A AND X
+// Function:  memory = A & X
+// Flags Out: None
+func SAX(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.Write(addressInfo.Address, cpu.A&cpu.X)
+	return false
+}
+
+// LAX - Load Accumulator and X Register
+// Function:  A = X = memory
+// Flags Out: Z, N
+func LAX(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.A = cpu.Read(addressInfo.Address)
+	cpu.X = cpu.A
+	cpu.SetZN(cpu.A)
+	return true
+}
+
+// DCP - Decrement Memory then Compare Accumulator
+// Function:  memory = memory - 1, A - memory
+// Flags Out: C, Z, N
+func DCP(cpu *CPU, addressInfo AddressInfo) bool {
+	value := cpu.Read(addressInfo.Address)
+	value--
+	cpu.Write(addressInfo.Address, value)
+	cpu.SetFlag(C, cpu.A >= value)
+	cpu.SetZN(cpu.A - value)
+	return false
+}
+
+// ISC - Increment Memory then Subtract with Carry
+// Function:  memory = memory + 1, A = A - memory - !C
+// Flags Out: C, Z, V, N
+//
+// Decimal mode is honoured.
+func ISC(cpu *CPU, addressInfo AddressInfo) bool {
+	value := cpu.Read(addressInfo.Address)
+	value++
+	cpu.Write(addressInfo.Address, value)
+	cpu.subtract(value)
+	return false
+}
+
+//
+// Immediate Instructions
+//
+
+// ANC - Bitwise AND then copy N to C
+// Function:  A = A & memory, C = N
+// Flags Out: C, Z, N
+func ANC(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.A &= cpu.Read(addressInfo.Address)
+	cpu.SetZN(cpu.A)
+	cpu.SetFlag(C, cpu.GetFlag(N))
+	return false
+}
+
+// ALR - Bitwise AND then Logical Shift Right (also known as ASR)
+// Function:  A = (A & memory) >> 1
+// Flags Out: C, Z, N
+func ALR(cpu *CPU, addressInfo AddressInfo) bool {
+	value := cpu.A & cpu.Read(addressInfo.Address)
+	cpu.SetFlag(C, (value&0x01) != 0)
+	cpu.A = value >> 1
+	cpu.SetZN(cpu.A)
+	return false
+}
+
+// ARR - Bitwise AND then Rotate Right
+// Function:  A = (A & memory) >> 1 through C
+// Flags Out: C, Z, V, N
+//
+// The rotate goes through the adder, so C and V are set in an unusual way: C is bit 6 of the result and V is
+// bit 6 XOR bit 5. In decimal mode the adder also applies a (buggy) decimal adjustment to each nibble.
+func ARR(cpu *CPU, addressInfo AddressInfo) bool {
+	value := cpu.A & cpu.Read(addressInfo.Address)
+	carry := ternary(cpu.GetFlag(C), byte(0x80), byte(0x00))
+	cpu.A = (value >> 1) | carry
+
+	if !cpu.decimalMode() {
+		cpu.SetZN(cpu.A)
+		cpu.SetFlag(C, cpu.A&0x40 != 0)
+		cpu.SetFlag(V, (cpu.A>>6)&0x01 != (cpu.A>>5)&0x01)
+		return false
+	}
+
+	// Decimal mode: N and Z come from the rotated value, V from the change in bit 6
+	cpu.SetFlag(N, carry != 0)
+	cpu.SetFlag(Z, cpu.A == 0)
+	cpu.SetFlag(V, (value^cpu.A)&0x40 != 0)
+	if (value&0x0F)+(value&0x01) > 0x05 {
+		cpu.A = (cpu.A & 0xF0) | ((cpu.A + 0x06) & 0x0F)
+	}
+	hi := value >> 4
+	cpu.SetFlag(C, hi+(hi&0x01) > 0x05)
+	if cpu.GetFlag(C) {
+		cpu.A += 0x60
+	}
+	return false
+}
+
+// SBX - Subtract from A AND X (also known as AXS)
+// Function:  X = (A & X) - memory
+// Flags Out: C, Z, N
+//
+// The subtraction behaves like CMP rather than SBC: the carry flag is not used as an input and decimal mode is
+// ignored.
+func SBX(cpu *CPU, addressInfo AddressInfo) bool {
+	value := cpu.Read(addressInfo.Address)
+	ax := cpu.A & cpu.X
+	cpu.SetFlag(C, ax >= value)
+	cpu.X = ax - value
+	cpu.SetZN(cpu.X)
+	return false
+}
+
+//
+// Unstable Instructions
+//
+// XAA and LXA mix the accumulator with a chip (and temperature) dependent "magic" constant. AHX, SHX, SHY and TAS
+// AND the value they store with the high byte of the base address plus one, and when the indexing crosses a page
+// boundary that same value replaces the high byte of the target address.
+//
+
+// XAA - Transfer X to A then Bitwise AND (also known as ANE)
+// Function:  A = (A | magic) & X & memory
+// Flags Out: Z, N
+func XAA(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.A = (cpu.A | cpu.MagicConstant) & cpu.X & cpu.Read(addressInfo.Address)
+	cpu.SetZN(cpu.A)
+	return false
+}
+
+// LXA - Load Accumulator and X Register (also known as LAX immediate or OAL)
+// Function:  A = X = (A | magic) & memory
+// Flags Out: Z, N
+func LXA(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.A = (cpu.A | cpu.MagicConstant) & cpu.Read(addressInfo.Address)
+	cpu.X = cpu.A
+	cpu.SetZN(cpu.A)
+	return false
+}
+
+// AHX - Store A AND X AND (high byte of address + 1) (also known as SHA)
+// Function:  memory = A & X & (H + 1)
+// Flags Out: None
+func AHX(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.storeHigh(addressInfo, cpu.Y, cpu.A&cpu.X)
+	return false
+}
+
+// SHX - Store X AND (high byte of address + 1)
+// Function:  memory = X & (H + 1)
+// Flags Out: None
+func SHX(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.storeHigh(addressInfo, cpu.Y, cpu.X)
+	return false
+}
+
+// SHY - Store Y AND (high byte of address + 1)
+// Function:  memory = Y & (H + 1)
+// Flags Out: None
+func SHY(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.storeHigh(addressInfo, cpu.X, cpu.Y)
+	return false
+}
+
+// TAS - Transfer A AND X to Stack Pointer, then store (also known as SHS)
+// Function:  SP = A & X, memory = SP & (H + 1)
+// Flags Out: None
+func TAS(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.SP = cpu.A & cpu.X
+	cpu.storeHigh(addressInfo, cpu.Y, cpu.SP)
+	return false
+}
+
+// LAS - Load Accumulator, X Register and Stack Pointer (also known as LAR)
+// Function:  A = X = SP = memory & SP
+// Flags Out: Z, N
+func LAS(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.SP &= cpu.Read(addressInfo.Address)
+	cpu.A = cpu.SP
+	cpu.X = cpu.SP
+	cpu.SetZN(cpu.A)
+	return true
+}
+
+// storeHigh implements the store behaviour shared by AHX, SHX, SHY and TAS. The value is ANDed with the high byte
+// of the base address (before indexing) plus one. If indexing crossed a page boundary, the high byte of the
+// effective address is replaced by the value being stored.
+func (c *CPU) storeHigh(addressInfo AddressInfo, index byte, value byte) {
+	baseHigh := uint8((addressInfo.Address - uint16(index)) >> 8)
+	value &= baseHigh + 1
+	addr := addressInfo.Address
+	if addressInfo.PageChanged {
+		addr = uint16(value)<<8 | addr&0x00FF
+	}
+	c.Write(addr, value)
+}
+
+//
+// Other Instructions
+//
+
+// JAM - Halt the processor (also known as KIL or HLT)
+//
+// The processor stops fetching instructions and the data bus is left stuck. Only a reset will bring it back to
+// life. The Program Counter is left pointing at the JAM opcode.
+func JAM(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.PC--
+	cpu.halt(HaltJAM)
+	return false
+}
@@ -0,0 +1,307 @@
+package processor_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/ukdave/6502_emulator/bus"
+	"github.com/ukdave/6502_emulator/processor"
+)
+
+type UndocumentedInstructionsSuite struct {
+	suite.Suite
+	bus bus.Bus
+	cpu *processor.CPU
+}
+
+func TestUndocumentedInstructionsSuite(t *testing.T) {
+	suite.Run(t, new(UndocumentedInstructionsSuite))
+}
+
+func (suite *UndocumentedInstructionsSuite) SetupTest() {
+	suite.bus = bus.NewSimpleBus()
+	suite.cpu = processor.NewCPU(suite.bus)
+}
+
+//
+// Combined Instructions
+//
+
+func (suite *UndocumentedInstructionsSuite) TestSLO() {
+	suite.bus.Write(0x2000, 0x81)
+	suite.cpu.A = 0x10
+
+	extraCycle := processor.SLO(suite.cpu, processor.AddressInfo{Address: 0x2000})
+
+	assert.Equal(suite.T(), uint8(0x02), suite.bus.Read(0x2000), "Memory should be shifted left to 0x02")
+	assert.Equal(suite.T(), uint8(0x12), suite.cpu.A, "Accumulator should be 0x12")
+	assert.True(suite.T(), suite.cpu.GetFlag(processor.C), "Carry flag should be set")
+	assert.False(suite.T(), suite.cpu.GetFlag(processor.Z), "Zero flag should be false")
+	assert.False(suite.T(), suite.cpu.GetFlag(processor.N), "Negative flag should be false")
+	assert.False(suite.T(), extraCycle, "Expected extraCycle to be false")
+}
+
+func (suite *UndocumentedInstructionsSuite) TestRLA() {
+	suite.bus.Write(0x2000, 0x81)
+	suite.cpu.A = 0xFF
+	suite.cpu.SetFlag(processor.C, true)
+
+	extraCycle := processor.RLA(suite.cpu, processor.AddressInfo{Address: 0x2000})
+
+	assert.Equal(suite.T(), uint8(0x03), suite.bus.Read(0x2000), "Memory should be rotated left to 0x03")
+	assert.Equal(suite.T(), uint8(0x03), suite.cpu.A, "Accumulator should be 0x03")
+	assert.True(suite.T(), suite.cpu.GetFlag(processor.C), "Carry flag should be set")
+	assert.False(suite.T(), extraCycle, "Expected extraCycle to be false")
+}
+
+func (suite *UndocumentedInstructionsSuite) TestSRE() {
+	suite.bus.Write(0x2000, 0x03)
+	suite.cpu.A = 0x01
+
+	extraCycle := processor.SRE(suite.cpu, processor.AddressInfo{Address: 0x2000})
+
+	assert.Equal(suite.T(), uint8(0x01), suite.bus.Read(0x2000), "Memory should be shifted right to 0x01")
+	assert.Equal(suite.T(), uint8(0x00), suite.cpu.A, "Accumulator should be 0x00")
+	assert.True(suite.T(), suite.cpu.GetFlag(processor.C), "Carry flag should be set")
+	assert.True(suite.T(), suite.cpu.GetFlag(processor.Z), "Zero flag should be set")
+	assert.False(suite.T(), extraCycle, "Expected extraCycle to be false")
+}
+
+func (suite *UndocumentedInstructionsSuite) TestRRA() {
+	suite.bus.Write(0x2000, 0x05)
+	suite.cpu.A = 0x10
+	suite.cpu.SetFlag(processor.C, false)
+
+	extraCycle := processor.RRA(suite.cpu, processor.AddressInfo{Address: 0x2000})
+
+	// 0x05 rotates to 0x02 with carry out, then A = 0x10 + 0x02 + 1
+	assert.Equal(suite.T(), uint8(0x02), suite.bus.Read(0x2000), "Memory should be rotated right to 0x02")
+	assert.Equal(suite.T(), uint8(0x13), suite.cpu.A, "Accumulator should be 0x13")
+	assert.False(suite.T(), suite.cpu.GetFlag(processor.C), "Carry flag should be false")
+	assert.False(suite.T(), extraCycle, "Expected extraCycle to be false")
+}
+
+func (suite *UndocumentedInstructionsSuite) TestSAX() {
+	suite.cpu.A = 0xF0
+	suite.cpu.X = 0x3C
+
+	extraCycle := processor.SAX(suite.cpu, processor.AddressInfo{Address: 0x2000})
+
+	assert.Equal(suite.T(), uint8(0x30), suite.bus.Read(0x2000), "Memory should be 0x30")
+	assert.False(suite.T(), extraCycle, "Expected extraCycle to be false")
+}
+
+func (suite *UndocumentedInstructionsSuite) TestLAX() {
+	suite.bus.Write(0x2000, 0x80)
+
+	extraCycle := processor.LAX(suite.cpu, processor.AddressInfo{Address: 0x2000})
+
+	assert.Equal(suite.T(), uint8(0x80), suite.cpu.A, "Accumulator should be 0x80")
+	assert.Equal(suite.T(), uint8(0x80), suite.cpu.X, "X Register should be 0x80")
+	assert.True(suite.T(), suite.cpu.GetFlag(processor.N), "Negative flag should be set")
+	assert.True(suite.T(), extraCycle, "Expected extraCycle to be true")
+}
+
+func (suite *UndocumentedInstructionsSuite) TestDCP() {
+	suite.bus.Write(0x2000, 0x11)
+	suite.cpu.A = 0x10
+
+	extraCycle := processor.DCP(suite.cpu, processor.AddressInfo{Address: 0x2000})
+
+	assert.Equal(suite.T(), uint8(0x10), suite.bus.Read(0x2000), "Memory should be decremented to 0x10")
+	assert.True(suite.T(), suite.cpu.GetFlag(processor.C), "Carry flag should be set")
+	assert.True(suite.T(), suite.cpu.GetFlag(processor.Z), "Zero flag should be set")
+	assert.False(suite.T(), extraCycle, "Expected extraCycle to be false")
+}
+
+func (suite *UndocumentedInstructionsSuite) TestISC() {
+	suite.bus.Write(0x2000, 0x01)
+	suite.cpu.A = 0x05
+	suite.cpu.SetFlag(processor.C, true)
+
+	extraCycle := processor.ISC(suite.cpu, processor.AddressInfo{Address: 0x2000})
+
+	assert.Equal(suite.T(), uint8(0x02), suite.bus.Read(0x2000), "Memory should be incremented to 0x02")
+	assert.Equal(suite.T(), uint8(0x03), suite.cpu.A, "Accumulator should be 0x03")
+	assert.True(suite.T(), suite.cpu.GetFlag(processor.C), "Carry flag should be set")
+	assert.False(suite.T(), extraCycle, "Expected extraCycle to be false")
+}
+
+//
+// Immediate Instructions
+//
+
+func (suite *UndocumentedInstructionsSuite) TestANC() {
+	suite.bus.Write(0x2000, 0x81)
+	suite.cpu.A = 0xF0
+
+	extraCycle := processor.ANC(suite.cpu, processor.AddressInfo{Address: 0x2000})
+
+	assert.Equal(suite.T(), uint8(0x80), suite.cpu.A, "Accumulator should be 0x80")
+	assert.True(suite.T(), suite.cpu.GetFlag(processor.N), "Negative flag should be set")
+	assert.True(suite.T(), suite.cpu.GetFlag(processor.C), "Carry flag should be copied from N")
+	assert.False(suite.T(), extraCycle, "Expected extraCycle to be false")
+}
+
+func (suite *UndocumentedInstructionsSuite) TestALR() {
+	suite.bus.Write(0x2000, 0x03)
+	suite.cpu.A = 0xFF
+
+	extraCycle := processor.ALR(suite.cpu, processor.AddressInfo{Address: 0x2000})
+
+	assert.Equal(suite.T(), uint8(0x01), suite.cpu.A, "Accumulator should be 0x01")
+	assert.True(suite.T(), suite.cpu.GetFlag(processor.C), "Carry flag should be set")
+	assert.False(suite.T(), extraCycle, "Expected extraCycle to be false")
+}
+
+func (suite *UndocumentedInstructionsSuite) TestARR() {
+	suite.bus.Write(0x2000, 0xC0)
+	suite.cpu.A = 0xFF
+	suite.cpu.SetFlag(processor.C, true)
+
+	extraCycle := processor.ARR(suite.cpu, processor.AddressInfo{Address: 0x2000})
+
+	// (0xFF & 0xC0) = 0xC0, rotated right with carry in = 0xE0. Bit 6 is set (C) and bit 5 is set (V = 6 ^ 5 = 0).
+	assert.Equal(suite.T(), uint8(0xE0), suite.cpu.A, "Accumulator should be 0xE0")
+	assert.True(suite.T(), suite.cpu.GetFlag(processor.C), "Carry flag should be set")
+	assert.False(suite.T(), suite.cpu.GetFlag(processor.V), "Overflow flag should be false")
+	assert.True(suite.T(), suite.cpu.GetFlag(processor.N), "Negative flag should be set")
+	assert.False(suite.T(), extraCycle, "Expected extraCycle to be false")
+}
+
+func (suite *UndocumentedInstructionsSuite) TestSBX() {
+	suite.bus.Write(0x2000, 0x10)
+	suite.cpu.A = 0xF3
+	suite.cpu.X = 0x3F
+	suite.cpu.SetFlag(processor.C, false)
+
+	extraCycle := processor.SBX(suite.cpu, processor.AddressInfo{Address: 0x2000})
+
+	// (0xF3 & 0x3F) - 0x10 = 0x33 - 0x10. The incoming carry flag is ignored.
+	assert.Equal(suite.T(), uint8(0x23), suite.cpu.X, "X Register should be 0x23")
+	assert.True(suite.T(), suite.cpu.GetFlag(processor.C), "Carry flag should be set")
+	assert.False(suite.T(), extraCycle, "Expected extraCycle to be false")
+}
+
+//
+// Unstable Instructions
+//
+
+func (suite *UndocumentedInstructionsSuite) TestXAA() {
+	suite.bus.Write(0x2000, 0xFF)
+	suite.cpu.A = 0x00
+	suite.cpu.X = 0x0F
+	suite.cpu.MagicConstant = 0xEE
+
+	processor.XAA(suite.cpu, processor.AddressInfo{Address: 0x2000})
+
+	assert.Equal(suite.T(), uint8(0x0E), suite.cpu.A, "Accumulator should be (0x00 | 0xEE) & 0x0F & 0xFF")
+}
+
+func (suite *UndocumentedInstructionsSuite) TestLXA() {
+	suite.bus.Write(0x2000, 0x3C)
+	suite.cpu.A = 0x01
+	suite.cpu.MagicConstant = 0xFF
+
+	processor.LXA(suite.cpu, processor.AddressInfo{Address: 0x2000})
+
+	assert.Equal(suite.T(), uint8(0x3C), suite.cpu.A, "Accumulator should be 0x3C")
+	assert.Equal(suite.T(), uint8(0x3C), suite.cpu.X, "X Register should be 0x3C")
+}
+
+func (suite *UndocumentedInstructionsSuite) TestSHY() {
+	// Base address 0x2010 + X (0x01), no page crossing
+	suite.cpu.X = 0x01
+	suite.cpu.Y = 0xFF
+
+	processor.SHY(suite.cpu, processor.AddressInfo{Address: 0x2011})
+
+	assert.Equal(suite.T(), uint8(0x21), suite.bus.Read(0x2011), "Memory should be Y & (0x20 + 1)")
+}
+
+func (suite *UndocumentedInstructionsSuite) TestSHX_PageCrossed() {
+	// Base address 0x20F0 + Y (0x20) = 0x2110, which crosses a page boundary
+	suite.cpu.X = 0x05
+	suite.cpu.Y = 0x20
+
+	processor.SHX(suite.cpu, processor.AddressInfo{Address: 0x2110, PageChanged: true})
+
+	// The value is X & (0x20 + 1) = 0x01, and that value also replaces the high byte of the address
+	assert.Equal(suite.T(), uint8(0x01), suite.bus.Read(0x0110), "Memory at 0x0110 should be 0x01")
+	assert.Equal(suite.T(), uint8(0x00), suite.bus.Read(0x2110), "Memory at 0x2110 should be untouched")
+}
+
+func (suite *UndocumentedInstructionsSuite) TestTAS() {
+	suite.cpu.A = 0xF0
+	suite.cpu.X = 0x3F
+	suite.cpu.Y = 0x00
+
+	processor.TAS(suite.cpu, processor.AddressInfo{Address: 0x7F00})
+
+	assert.Equal(suite.T(), uint8(0x30), suite.cpu.SP, "Stack Pointer should be 0x30")
+	assert.Equal(suite.T(), uint8(0x00), suite.bus.Read(0x7F00), "Memory should be 0x30 & 0x80")
+}
+
+func (suite *UndocumentedInstructionsSuite) TestLAS() {
+	suite.bus.Write(0x2000, 0x8F)
+	suite.cpu.SP = 0xF0
+
+	extraCycle := processor.LAS(suite.cpu, processor.AddressInfo{Address: 0x2000})
+
+	assert.Equal(suite.T(), uint8(0x80), suite.cpu.A, "Accumulator should be 0x80")
+	assert.Equal(suite.T(), uint8(0x80), suite.cpu.X, "X Register should be 0x80")
+	assert.Equal(suite.T(), uint8(0x80), suite.cpu.SP, "Stack Pointer should be 0x80")
+	assert.True(suite.T(), suite.cpu.GetFlag(processor.N), "Negative flag should be set")
+	assert.True(suite.T(), extraCycle, "Expected extraCycle to be true")
+}
+
+//
+// Other Instructions
+//
+
+func (suite *UndocumentedInstructionsSuite) TestJAM() {
+	suite.cpu.PC = 0x8001
+
+	processor.JAM(suite.cpu, processor.AddressInfo{})
+
+	assert.True(suite.T(), suite.cpu.Halted(), "CPU should be halted")
+	assert.Equal(suite.T(), uint16(0x8000), suite.cpu.PC, "Expected PC to point at the JAM opcode")
+}
+
+func (suite *UndocumentedInstructionsSuite) TestJAM_Clock() {
+	// JAM; LDA #$05
+	suite.bus.Write(0x8000, 0x02)
+	suite.bus.Write(0x8001, 0xA9)
+	suite.bus.Write(0x8002, 0x05)
+	suite.cpu.PC = 0x8000
+
+	for range 10 {
+		suite.cpu.Clock()
+	}
+
+	assert.True(suite.T(), suite.cpu.Halted(), "CPU should be halted")
+	assert.Equal(suite.T(), uint16(0x8000), suite.cpu.PC, "Expected PC to stay at the JAM opcode")
+	assert.Equal(suite.T(), uint8(0x00), suite.cpu.A, "Expected LDA not to have been executed")
+	assert.Equal(suite.T(), uint64(10), suite.cpu.TotalCycles, "Expected cycles to keep counting")
+
+	suite.cpu.Reset()
+	assert.False(suite.T(), suite.cpu.Halted(), "CPU should not be halted after a reset")
+}
+
+func (suite *UndocumentedInstructionsSuite) TestMultiByteNOP_PageCrossed() {
+	// NOP $20FF,X with X = 1 takes 4 cycles + 1 for crossing a page boundary, then LDA #$05
+	suite.bus.Write(0x8000, 0x1C)
+	suite.bus.Write(0x8001, 0xFF)
+	suite.bus.Write(0x8002, 0x20)
+	suite.bus.Write(0x8003, 0xA9)
+	suite.bus.Write(0x8004, 0x05)
+	suite.cpu.PC = 0x8000
+	suite.cpu.X = 0x01
+
+	suite.cpu.Clock()
+
+	assert.Equal(suite.T(), uint16(0x8003), suite.cpu.PC, "Expected PC to skip over the operand")
+	assert.Equal(suite.T(), uint8(4), suite.cpu.Cycles(), "Expected 4 cycles remaining")
+}
@@ -0,0 +1,146 @@
+package processor
+
+import "fmt"
+
+// Interrupt lines.
+//
+// The IRQ line is level-triggered and shared: any number of devices can hold it asserted, and the CPU sees it
+// asserted until every one of them has released it. An interrupt handler therefore has to acknowledge the device
+// (making it release the line) before returning, or the IRQ is taken again straight away.
+//
+// The NMI line is edge-triggered. Asserting it latches a pending NMI, which is serviced once however long the line
+// is held; the line has to be released and asserted again to signal another one.
+//
+// The lines are not acted on immediately. The CPU polls them on the penultimate cycle of each instruction and, if
+// an interrupt is due, runs the interrupt sequence instead of fetching the next instruction. Because CLI, SEI and
+// PLP change the I flag on their final cycle, after the poll, the change only affects the instruction after next:
+// an IRQ that is pending when CLI executes is taken after the following instruction, and one that is pending when
+// SEI executes is still taken. RTI restores the I flag before the poll, so it has no such delay.
+//
+// On the NMOS variants an NMI that arrives during the first cycles of a BRK or IRQ sequence hijacks it: the
+// sequence completes (pushing the B flag as it would have) but loads PC from the NMI vector, and the BRK or IRQ is
+// lost. The CMOS variants service the NMI after the sequence instead.
+
+const (
+	nmiVector = 0xFFFA
+	irqVector = 0xFFFE
+)
+
+// MaxIRQSources is the number of devices that can share the IRQ line.
+const MaxIRQSources = 32
+
+// IRQSource identifies one of up to MaxIRQSources devices that can assert the shared IRQ line, numbered from 0.
+type IRQSource uint8
+
+// AssertIRQ asserts the IRQ line on behalf of the given source. The line stays asserted until every source that
+// has asserted it has released it. It panics if the source is not less than MaxIRQSources.
+func (c *CPU) AssertIRQ(source IRQSource) {
+	c.irqLines |= source.mask()
+}
+
+// ReleaseIRQ releases the IRQ line on behalf of the given source. It panics if the source is not less than
+// MaxIRQSources.
+func (c *CPU) ReleaseIRQ(source IRQSource) {
+	c.irqLines &^= source.mask()
+}
+
+// mask returns the bit of irqLines that records the source asserting the IRQ line.
+func (s IRQSource) mask() uint32 {
+	if s >= MaxIRQSources {
+		panic(fmt.Sprintf("IRQ source %d is out of range (there are %d)", s, MaxIRQSources))
+	}
+	return 1 << s
+}
+
+// IRQAsserted returns true if any source is asserting the IRQ line.
+func (c *CPU) IRQAsserted() bool {
+	return c.irqLines != 0
+}
+
+// SetNMI sets the level of the NMI line. Asserting a released line signals a non-maskable interrupt.
+func (c *CPU) SetNMI(asserted bool) {
+	if asserted && !c.nmiLine {
+		c.nmiPending = true
+	}
+	c.nmiLine = asserted
+}
+
+// NMIAsserted returns true if the NMI line is asserted.
+func (c *CPU) NMIAsserted() bool {
+	return c.nmiLine
+}
+
+// pollInterrupts decides whether an interrupt sequence should run at the next instruction boundary. masked is the
+// I flag as seen by the poll. A pending NMI takes priority over an IRQ.
+func (c *CPU) pollInterrupts(masked bool) {
+	switch {
+	case c.nmiPending:
+		c.pendingInterrupt = nmiVector
+	case c.irqLines != 0 && !masked:
+		c.pendingInterrupt = irqVector
+	default:
+		c.pendingInterrupt = 0
+	}
+}
+
+// wake returns true if the CPU can start an instruction (or interrupt sequence). A CPU paused by WAI resumes
+// when either interrupt line is signalled, even if the IRQ is masked, in which case it carries on with the next
+// instruction.
+func (c *CPU) wake() bool {
+	if c.halted {
+		return false
+	}
+	if c.waiting {
+		if !c.nmiPending && c.irqLines == 0 {
+			return false
+		}
+		c.waiting = false
+		c.haltReason = HaltNone
+		c.pollInterrupts(c.GetFlag(I))
+	}
+	return true
+}
+
+// interrupt performs the IRQ or NMI sequence chosen by the last poll. The current Program Counter and status flags
+// are pushed onto the stack and then we jump to the address stored in the vector.
+func (c *CPU) interrupt() {
+	vector := c.pendingInterrupt
+	c.pendingInterrupt = 0
+	c.haltReason = HaltNone
+	c.last = StepResult{PC: c.PC, Address: vector, Interrupt: interruptFor(vector)}
+	if vector == nmiVector {
+		c.nmiPending = false
+	}
+	c.Push16(c.PC)
+	c.Push(c.Status &^ byte(B)) // Clear the Break flag (but only in the value pushed to the stack)
+	c.enterInterrupt()
+	c.PC = c.read16(vector, AccessVector)
+	c.interruptVector = vector
+	c.cycles = 7 - 1
+}
+
+// hijack redirects a BRK or IRQ sequence in progress to the NMI vector if an NMI is pending. It is called before
+// each cycle of the sequence; the vector is chosen on cycle 5.
+func (c *CPU) hijack() {
+	cycle := 7 - c.cycles + 1
+	if c.interruptVector == irqVector && c.nmiPending && cycle <= 5 && !c.variant.isCMOS() {
+		c.nmiPending = false
+		c.interruptVector = nmiVector
+		c.hijacked()
+		c.PC = c.read16(nmiVector, AccessVector)
+	}
+}
+
+// hijacked records that the instruction or sequence being reported by Step was hijacked by an NMI.
+func (c *CPU) hijacked() {
+	c.last.Interrupt = InterruptNMI
+	if c.last.decoded == nil {
+		c.last.Address = nmiVector
+	}
+}
+
+// delaysInterruptMask returns true for the opcodes that change the I flag on their final cycle, after the
+// interrupt poll (CLI, SEI and PLP).
+func delaysInterruptMask(opcode byte) bool {
+	return opcode == 0x58 || opcode == 0x78 || opcode == 0x28
+}
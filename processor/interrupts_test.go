@@ -0,0 +1,236 @@
+package processor_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ukdave/6502_emulator/internal/cputest"
+	"github.com/ukdave/6502_emulator/processor"
+)
+
+func TestIRQ_Enabled(t *testing.T) {
+	cpu, ram := cputest.New(processor.Variant2A03, 0x2000, 0xEA) // NOP
+	handler(ram, 0xFFFE, 0x1005, 0xEA)                           // NOP
+
+	// Enable interrupts
+	cpu.SetFlag(processor.I, false)
+	statusFlags := cpu.Status
+
+	// Assert the IRQ line. The interrupt is taken at the end of the current instruction.
+	cpu.AssertIRQ(0)
+	assert.True(t, cpu.IRQAsserted(), "IRQ line should be asserted")
+	assert.Equal(t, uint16(0x2000), cpu.PC, "Expected PC to be 0x2000 (the interrupt is not taken immediately)")
+	cpu.Step()
+	assert.Equal(t, uint16(0x2001), cpu.PC, "Expected PC to be 0x2001")
+	cpu.Step()
+
+	assert.Equal(t, uint16(0x1005), cpu.PC, "Expected PC to be 0x1005")
+	assert.Equal(t, uint64(9), cpu.TotalCycles, "Expected the IRQ sequence to take 7 cycles")
+	assert.Equal(t, true, cpu.GetFlag(processor.I), "Disable Interrupt flag should be true")
+	assert.Equal(t, uint8(0xFA), cpu.SP, "Expected stack pointer to be 0xFA (3 bytes pushed)")
+	assert.Equal(t, statusFlags&^0x10, cpu.Pop(), "Expected stack to contain status flags with B clear")
+	assert.Equal(t, uint16(0x2001), cpu.Pop16(), "Expected stack contain the address of the next instruction")
+}
+
+func TestIRQ_Disabled(t *testing.T) {
+	cpu, _ := cputest.New(processor.Variant2A03, 0x2000, 0xEA, 0xEA) // NOP; NOP
+
+	// Disable interrupts
+	cpu.SetFlag(processor.I, true)
+
+	cpu.AssertIRQ(0)
+	cpu.Step()
+	cpu.Step()
+
+	assert.Equal(t, uint16(0x2002), cpu.PC, "Expected PC to be 0x2002")
+	assert.Equal(t, uint8(0xFD), cpu.SP, "Expected stack pointer to be 0xFD (nothing pushed)")
+}
+
+func TestIRQ_MultipleSources(t *testing.T) {
+	cpu, _ := cputest.New(processor.Variant2A03, 0x2000)
+
+	cpu.AssertIRQ(1)
+	cpu.AssertIRQ(2)
+	cpu.ReleaseIRQ(1)
+	assert.True(t, cpu.IRQAsserted(), "IRQ line should be held by the second source")
+	cpu.ReleaseIRQ(2)
+	assert.False(t, cpu.IRQAsserted(), "IRQ line should be released")
+}
+
+func TestIRQ_SourceOutOfRange(t *testing.T) {
+	cpu, _ := cputest.New(processor.Variant2A03, 0x2000)
+
+	cpu.AssertIRQ(processor.MaxIRQSources - 1)
+	assert.Panics(t, func() { cpu.AssertIRQ(processor.MaxIRQSources) }, "Source 32 should not alias source 0")
+	assert.Panics(t, func() { cpu.ReleaseIRQ(processor.MaxIRQSources) }, "Source 32 should not alias source 0")
+	cpu.ReleaseIRQ(processor.MaxIRQSources - 1)
+	assert.False(t, cpu.IRQAsserted(), "IRQ line should be released")
+}
+
+func TestIRQ_ReleasedBeforePoll(t *testing.T) {
+	cpu, _ := cputest.New(processor.Variant2A03, 0x2000, 0xAD, 0x00, 0x30, 0xEA) // LDA $3000; NOP
+	cpu.SetFlag(processor.I, false)
+
+	// The line is released before the penultimate cycle of LDA, so the interrupt is never seen
+	cpu.Clock()
+	cpu.AssertIRQ(0)
+	cpu.Clock()
+	cpu.ReleaseIRQ(0)
+	cpu.Clock()
+	cpu.Clock()
+	cpu.Step()
+
+	assert.Equal(t, uint16(0x2004), cpu.PC, "Expected PC to be 0x2004")
+}
+
+func TestIRQ_CLILatency(t *testing.T) {
+	cpu, ram := cputest.New(processor.Variant2A03, 0x2000, 0x58, 0xEA, 0xEA) // CLI; NOP; NOP
+	handler(ram, 0xFFFE, 0x1005, 0xEA)                                       // NOP
+	cpu.SetFlag(processor.I, true)
+	cpu.AssertIRQ(0)
+
+	// CLI clears the flag after the interrupt poll, so the IRQ is taken after the following instruction
+	cpu.Step()
+	assert.Equal(t, uint16(0x2001), cpu.PC, "Expected PC to be 0x2001")
+	cpu.Step()
+	assert.Equal(t, uint16(0x2002), cpu.PC, "Expected PC to be 0x2002")
+	cpu.Step()
+	assert.Equal(t, uint16(0x1005), cpu.PC, "Expected PC to be 0x1005")
+	cpu.Pop()
+	assert.Equal(t, uint16(0x2002), cpu.Pop16(), "Expected stack to contain the address after the NOP")
+}
+
+func TestIRQ_SEILatency(t *testing.T) {
+	cpu, ram := cputest.New(processor.Variant2A03, 0x2000, 0x78, 0xEA) // SEI; NOP
+	handler(ram, 0xFFFE, 0x1005, 0xEA)                                 // NOP
+	cpu.SetFlag(processor.I, false)
+	cpu.AssertIRQ(0)
+
+	// SEI sets the flag after the interrupt poll, so the IRQ is still taken
+	cpu.Step()
+	cpu.Step()
+	assert.Equal(t, uint16(0x1005), cpu.PC, "Expected PC to be 0x1005")
+	assert.Equal(t, uint8(0x04), cpu.Pop()&0x04, "Expected the pushed status to have the I flag set")
+	assert.Equal(t, uint16(0x2001), cpu.Pop16(), "Expected stack to contain the address after SEI")
+}
+
+func TestIRQ_RTINoLatency(t *testing.T) {
+	cpu, ram := cputest.New(processor.Variant2A03, 0x2000, 0x40) // RTI
+	handler(ram, 0xFFFE, 0x1005, 0xEA)                           // NOP
+	cpu.SetFlag(processor.I, true)
+	cpu.Push16(0x3000)
+	cpu.Push(0x20) // I clear
+	cpu.AssertIRQ(0)
+
+	// RTI restores the I flag before the interrupt poll, so the IRQ is taken straight away
+	cpu.Step()
+	assert.Equal(t, uint16(0x3000), cpu.PC, "Expected PC to be 0x3000")
+	cpu.Step()
+	assert.Equal(t, uint16(0x1005), cpu.PC, "Expected PC to be 0x1005")
+}
+
+func TestNMI(t *testing.T) {
+	cpu, ram := cputest.New(processor.Variant2A03, 0x2000, 0xEA, 0xEA, 0xEA) // NOP; NOP; NOP
+	handler(ram, 0xFFFA, 0x1800, 0xEA, 0xEA)                                 // NOP; NOP
+	cpu.SetFlag(processor.I, true)
+	statusFlags := cpu.Status
+
+	// NMI is edge-triggered: holding the line asserted signals a single interrupt
+	cpu.SetNMI(true)
+	assert.True(t, cpu.NMIAsserted(), "NMI line should be asserted")
+	cpu.Step()
+	cpu.Step()
+
+	assert.Equal(t, uint16(0x1800), cpu.PC, "Expected PC to be 0x1800")
+	assert.Equal(t, true, cpu.GetFlag(processor.I), "Disable Interrupt flag should be true")
+	assert.Equal(t, uint8(0xFA), cpu.SP, "Expected stack pointer to be 0xFA (3 bytes pushed)")
+	assert.Equal(t, statusFlags&^0x10, cpu.Pop(), "Expected stack to contain status flags with B clear")
+	assert.Equal(t, uint16(0x2001), cpu.Pop16(), "Expected stack contain the address of the next instruction")
+
+	cpu.Step()
+	cpu.Step()
+	assert.Equal(t, uint16(0x1802), cpu.PC, "Expected the NMI not to be taken again while the line is held")
+}
+
+func TestBRK_ReturnAddress(t *testing.T) {
+	for _, variant := range []processor.Variant{processor.Variant2A03, processor.VariantNMOS, processor.Variant65C02} {
+		cpu, ram := cputest.New(variant, 0x2000, 0x00, 0xFF, 0xE8) // BRK #$FF; INX
+		handler(ram, 0xFFFE, 0x1005, 0x40)                         // RTI
+
+		cpu.Step()
+		assert.Equal(t, uint16(0x1005), cpu.PC, "Expected BRK to jump through the IRQ vector (%s)", variant)
+		assert.Equal(t, uint16(0x2002), cpu.Read16(0x0100+uint16(cpu.SP)+2),
+			"Expected BRK to push the address after its padding byte (%s)", variant)
+
+		cpu.Step()
+		assert.Equal(t, uint16(0x2002), cpu.PC, "Expected RTI to skip the padding byte (%s)", variant)
+		cpu.Step()
+		assert.Equal(t, uint8(1), cpu.X, "Expected the instruction after the padding byte to run (%s)", variant)
+	}
+}
+
+func TestNMI_HijacksBRK(t *testing.T) {
+	cpu, ram := cputest.New(processor.VariantNMOS, 0x2000, 0x00, 0x00) // BRK
+	handler(ram, 0xFFFA, 0x1800, 0xEA)                                 // NOP
+	cpu.SetFlag(processor.I, false)
+
+	cpu.Clock()
+	cpu.SetNMI(true)
+	cpu.Step()
+
+	assert.Equal(t, uint16(0x1800), cpu.PC, "Expected BRK to be hijacked by the NMI")
+	assert.Equal(t, uint8(0x10), cpu.Pop()&0x10, "Expected the pushed status to have the B flag set")
+	assert.Equal(t, uint16(0x2002), cpu.Pop16(), "Expected stack to contain the BRK return address")
+
+	// The NMI has been serviced
+	cpu.Step()
+	assert.Equal(t, uint16(0x1801), cpu.PC, "Expected PC to be 0x1801")
+}
+
+func TestNMI_AfterBRKOnCMOS(t *testing.T) {
+	cpu, ram := cputest.New(processor.Variant65C02, 0x2000, 0x00, 0x00) // BRK
+	handler(ram, 0xFFFE, 0x1005, 0xEA)                                  // NOP
+	handler(ram, 0xFFFA, 0x1800, 0xEA)                                  // NOP
+	cpu.SetFlag(processor.I, false)
+
+	cpu.Clock()
+	cpu.SetNMI(true)
+	cpu.Step()
+	assert.Equal(t, uint16(0x1005), cpu.PC, "Expected BRK to complete")
+
+	// The NMI is taken after the first instruction of the BRK handler
+	cpu.Step()
+	cpu.Step()
+	assert.Equal(t, uint16(0x1800), cpu.PC, "Expected PC to be 0x1800")
+}
+
+func TestNMI_HijacksBRK_CycleAccurate(t *testing.T) {
+	cpu, ram := cputest.New(processor.VariantNMOS, 0x2000, 0x00, 0x00) // BRK
+	handler(ram, 0xFFFE, 0x1005, 0xEA)                                 // NOP
+	handler(ram, 0xFFFA, 0x1800, 0xEA)                                 // NOP
+	assert.NoError(t, cpu.SetCycleAccurate(true))
+
+	// An NMI arriving after the vector has been chosen does not hijack the BRK
+	for range 5 {
+		cpu.Clock()
+	}
+	cpu.SetNMI(true)
+	cpu.Clock()
+	cpu.Clock()
+	assert.Equal(t, uint16(0x1005), cpu.PC, "Expected BRK to complete")
+
+	// An NMI arriving before it does
+	cpu, ram = cputest.New(processor.VariantNMOS, 0x2000, 0x00, 0x00) // BRK
+	handler(ram, 0xFFFE, 0x1005, 0xEA)                                // NOP
+	handler(ram, 0xFFFA, 0x1800, 0xEA)                                // NOP
+	assert.NoError(t, cpu.SetCycleAccurate(true))
+	for range 4 {
+		cpu.Clock()
+	}
+	cpu.SetNMI(true)
+	cpu.Clock()
+	cpu.Clock()
+	cpu.Clock()
+	assert.Equal(t, uint16(0x1800), cpu.PC, "Expected BRK to be hijacked by the NMI")
+}
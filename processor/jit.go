@@ -0,0 +1,222 @@
+package processor
+
+import "slices"
+
+// Basic block JIT.
+//
+// When the JIT is enabled, RunCycles and RunUntil translate each straight-line run of instructions (a basic block)
+// into a list of Go closures the first time it is run, and cache it by its start address. Each closure is bound to
+// its opcode and operation and, where the address mode allows it, to its operand address, so running a cached block
+// skips fetching and decoding its instructions. The instructions themselves are executed by the same code as Clock,
+// so the registers, memory and cycle counts are exactly the same as the interpreter's.
+//
+// A block ends after an instruction that can change the flow of control (a branch, jump, call or return). BRK,
+// instructions that halt or pause the CPU, and opcodes handled by the unimplemented opcode policy are never compiled,
+// but are left to the interpreter. A write made through the CPU to the code of a cached block discards the block, so
+// self-modifying code still works. Writes made directly to the bus are not seen; call FlushJIT after changing code
+// that way.
+//
+// The JIT is only used with a SimpleBus, where fetching code has no side effects, and in the fast (not cycle
+// accurate) mode. The interpreter takes over whenever a cycle needs individual attention: while an interrupt is
+// pending, while RDY or RESET is holding the CPU, while rewind history or Step's bus access log is recording, and
+// while hooks are registered (see hooks.go).
+
+// maxBlockInstructions limits the length of a block, so that a block doesn't run for long without checking whether
+// RunCycles or RunUntil should stop.
+const maxBlockInstructions = 32
+
+// jitInstruction executes a single compiled instruction.
+type jitInstruction func(c *CPU)
+
+// jitBlock is a compiled basic block. A block with no instructions records that the instruction at start must be
+// left to the interpreter.
+type jitBlock struct {
+	start, end   int // The address of the first byte of the block, and of the byte after it
+	instructions []jitInstruction
+}
+
+// contains returns true if addr is part of the code of the block.
+func (b *jitBlock) contains(addr uint16) bool {
+	return int(addr) >= b.start && int(addr) < b.end
+}
+
+// jit is the cache of compiled blocks.
+type jit struct {
+	blocks      [0x10000]*jitBlock // Compiled blocks by start address
+	pages       [256][]*jitBlock   // The blocks covering each page of memory
+	code        [0x10000 / 8]byte  // A bit for each address that has been compiled, to make checking writes cheap
+	current     *jitBlock          // The block being run
+	invalidated bool               // The current block has been discarded
+}
+
+// SetJIT enables or disables the basic block JIT used by RunCycles and RunUntil. Disabling it discards the cached
+// blocks. The JIT has no effect in cycle accurate mode, or unless the CPU's bus is a SimpleBus.
+func (c *CPU) SetJIT(enabled bool) {
+	if !enabled {
+		c.jit = nil
+	} else if c.jit == nil {
+		c.jit = &jit{}
+	}
+}
+
+// JIT returns true if the basic block JIT is enabled.
+func (c *CPU) JIT() bool {
+	return c.jit != nil
+}
+
+// FlushJIT discards the cached blocks. It must be called after code is changed by writing directly to the bus rather
+// than through the CPU.
+func (c *CPU) FlushJIT() {
+	if c.jit != nil {
+		c.jit = &jit{}
+	}
+}
+
+// jitReady returns true if the next instruction can be run by the JIT.
+func (c *CPU) jitReady() bool {
+	return c.jit != nil && c.ram != nil && c.cycles == 0 && c.canSkip() && c.pendingInterrupt == 0 &&
+		!c.halted && !c.waiting && c.history == nil && c.accessLog == nil && c.hooks == nil
+}
+
+// runBlock runs the block at PC, compiling it first if necessary, until the block ends, the total cycle count reaches
+// target, or stop (if not nil) returns true between two of its instructions. It returns false if the JIT can't run
+// the instruction at PC, which must then be left to Clock, and stopped is true if stop returned true.
+func (c *CPU) runBlock(target uint64, stop func(*CPU) bool) (ran, stopped bool) {
+	if !c.jitReady() {
+		return false, false
+	}
+	j := c.jit
+	block := j.blocks[c.PC]
+	if block == nil {
+		block = j.compile(c, c.PC)
+	}
+	if len(block.instructions) == 0 {
+		return false, false
+	}
+
+	j.current, j.invalidated = block, false
+	for i, instruction := range block.instructions {
+		if i > 0 {
+			// Leave anything but a plain instruction boundary to the caller
+			if c.cycles > 0 || c.TotalCycles >= target || j.invalidated || c.pendingInterrupt != 0 {
+				break
+			}
+			if stop != nil && stop(c) {
+				stopped = true
+				break
+			}
+		}
+		// The first cycle fetches the opcode, as in Clock
+		c.TotalCycles++
+		instruction(c)
+		if c.cycles > 0 {
+			c.skip(min(uint64(c.cycles), target-c.TotalCycles))
+		}
+	}
+	j.current = nil
+	return true, stopped
+}
+
+// compile translates the block starting at start and adds it to the cache.
+func (j *jit) compile(c *CPU, start uint16) *jitBlock {
+	block := &jitBlock{start: int(start)}
+	pc := int(start)
+	for len(block.instructions) < maxBlockInstructions {
+		opcode := c.ram.Read(uint16(pc))
+		d := &c.decoded[opcode]
+		if d.interpreted || pc+int(d.op.Size) > 0x10000 {
+			break
+		}
+		block.instructions = append(block.instructions, c.compileInstruction(uint16(pc), opcode, d))
+		pc += int(d.op.Size)
+		if d.blockEnd {
+			break
+		}
+	}
+	// An empty block still covers the opcode that stopped it, so that changing the opcode discards it
+	block.end = max(pc, block.start+1)
+
+	j.blocks[start] = block
+	for page := block.start >> 8; page <= (block.end-1)>>8; page++ {
+		j.pages[page] = append(j.pages[page], block)
+	}
+	for addr := block.start; addr < block.end; addr++ {
+		j.code[addr>>3] |= 1 << (addr & 7)
+	}
+	return block
+}
+
+// compileInstruction returns a closure that executes the instruction at pc. The address of the operand is worked
+// out now for the address modes where it only depends on the instruction's own bytes, and the indexed modes only
+// add the index register at run time.
+func (c *CPU) compileInstruction(pc uint16, opcode byte, d *decodedOp) jitInstruction {
+	switch d.op.AddressMode {
+	case ModeIMP, ModeACC, ModeIMM, ModeZP0, ModeABS, ModeREL, ModeZPR:
+		savedPC := c.PC
+		c.PC = pc
+		addressInfo := c.resolve(d.op.AddressMode)
+		c.PC = savedPC
+		return func(c *CPU) {
+			c.execute(pc, opcode, d, addressInfo)
+		}
+	case ModeZPX:
+		base := c.ram.Read(pc + 1)
+		return func(c *CPU) {
+			c.execute(pc, opcode, d, AddressInfo{Address: uint16(base + c.X)})
+		}
+	case ModeZPY:
+		base := c.ram.Read(pc + 1)
+		return func(c *CPU) {
+			c.execute(pc, opcode, d, AddressInfo{Address: uint16(base + c.Y)})
+		}
+	case ModeABX:
+		base := uint16(c.ram.Read(pc+2))<<8 | uint16(c.ram.Read(pc+1))
+		return func(c *CPU) {
+			addr := base + uint16(c.X)
+			c.execute(pc, opcode, d, AddressInfo{Address: addr, PageChanged: pagesDiffer(base, addr)})
+		}
+	case ModeABY:
+		base := uint16(c.ram.Read(pc+2))<<8 | uint16(c.ram.Read(pc+1))
+		return func(c *CPU) {
+			addr := base + uint16(c.Y)
+			c.execute(pc, opcode, d, AddressInfo{Address: addr, PageChanged: pagesDiffer(base, addr)})
+		}
+	}
+	mode := d.op.AddressMode
+	return func(c *CPU) {
+		c.execute(pc, opcode, d, c.resolve(mode))
+	}
+}
+
+// written discards any blocks whose code includes addr. It is called before the CPU writes to addr.
+func (j *jit) written(addr uint16) {
+	if j.code[addr>>3]&(1<<(addr&7)) == 0 {
+		return
+	}
+	page := addr >> 8
+	for i := 0; i < len(j.pages[page]); {
+		if block := j.pages[page][i]; block.contains(addr) {
+			j.discard(block) // Removes the block from this page
+		} else {
+			i++
+		}
+	}
+}
+
+// discard removes a block from the cache.
+func (j *jit) discard(block *jitBlock) {
+	j.blocks[block.start] = nil
+	for page := block.start >> 8; page <= (block.end-1)>>8; page++ {
+		j.pages[page] = slices.DeleteFunc(j.pages[page], func(b *jitBlock) bool { return b == block })
+	}
+	if j.current == block {
+		j.invalidated = true
+	}
+}
+
+// interpretedInstructions are never compiled, as they need the interpreter's handling of the instruction boundary
+// that follows them. They are looked up when the opcodes are decoded (see decodeOpcodes), as is blockEnds.
+var interpretedInstructions = map[string]bool{"BRK": true, "JAM": true, "STP": true, "WAI": true}
+
+// blockEnds are the instructions, besides branches, that end a block because they change the flow of control.
+var blockEnds = map[string]bool{"JMP": true, "JSR": true, "RTS": true, "RTI": true}
@@ -0,0 +1,221 @@
+package processor_test
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ukdave/6502_emulator/bus"
+	"github.com/ukdave/6502_emulator/processor"
+)
+
+// Differential testing: the JIT must leave the CPU and memory in exactly the same state as the interpreter. A pair of
+// CPUs is run side by side, one with the JIT enabled, and their snapshots compared after every run.
+
+// jitPair is a CPU using the JIT and a CPU using the interpreter, each with its own copy of memory.
+type jitPair struct {
+	interpreted, jitted       *processor.CPU
+	interpretedBus, jittedBus *bus.SimpleBus
+}
+
+// newJITPair creates a pair of CPUs with the same memory contents, starting at PC.
+func newJITPair(variant processor.Variant, memory []byte, pc uint16) *jitPair {
+	p := &jitPair{interpretedBus: bus.NewSimpleBus(), jittedBus: bus.NewSimpleBus()}
+	for i, v := range memory {
+		p.interpretedBus.Write(uint16(i), v)
+		p.jittedBus.Write(uint16(i), v)
+	}
+	p.interpreted = processor.NewCPUWithVariant(p.interpretedBus, variant)
+	p.jitted = processor.NewCPUWithVariant(p.jittedBus, variant)
+	p.jitted.SetJIT(true)
+	for _, cpu := range p.cpus() {
+		cpu.PC = pc
+	}
+	return p
+}
+
+func (p *jitPair) cpus() []*processor.CPU {
+	return []*processor.CPU{p.interpreted, p.jitted}
+}
+
+// runCycles runs both CPUs for n cycles and reports whether they are still in the same state.
+func (p *jitPair) runCycles(t *testing.T, n uint64) bool {
+	p.interpreted.RunCycles(n)
+	p.jitted.RunCycles(n)
+	return p.assertSameState(t)
+}
+
+// assertSameState compares the snapshots of the two CPUs and their memory.
+func (p *jitPair) assertSameState(t *testing.T) bool {
+	t.Helper()
+	var interpreted, jitted bytes.Buffer
+	for _, s := range []struct {
+		cpu *processor.CPU
+		bus *bus.SimpleBus
+		buf *bytes.Buffer
+	}{{p.interpreted, p.interpretedBus, &interpreted}, {p.jitted, p.jittedBus, &jitted}} {
+		assert.NoError(t, s.cpu.SaveSnapshot(s.buf))
+		assert.NoError(t, s.bus.SaveSnapshot(s.buf))
+	}
+	return assert.True(t, bytes.Equal(interpreted.Bytes(), jitted.Bytes()),
+		"JIT state differs from the interpreter after %d cycles (PC $%04X, JIT PC $%04X)",
+		p.interpreted.TotalCycles, p.interpreted.PC, p.jitted.PC)
+}
+
+// runRandom fills memory from the given seed and runs both CPUs in chunks of random length, toggling the IRQ and NMI
+// lines between chunks.
+func runRandom(t *testing.T, variant processor.Variant, seed int64, cycles uint64) {
+	rng := rand.New(rand.NewSource(seed))
+	memory := make([]byte, 0x10000)
+	rng.Read(memory)
+	p := newJITPair(variant, memory, uint16(rng.Intn(0x10000)))
+	masked := rng.Intn(2) == 0
+	for _, cpu := range p.cpus() {
+		cpu.SetFlag(processor.I, masked)
+	}
+
+	for p.interpreted.TotalCycles < cycles {
+		n := uint64(rng.Intn(200) + 1)
+		irq, nmi := rng.Intn(8), rng.Intn(32)
+		for _, cpu := range p.cpus() {
+			switch irq {
+			case 0:
+				cpu.AssertIRQ(0)
+			case 1:
+				cpu.ReleaseIRQ(0)
+			}
+			if nmi == 0 {
+				cpu.SetNMI(!cpu.NMIAsserted())
+			}
+		}
+		if !p.runCycles(t, n) {
+			t.Logf("variant %s, seed %d", variant, seed)
+			return
+		}
+	}
+}
+
+// jitVariants are the variants compared by the differential tests
+var jitVariants = []processor.Variant{processor.VariantNMOS, processor.Variant2A03, processor.Variant65C02, processor.VariantR65C02}
+
+func TestJIT_MatchesInterpreter(t *testing.T) {
+	for _, variant := range jitVariants {
+		for seed := int64(1); seed <= 50; seed++ {
+			runRandom(t, variant, seed, 20000)
+		}
+	}
+}
+
+func FuzzJIT(f *testing.F) {
+	f.Add(int64(1), uint8(0))
+	f.Add(int64(2), uint8(2))
+	f.Fuzz(func(t *testing.T, seed int64, variant uint8) {
+		runRandom(t, jitVariants[int(variant)%len(jitVariants)], seed, 5000)
+	})
+}
+
+func TestJIT_BenchmarkProgram(t *testing.T) {
+	memory := make([]byte, 0x10000)
+	copy(memory[0x8000:], benchmarkProgram)
+	memory[0x9000] = 0x40 // RTI
+	memory[0xFFFE], memory[0xFFFF] = 0x00, 0x90
+	p := newJITPair(processor.VariantNMOS, memory, 0x8000)
+	for _, cpu := range p.cpus() {
+		cpu.SetFlag(processor.I, false)
+	}
+
+	p.runCycles(t, 10007)
+	for _, cpu := range p.cpus() {
+		cpu.AssertIRQ(0)
+	}
+	p.runCycles(t, 13)
+	for _, cpu := range p.cpus() {
+		cpu.ReleaseIRQ(0)
+	}
+	p.runCycles(t, 20000)
+}
+
+func TestJIT_SelfModifyingCode(t *testing.T) {
+	memory := make([]byte, 0x10000)
+	copy(memory[0x8000:], []byte{
+		0xA9, 0x01, //       LDA #$01
+		0x8D, 0x06, 0x80, // STA $8006 (the operand of the next instruction)
+		0xA2, 0x00, //       LDX #$00
+		0xEE, 0x01, 0x80, // INC $8001 (the operand of the first instruction)
+		0x4C, 0x00, 0x80, // JMP $8000
+	})
+	p := newJITPair(processor.VariantNMOS, memory, 0x8000)
+
+	// The first pass compiles the block before the STA changes it
+	p.runCycles(t, 2+4+2)
+	assert.Equal(t, uint8(0x01), p.jitted.X, "X should be loaded from the modified code")
+
+	// Each pass of the loop takes 17 cycles
+	p.runCycles(t, 17*50-8)
+	assert.Equal(t, uint8(50), p.jitted.X, "X should always be loaded from the modified code")
+}
+
+func TestJIT_StackOverwritesCode(t *testing.T) {
+	memory := make([]byte, 0x10000)
+	copy(memory[0x0100:], []byte{
+		0xA9, 0x42, // LDA #$42
+		0x48,             // PHA (overwrites the JMP with $42, a JAM)
+		0xEA,             // NOP
+		0x4C, 0x00, 0x01, // JMP $0100
+	})
+	p := newJITPair(processor.VariantNMOS, memory, 0x0100)
+	for _, cpu := range p.cpus() {
+		cpu.SP = 0x04
+	}
+
+	p.runCycles(t, 100)
+	assert.True(t, p.jitted.Halted(), "The CPU should halt on the pushed JAM")
+}
+
+func TestJIT_RunUntil(t *testing.T) {
+	memory := make([]byte, 0x10000)
+	copy(memory[0x8000:], benchmarkProgram)
+	p := newJITPair(processor.Variant2A03, memory, 0x8000)
+
+	calls := [2]int{}
+	for i, cpu := range p.cpus() {
+		cycles := cpu.RunUntil(func(c *processor.CPU) bool {
+			calls[i]++
+			return c.PC == 0x8007 && c.X == 0x10
+		}, 0)
+		assert.Equal(t, cpu.TotalCycles, cycles, "The cycles run should be returned")
+	}
+	p.assertSameState(t)
+	assert.Equal(t, calls[0], calls[1], "stop should be called once per instruction")
+	assert.Equal(t, uint16(0x8007), p.jitted.PC, "The run should stop part way through a block")
+
+	for _, cpu := range p.cpus() {
+		cpu.RunUntil(func(c *processor.CPU) bool { return false }, 1001)
+	}
+	p.assertSameState(t)
+}
+
+func TestFlushJIT(t *testing.T) {
+	b := bus.NewSimpleBus()
+	b.Write(0x8000, 0xA9) // LDA #$01
+	b.Write(0x8001, 0x01)
+	b.Write(0x8002, 0x4C) // JMP $8000
+	store16(b, 0x8003, 0x8000)
+	cpu := processor.NewCPU(b)
+	cpu.PC = 0x8000
+	cpu.SetJIT(true)
+	assert.True(t, cpu.JIT(), "The JIT should be enabled")
+	cpu.RunCycles(10)
+	assert.Equal(t, uint8(0x01), cpu.A, "A should be 0x01")
+
+	// Changing the code behind the CPU's back needs a flush
+	b.Write(0x8001, 0x05)
+	cpu.FlushJIT()
+	cpu.RunCycles(10)
+	assert.Equal(t, uint8(0x05), cpu.A, "A should be loaded by the new code")
+
+	cpu.SetJIT(false)
+	assert.False(t, cpu.JIT(), "The JIT should be disabled")
+}
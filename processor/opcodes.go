@@ -1,65 +1,92 @@
 package processor
 
-import (
-	"reflect"
-	"runtime"
-	"strings"
-)
-
+// Operation is an entry in an opcode table: the instruction an opcode executes, and how. The tables are the one
+// description of each opcode, from which the InstructionSet of each variant is built.
 type Operation struct {
-	Instruction InstructionFunc
-	AddressMode AddressModeFunc
-	Size        uint8
-	Cycles      uint8
+	Mnemonic     string // The instruction name, for example "LDA"
+	Instruction  InstructionFunc
+	AddressMode  Mode
+	Size         uint8
+	Cycles       uint8
+	Penalty      Penalty // The conditions under which the instruction takes more than Cycles
+	FlagsRead    Flag    // Status flags that affect the result
+	FlagsWritten Flag    // Status flags that may be changed
 }
 
-// operations is the lookup table for all 6502 instructions.
+// nmosOperations is the lookup table for all NMOS 6502 instructions (used by the 2A03 and NMOS variants).
 // It is 16x16 entries which gives 256 instructions. It is arranged so that the bottom
 // 4 bits of the opcode choose the column, and the top 4 bits choose the row.
 //
-// Note that "illegal" opcodes are not currently implemented and are treated as a 1-byte NOP instruction.
-var operations = [...]Operation{
-	{BRK, IMM, 1, 7}, {ORA, INDX, 2, 6}, {XXX, IMP, 1, 1}, {XXX, IMP, 1, 1}, {XXX, IMP, 1, 1}, {ORA, ZP0, 2, 3}, {ASL, ZP0, 2, 5}, {XXX, IMP, 1, 1}, {PHP, IMP, 1, 3}, {ORA, IMM, 2, 2}, {ASL, ACC, 1, 2}, {XXX, IMP, 1, 1}, {XXX, IMP, 1, 1}, {ORA, ABS, 3, 4}, {ASL, ABS, 3, 6}, {XXX, IMP, 1, 1},
-	{BPL, REL, 2, 2}, {ORA, INDY, 2, 5}, {XXX, IMP, 1, 1}, {XXX, IMP, 1, 1}, {XXX, IMP, 1, 1}, {ORA, ZPX, 2, 4}, {ASL, ZPX, 2, 6}, {XXX, IMP, 1, 1}, {CLC, IMP, 1, 2}, {ORA, ABY, 3, 4}, {XXX, IMP, 1, 1}, {XXX, IMP, 1, 1}, {XXX, IMP, 1, 1}, {ORA, ABX, 3, 4}, {ASL, ABX, 3, 7}, {XXX, IMP, 1, 1},
-	{JSR, ABS, 3, 6}, {AND, INDX, 2, 6}, {XXX, IMP, 1, 1}, {XXX, IMP, 1, 1}, {BIT, ZP0, 2, 3}, {AND, ZP0, 2, 3}, {ROL, ZP0, 2, 5}, {XXX, IMP, 1, 1}, {PLP, IMP, 1, 4}, {AND, IMM, 2, 2}, {ROL, ACC, 1, 2}, {XXX, IMP, 1, 1}, {BIT, ABS, 3, 4}, {AND, ABS, 3, 4}, {ROL, ABS, 3, 6}, {XXX, IMP, 1, 1},
-	{BMI, REL, 2, 2}, {AND, INDY, 2, 5}, {XXX, IMP, 1, 1}, {XXX, IMP, 1, 1}, {XXX, IMP, 1, 1}, {AND, ZPX, 2, 4}, {ROL, ZPX, 2, 6}, {XXX, IMP, 1, 1}, {SEC, IMP, 1, 2}, {AND, ABY, 3, 4}, {XXX, IMP, 1, 1}, {XXX, IMP, 1, 1}, {XXX, IMP, 1, 1}, {AND, ABX, 3, 4}, {ROL, ABX, 3, 7}, {XXX, IMP, 1, 1},
-	{RTI, IMP, 1, 6}, {EOR, INDX, 2, 6}, {XXX, IMP, 1, 1}, {XXX, IMP, 1, 1}, {XXX, IMP, 1, 1}, {EOR, ZP0, 2, 3}, {LSR, ZP0, 2, 5}, {XXX, IMP, 1, 1}, {PHA, IMP, 1, 3}, {EOR, IMM, 2, 2}, {LSR, ACC, 1, 2}, {XXX, IMP, 1, 1}, {JMP, ABS, 3, 3}, {EOR, ABS, 3, 4}, {LSR, ABS, 3, 6}, {XXX, IMP, 1, 1},
-	{BVC, REL, 2, 2}, {EOR, INDY, 2, 5}, {XXX, IMP, 1, 1}, {XXX, IMP, 1, 1}, {XXX, IMP, 1, 1}, {EOR, ZPX, 2, 4}, {LSR, ZPX, 2, 6}, {XXX, IMP, 1, 1}, {CLI, IMP, 1, 2}, {EOR, ABY, 3, 4}, {XXX, IMP, 1, 1}, {XXX, IMP, 1, 1}, {XXX, IMP, 1, 1}, {EOR, ABX, 3, 4}, {LSR, ABX, 3, 7}, {XXX, IMP, 1, 1},
-	{RTS, IMP, 1, 6}, {ADC, INDX, 2, 6}, {XXX, IMP, 1, 1}, {XXX, IMP, 1, 1}, {XXX, IMP, 1, 1}, {ADC, ZP0, 2, 3}, {ROR, ZP0, 2, 5}, {XXX, IMP, 1, 1}, {PLA, IMP, 1, 4}, {ADC, IMM, 2, 2}, {ROR, ACC, 1, 2}, {XXX, IMP, 1, 1}, {JMP, IND, 3, 5}, {ADC, ABS, 3, 4}, {ROR, ABS, 3, 6}, {XXX, IMP, 1, 1},
-	{BVS, REL, 2, 2}, {ADC, INDY, 2, 5}, {XXX, IMP, 1, 1}, {XXX, IMP, 1, 1}, {XXX, IMP, 1, 1}, {ADC, ZPX, 2, 4}, {ROR, ZPX, 2, 6}, {XXX, IMP, 1, 1}, {SEI, IMP, 1, 2}, {ADC, ABY, 3, 4}, {XXX, IMP, 1, 1}, {XXX, IMP, 1, 1}, {XXX, IMP, 1, 1}, {ADC, ABX, 3, 4}, {ROR, ABX, 3, 7}, {XXX, IMP, 1, 1},
-	{XXX, IMP, 1, 1}, {STA, INDX, 2, 6}, {XXX, IMP, 1, 1}, {XXX, IMP, 1, 1}, {STY, ZP0, 2, 3}, {STA, ZP0, 2, 3}, {STX, ZP0, 2, 3}, {XXX, IMP, 1, 1}, {DEY, IMP, 1, 2}, {XXX, IMP, 1, 1}, {TXA, IMP, 1, 2}, {XXX, IMP, 1, 1}, {STY, ABS, 3, 4}, {STA, ABS, 3, 4}, {STX, ABS, 3, 4}, {XXX, IMP, 1, 1},
-	{BCC, REL, 2, 2}, {STA, INDY, 2, 6}, {XXX, IMP, 1, 1}, {XXX, IMP, 1, 1}, {STY, ZPX, 2, 4}, {STA, ZPX, 2, 4}, {STX, ZPY, 2, 4}, {XXX, IMP, 1, 1}, {TYA, IMP, 1, 2}, {STA, ABY, 3, 5}, {TXS, IMP, 1, 2}, {XXX, IMP, 1, 1}, {XXX, IMP, 1, 1}, {STA, ABX, 3, 5}, {XXX, IMP, 1, 1}, {XXX, IMP, 1, 1},
-	{LDY, IMM, 2, 2}, {LDA, INDX, 2, 6}, {LDX, IMM, 2, 2}, {XXX, IMP, 1, 1}, {LDY, ZP0, 2, 3}, {LDA, ZP0, 2, 3}, {LDX, ZP0, 2, 3}, {XXX, IMP, 1, 1}, {TAY, IMP, 1, 2}, {LDA, IMM, 2, 2}, {TAX, IMP, 1, 2}, {XXX, IMP, 1, 1}, {LDY, ABS, 3, 4}, {LDA, ABS, 3, 4}, {LDX, ABS, 3, 4}, {XXX, IMP, 1, 1},
-	{BCS, REL, 2, 2}, {LDA, INDY, 2, 5}, {XXX, IMP, 1, 1}, {XXX, IMP, 1, 1}, {LDY, ZPX, 2, 4}, {LDA, ZPX, 2, 4}, {LDX, ZPY, 2, 4}, {XXX, IMP, 1, 1}, {CLV, IMP, 1, 2}, {LDA, ABY, 3, 4}, {TSX, IMP, 1, 2}, {XXX, IMP, 1, 1}, {LDY, ABX, 3, 4}, {LDA, ABX, 3, 4}, {LDX, ABY, 3, 4}, {XXX, IMP, 1, 1},
-	{CPY, IMM, 2, 2}, {CMP, INDX, 2, 6}, {XXX, IMP, 1, 1}, {XXX, IMP, 1, 1}, {CPY, ZP0, 2, 3}, {CMP, ZP0, 2, 3}, {DEC, ZP0, 2, 5}, {XXX, IMP, 1, 1}, {INY, IMP, 1, 2}, {CMP, IMM, 2, 2}, {DEX, IMP, 1, 2}, {XXX, IMP, 1, 1}, {CPY, ABS, 3, 4}, {CMP, ABS, 3, 4}, {DEC, ABS, 3, 6}, {XXX, IMP, 1, 1},
-	{BNE, REL, 2, 2}, {CMP, INDY, 2, 5}, {XXX, IMP, 1, 1}, {XXX, IMP, 1, 1}, {XXX, IMP, 1, 1}, {CMP, ZPX, 2, 4}, {DEC, ZPX, 2, 6}, {XXX, IMP, 1, 1}, {CLD, IMP, 1, 2}, {CMP, ABY, 3, 4}, {XXX, IMP, 1, 1}, {XXX, IMP, 1, 1}, {XXX, IMP, 1, 1}, {CMP, ABX, 3, 4}, {DEC, ABX, 3, 7}, {XXX, IMP, 1, 1},
-	{CPX, IMM, 2, 2}, {SBC, INDX, 2, 6}, {XXX, IMP, 1, 1}, {XXX, IMP, 1, 1}, {CPX, ZP0, 2, 3}, {SBC, ZP0, 2, 3}, {INC, ZP0, 2, 5}, {XXX, IMP, 1, 1}, {INX, IMP, 1, 2}, {SBC, IMM, 2, 2}, {NOP, IMP, 1, 2}, {XXX, IMP, 1, 1}, {CPX, ABS, 3, 4}, {SBC, ABS, 3, 4}, {INC, ABS, 3, 6}, {XXX, IMP, 1, 1},
-	{BEQ, REL, 2, 2}, {SBC, INDY, 2, 5}, {XXX, IMP, 1, 1}, {XXX, IMP, 1, 1}, {XXX, IMP, 1, 1}, {SBC, ZPX, 2, 4}, {INC, ZPX, 2, 6}, {XXX, IMP, 1, 1}, {SED, IMP, 1, 2}, {SBC, ABY, 3, 4}, {XXX, IMP, 1, 1}, {XXX, IMP, 1, 1}, {XXX, IMP, 1, 1}, {SBC, ABX, 3, 4}, {INC, ABX, 3, 7}, {XXX, IMP, 1, 1},
+// The 105 opcodes that MOS never documented (often called "illegal" opcodes) are included. Most of them combine
+// two documented instructions in a single opcode, a few are unstable on real hardware, and twelve of them (JAM)
+// lock up the processor until it is reset.
+var nmosOperations = [256]Operation{
+	{"BRK", BRK, ModeIMM, 2, 7, 0, allFlags, I}, {"ORA", ORA, ModeINDX, 2, 6, 0, 0, N | Z}, {"JAM", JAM, ModeIMP, 1, 2, 0, 0, 0}, {"SLO", SLO, ModeINDX, 2, 8, 0, 0, N | Z | C}, {"NOP", NOP, ModeZP0, 2, 3, 0, 0, 0}, {"ORA", ORA, ModeZP0, 2, 3, 0, 0, N | Z}, {"ASL", ASL, ModeZP0, 2, 5, 0, 0, N | Z | C}, {"SLO", SLO, ModeZP0, 2, 5, 0, 0, N | Z | C}, {"PHP", PHP, ModeIMP, 1, 3, 0, allFlags, 0}, {"ORA", ORA, ModeIMM, 2, 2, 0, 0, N | Z}, {"ASL", ASL, ModeACC, 1, 2, 0, 0, N | Z | C}, {"ANC", ANC, ModeIMM, 2, 2, 0, 0, N | Z | C}, {"NOP", NOP, ModeABS, 3, 4, 0, 0, 0}, {"ORA", ORA, ModeABS, 3, 4, 0, 0, N | Z}, {"ASL", ASL, ModeABS, 3, 6, 0, 0, N | Z | C}, {"SLO", SLO, ModeABS, 3, 6, 0, 0, N | Z | C},
+	{"BPL", BPL, ModeREL, 2, 2, PenaltyBranch, N, 0}, {"ORA", ORA, ModeINDY, 2, 5, PenaltyPageCross, 0, N | Z}, {"JAM", JAM, ModeIMP, 1, 2, 0, 0, 0}, {"SLO", SLO, ModeINDY, 2, 8, 0, 0, N | Z | C}, {"NOP", NOP, ModeZPX, 2, 4, 0, 0, 0}, {"ORA", ORA, ModeZPX, 2, 4, 0, 0, N | Z}, {"ASL", ASL, ModeZPX, 2, 6, 0, 0, N | Z | C}, {"SLO", SLO, ModeZPX, 2, 6, 0, 0, N | Z | C}, {"CLC", CLC, ModeIMP, 1, 2, 0, 0, C}, {"ORA", ORA, ModeABY, 3, 4, PenaltyPageCross, 0, N | Z}, {"NOP", NOP, ModeIMP, 1, 2, 0, 0, 0}, {"SLO", SLO, ModeABY, 3, 7, 0, 0, N | Z | C}, {"NOP", NOP, ModeABX, 3, 4, PenaltyPageCross, 0, 0}, {"ORA", ORA, ModeABX, 3, 4, PenaltyPageCross, 0, N | Z}, {"ASL", ASL, ModeABX, 3, 7, 0, 0, N | Z | C}, {"SLO", SLO, ModeABX, 3, 7, 0, 0, N | Z | C},
+	{"JSR", JSR, ModeABS, 3, 6, 0, 0, 0}, {"AND", AND, ModeINDX, 2, 6, 0, 0, N | Z}, {"JAM", JAM, ModeIMP, 1, 2, 0, 0, 0}, {"RLA", RLA, ModeINDX, 2, 8, 0, C, N | Z | C}, {"BIT", BIT, ModeZP0, 2, 3, 0, 0, N | V | Z}, {"AND", AND, ModeZP0, 2, 3, 0, 0, N | Z}, {"ROL", ROL, ModeZP0, 2, 5, 0, C, N | Z | C}, {"RLA", RLA, ModeZP0, 2, 5, 0, C, N | Z | C}, {"PLP", PLP, ModeIMP, 1, 4, 0, 0, allFlags}, {"AND", AND, ModeIMM, 2, 2, 0, 0, N | Z}, {"ROL", ROL, ModeACC, 1, 2, 0, C, N | Z | C}, {"ANC", ANC, ModeIMM, 2, 2, 0, 0, N | Z | C}, {"BIT", BIT, ModeABS, 3, 4, 0, 0, N | V | Z}, {"AND", AND, ModeABS, 3, 4, 0, 0, N | Z}, {"ROL", ROL, ModeABS, 3, 6, 0, C, N | Z | C}, {"RLA", RLA, ModeABS, 3, 6, 0, C, N | Z | C},
+	{"BMI", BMI, ModeREL, 2, 2, PenaltyBranch, N, 0}, {"AND", AND, ModeINDY, 2, 5, PenaltyPageCross, 0, N | Z}, {"JAM", JAM, ModeIMP, 1, 2, 0, 0, 0}, {"RLA", RLA, ModeINDY, 2, 8, 0, C, N | Z | C}, {"NOP", NOP, ModeZPX, 2, 4, 0, 0, 0}, {"AND", AND, ModeZPX, 2, 4, 0, 0, N | Z}, {"ROL", ROL, ModeZPX, 2, 6, 0, C, N | Z | C}, {"RLA", RLA, ModeZPX, 2, 6, 0, C, N | Z | C}, {"SEC", SEC, ModeIMP, 1, 2, 0, 0, C}, {"AND", AND, ModeABY, 3, 4, PenaltyPageCross, 0, N | Z}, {"NOP", NOP, ModeIMP, 1, 2, 0, 0, 0}, {"RLA", RLA, ModeABY, 3, 7, 0, C, N | Z | C}, {"NOP", NOP, ModeABX, 3, 4, PenaltyPageCross, 0, 0}, {"AND", AND, ModeABX, 3, 4, PenaltyPageCross, 0, N | Z}, {"ROL", ROL, ModeABX, 3, 7, 0, C, N | Z | C}, {"RLA", RLA, ModeABX, 3, 7, 0, C, N | Z | C},
+	{"RTI", RTI, ModeIMP, 1, 6, 0, 0, allFlags}, {"EOR", EOR, ModeINDX, 2, 6, 0, 0, N | Z}, {"JAM", JAM, ModeIMP, 1, 2, 0, 0, 0}, {"SRE", SRE, ModeINDX, 2, 8, 0, 0, N | Z | C}, {"NOP", NOP, ModeZP0, 2, 3, 0, 0, 0}, {"EOR", EOR, ModeZP0, 2, 3, 0, 0, N | Z}, {"LSR", LSR, ModeZP0, 2, 5, 0, 0, N | Z | C}, {"SRE", SRE, ModeZP0, 2, 5, 0, 0, N | Z | C}, {"PHA", PHA, ModeIMP, 1, 3, 0, 0, 0}, {"EOR", EOR, ModeIMM, 2, 2, 0, 0, N | Z}, {"LSR", LSR, ModeACC, 1, 2, 0, 0, N | Z | C}, {"ALR", ALR, ModeIMM, 2, 2, 0, 0, N | Z | C}, {"JMP", JMP, ModeABS, 3, 3, 0, 0, 0}, {"EOR", EOR, ModeABS, 3, 4, 0, 0, N | Z}, {"LSR", LSR, ModeABS, 3, 6, 0, 0, N | Z | C}, {"SRE", SRE, ModeABS, 3, 6, 0, 0, N | Z | C},
+	{"BVC", BVC, ModeREL, 2, 2, PenaltyBranch, V, 0}, {"EOR", EOR, ModeINDY, 2, 5, PenaltyPageCross, 0, N | Z}, {"JAM", JAM, ModeIMP, 1, 2, 0, 0, 0}, {"SRE", SRE, ModeINDY, 2, 8, 0, 0, N | Z | C}, {"NOP", NOP, ModeZPX, 2, 4, 0, 0, 0}, {"EOR", EOR, ModeZPX, 2, 4, 0, 0, N | Z}, {"LSR", LSR, ModeZPX, 2, 6, 0, 0, N | Z | C}, {"SRE", SRE, ModeZPX, 2, 6, 0, 0, N | Z | C}, {"CLI", CLI, ModeIMP, 1, 2, 0, 0, I}, {"EOR", EOR, ModeABY, 3, 4, PenaltyPageCross, 0, N | Z}, {"NOP", NOP, ModeIMP, 1, 2, 0, 0, 0}, {"SRE", SRE, ModeABY, 3, 7, 0, 0, N | Z | C}, {"NOP", NOP, ModeABX, 3, 4, PenaltyPageCross, 0, 0}, {"EOR", EOR, ModeABX, 3, 4, PenaltyPageCross, 0, N | Z}, {"LSR", LSR, ModeABX, 3, 7, 0, 0, N | Z | C}, {"SRE", SRE, ModeABX, 3, 7, 0, 0, N | Z | C},
+	{"RTS", RTS, ModeIMP, 1, 6, 0, 0, 0}, {"ADC", ADC, ModeINDX, 2, 6, 0, D | C, N | V | Z | C}, {"JAM", JAM, ModeIMP, 1, 2, 0, 0, 0}, {"RRA", RRA, ModeINDX, 2, 8, 0, D | C, N | V | Z | C}, {"NOP", NOP, ModeZP0, 2, 3, 0, 0, 0}, {"ADC", ADC, ModeZP0, 2, 3, 0, D | C, N | V | Z | C}, {"ROR", ROR, ModeZP0, 2, 5, 0, C, N | Z | C}, {"RRA", RRA, ModeZP0, 2, 5, 0, D | C, N | V | Z | C}, {"PLA", PLA, ModeIMP, 1, 4, 0, 0, N | Z}, {"ADC", ADC, ModeIMM, 2, 2, 0, D | C, N | V | Z | C}, {"ROR", ROR, ModeACC, 1, 2, 0, C, N | Z | C}, {"ARR", ARR, ModeIMM, 2, 2, 0, D | C, N | V | Z | C}, {"JMP", JMP, ModeIND, 3, 5, 0, 0, 0}, {"ADC", ADC, ModeABS, 3, 4, 0, D | C, N | V | Z | C}, {"ROR", ROR, ModeABS, 3, 6, 0, C, N | Z | C}, {"RRA", RRA, ModeABS, 3, 6, 0, D | C, N | V | Z | C},
+	{"BVS", BVS, ModeREL, 2, 2, PenaltyBranch, V, 0}, {"ADC", ADC, ModeINDY, 2, 5, PenaltyPageCross, D | C, N | V | Z | C}, {"JAM", JAM, ModeIMP, 1, 2, 0, 0, 0}, {"RRA", RRA, ModeINDY, 2, 8, 0, D | C, N | V | Z | C}, {"NOP", NOP, ModeZPX, 2, 4, 0, 0, 0}, {"ADC", ADC, ModeZPX, 2, 4, 0, D | C, N | V | Z | C}, {"ROR", ROR, ModeZPX, 2, 6, 0, C, N | Z | C}, {"RRA", RRA, ModeZPX, 2, 6, 0, D | C, N | V | Z | C}, {"SEI", SEI, ModeIMP, 1, 2, 0, 0, I}, {"ADC", ADC, ModeABY, 3, 4, PenaltyPageCross, D | C, N | V | Z | C}, {"NOP", NOP, ModeIMP, 1, 2, 0, 0, 0}, {"RRA", RRA, ModeABY, 3, 7, 0, D | C, N | V | Z | C}, {"NOP", NOP, ModeABX, 3, 4, PenaltyPageCross, 0, 0}, {"ADC", ADC, ModeABX, 3, 4, PenaltyPageCross, D | C, N | V | Z | C}, {"ROR", ROR, ModeABX, 3, 7, 0, C, N | Z | C}, {"RRA", RRA, ModeABX, 3, 7, 0, D | C, N | V | Z | C},
+	{"NOP", NOP, ModeIMM, 2, 2, 0, 0, 0}, {"STA", STA, ModeINDX, 2, 6, 0, 0, 0}, {"NOP", NOP, ModeIMM, 2, 2, 0, 0, 0}, {"SAX", SAX, ModeINDX, 2, 6, 0, 0, 0}, {"STY", STY, ModeZP0, 2, 3, 0, 0, 0}, {"STA", STA, ModeZP0, 2, 3, 0, 0, 0}, {"STX", STX, ModeZP0, 2, 3, 0, 0, 0}, {"SAX", SAX, ModeZP0, 2, 3, 0, 0, 0}, {"DEY", DEY, ModeIMP, 1, 2, 0, 0, N | Z}, {"NOP", NOP, ModeIMM, 2, 2, 0, 0, 0}, {"TXA", TXA, ModeIMP, 1, 2, 0, 0, N | Z}, {"XAA", XAA, ModeIMM, 2, 2, 0, 0, N | Z}, {"STY", STY, ModeABS, 3, 4, 0, 0, 0}, {"STA", STA, ModeABS, 3, 4, 0, 0, 0}, {"STX", STX, ModeABS, 3, 4, 0, 0, 0}, {"SAX", SAX, ModeABS, 3, 4, 0, 0, 0},
+	{"BCC", BCC, ModeREL, 2, 2, PenaltyBranch, C, 0}, {"STA", STA, ModeINDY, 2, 6, 0, 0, 0}, {"JAM", JAM, ModeIMP, 1, 2, 0, 0, 0}, {"AHX", AHX, ModeINDY, 2, 6, 0, 0, 0}, {"STY", STY, ModeZPX, 2, 4, 0, 0, 0}, {"STA", STA, ModeZPX, 2, 4, 0, 0, 0}, {"STX", STX, ModeZPY, 2, 4, 0, 0, 0}, {"SAX", SAX, ModeZPY, 2, 4, 0, 0, 0}, {"TYA", TYA, ModeIMP, 1, 2, 0, 0, N | Z}, {"STA", STA, ModeABY, 3, 5, 0, 0, 0}, {"TXS", TXS, ModeIMP, 1, 2, 0, 0, 0}, {"TAS", TAS, ModeABY, 3, 5, 0, 0, 0}, {"SHY", SHY, ModeABX, 3, 5, 0, 0, 0}, {"STA", STA, ModeABX, 3, 5, 0, 0, 0}, {"SHX", SHX, ModeABY, 3, 5, 0, 0, 0}, {"AHX", AHX, ModeABY, 3, 5, 0, 0, 0},
+	{"LDY", LDY, ModeIMM, 2, 2, 0, 0, N | Z}, {"LDA", LDA, ModeINDX, 2, 6, 0, 0, N | Z}, {"LDX", LDX, ModeIMM, 2, 2, 0, 0, N | Z}, {"LAX", LAX, ModeINDX, 2, 6, 0, 0, N | Z}, {"LDY", LDY, ModeZP0, 2, 3, 0, 0, N | Z}, {"LDA", LDA, ModeZP0, 2, 3, 0, 0, N | Z}, {"LDX", LDX, ModeZP0, 2, 3, 0, 0, N | Z}, {"LAX", LAX, ModeZP0, 2, 3, 0, 0, N | Z}, {"TAY", TAY, ModeIMP, 1, 2, 0, 0, N | Z}, {"LDA", LDA, ModeIMM, 2, 2, 0, 0, N | Z}, {"TAX", TAX, ModeIMP, 1, 2, 0, 0, N | Z}, {"LXA", LXA, ModeIMM, 2, 2, 0, 0, N | Z}, {"LDY", LDY, ModeABS, 3, 4, 0, 0, N | Z}, {"LDA", LDA, ModeABS, 3, 4, 0, 0, N | Z}, {"LDX", LDX, ModeABS, 3, 4, 0, 0, N | Z}, {"LAX", LAX, ModeABS, 3, 4, 0, 0, N | Z},
+	{"BCS", BCS, ModeREL, 2, 2, PenaltyBranch, C, 0}, {"LDA", LDA, ModeINDY, 2, 5, PenaltyPageCross, 0, N | Z}, {"JAM", JAM, ModeIMP, 1, 2, 0, 0, 0}, {"LAX", LAX, ModeINDY, 2, 5, PenaltyPageCross, 0, N | Z}, {"LDY", LDY, ModeZPX, 2, 4, 0, 0, N | Z}, {"LDA", LDA, ModeZPX, 2, 4, 0, 0, N | Z}, {"LDX", LDX, ModeZPY, 2, 4, 0, 0, N | Z}, {"LAX", LAX, ModeZPY, 2, 4, 0, 0, N | Z}, {"CLV", CLV, ModeIMP, 1, 2, 0, 0, V}, {"LDA", LDA, ModeABY, 3, 4, PenaltyPageCross, 0, N | Z}, {"TSX", TSX, ModeIMP, 1, 2, 0, 0, N | Z}, {"LAS", LAS, ModeABY, 3, 4, PenaltyPageCross, 0, N | Z}, {"LDY", LDY, ModeABX, 3, 4, PenaltyPageCross, 0, N | Z}, {"LDA", LDA, ModeABX, 3, 4, PenaltyPageCross, 0, N | Z}, {"LDX", LDX, ModeABY, 3, 4, PenaltyPageCross, 0, N | Z}, {"LAX", LAX, ModeABY, 3, 4, PenaltyPageCross, 0, N | Z},
+	{"CPY", CPY, ModeIMM, 2, 2, 0, 0, N | Z | C}, {"CMP", CMP, ModeINDX, 2, 6, 0, 0, N | Z | C}, {"NOP", NOP, ModeIMM, 2, 2, 0, 0, 0}, {"DCP", DCP, ModeINDX, 2, 8, 0, 0, N | Z | C}, {"CPY", CPY, ModeZP0, 2, 3, 0, 0, N | Z | C}, {"CMP", CMP, ModeZP0, 2, 3, 0, 0, N | Z | C}, {"DEC", DEC, ModeZP0, 2, 5, 0, 0, N | Z}, {"DCP", DCP, ModeZP0, 2, 5, 0, 0, N | Z | C}, {"INY", INY, ModeIMP, 1, 2, 0, 0, N | Z}, {"CMP", CMP, ModeIMM, 2, 2, 0, 0, N | Z | C}, {"DEX", DEX, ModeIMP, 1, 2, 0, 0, N | Z}, {"SBX", SBX, ModeIMM, 2, 2, 0, 0, N | Z | C}, {"CPY", CPY, ModeABS, 3, 4, 0, 0, N | Z | C}, {"CMP", CMP, ModeABS, 3, 4, 0, 0, N | Z | C}, {"DEC", DEC, ModeABS, 3, 6, 0, 0, N | Z}, {"DCP", DCP, ModeABS, 3, 6, 0, 0, N | Z | C},
+	{"BNE", BNE, ModeREL, 2, 2, PenaltyBranch, Z, 0}, {"CMP", CMP, ModeINDY, 2, 5, PenaltyPageCross, 0, N | Z | C}, {"JAM", JAM, ModeIMP, 1, 2, 0, 0, 0}, {"DCP", DCP, ModeINDY, 2, 8, 0, 0, N | Z | C}, {"NOP", NOP, ModeZPX, 2, 4, 0, 0, 0}, {"CMP", CMP, ModeZPX, 2, 4, 0, 0, N | Z | C}, {"DEC", DEC, ModeZPX, 2, 6, 0, 0, N | Z}, {"DCP", DCP, ModeZPX, 2, 6, 0, 0, N | Z | C}, {"CLD", CLD, ModeIMP, 1, 2, 0, 0, D}, {"CMP", CMP, ModeABY, 3, 4, PenaltyPageCross, 0, N | Z | C}, {"NOP", NOP, ModeIMP, 1, 2, 0, 0, 0}, {"DCP", DCP, ModeABY, 3, 7, 0, 0, N | Z | C}, {"NOP", NOP, ModeABX, 3, 4, PenaltyPageCross, 0, 0}, {"CMP", CMP, ModeABX, 3, 4, PenaltyPageCross, 0, N | Z | C}, {"DEC", DEC, ModeABX, 3, 7, 0, 0, N | Z}, {"DCP", DCP, ModeABX, 3, 7, 0, 0, N | Z | C},
+	{"CPX", CPX, ModeIMM, 2, 2, 0, 0, N | Z | C}, {"SBC", SBC, ModeINDX, 2, 6, 0, D | C, N | V | Z | C}, {"NOP", NOP, ModeIMM, 2, 2, 0, 0, 0}, {"ISC", ISC, ModeINDX, 2, 8, 0, D | C, N | V | Z | C}, {"CPX", CPX, ModeZP0, 2, 3, 0, 0, N | Z | C}, {"SBC", SBC, ModeZP0, 2, 3, 0, D | C, N | V | Z | C}, {"INC", INC, ModeZP0, 2, 5, 0, 0, N | Z}, {"ISC", ISC, ModeZP0, 2, 5, 0, D | C, N | V | Z | C}, {"INX", INX, ModeIMP, 1, 2, 0, 0, N | Z}, {"SBC", SBC, ModeIMM, 2, 2, 0, D | C, N | V | Z | C}, {"NOP", NOP, ModeIMP, 1, 2, 0, 0, 0}, {"SBC", SBC, ModeIMM, 2, 2, 0, D | C, N | V | Z | C}, {"CPX", CPX, ModeABS, 3, 4, 0, 0, N | Z | C}, {"SBC", SBC, ModeABS, 3, 4, 0, D | C, N | V | Z | C}, {"INC", INC, ModeABS, 3, 6, 0, 0, N | Z}, {"ISC", ISC, ModeABS, 3, 6, 0, D | C, N | V | Z | C},
+	{"BEQ", BEQ, ModeREL, 2, 2, PenaltyBranch, Z, 0}, {"SBC", SBC, ModeINDY, 2, 5, PenaltyPageCross, D | C, N | V | Z | C}, {"JAM", JAM, ModeIMP, 1, 2, 0, 0, 0}, {"ISC", ISC, ModeINDY, 2, 8, 0, D | C, N | V | Z | C}, {"NOP", NOP, ModeZPX, 2, 4, 0, 0, 0}, {"SBC", SBC, ModeZPX, 2, 4, 0, D | C, N | V | Z | C}, {"INC", INC, ModeZPX, 2, 6, 0, 0, N | Z}, {"ISC", ISC, ModeZPX, 2, 6, 0, D | C, N | V | Z | C}, {"SED", SED, ModeIMP, 1, 2, 0, 0, D}, {"SBC", SBC, ModeABY, 3, 4, PenaltyPageCross, D | C, N | V | Z | C}, {"NOP", NOP, ModeIMP, 1, 2, 0, 0, 0}, {"ISC", ISC, ModeABY, 3, 7, 0, D | C, N | V | Z | C}, {"NOP", NOP, ModeABX, 3, 4, PenaltyPageCross, 0, 0}, {"SBC", SBC, ModeABX, 3, 4, PenaltyPageCross, D | C, N | V | Z | C}, {"INC", INC, ModeABX, 3, 7, 0, 0, N | Z}, {"ISC", ISC, ModeABX, 3, 7, 0, D | C, N | V | Z | C},
 }
 
-// getFunctionName extracts the short name from a function pointer
-func getFunctionName(fn interface{}) string {
-	name := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name()
-	// Extract just the function name (e.g., "cpu.LDA" -> "LDA")
-	parts := strings.Split(name, ".")
-	return parts[len(parts)-1]
+// wdc65c02Operations is the lookup table for the WDC W65C02S. It is laid out in the same way as nmosOperations.
+//
+// The CMOS parts add a number of new instructions and addressing modes, and fix the timing of a few existing ones.
+// Every opcode that is not used by an instruction is a NOP of a well defined size and cycle count.
+var wdc65c02Operations = [256]Operation{
+	{"BRK", BRK, ModeIMM, 2, 7, 0, allFlags, D | I}, {"ORA", ORA, ModeINDX, 2, 6, 0, 0, N | Z}, {"NOP", NOP, ModeIMM, 2, 2, 0, 0, 0}, {"NOP", NOP, ModeIMP, 1, 1, 0, 0, 0}, {"TSB", TSB, ModeZP0, 2, 5, 0, 0, Z}, {"ORA", ORA, ModeZP0, 2, 3, 0, 0, N | Z}, {"ASL", ASL, ModeZP0, 2, 5, 0, 0, N | Z | C}, {"RMB0", RMB0, ModeZP0, 2, 5, 0, 0, 0}, {"PHP", PHP, ModeIMP, 1, 3, 0, allFlags, 0}, {"ORA", ORA, ModeIMM, 2, 2, 0, 0, N | Z}, {"ASL", ASL, ModeACC, 1, 2, 0, 0, N | Z | C}, {"NOP", NOP, ModeIMP, 1, 1, 0, 0, 0}, {"TSB", TSB, ModeABS, 3, 6, 0, 0, Z}, {"ORA", ORA, ModeABS, 3, 4, 0, 0, N | Z}, {"ASL", ASL, ModeABS, 3, 6, 0, 0, N | Z | C}, {"BBR0", BBR0, ModeZPR, 3, 5, PenaltyBranch, 0, 0},
+	{"BPL", BPL, ModeREL, 2, 2, PenaltyBranch, N, 0}, {"ORA", ORA, ModeINDY, 2, 5, PenaltyPageCross, 0, N | Z}, {"ORA", ORA, ModeZPI, 2, 5, 0, 0, N | Z}, {"NOP", NOP, ModeIMP, 1, 1, 0, 0, 0}, {"TRB", TRB, ModeZP0, 2, 5, 0, 0, Z}, {"ORA", ORA, ModeZPX, 2, 4, 0, 0, N | Z}, {"ASL", ASL, ModeZPX, 2, 6, 0, 0, N | Z | C}, {"RMB1", RMB1, ModeZP0, 2, 5, 0, 0, 0}, {"CLC", CLC, ModeIMP, 1, 2, 0, 0, C}, {"ORA", ORA, ModeABY, 3, 4, PenaltyPageCross, 0, N | Z}, {"INC", INC, ModeACC, 1, 2, 0, 0, N | Z}, {"NOP", NOP, ModeIMP, 1, 1, 0, 0, 0}, {"TRB", TRB, ModeABS, 3, 6, 0, 0, Z}, {"ORA", ORA, ModeABX, 3, 4, PenaltyPageCross, 0, N | Z}, {"ASL", ASL, ModeABX, 3, 6, PenaltyPageCross, 0, N | Z | C}, {"BBR1", BBR1, ModeZPR, 3, 5, PenaltyBranch, 0, 0},
+	{"JSR", JSR, ModeABS, 3, 6, 0, 0, 0}, {"AND", AND, ModeINDX, 2, 6, 0, 0, N | Z}, {"NOP", NOP, ModeIMM, 2, 2, 0, 0, 0}, {"NOP", NOP, ModeIMP, 1, 1, 0, 0, 0}, {"BIT", BIT, ModeZP0, 2, 3, 0, 0, N | V | Z}, {"AND", AND, ModeZP0, 2, 3, 0, 0, N | Z}, {"ROL", ROL, ModeZP0, 2, 5, 0, C, N | Z | C}, {"RMB2", RMB2, ModeZP0, 2, 5, 0, 0, 0}, {"PLP", PLP, ModeIMP, 1, 4, 0, 0, allFlags}, {"AND", AND, ModeIMM, 2, 2, 0, 0, N | Z}, {"ROL", ROL, ModeACC, 1, 2, 0, C, N | Z | C}, {"NOP", NOP, ModeIMP, 1, 1, 0, 0, 0}, {"BIT", BIT, ModeABS, 3, 4, 0, 0, N | V | Z}, {"AND", AND, ModeABS, 3, 4, 0, 0, N | Z}, {"ROL", ROL, ModeABS, 3, 6, 0, C, N | Z | C}, {"BBR2", BBR2, ModeZPR, 3, 5, PenaltyBranch, 0, 0},
+	{"BMI", BMI, ModeREL, 2, 2, PenaltyBranch, N, 0}, {"AND", AND, ModeINDY, 2, 5, PenaltyPageCross, 0, N | Z}, {"AND", AND, ModeZPI, 2, 5, 0, 0, N | Z}, {"NOP", NOP, ModeIMP, 1, 1, 0, 0, 0}, {"BIT", BIT, ModeZPX, 2, 4, 0, 0, N | V | Z}, {"AND", AND, ModeZPX, 2, 4, 0, 0, N | Z}, {"ROL", ROL, ModeZPX, 2, 6, 0, C, N | Z | C}, {"RMB3", RMB3, ModeZP0, 2, 5, 0, 0, 0}, {"SEC", SEC, ModeIMP, 1, 2, 0, 0, C}, {"AND", AND, ModeABY, 3, 4, PenaltyPageCross, 0, N | Z}, {"DEC", DEC, ModeACC, 1, 2, 0, 0, N | Z}, {"NOP", NOP, ModeIMP, 1, 1, 0, 0, 0}, {"BIT", BIT, ModeABX, 3, 4, PenaltyPageCross, 0, N | V | Z}, {"AND", AND, ModeABX, 3, 4, PenaltyPageCross, 0, N | Z}, {"ROL", ROL, ModeABX, 3, 6, PenaltyPageCross, C, N | Z | C}, {"BBR3", BBR3, ModeZPR, 3, 5, PenaltyBranch, 0, 0},
+	{"RTI", RTI, ModeIMP, 1, 6, 0, 0, allFlags}, {"EOR", EOR, ModeINDX, 2, 6, 0, 0, N | Z}, {"NOP", NOP, ModeIMM, 2, 2, 0, 0, 0}, {"NOP", NOP, ModeIMP, 1, 1, 0, 0, 0}, {"NOP", NOP, ModeZP0, 2, 3, 0, 0, 0}, {"EOR", EOR, ModeZP0, 2, 3, 0, 0, N | Z}, {"LSR", LSR, ModeZP0, 2, 5, 0, 0, N | Z | C}, {"RMB4", RMB4, ModeZP0, 2, 5, 0, 0, 0}, {"PHA", PHA, ModeIMP, 1, 3, 0, 0, 0}, {"EOR", EOR, ModeIMM, 2, 2, 0, 0, N | Z}, {"LSR", LSR, ModeACC, 1, 2, 0, 0, N | Z | C}, {"NOP", NOP, ModeIMP, 1, 1, 0, 0, 0}, {"JMP", JMP, ModeABS, 3, 3, 0, 0, 0}, {"EOR", EOR, ModeABS, 3, 4, 0, 0, N | Z}, {"LSR", LSR, ModeABS, 3, 6, 0, 0, N | Z | C}, {"BBR4", BBR4, ModeZPR, 3, 5, PenaltyBranch, 0, 0},
+	{"BVC", BVC, ModeREL, 2, 2, PenaltyBranch, V, 0}, {"EOR", EOR, ModeINDY, 2, 5, PenaltyPageCross, 0, N | Z}, {"EOR", EOR, ModeZPI, 2, 5, 0, 0, N | Z}, {"NOP", NOP, ModeIMP, 1, 1, 0, 0, 0}, {"NOP", NOP, ModeZPX, 2, 4, 0, 0, 0}, {"EOR", EOR, ModeZPX, 2, 4, 0, 0, N | Z}, {"LSR", LSR, ModeZPX, 2, 6, 0, 0, N | Z | C}, {"RMB5", RMB5, ModeZP0, 2, 5, 0, 0, 0}, {"CLI", CLI, ModeIMP, 1, 2, 0, 0, I}, {"EOR", EOR, ModeABY, 3, 4, PenaltyPageCross, 0, N | Z}, {"PHY", PHY, ModeIMP, 1, 3, 0, 0, 0}, {"NOP", NOP, ModeIMP, 1, 1, 0, 0, 0}, {"NOP", NOP, ModeABS, 3, 8, 0, 0, 0}, {"EOR", EOR, ModeABX, 3, 4, PenaltyPageCross, 0, N | Z}, {"LSR", LSR, ModeABX, 3, 6, PenaltyPageCross, 0, N | Z | C}, {"BBR5", BBR5, ModeZPR, 3, 5, PenaltyBranch, 0, 0},
+	{"RTS", RTS, ModeIMP, 1, 6, 0, 0, 0}, {"ADC", ADC, ModeINDX, 2, 6, PenaltyDecimal, D | C, N | V | Z | C}, {"NOP", NOP, ModeIMM, 2, 2, 0, 0, 0}, {"NOP", NOP, ModeIMP, 1, 1, 0, 0, 0}, {"STZ", STZ, ModeZP0, 2, 3, 0, 0, 0}, {"ADC", ADC, ModeZP0, 2, 3, PenaltyDecimal, D | C, N | V | Z | C}, {"ROR", ROR, ModeZP0, 2, 5, 0, C, N | Z | C}, {"RMB6", RMB6, ModeZP0, 2, 5, 0, 0, 0}, {"PLA", PLA, ModeIMP, 1, 4, 0, 0, N | Z}, {"ADC", ADC, ModeIMM, 2, 2, PenaltyDecimal, D | C, N | V | Z | C}, {"ROR", ROR, ModeACC, 1, 2, 0, C, N | Z | C}, {"NOP", NOP, ModeIMP, 1, 1, 0, 0, 0}, {"JMP", JMP, ModeIND, 3, 6, 0, 0, 0}, {"ADC", ADC, ModeABS, 3, 4, PenaltyDecimal, D | C, N | V | Z | C}, {"ROR", ROR, ModeABS, 3, 6, 0, C, N | Z | C}, {"BBR6", BBR6, ModeZPR, 3, 5, PenaltyBranch, 0, 0},
+	{"BVS", BVS, ModeREL, 2, 2, PenaltyBranch, V, 0}, {"ADC", ADC, ModeINDY, 2, 5, PenaltyPageCross | PenaltyDecimal, D | C, N | V | Z | C}, {"ADC", ADC, ModeZPI, 2, 5, PenaltyDecimal, D | C, N | V | Z | C}, {"NOP", NOP, ModeIMP, 1, 1, 0, 0, 0}, {"STZ", STZ, ModeZPX, 2, 4, 0, 0, 0}, {"ADC", ADC, ModeZPX, 2, 4, PenaltyDecimal, D | C, N | V | Z | C}, {"ROR", ROR, ModeZPX, 2, 6, 0, C, N | Z | C}, {"RMB7", RMB7, ModeZP0, 2, 5, 0, 0, 0}, {"SEI", SEI, ModeIMP, 1, 2, 0, 0, I}, {"ADC", ADC, ModeABY, 3, 4, PenaltyPageCross | PenaltyDecimal, D | C, N | V | Z | C}, {"PLY", PLY, ModeIMP, 1, 4, 0, 0, N | Z}, {"NOP", NOP, ModeIMP, 1, 1, 0, 0, 0}, {"JMP", JMP, ModeIAX, 3, 6, 0, 0, 0}, {"ADC", ADC, ModeABX, 3, 4, PenaltyPageCross | PenaltyDecimal, D | C, N | V | Z | C}, {"ROR", ROR, ModeABX, 3, 6, PenaltyPageCross, C, N | Z | C}, {"BBR7", BBR7, ModeZPR, 3, 5, PenaltyBranch, 0, 0},
+	{"BRA", BRA, ModeREL, 2, 2, PenaltyBranch, 0, 0}, {"STA", STA, ModeINDX, 2, 6, 0, 0, 0}, {"NOP", NOP, ModeIMM, 2, 2, 0, 0, 0}, {"NOP", NOP, ModeIMP, 1, 1, 0, 0, 0}, {"STY", STY, ModeZP0, 2, 3, 0, 0, 0}, {"STA", STA, ModeZP0, 2, 3, 0, 0, 0}, {"STX", STX, ModeZP0, 2, 3, 0, 0, 0}, {"SMB0", SMB0, ModeZP0, 2, 5, 0, 0, 0}, {"DEY", DEY, ModeIMP, 1, 2, 0, 0, N | Z}, {"BIT", BIT, ModeIMM, 2, 2, 0, 0, Z}, {"TXA", TXA, ModeIMP, 1, 2, 0, 0, N | Z}, {"NOP", NOP, ModeIMP, 1, 1, 0, 0, 0}, {"STY", STY, ModeABS, 3, 4, 0, 0, 0}, {"STA", STA, ModeABS, 3, 4, 0, 0, 0}, {"STX", STX, ModeABS, 3, 4, 0, 0, 0}, {"BBS0", BBS0, ModeZPR, 3, 5, PenaltyBranch, 0, 0},
+	{"BCC", BCC, ModeREL, 2, 2, PenaltyBranch, C, 0}, {"STA", STA, ModeINDY, 2, 6, 0, 0, 0}, {"STA", STA, ModeZPI, 2, 5, 0, 0, 0}, {"NOP", NOP, ModeIMP, 1, 1, 0, 0, 0}, {"STY", STY, ModeZPX, 2, 4, 0, 0, 0}, {"STA", STA, ModeZPX, 2, 4, 0, 0, 0}, {"STX", STX, ModeZPY, 2, 4, 0, 0, 0}, {"SMB1", SMB1, ModeZP0, 2, 5, 0, 0, 0}, {"TYA", TYA, ModeIMP, 1, 2, 0, 0, N | Z}, {"STA", STA, ModeABY, 3, 5, 0, 0, 0}, {"TXS", TXS, ModeIMP, 1, 2, 0, 0, 0}, {"NOP", NOP, ModeIMP, 1, 1, 0, 0, 0}, {"STZ", STZ, ModeABS, 3, 4, 0, 0, 0}, {"STA", STA, ModeABX, 3, 5, 0, 0, 0}, {"STZ", STZ, ModeABX, 3, 5, 0, 0, 0}, {"BBS1", BBS1, ModeZPR, 3, 5, PenaltyBranch, 0, 0},
+	{"LDY", LDY, ModeIMM, 2, 2, 0, 0, N | Z}, {"LDA", LDA, ModeINDX, 2, 6, 0, 0, N | Z}, {"LDX", LDX, ModeIMM, 2, 2, 0, 0, N | Z}, {"NOP", NOP, ModeIMP, 1, 1, 0, 0, 0}, {"LDY", LDY, ModeZP0, 2, 3, 0, 0, N | Z}, {"LDA", LDA, ModeZP0, 2, 3, 0, 0, N | Z}, {"LDX", LDX, ModeZP0, 2, 3, 0, 0, N | Z}, {"SMB2", SMB2, ModeZP0, 2, 5, 0, 0, 0}, {"TAY", TAY, ModeIMP, 1, 2, 0, 0, N | Z}, {"LDA", LDA, ModeIMM, 2, 2, 0, 0, N | Z}, {"TAX", TAX, ModeIMP, 1, 2, 0, 0, N | Z}, {"NOP", NOP, ModeIMP, 1, 1, 0, 0, 0}, {"LDY", LDY, ModeABS, 3, 4, 0, 0, N | Z}, {"LDA", LDA, ModeABS, 3, 4, 0, 0, N | Z}, {"LDX", LDX, ModeABS, 3, 4, 0, 0, N | Z}, {"BBS2", BBS2, ModeZPR, 3, 5, PenaltyBranch, 0, 0},
+	{"BCS", BCS, ModeREL, 2, 2, PenaltyBranch, C, 0}, {"LDA", LDA, ModeINDY, 2, 5, PenaltyPageCross, 0, N | Z}, {"LDA", LDA, ModeZPI, 2, 5, 0, 0, N | Z}, {"NOP", NOP, ModeIMP, 1, 1, 0, 0, 0}, {"LDY", LDY, ModeZPX, 2, 4, 0, 0, N | Z}, {"LDA", LDA, ModeZPX, 2, 4, 0, 0, N | Z}, {"LDX", LDX, ModeZPY, 2, 4, 0, 0, N | Z}, {"SMB3", SMB3, ModeZP0, 2, 5, 0, 0, 0}, {"CLV", CLV, ModeIMP, 1, 2, 0, 0, V}, {"LDA", LDA, ModeABY, 3, 4, PenaltyPageCross, 0, N | Z}, {"TSX", TSX, ModeIMP, 1, 2, 0, 0, N | Z}, {"NOP", NOP, ModeIMP, 1, 1, 0, 0, 0}, {"LDY", LDY, ModeABX, 3, 4, PenaltyPageCross, 0, N | Z}, {"LDA", LDA, ModeABX, 3, 4, PenaltyPageCross, 0, N | Z}, {"LDX", LDX, ModeABY, 3, 4, PenaltyPageCross, 0, N | Z}, {"BBS3", BBS3, ModeZPR, 3, 5, PenaltyBranch, 0, 0},
+	{"CPY", CPY, ModeIMM, 2, 2, 0, 0, N | Z | C}, {"CMP", CMP, ModeINDX, 2, 6, 0, 0, N | Z | C}, {"NOP", NOP, ModeIMM, 2, 2, 0, 0, 0}, {"NOP", NOP, ModeIMP, 1, 1, 0, 0, 0}, {"CPY", CPY, ModeZP0, 2, 3, 0, 0, N | Z | C}, {"CMP", CMP, ModeZP0, 2, 3, 0, 0, N | Z | C}, {"DEC", DEC, ModeZP0, 2, 5, 0, 0, N | Z}, {"SMB4", SMB4, ModeZP0, 2, 5, 0, 0, 0}, {"INY", INY, ModeIMP, 1, 2, 0, 0, N | Z}, {"CMP", CMP, ModeIMM, 2, 2, 0, 0, N | Z | C}, {"DEX", DEX, ModeIMP, 1, 2, 0, 0, N | Z}, {"WAI", WAI, ModeIMP, 1, 3, 0, 0, 0}, {"CPY", CPY, ModeABS, 3, 4, 0, 0, N | Z | C}, {"CMP", CMP, ModeABS, 3, 4, 0, 0, N | Z | C}, {"DEC", DEC, ModeABS, 3, 6, 0, 0, N | Z}, {"BBS4", BBS4, ModeZPR, 3, 5, PenaltyBranch, 0, 0},
+	{"BNE", BNE, ModeREL, 2, 2, PenaltyBranch, Z, 0}, {"CMP", CMP, ModeINDY, 2, 5, PenaltyPageCross, 0, N | Z | C}, {"CMP", CMP, ModeZPI, 2, 5, 0, 0, N | Z | C}, {"NOP", NOP, ModeIMP, 1, 1, 0, 0, 0}, {"NOP", NOP, ModeZPX, 2, 4, 0, 0, 0}, {"CMP", CMP, ModeZPX, 2, 4, 0, 0, N | Z | C}, {"DEC", DEC, ModeZPX, 2, 6, 0, 0, N | Z}, {"SMB5", SMB5, ModeZP0, 2, 5, 0, 0, 0}, {"CLD", CLD, ModeIMP, 1, 2, 0, 0, D}, {"CMP", CMP, ModeABY, 3, 4, PenaltyPageCross, 0, N | Z | C}, {"PHX", PHX, ModeIMP, 1, 3, 0, 0, 0}, {"STP", STP, ModeIMP, 1, 3, 0, 0, 0}, {"NOP", NOP, ModeABS, 3, 4, 0, 0, 0}, {"CMP", CMP, ModeABX, 3, 4, PenaltyPageCross, 0, N | Z | C}, {"DEC", DEC, ModeABX, 3, 7, 0, 0, N | Z}, {"BBS5", BBS5, ModeZPR, 3, 5, PenaltyBranch, 0, 0},
+	{"CPX", CPX, ModeIMM, 2, 2, 0, 0, N | Z | C}, {"SBC", SBC, ModeINDX, 2, 6, PenaltyDecimal, D | C, N | V | Z | C}, {"NOP", NOP, ModeIMM, 2, 2, 0, 0, 0}, {"NOP", NOP, ModeIMP, 1, 1, 0, 0, 0}, {"CPX", CPX, ModeZP0, 2, 3, 0, 0, N | Z | C}, {"SBC", SBC, ModeZP0, 2, 3, PenaltyDecimal, D | C, N | V | Z | C}, {"INC", INC, ModeZP0, 2, 5, 0, 0, N | Z}, {"SMB6", SMB6, ModeZP0, 2, 5, 0, 0, 0}, {"INX", INX, ModeIMP, 1, 2, 0, 0, N | Z}, {"SBC", SBC, ModeIMM, 2, 2, PenaltyDecimal, D | C, N | V | Z | C}, {"NOP", NOP, ModeIMP, 1, 2, 0, 0, 0}, {"NOP", NOP, ModeIMP, 1, 1, 0, 0, 0}, {"CPX", CPX, ModeABS, 3, 4, 0, 0, N | Z | C}, {"SBC", SBC, ModeABS, 3, 4, PenaltyDecimal, D | C, N | V | Z | C}, {"INC", INC, ModeABS, 3, 6, 0, 0, N | Z}, {"BBS6", BBS6, ModeZPR, 3, 5, PenaltyBranch, 0, 0},
+	{"BEQ", BEQ, ModeREL, 2, 2, PenaltyBranch, Z, 0}, {"SBC", SBC, ModeINDY, 2, 5, PenaltyPageCross | PenaltyDecimal, D | C, N | V | Z | C}, {"SBC", SBC, ModeZPI, 2, 5, PenaltyDecimal, D | C, N | V | Z | C}, {"NOP", NOP, ModeIMP, 1, 1, 0, 0, 0}, {"NOP", NOP, ModeZPX, 2, 4, 0, 0, 0}, {"SBC", SBC, ModeZPX, 2, 4, PenaltyDecimal, D | C, N | V | Z | C}, {"INC", INC, ModeZPX, 2, 6, 0, 0, N | Z}, {"SMB7", SMB7, ModeZP0, 2, 5, 0, 0, 0}, {"SED", SED, ModeIMP, 1, 2, 0, 0, D}, {"SBC", SBC, ModeABY, 3, 4, PenaltyPageCross | PenaltyDecimal, D | C, N | V | Z | C}, {"PLX", PLX, ModeIMP, 1, 4, 0, 0, N | Z}, {"NOP", NOP, ModeIMP, 1, 1, 0, 0, 0}, {"NOP", NOP, ModeABS, 3, 4, 0, 0, 0}, {"SBC", SBC, ModeABX, 3, 4, PenaltyPageCross | PenaltyDecimal, D | C, N | V | Z | C}, {"INC", INC, ModeABX, 3, 7, 0, 0, N | Z}, {"BBS7", BBS7, ModeZPR, 3, 5, PenaltyBranch, 0, 0},
 }
 
-// Name returns the instruction name derived from the instruction function
+// rockwell65c02Operations is the lookup table for the Rockwell R65C02. It is identical to the WDC part except that
+// WAI and STP are not implemented and execute as single byte NOPs.
+var rockwell65c02Operations = func() [256]Operation {
+	ops := wdc65c02Operations
+	ops[0xCB] = Operation{"NOP", NOP, ModeIMP, 1, 1, 0, 0, 0}
+	ops[0xDB] = Operation{"NOP", NOP, ModeIMP, 1, 1, 0, 0, 0}
+	return ops
+}()
+
+// Name returns the instruction name (mnemonic) of the operation.
 func (o Operation) Name() string {
-	name := getFunctionName(o.Instruction)
-	if name == "XXX" {
-		return "???"
-	}
-	return name
+	return o.Mnemonic
 }
 
-// AddressModeName returns the addressing mode name derived from the address mode function
+// AddressModeName returns the name of the operation's address mode
 func (o Operation) AddressModeName() string {
-	return getFunctionName(o.AddressMode)
+	return o.AddressMode.String()
+}
+
+// Mnemonic returns the instruction name of the specified opcode on this CPU's variant
+func (c *CPU) Mnemonic(opcode uint8) string {
+	return c.instructions.opcodes[opcode].Mnemonic
 }
 
-// GetOperation returns the operation information for the specified opcode
+// GetOperation returns the operation information for the specified opcode on this CPU's variant
 func (c *CPU) GetOperation(opcode uint8) Operation {
-	return operations[opcode]
+	return c.operations[opcode]
 }
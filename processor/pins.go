@@ -0,0 +1,90 @@
+package processor
+
+// External control pins.
+//
+// RDY lets external hardware stall the CPU, for example to perform DMA or to add wait states for slow memory.
+// While it is released (not ready) the NMOS 6502 stops on the next read cycle and stays there, finishing any
+// write cycles first; the CMOS variants stop on any cycle. A stalled cycle makes no bus access. In the default
+// execution mode the CPU does not know which of an instruction's cycles are reads, so it stalls on every cycle.
+//
+// SO (set overflow) sets the V flag when it is asserted. Some disk drives wire it to the byte-ready signal so that
+// a tight "BVC *" loop can wait for data.
+//
+// RESET holds the CPU while it is asserted. Releasing it starts the 7-cycle reset sequence, which behaves like an
+// interrupt sequence with the stack writes suppressed: SP is decremented by 3 but nothing is written, the I flag
+// is set (and D cleared on the CMOS variants), and PC is loaded from the reset vector. The A, X and Y registers
+// and the other flags are left alone, as they are by a real warm reset.
+
+const resetVector = 0xFFFC
+
+// SetRDY sets the level of the RDY pin. The CPU stalls while ready is false.
+func (c *CPU) SetRDY(ready bool) {
+	c.notReady = !ready
+}
+
+// Ready returns the level of the RDY pin.
+func (c *CPU) Ready() bool {
+	return !c.notReady
+}
+
+// SetSO sets the level of the SO pin. Asserting it sets the V flag.
+func (c *CPU) SetSO(asserted bool) {
+	if asserted && !c.soLine {
+		c.SetFlag(V, true)
+	}
+	c.soLine = asserted
+}
+
+// SetReset sets the level of the RESET pin. Asserting it abandons the current instruction and holds the CPU, and
+// releasing it starts the reset sequence.
+func (c *CPU) SetReset(asserted bool) {
+	if asserted {
+		c.abort()
+	} else if c.resetLine {
+		c.pendingReset = true
+	}
+	c.resetLine = asserted
+}
+
+// abort abandons the current instruction and clears any pending interrupt, halt or wait state.
+func (c *CPU) abort() {
+	c.cycles = 0
+	c.halted = false
+	c.waiting = false
+	c.haltReason = HaltNone
+	c.err = nil
+	c.micro = microState{}
+	c.latched = false
+	c.nmiPending = false
+	c.pendingInterrupt = 0
+	c.interruptVector = 0
+	c.pendingReset = false
+}
+
+// Stalled returns true if the RDY or RESET pins stop the CPU on the next cycle. Step returns early when the CPU is
+// stalled, as the instruction cannot complete until something outside the CPU changes the pins.
+func (c *CPU) Stalled() bool {
+	return c.stalled()
+}
+
+// stalled returns true if the RDY or RESET pins stop the CPU on the next cycle.
+func (c *CPU) stalled() bool {
+	if c.resetLine {
+		return true
+	}
+	if !c.notReady {
+		return false
+	}
+	return !c.cycleAccurate || !c.nextCycleWrites()
+}
+
+// reset performs the reset sequence.
+func (c *CPU) reset() {
+	c.pendingReset = false
+	c.last = StepResult{PC: c.PC, Address: resetVector, Interrupt: InterruptReset}
+	c.SP -= 3
+	c.enterInterrupt()
+	c.PC = c.read16(resetVector, AccessVector)
+	c.interruptVector = resetVector
+	c.cycles = 7 - 1
+}
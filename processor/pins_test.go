@@ -0,0 +1,170 @@
+package processor_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ukdave/6502_emulator/bus"
+	"github.com/ukdave/6502_emulator/processor"
+)
+
+// recordingBus wraps a SimpleBus and records every access made to it, one per clock cycle
+type recordingBus struct {
+	*bus.SimpleBus
+	accesses []processor.BusAccess
+}
+
+func (b *recordingBus) Read(addr uint16) byte {
+	data := b.SimpleBus.Read(addr)
+	b.accesses = append(b.accesses, read(addr, data))
+	return data
+}
+
+func (b *recordingBus) Write(addr uint16, data byte) {
+	b.accesses = append(b.accesses, write(addr, data))
+	b.SimpleBus.Write(addr, data)
+}
+
+func TestPowerOn(t *testing.T) {
+	bus := bus.NewSimpleBus()
+	store16(bus, 0xFFFC, 0x1234)
+	cpu := processor.NewCPU(bus)
+
+	cpu.A, cpu.X, cpu.Y, cpu.SP, cpu.Status = 0x11, 0x22, 0x33, 0x44, 0xFF
+	cpu.Clock()
+	cpu.PowerOn()
+
+	assert.Equal(t, uint8(0x00), cpu.A, "Accumulator should be 0x00")
+	assert.Equal(t, uint8(0x00), cpu.X, "X should be 0x00")
+	assert.Equal(t, uint8(0x00), cpu.Y, "Y should be 0x00")
+	assert.Equal(t, uint8(0xFD), cpu.SP, "Stack pointer should be 0xFD")
+	assert.Equal(t, uint8(0x24), cpu.Status, "Status should be 0x24")
+	assert.Equal(t, uint16(0x1234), cpu.PC, "PC should be 0x1234")
+	assert.Equal(t, uint64(0), cpu.TotalCycles, "Total cycles should be 0")
+	assert.Equal(t, uint8(0), cpu.Cycles(), "Cycles should be 0")
+}
+
+func TestReset_Warm(t *testing.T) {
+	bus := bus.NewSimpleBus()
+	store16(bus, 0xFFFC, 0x1234)
+	cpu := processor.NewCPU(bus)
+
+	cpu.A, cpu.X, cpu.Y, cpu.SP, cpu.Status = 0x11, 0x22, 0x33, 0xF0, 0x20
+	cpu.PC = 0x8000
+	cpu.Reset()
+	cpu.Step()
+
+	assert.Equal(t, uint8(0x11), cpu.A, "Accumulator should be unchanged")
+	assert.Equal(t, uint8(0x22), cpu.X, "X should be unchanged")
+	assert.Equal(t, uint8(0x33), cpu.Y, "Y should be unchanged")
+	assert.Equal(t, uint8(0xED), cpu.SP, "Stack pointer should be decremented by 3")
+	assert.Equal(t, uint8(0x24), cpu.Status, "Only the I flag should be set")
+	assert.Equal(t, uint16(0x1234), cpu.PC, "PC should be 0x1234")
+	assert.Equal(t, uint64(7), cpu.TotalCycles, "The reset sequence should take 7 cycles")
+	assert.Equal(t, uint8(0x00), bus.Read(0x01F0), "Nothing should be written to the stack")
+}
+
+func TestReset_Held(t *testing.T) {
+	bus := bus.NewSimpleBus()
+	store16(bus, 0xFFFC, 0x1234)
+	bus.Write(0x8000, 0xE8) // INX
+	cpu := processor.NewCPU(bus)
+	cpu.PC = 0x8000
+
+	cpu.SetReset(true)
+	for range 10 {
+		cpu.Clock()
+	}
+	assert.Equal(t, uint16(0x8000), cpu.PC, "PC should not change while RESET is asserted")
+	assert.Equal(t, uint8(0x00), cpu.X, "No instructions should be executed while RESET is asserted")
+
+	cpu.SetReset(false)
+	cpu.Step()
+	assert.Equal(t, uint16(0x1234), cpu.PC, "PC should be 0x1234")
+	assert.Equal(t, uint64(17), cpu.TotalCycles, "Total cycles should be 17")
+}
+
+func TestReset_CycleAccurate(t *testing.T) {
+	bus := bus.NewSimpleBus()
+	store16(bus, 0xFFFC, 0x1234)
+	cpu := processor.NewCPUWithVariant(bus, processor.VariantNMOS)
+	assert.NoError(t, cpu.SetCycleAccurate(true))
+	cpu.PC = 0x8000
+
+	cpu.Reset()
+	result := cpu.Step()
+
+	expected := []processor.BusAccess{
+		read(0x8000, 0x00), read(0x8000, 0x00),
+		read(0x01FD, 0x00), read(0x01FC, 0x00), read(0x01FB, 0x00),
+		read(0xFFFC, 0x34), read(0xFFFD, 0x12),
+	}
+	assert.Equal(t, expected, result.Accesses)
+	assert.Equal(t, processor.InterruptReset, result.Interrupt, "Expected the reset sequence to be reported")
+	assert.Equal(t, uint16(0x1234), cpu.PC, "PC should be 0x1234")
+	assert.Equal(t, uint8(0xFA), cpu.SP, "Stack pointer should be decremented by 3")
+}
+
+func TestSO(t *testing.T) {
+	cpu := processor.NewCPU(bus.NewSimpleBus())
+
+	cpu.SetSO(true)
+	assert.True(t, cpu.GetFlag(processor.V), "Overflow flag should be set")
+
+	// Holding the pin does not set the flag again
+	cpu.SetFlag(processor.V, false)
+	cpu.SetSO(true)
+	assert.False(t, cpu.GetFlag(processor.V), "Overflow flag should be clear")
+
+	cpu.SetSO(false)
+	cpu.SetSO(true)
+	assert.True(t, cpu.GetFlag(processor.V), "Overflow flag should be set")
+}
+
+func TestRDY(t *testing.T) {
+	bus := bus.NewSimpleBus()
+	bus.Write(0x8000, 0xE8) // INX
+	cpu := processor.NewCPU(bus)
+	cpu.PC = 0x8000
+
+	cpu.SetRDY(false)
+	assert.False(t, cpu.Ready(), "RDY should be released")
+	for range 5 {
+		cpu.Clock()
+	}
+	assert.Equal(t, uint16(0x8000), cpu.PC, "PC should not change while the CPU is stalled")
+	assert.Equal(t, uint64(5), cpu.TotalCycles, "Stalled cycles should still be counted")
+
+	cpu.SetRDY(true)
+	cpu.Step()
+	assert.Equal(t, uint8(0x01), cpu.X, "X should be 0x01")
+}
+
+func TestRDY_CycleAccurate(t *testing.T) {
+	recorder := &recordingBus{SimpleBus: bus.NewSimpleBus()}
+	cpu := processor.NewCPUWithVariant(recorder, processor.VariantNMOS)
+	assert.NoError(t, cpu.SetCycleAccurate(true))
+	for i, b := range []byte{0x8D, 0x00, 0x20, 0xE8} { // STA $2000; INX
+		recorder.SimpleBus.Write(0x8000+uint16(i), b)
+	}
+	cpu.PC = 0x8000
+	cpu.A = 0x42
+
+	for range 3 {
+		cpu.Clock()
+	}
+
+	// The write cycle completes, then the CPU stops on the opcode fetch
+	recorder.accesses = nil
+	cpu.SetRDY(false)
+	for range 3 {
+		cpu.Clock()
+	}
+	assert.Equal(t, []processor.BusAccess{write(0x2000, 0x42)}, recorder.accesses)
+
+	cpu.SetRDY(true)
+	cpu.Clock()
+	cpu.Clock()
+	assert.Equal(t, uint8(0x01), cpu.X, "X should be 0x01")
+}
@@ -0,0 +1,68 @@
+package processor
+
+import "math"
+
+// RunCycles runs the CPU for exactly n clock cycles and returns n.
+//
+// It has the same effect as calling Clock n times, but is much faster: once an instruction has executed, the
+// remaining cycles it takes are counted in one go rather than one call at a time. Cycles are still clocked
+// individually when that would change the result, such as in cycle accurate mode, while RDY or RESET is holding
+// the CPU, or while a BRK may be hijacked by an NMI. If the JIT is enabled (see SetJIT), whole blocks of
+// instructions are run from its cache.
+func (c *CPU) RunCycles(n uint64) uint64 {
+	target := c.TotalCycles + n
+	for c.TotalCycles < target {
+		if c.cycles > 0 && c.canSkip() {
+			c.skip(min(uint64(c.cycles), target-c.TotalCycles))
+		} else if ran, _ := c.runBlock(target, nil); !ran {
+			c.Clock()
+		}
+	}
+	return n
+}
+
+// RunUntil runs the CPU until stop returns true or maxCycles cycles have been run, and returns the number of cycles
+// run. A maxCycles of 0 means no limit. stop is called between instructions (and interrupt sequences), and before
+// the first one; it is not called part way through an instruction.
+//
+// RunUntil also returns as soon as the CPU halts (see Halted): a JAM or STP instruction, a trapped unimplemented
+// opcode or a bus fault, as nothing changes after that until it is reset. A CPU waiting in WAI or looping on itself
+// keeps running, as an interrupt could still arrive, so with no cycle limit the stop function must check for these
+// if nothing will interrupt the CPU (for example by stopping when HaltReason is not HaltNone).
+func (c *CPU) RunUntil(stop func(*CPU) bool, maxCycles uint64) uint64 {
+	start := c.TotalCycles
+	target := uint64(math.MaxUint64)
+	if maxCycles != 0 {
+		target = start + maxCycles
+	}
+	for c.TotalCycles < target {
+		if c.cycles == 0 && (c.halted || stop(c)) {
+			break
+		}
+		if c.cycles > 0 && c.canSkip() {
+			c.skip(min(uint64(c.cycles), target-c.TotalCycles))
+		} else if ran, stopped := c.runBlock(target, stop); stopped {
+			break
+		} else if !ran {
+			c.Clock()
+		}
+	}
+	return c.TotalCycles - start
+}
+
+// canSkip returns true if the remaining cycles of the current instruction do nothing but count down and poll for
+// interrupts, so that they can be skipped.
+func (c *CPU) canSkip() bool {
+	return !c.cycleAccurate && c.interruptVector == 0 && !c.notReady && !c.resetLine && !c.pendingReset
+}
+
+// skip counts down n of the remaining cycles of the current instruction at once. It has the same effect as calling
+// Clock n times, including the interrupt poll made when two cycles remain.
+func (c *CPU) skip(n uint64) {
+	remaining := uint64(c.cycles)
+	if remaining >= 2 && remaining-n < 2 {
+		c.pollInterrupts(c.pollMask)
+	}
+	c.cycles -= uint8(n)
+	c.TotalCycles += n
+}
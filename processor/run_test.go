@@ -0,0 +1,95 @@
+package processor_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ukdave/6502_emulator/bus"
+	"github.com/ukdave/6502_emulator/internal/cputest"
+	"github.com/ukdave/6502_emulator/processor"
+)
+
+// benchmarkProgram increments each byte of page 2 in an endless loop
+var benchmarkProgram = []byte{
+	0xA2, 0x00, //       LDX #$00
+	0xBD, 0x00, 0x02, // LDA $0200,X
+	0x69, 0x01, //       ADC #$01
+	0x9D, 0x00, 0x02, // STA $0200,X
+	0xE8,       //       INX
+	0xD0, 0xF5, //       BNE $8002
+	0x4C, 0x00, 0x80, // JMP $8000
+}
+
+func TestRunCycles_MatchesClock(t *testing.T) {
+	for _, n := range []uint64{1, 2, 3, 1000, 12345} {
+		clocked, clockedBus := cputest.New(processor.VariantNMOS, 0x8000, benchmarkProgram...)
+		run, runBus := cputest.New(processor.VariantNMOS, 0x8000, benchmarkProgram...)
+		for _, b := range []*bus.SimpleBus{clockedBus, runBus} {
+			handler(b, 0xFFFE, 0x9000, 0x40) // RTI
+		}
+		for _, cpu := range []*processor.CPU{clocked, run} {
+			cpu.SetFlag(processor.I, false)
+		}
+
+		for i := uint64(0); i < n; i++ {
+			if i == 500 {
+				clocked.AssertIRQ(0)
+			}
+			if i == 520 {
+				clocked.ReleaseIRQ(0)
+			}
+			clocked.Clock()
+		}
+		done := run.RunCycles(min(n, 500))
+		if n > 500 {
+			run.AssertIRQ(0)
+			done += run.RunCycles(min(n, 520) - 500)
+			run.ReleaseIRQ(0)
+			done += run.RunCycles(n - 520)
+		}
+
+		assert.Equal(t, n, done, "RunCycles should run %d cycles", n)
+		assert.Equal(t, clocked.TotalCycles, run.TotalCycles, "TotalCycles should match")
+		assert.Equal(t, clocked.Cycles(), run.Cycles(), "Cycles should match")
+		assert.Equal(t, clocked.PC, run.PC, "PC should match")
+		assert.Equal(t, clocked.A, run.A, "A should match")
+		assert.Equal(t, clocked.X, run.X, "X should match")
+		assert.Equal(t, clocked.SP, run.SP, "SP should match")
+		assert.Equal(t, clocked.Status, run.Status, "Status should match")
+		assert.Equal(t, clockedBus.Read(0x0200), runBus.Read(0x0200), "Memory should match")
+	}
+}
+
+func TestRunUntil(t *testing.T) {
+	cpu, b := cputest.New(processor.Variant2A03, 0x8000, benchmarkProgram...)
+	cycles := cpu.RunUntil(func(c *processor.CPU) bool { return c.PC == 0x800D }, 0)
+	assert.Equal(t, uint16(0x800D), cpu.PC, "PC should be 0x800D")
+	assert.Equal(t, uint8(0), cpu.Cycles(), "The CPU should stop between instructions")
+	assert.Equal(t, cpu.TotalCycles, cycles, "The cycles run should be returned")
+	assert.Equal(t, uint8(0x01), b.Read(0x02FF), "Memory at 0x02FF should be 0x01")
+
+	cycles = cpu.RunUntil(func(c *processor.CPU) bool { return false }, 100)
+	assert.Equal(t, uint64(100), cycles, "The cycle limit should stop the run")
+}
+
+func TestRunUntil_Halted(t *testing.T) {
+	cpu := newCPU(processor.VariantNMOS, 0xEA, 0x02) // NOP; JAM
+	cycles := cpu.RunUntil(func(c *processor.CPU) bool { return false }, 0)
+	assert.True(t, cpu.Halted(), "CPU should be halted")
+	assert.Equal(t, uint64(4), cycles, "Cycles should be 4")
+}
+
+func TestRunUntil_Waiting(t *testing.T) {
+	cpu := newCPU(processor.Variant65C02, 0xCB, 0xDB) // WAI; STP
+	cycles := cpu.RunUntil(func(c *processor.CPU) bool { return false }, 1000)
+	assert.Equal(t, uint64(1000), cycles, "A waiting CPU should keep running until the cycle limit")
+	assert.Equal(t, processor.HaltWAI, cpu.HaltReason())
+
+	cycles = cpu.RunUntil(func(c *processor.CPU) bool { return c.HaltReason() != processor.HaltNone }, 0)
+	assert.Equal(t, uint64(0), cycles, "The stop function should stop a waiting CPU")
+
+	cpu.AssertIRQ(1)
+	cpu.RunUntil(func(c *processor.CPU) bool { return false }, 0)
+	assert.Equal(t, processor.HaltSTP, cpu.HaltReason(), "An interrupt should wake the CPU")
+}
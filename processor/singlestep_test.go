@@ -0,0 +1,196 @@
+package processor_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ukdave/6502_emulator/bus"
+	"github.com/ukdave/6502_emulator/processor"
+)
+
+// Tom Harte's SingleStepTests (https://github.com/SingleStepTests/65x02) give the state of the CPU and memory before
+// and after a single instruction, along with the bus access made on each cycle, for 10,000 random cases of every
+// opcode. The data isn't part of this repository: point SINGLESTEP_TESTS at a checkout of the 65x02 repository to run
+// it, for example
+//
+//	SINGLESTEP_TESTS=~/src/65x02 go test -run SingleStep -v ./processor
+//
+// Without it the harness runs the handful of cases in testdata/singlestep, which use the same layout.
+
+// singleStepVariants are the directories of the suite and the variant each one describes. The NMOS tests are run
+// twice so that cycle accurate mode also has its bus accesses checked.
+var singleStepVariants = []struct {
+	dir           string
+	variant       processor.Variant
+	cycleAccurate bool
+}{
+	{"6502", processor.VariantNMOS, false},
+	{"6502", processor.VariantNMOS, true},
+	{"nes6502", processor.Variant2A03, false},
+	{"wdc65c02", processor.Variant65C02, false},
+	{"rockwell65c02", processor.VariantR65C02, false},
+}
+
+// singleStepSkipped are instructions that stop the CPU, which the suite records as if they kept on fetching.
+var singleStepSkipped = map[string]bool{"JAM": true, "STP": true, "WAI": true}
+
+// singleStepCase is a single test case.
+type singleStepCase struct {
+	Name    string          `json:"name"`
+	Initial singleStepState `json:"initial"`
+	Final   singleStepState `json:"final"`
+	Cycles  [][3]any        `json:"cycles"` // Address, data and "read" or "write"
+}
+
+// singleStepState is the state of the CPU and of the memory that the instruction uses.
+type singleStepState struct {
+	PC  uint16      `json:"pc"`
+	S   uint8       `json:"s"`
+	A   uint8       `json:"a"`
+	X   uint8       `json:"x"`
+	Y   uint8       `json:"y"`
+	P   uint8       `json:"p"`
+	RAM [][2]uint16 `json:"ram"`
+}
+
+// accesses returns the bus accesses the case expects.
+func (c *singleStepCase) accesses() []processor.BusAccess {
+	accesses := make([]processor.BusAccess, len(c.Cycles))
+	for i, cycle := range c.Cycles {
+		address, _ := cycle[0].(float64)
+		data, _ := cycle[1].(float64)
+		accesses[i] = processor.BusAccess{Address: uint16(address), Data: byte(data), Write: cycle[2] == "write"}
+	}
+	return accesses
+}
+
+func TestSingleStep(t *testing.T) {
+	root := os.Getenv("SINGLESTEP_TESTS")
+	if root == "" {
+		root = filepath.Join("testdata", "singlestep")
+	}
+
+	for _, v := range singleStepVariants {
+		name := v.dir
+		if v.cycleAccurate {
+			name += "/cycle-accurate"
+		}
+		t.Run(name, func(t *testing.T) {
+			files, _ := filepath.Glob(filepath.Join(root, v.dir, "v1", "*.json"))
+			if len(files) == 0 {
+				t.Skipf("No tests found in %s", filepath.Join(root, v.dir, "v1"))
+			}
+
+			var summary []string
+			for _, file := range files {
+				opcode, err := strconv.ParseUint(strings.TrimSuffix(filepath.Base(file), ".json"), 16, 8)
+				if err != nil {
+					continue
+				}
+				info := v.variant.InstructionSet().Opcode(byte(opcode))
+				t.Run(fmt.Sprintf("%02X_%s", opcode, info.Mnemonic), func(t *testing.T) {
+					if singleStepSkipped[info.Mnemonic] {
+						summary = append(summary, fmt.Sprintf("$%02X %s skipped", opcode, info.Mnemonic))
+						t.Skipf("%s stops the CPU", info.Mnemonic)
+					}
+					passed, total := runSingleStepFile(t, file, v.variant, v.cycleAccurate)
+					summary = append(summary, fmt.Sprintf("$%02X %s %d/%d", opcode, info.Mnemonic, passed, total))
+				})
+			}
+			t.Logf("Passed per opcode:\n%s", strings.Join(summary, "\n"))
+		})
+	}
+}
+
+// runSingleStepFile runs the cases for one opcode, reporting the first few failures, and returns how many passed.
+func runSingleStepFile(t *testing.T, file string, variant processor.Variant, cycleAccurate bool) (passed, total int) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cases []singleStepCase
+	if err := json.Unmarshal(data, &cases); err != nil {
+		t.Fatalf("%s: %v", file, err)
+	}
+
+	b := bus.NewSimpleBus()
+	cpu := processor.NewCPUWithVariant(b, variant)
+	if err := cpu.SetCycleAccurate(cycleAccurate); err != nil {
+		t.Fatal(err)
+	}
+	const maxReported = 5
+	for _, c := range cases {
+		if failures := runSingleStepCase(cpu, b, &c, cycleAccurate); len(failures) == 0 {
+			passed++
+		} else if total-passed < maxReported {
+			t.Errorf("%s: %s", c.Name, strings.Join(failures, ", "))
+		}
+		total++
+	}
+	if passed != total {
+		t.Errorf("%d of %d cases failed", total-passed, total)
+	}
+	return passed, total
+}
+
+// runSingleStepCase runs one case and returns a description of each difference from the expected final state.
+func runSingleStepCase(cpu *processor.CPU, b *bus.SimpleBus, c *singleStepCase, cycleAccurate bool) []string {
+	cpu.PC, cpu.SP, cpu.A, cpu.X, cpu.Y, cpu.Status = c.Initial.PC, c.Initial.S, c.Initial.A, c.Initial.X,
+		c.Initial.Y, c.Initial.P
+	for _, ram := range c.Initial.RAM {
+		b.Write(ram[0], byte(ram[1]))
+	}
+
+	result := cpu.Step()
+
+	var failures []string
+	check := func(name string, got, expected byte) {
+		if got != expected {
+			failures = append(failures, fmt.Sprintf("%s is $%02X, expected $%02X", name, got, expected))
+		}
+	}
+	if cpu.PC != c.Final.PC {
+		failures = append(failures, fmt.Sprintf("PC is $%04X, expected $%04X", cpu.PC, c.Final.PC))
+	}
+	check("S", cpu.SP, c.Final.S)
+	check("A", cpu.A, c.Final.A)
+	check("X", cpu.X, c.Final.X)
+	check("Y", cpu.Y, c.Final.Y)
+	// B and U aren't stored in the status register, so the suite's values for them don't mean anything
+	const unstored = byte(processor.B | processor.U)
+	check("P", cpu.Status&^unstored, c.Final.P&^unstored)
+	for _, ram := range c.Final.RAM {
+		check(fmt.Sprintf("$%04X", ram[0]), b.Read(ram[0]), byte(ram[1]))
+	}
+	if int(result.Cycles) != len(c.Cycles) {
+		failures = append(failures, fmt.Sprintf("took %d cycles, expected %d", result.Cycles, len(c.Cycles)))
+	}
+	if cycleAccurate {
+		expected := c.accesses()
+		for i := range max(len(result.Accesses), len(expected)) {
+			var got, want string
+			if i < len(result.Accesses) {
+				got = result.Accesses[i].String()
+			}
+			if i < len(expected) {
+				want = expected[i].String()
+			}
+			if got != want {
+				failures = append(failures, fmt.Sprintf("cycle %d was %q, expected %q", i+1, got, want))
+				break
+			}
+		}
+	}
+
+	// Clear the memory used, ready for the next case
+	for _, ram := range slices.Concat(c.Initial.RAM, c.Final.RAM) {
+		b.Write(ram[0], 0)
+	}
+	return failures
+}
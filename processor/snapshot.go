@@ -0,0 +1,145 @@
+package processor
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// cpuState is the state of the CPU as stored in a snapshot (see the snapshot package). Fields are only ever added
+// to the end, and the snapshot format version is bumped when they are.
+type cpuState struct {
+	Variant       Variant
+	A, X, Y, SP   byte
+	PC            uint16
+	Status        byte
+	Cycles        uint8
+	TotalCycles   uint64
+	MagicConstant byte
+
+	Halted     bool
+	Waiting    bool
+	HaltReason HaltReason
+
+	CycleAccurate bool
+	Latched       bool
+	Latch         byte
+
+	IRQLines         uint32
+	NMILine          bool
+	NMIPending       bool
+	PollMask         bool
+	PendingInterrupt uint16
+	InterruptVector  uint16
+
+	NotReady     bool
+	SOLine       bool
+	ResetLine    bool
+	PendingReset bool
+
+	// The instruction or sequence started most recently
+	LastPC        uint16
+	LastOpcode    byte
+	LastAddress   uint16
+	LastInterrupt Interrupt
+
+	// The progress of the current instruction in cycle accurate mode. The operation is decoded again from
+	// LastOpcode when the snapshot is loaded.
+	MicroActive      bool
+	MicroCycles      uint8
+	MicroStep        uint8
+	MicroAddr        uint16
+	MicroPtr         uint16
+	MicroPageChanged bool
+	MicroData        byte
+	MicroVector      uint16
+	MicroInterrupt   bool
+}
+
+// SnapshotID returns the ID of the CPU's section in a snapshot.
+func (c *CPU) SnapshotID() string {
+	return "CPU "
+}
+
+// SaveSnapshot writes the complete state of the CPU, including any instruction in progress, to w. The
+// configuration set by SetUnimplementedPolicy and SetUnimplementedHandler is not included.
+func (c *CPU) SaveSnapshot(w io.Writer) error {
+	state := c.state()
+	return binary.Write(w, binary.LittleEndian, &state)
+}
+
+// LoadSnapshot restores the state of the CPU from r. The snapshot must have been taken from a CPU of the same
+// variant. The rewind history and cached JIT blocks no longer match the restored state, and must be discarded by
+// calling CommitSnapshot once the rest of the snapshot has loaded (snapshot.Load does this).
+func (c *CPU) LoadSnapshot(r io.Reader) error {
+	var state cpuState
+	if err := binary.Read(r, binary.LittleEndian, &state); err != nil {
+		return err
+	}
+	if state.Variant != c.variant {
+		return fmt.Errorf("snapshot is of a %s CPU, not %s", state.Variant, c.variant)
+	}
+	c.setState(&state)
+	return nil
+}
+
+// CommitSnapshot discards the rewind history and cached JIT blocks after a snapshot has been loaded.
+func (c *CPU) CommitSnapshot() {
+	c.clearHistory()
+	c.FlushJIT()
+}
+
+// state returns the complete state of the CPU.
+func (c *CPU) state() cpuState {
+	m := &c.micro
+	return cpuState{
+		Variant: c.variant, A: c.A, X: c.X, Y: c.Y, SP: c.SP, PC: c.PC, Status: c.Status,
+		Cycles: c.cycles, TotalCycles: c.TotalCycles, MagicConstant: c.MagicConstant,
+		Halted: c.halted, Waiting: c.waiting, HaltReason: c.haltReason,
+		CycleAccurate: c.cycleAccurate, Latched: c.latched, Latch: c.latch,
+		IRQLines: c.irqLines, NMILine: c.nmiLine, NMIPending: c.nmiPending, PollMask: c.pollMask,
+		PendingInterrupt: c.pendingInterrupt, InterruptVector: c.interruptVector,
+		NotReady: c.notReady, SOLine: c.soLine, ResetLine: c.resetLine, PendingReset: c.pendingReset,
+		LastPC: c.last.PC, LastOpcode: c.last.Opcode, LastAddress: c.last.Address, LastInterrupt: c.last.Interrupt,
+		MicroActive: m.active, MicroCycles: m.op.Cycles, MicroStep: m.step, MicroAddr: m.addr, MicroPtr: m.ptr,
+		MicroPageChanged: m.pageChanged, MicroData: m.data, MicroVector: m.vector, MicroInterrupt: m.interrupt,
+	}
+}
+
+// setState restores the state of the CPU returned by state.
+func (c *CPU) setState(state *cpuState) {
+	c.A, c.X, c.Y, c.SP, c.PC, c.Status = state.A, state.X, state.Y, state.SP, state.PC, state.Status
+	c.cycles, c.TotalCycles, c.MagicConstant = state.Cycles, state.TotalCycles, state.MagicConstant
+	c.halted, c.waiting, c.haltReason = state.Halted, state.Waiting, state.HaltReason
+	c.cycleAccurate, c.latched, c.latch = state.CycleAccurate, state.Latched, state.Latch
+	c.irqLines, c.nmiLine, c.nmiPending, c.pollMask = state.IRQLines, state.NMILine, state.NMIPending, state.PollMask
+	c.pendingInterrupt, c.interruptVector = state.PendingInterrupt, state.InterruptVector
+	c.notReady, c.soLine, c.resetLine, c.pendingReset = state.NotReady, state.SOLine, state.ResetLine, state.PendingReset
+
+	c.last = StepResult{PC: state.LastPC, Opcode: state.LastOpcode, Address: state.LastAddress, Interrupt: state.LastInterrupt}
+	if state.LastInterrupt == InterruptNone {
+		c.last.decoded = &c.decoded[state.LastOpcode]
+	}
+	c.err = nil
+	if c.haltReason == HaltUnimplemented {
+		c.err = &UnimplementedOpcodeError{Opcode: state.LastOpcode, PC: state.LastPC}
+	}
+
+	c.micro = microState{}
+	if state.MicroActive {
+		op := Operation{}
+		if !state.MicroInterrupt {
+			op = c.decoded[state.LastOpcode].op
+		}
+		op.Cycles = state.MicroCycles
+		c.micro = microState{
+			active: true, op: op, step: state.MicroStep, addr: state.MicroAddr, ptr: state.MicroPtr,
+			pageChanged: state.MicroPageChanged, data: state.MicroData, vector: state.MicroVector,
+			interrupt: state.MicroInterrupt,
+		}
+		if !state.MicroInterrupt {
+			d := &c.decoded[state.LastOpcode]
+			c.micro.sequence, c.micro.mode, c.micro.kind = d.sequence, op.AddressMode, d.kind
+		}
+	}
+}
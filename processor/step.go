@@ -0,0 +1,117 @@
+package processor
+
+import "fmt"
+
+// Interrupt identifies an interrupt or reset sequence.
+type Interrupt uint8
+
+const (
+	InterruptNone Interrupt = iota
+	InterruptIRQ
+	InterruptNMI
+	InterruptReset
+)
+
+// String returns the name of the interrupt sequence.
+func (i Interrupt) String() string {
+	switch i {
+	case InterruptNone:
+		return "none"
+	case InterruptIRQ:
+		return "IRQ"
+	case InterruptNMI:
+		return "NMI"
+	case InterruptReset:
+		return "RESET"
+	}
+	return fmt.Sprintf("Interrupt(%d)", uint8(i))
+}
+
+// interruptFor returns the interrupt sequence that uses the given vector.
+func interruptFor(vector uint16) Interrupt {
+	switch vector {
+	case irqVector:
+		return InterruptIRQ
+	case nmiVector:
+		return InterruptNMI
+	case resetVector:
+		return InterruptReset
+	}
+	return InterruptNone
+}
+
+// BusAccess is a single read or write performed by the CPU.
+type BusAccess struct {
+	Address uint16
+	Data    byte
+	Write   bool
+}
+
+// String returns the access in the form "R $1234 = $56" or "W $1234 = $56".
+func (a BusAccess) String() string {
+	return fmt.Sprintf("%s $%04X = $%02X", ternary(a.Write, "W", "R"), a.Address, a.Data)
+}
+
+// StepResult describes what happened during a call to Step.
+//
+// A step is either a single instruction or a single interrupt (or reset) sequence, which is taken in place of the
+// instruction at PC. Interrupt is set for an interrupt sequence, in which case there is no opcode or Operation,
+// and also for a BRK instruction that was hijacked by an NMI.
+//
+// Accesses shares its storage with the CPU, which reuses it on the next call to Step, so copy it to keep it for
+// longer.
+type StepResult struct {
+	PC        uint16      // Address of the instruction (or, for an interrupt sequence, of the instruction interrupted)
+	Opcode    byte        // The opcode executed
+	Operation Operation   // The decoded operation
+	Address   uint16      // The effective address (zero for implied instructions, the vector for a sequence)
+	Cycles    uint8       // Cycles taken, including any page crossing and branch penalties
+	Accesses  []BusAccess // Bus reads and writes, in the order they were made (reused by the next Step)
+	Interrupt Interrupt   // The interrupt sequence that was run (or that hijacked a BRK)
+	Idle      bool        // Nothing was executed because the CPU is halted, waiting or stalled
+
+	decoded *decodedOp // The decoded instruction, or nil for a sequence; Operation is filled in from it by Step
+}
+
+// started records the start of an instruction. It is called for every instruction, so it updates the fields in
+// place rather than building a new StepResult, and leaves the Operation to be copied by Step.
+func (r *StepResult) started(pc uint16, opcode byte, d *decodedOp, address uint16) {
+	r.PC = pc
+	r.Opcode = opcode
+	r.decoded = d
+	r.Address = address
+	r.Interrupt = InterruptNone
+	r.Idle = false
+}
+
+// Step runs the CPU until the current instruction (or interrupt sequence) has completed and reports what it did.
+// If the CPU is between instructions, exactly one instruction is run.
+//
+// If the CPU is halted, waiting for an interrupt, or stalled by its RDY or RESET pins, Step performs a single clock
+// cycle and returns a result with Idle set. It also returns early if the CPU becomes stalled part way through an
+// instruction.
+func (c *CPU) Step() StepResult {
+	start := c.TotalCycles
+	if c.accessBuffer == nil {
+		c.accessBuffer = make([]BusAccess, 0, 16)
+	}
+	c.accessLog = c.accessBuffer[:0]
+	defer func() { c.accessBuffer, c.accessLog = c.accessLog, nil }()
+
+	if c.cycles == 0 {
+		// Replaced when an instruction or sequence starts
+		c.last = StepResult{PC: c.PC, Idle: true}
+	}
+	c.Clock()
+	for c.cycles > 0 && !c.stalled() {
+		c.Clock()
+	}
+
+	result := c.last
+	if result.decoded != nil {
+		result.Operation = result.decoded.op
+	}
+	result.Cycles = uint8(c.TotalCycles - start)
+	result.Accesses = c.accessLog
+	return result
+}
@@ -0,0 +1,134 @@
+package processor_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/ukdave/6502_emulator/bus"
+	"github.com/ukdave/6502_emulator/processor"
+)
+
+type StepSuite struct {
+	cpuSuite
+}
+
+func TestStepSuite(t *testing.T) {
+	suite.Run(t, new(StepSuite))
+}
+
+func (suite *StepSuite) SetupTest() {
+	suite.bus = bus.NewSimpleBus()
+	suite.cpu = processor.NewCPU(suite.bus)
+}
+
+func (suite *StepSuite) TestStep() {
+	suite.load(0xBD, 0x80, 0x20) // LDA $2080,X
+	suite.cpu.X = 0x90
+	suite.bus.Write(0x2110, 0x42)
+
+	result := suite.cpu.Step()
+
+	assert.Equal(suite.T(), uint16(0x8000), result.PC, "PC should be 0x8000")
+	assert.Equal(suite.T(), uint8(0xBD), result.Opcode, "Opcode should be 0xBD")
+	assert.Equal(suite.T(), "LDA", result.Operation.Name(), "Operation should be LDA")
+	assert.Equal(suite.T(), uint16(0x2110), result.Address, "Address should be 0x2110")
+	assert.Equal(suite.T(), uint8(5), result.Cycles, "Cycles should include the page crossing penalty")
+	assert.Equal(suite.T(), processor.InterruptNone, result.Interrupt, "No interrupt should be taken")
+	assert.False(suite.T(), result.Idle, "Step should not be idle")
+	expected := []processor.BusAccess{read(0x8000, 0xBD), read(0x8001, 0x80), read(0x8002, 0x20), read(0x2110, 0x42)}
+	assert.Equal(suite.T(), expected, result.Accesses)
+	assert.Equal(suite.T(), uint8(0), suite.cpu.Cycles(), "The instruction should be complete")
+}
+
+func (suite *StepSuite) TestStep_Write() {
+	suite.load(0xE6, 0x10) // INC $10
+	suite.bus.Write(0x0010, 0x41)
+
+	result := suite.cpu.Step()
+
+	assert.Equal(suite.T(), uint16(0x0010), result.Address, "Address should be 0x0010")
+	assert.Equal(suite.T(), uint8(5), result.Cycles, "Cycles should be 5")
+	expected := []processor.BusAccess{read(0x8000, 0xE6), read(0x8001, 0x10), read(0x0010, 0x41), write(0x0010, 0x42)}
+	assert.Equal(suite.T(), expected, result.Accesses)
+}
+
+func (suite *StepSuite) TestStep_Branch() {
+	suite.load(0xD0, 0x80) // BNE $80
+
+	result := suite.cpu.Step()
+
+	assert.Equal(suite.T(), uint16(0x7F82), result.Address, "Address should be the branch target")
+	assert.Equal(suite.T(), uint8(4), result.Cycles, "Cycles should include the branch penalties")
+}
+
+func (suite *StepSuite) TestStep_PartlyExecuted() {
+	suite.load(0xAD, 0x00, 0x20, 0xE8) // LDA $2000; INX
+
+	suite.cpu.Clock()
+	result := suite.cpu.Step()
+	assert.Equal(suite.T(), "LDA", result.Operation.Name(), "Step should finish the current instruction")
+	assert.Equal(suite.T(), uint8(3), result.Cycles, "Cycles should be 3")
+	assert.Empty(suite.T(), result.Accesses, "The bus accesses were made on the first cycle")
+
+	result = suite.cpu.Step()
+	assert.Equal(suite.T(), "INX", result.Operation.Name(), "Step should run the next instruction")
+}
+
+func (suite *StepSuite) TestStep_Interrupt() {
+	suite.load(0xEA) // NOP
+	store16(suite.bus, 0xFFFE, 0x9000)
+	suite.cpu.SetFlag(processor.I, false)
+	suite.cpu.AssertIRQ(0)
+
+	assert.Equal(suite.T(), processor.InterruptNone, suite.cpu.Step().Interrupt, "The NOP should run first")
+	result := suite.cpu.Step()
+	assert.Equal(suite.T(), processor.InterruptIRQ, result.Interrupt, "An IRQ should be taken")
+	assert.Equal(suite.T(), uint16(0x8001), result.PC, "PC should be the address of the interrupted instruction")
+	assert.Equal(suite.T(), uint16(0xFFFE), result.Address, "Address should be the IRQ vector")
+	assert.Nil(suite.T(), result.Operation.Instruction, "No instruction should be executed")
+	assert.Equal(suite.T(), uint8(7), result.Cycles, "Cycles should be 7")
+	assert.Equal(suite.T(), uint16(0x9000), suite.cpu.PC, "PC should be 0x9000")
+}
+
+func (suite *StepSuite) TestStep_Idle() {
+	suite.load(0x02) // JAM
+	suite.cpu.Step()
+
+	result := suite.cpu.Step()
+	assert.True(suite.T(), result.Idle, "Step should be idle when the CPU is halted")
+	assert.Equal(suite.T(), uint8(1), result.Cycles, "Cycles should be 1")
+}
+
+func (suite *StepSuite) TestStep_CycleAccurate() {
+	cpu := processor.NewCPUWithVariant(suite.bus, processor.VariantNMOS)
+	assert.NoError(suite.T(), cpu.SetCycleAccurate(true))
+	suite.load(0xE6, 0x10) // INC $10
+	cpu.PC = 0x8000
+
+	result := cpu.Step()
+	assert.Equal(suite.T(), "INC", result.Operation.Name(), "Operation should be INC")
+	assert.Equal(suite.T(), uint16(0x0010), result.Address, "Address should be 0x0010")
+	assert.Equal(suite.T(), uint8(5), result.Cycles, "Cycles should be 5")
+	assert.Len(suite.T(), result.Accesses, 5, "Each cycle should make one bus access")
+}
+
+func TestBusAccess_String(t *testing.T) {
+	assert.Equal(t, "R $1234 = $56", read(0x1234, 0x56).String())
+	assert.Equal(t, "W $1234 = $56", write(0x1234, 0x56).String())
+}
+
+func TestInterrupt_String(t *testing.T) {
+	assert.Equal(t, "none", processor.InterruptNone.String())
+	assert.Equal(t, "IRQ", processor.InterruptIRQ.String())
+	assert.Equal(t, "NMI", processor.InterruptNMI.String())
+	assert.Equal(t, "RESET", processor.InterruptReset.String())
+}
+
+func TestStep_ReusesAccesses(t *testing.T) {
+	cpu := newCPU(processor.VariantNMOS, 0xE6, 0x10, 0x4C, 0x00, 0x80) // INC $10; JMP $8000
+	cpu.Step()
+	allocs := testing.AllocsPerRun(100, func() { cpu.Step() })
+	assert.Zero(t, allocs, "Step should reuse the storage for its bus accesses")
+}
@@ -0,0 +1,182 @@
+package processor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Unimplemented opcodes.
+//
+// An opcode counts as unimplemented if it is not part of the documented instruction set of the variant: the
+// undocumented NMOS opcodes (including JAM and the duplicate SBC at $EB), the reserved 65C02 opcodes that execute
+// as NOPs, and any opcode left as XXX in the variant's table. By default they execute as they do on the real chip,
+// which is what well behaved programs that use them rely on. When code runs away into data, though, it is usually
+// more useful to find out straight away, and the unimplemented opcode policy controls what happens instead.
+
+// UnimplementedPolicy chooses what the CPU does when it fetches an unimplemented opcode.
+type UnimplementedPolicy uint8
+
+const (
+	// UnimplementedExecute executes the opcode as the real chip does (the default).
+	UnimplementedExecute UnimplementedPolicy = iota
+
+	// UnimplementedNOP skips the opcode and its operands, taking the same number of cycles as the real opcode.
+	UnimplementedNOP
+
+	// UnimplementedTrap halts the CPU with HaltUnimplemented, leaving PC pointing at the opcode. Err returns an
+	// UnimplementedOpcodeError.
+	UnimplementedTrap
+
+	// UnimplementedHandler calls the handler set by SetUnimplementedHandler. The opcode takes 2 cycles.
+	UnimplementedHandler
+
+	// UnimplementedInterrupt behaves as if the opcode were BRK, so the program's IRQ/BRK handler can deal with it.
+	UnimplementedInterrupt
+)
+
+var unimplementedPolicyNames = map[UnimplementedPolicy]string{
+	UnimplementedExecute:   "execute",
+	UnimplementedNOP:       "nop",
+	UnimplementedTrap:      "trap",
+	UnimplementedHandler:   "handler",
+	UnimplementedInterrupt: "brk",
+}
+
+// String returns the short name of the policy, as accepted by ParseUnimplementedPolicy.
+func (p UnimplementedPolicy) String() string {
+	if name, ok := unimplementedPolicyNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("UnimplementedPolicy(%d)", uint8(p))
+}
+
+// ParseUnimplementedPolicy returns the policy with the given (case-insensitive) name.
+func ParseUnimplementedPolicy(name string) (UnimplementedPolicy, error) {
+	for p, n := range unimplementedPolicyNames {
+		if strings.EqualFold(n, name) {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown unimplemented opcode policy %q", name)
+}
+
+// UnimplementedHandlerFunc is called by the UnimplementedHandler policy. When it is called PC points at the byte
+// after the opcode; the handler may change PC (for example to skip operands) and any other CPU state.
+type UnimplementedHandlerFunc func(cpu *CPU, opcode byte, pc uint16)
+
+// UnimplementedOpcodeError is reported by Err when the UnimplementedTrap policy halts the CPU.
+type UnimplementedOpcodeError struct {
+	Opcode byte
+	PC     uint16
+}
+
+func (e *UnimplementedOpcodeError) Error() string {
+	return fmt.Sprintf("unimplemented opcode $%02X at $%04X", e.Opcode, e.PC)
+}
+
+// SetUnimplementedPolicy sets what the CPU does when it fetches an unimplemented opcode.
+func (c *CPU) SetUnimplementedPolicy(policy UnimplementedPolicy) {
+	c.unimplementedPolicy = policy
+	c.decodeOpcodes()
+	c.FlushJIT()
+}
+
+// SetUnimplementedHandler sets the handler for unimplemented opcodes and selects the UnimplementedHandler policy.
+func (c *CPU) SetUnimplementedHandler(handler UnimplementedHandlerFunc) {
+	c.unimplementedHandler = handler
+	c.unimplementedPolicy = UnimplementedHandler
+	c.decodeOpcodes()
+	c.FlushJIT()
+}
+
+// Err returns the error that halted the CPU, or nil.
+func (c *CPU) Err() error {
+	return c.err
+}
+
+// decodedOp is an opcode as the CPU executes it under the current unimplemented opcode policy, with what the
+// execution paths need to know about it worked out in advance, so that they never compare mnemonics.
+type decodedOp struct {
+	op          Operation
+	sequence    microSequence // The sequence followed in cycle accurate mode (see cycle_accurate.go)
+	kind        accessKind    // How the instruction uses its operand address in cycle accurate mode
+	brk         bool          // Runs the BRK sequence
+	rti         bool          // Returns from an interrupt handler, so the Return hooks are called
+	delaysMask  bool          // Changes the I flag after the interrupt poll (see delaysInterruptMask)
+	interpreted bool          // Never compiled by the JIT (see jit.go)
+	blockEnd    bool          // Ends a JIT block because it changes the flow of control
+}
+
+// decodeOpcodes builds the decoded table, which the execution paths index by opcode. It is called whenever the
+// unimplemented opcode policy changes.
+func (c *CPU) decodeOpcodes() {
+	for opcode := range c.decoded {
+		op := c.applyPolicy(byte(opcode))
+		brk := opcode == 0x00 || (c.unimplementedPolicy == UnimplementedInterrupt && c.isUnimplemented(byte(opcode)))
+		c.decoded[opcode] = decodedOp{
+			op:          op,
+			sequence:    instructionSequences[op.Mnemonic],
+			kind:        instructionAccess[op.Mnemonic],
+			brk:         brk,
+			rti:         op.Mnemonic == "RTI",
+			delaysMask:  delaysInterruptMask(byte(opcode)),
+			interpreted: c.isUnimplemented(byte(opcode)) || interpretedInstructions[op.Mnemonic],
+			blockEnd:    blockEnds[op.Mnemonic] || op.AddressMode == ModeREL || op.AddressMode == ModeZPR,
+		}
+	}
+}
+
+// applyPolicy returns the operation to execute for an opcode under the unimplemented opcode policy.
+func (c *CPU) applyPolicy(opcode byte) Operation {
+	op := c.operations[opcode]
+	if !c.isUnimplemented(opcode) {
+		return op
+	}
+	switch c.unimplementedPolicy {
+	case UnimplementedNOP:
+		nop := Operation{"NOP", NOP, op.AddressMode, op.Size, op.Cycles, 0, 0, 0}
+		if op.AddressMode == ModeABX || op.AddressMode == ModeABY || op.AddressMode == ModeINDY {
+			nop.Penalty = PenaltyPageCross // NOP reads its operand, so it takes the extra cycle of an indexed read
+		}
+		return nop
+	case UnimplementedTrap:
+		return Operation{"???", XXX, ModeIMP, 1, 2, 0, 0, 0}
+	case UnimplementedHandler:
+		return Operation{"???", callUnimplementedHandler, ModeIMP, 1, 2, 0, 0, 0}
+	case UnimplementedInterrupt:
+		return Operation{"BRK", BRK, ModeIMM, 2, 7, 0, allFlags, I}
+	}
+	return op
+}
+
+// isUnimplemented returns true if the policy applies to an opcode.
+func (c *CPU) isUnimplemented(opcode byte) bool {
+	return c.unimplementedPolicy != UnimplementedExecute && c.instructions.opcodes[opcode].Undocumented
+}
+
+// callUnimplementedHandler is the instruction executed for an unimplemented opcode by the UnimplementedHandler
+// policy.
+func callUnimplementedHandler(cpu *CPU, addressInfo AddressInfo) bool {
+	if cpu.unimplementedHandler != nil {
+		cpu.unimplementedHandler(cpu, cpu.last.Opcode, cpu.last.PC)
+	}
+	return false
+}
+
+// trap halts the CPU on the unimplemented opcode being executed.
+func (c *CPU) trap() {
+	c.err = &UnimplementedOpcodeError{Opcode: c.last.Opcode, PC: c.last.PC}
+	c.halt(HaltUnimplemented)
+}
+
+// undocumentedNames are the instructions that only exist as undocumented NMOS opcodes.
+var undocumentedNames = map[string]bool{
+	"SLO": true, "RLA": true, "SRE": true, "RRA": true, "SAX": true, "LAX": true, "DCP": true, "ISC": true,
+	"ANC": true, "ALR": true, "ARR": true, "XAA": true, "LXA": true, "AHX": true, "TAS": true, "SHX": true,
+	"SHY": true, "LAS": true, "SBX": true, "JAM": true,
+}
+
+// isUndocumented returns true if an opcode, given its mnemonic, is outside the documented instruction set.
+func isUndocumented(opcode byte, name string) bool {
+	return undocumentedNames[name] || (name == "NOP" && opcode != 0xEA) || (name == "SBC" && opcode == 0xEB)
+}
@@ -0,0 +1,113 @@
+package processor_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ukdave/6502_emulator/processor"
+)
+
+func TestUnimplemented_Execute(t *testing.T) {
+	cpu := newCPU(processor.VariantNMOS, 0xA7, 0x10) // LAX $10
+	cpu.Write(0x0010, 0x42)
+	cpu.Step()
+	assert.Equal(t, uint8(0x42), cpu.A, "A should be 0x42")
+	assert.Equal(t, uint8(0x42), cpu.X, "X should be 0x42")
+	assert.NoError(t, cpu.Err())
+}
+
+func TestUnimplemented_NOP(t *testing.T) {
+	cpu := newCPU(processor.VariantNMOS, 0xA7, 0x10) // LAX $10
+	cpu.SetUnimplementedPolicy(processor.UnimplementedNOP)
+	cpu.Write(0x0010, 0x42)
+
+	result := cpu.Step()
+	assert.Equal(t, uint8(0x00), cpu.A, "A should be 0x00")
+	assert.Equal(t, uint8(0x00), cpu.X, "X should be 0x00")
+	assert.Equal(t, uint16(0x8002), cpu.PC, "PC should skip the operand")
+	assert.Equal(t, uint8(3), result.Cycles, "Cycles should be 3")
+}
+
+func TestUnimplemented_Trap(t *testing.T) {
+	for _, cycleAccurate := range []bool{false, true} {
+		cpu := newCPU(processor.VariantNMOS, 0xEA, 0x02) // NOP; JAM
+		cpu.SetUnimplementedPolicy(processor.UnimplementedTrap)
+		assert.NoError(t, cpu.SetCycleAccurate(cycleAccurate))
+
+		cpu.Step()
+		assert.NoError(t, cpu.Err(), "A documented NOP should not trap")
+
+		result := cpu.Step()
+		assert.Equal(t, uint8(2), result.Cycles, "Cycles should be 2")
+		assert.True(t, cpu.Halted(), "CPU should be halted")
+		assert.Equal(t, processor.HaltUnimplemented, cpu.HaltReason(), "Expected the unimplemented opcode to be reported")
+		assert.Equal(t, uint16(0x8001), cpu.PC, "PC should point at the opcode")
+		assert.EqualError(t, cpu.Err(), "unimplemented opcode $02 at $8001")
+
+		var opcodeErr *processor.UnimplementedOpcodeError
+		assert.ErrorAs(t, cpu.Err(), &opcodeErr)
+
+		cpu.Reset()
+		assert.NoError(t, cpu.Err(), "Expected the reset to clear the error")
+	}
+}
+
+func TestUnimplemented_Trap65C02(t *testing.T) {
+	cpu := newCPU(processor.Variant65C02, 0x44, 0x10) // NOP zp (reserved)
+	cpu.SetUnimplementedPolicy(processor.UnimplementedTrap)
+	cpu.Step()
+	assert.EqualError(t, cpu.Err(), "unimplemented opcode $44 at $8000")
+
+	cpu = newCPU(processor.Variant65C02, 0xDB) // STP
+	cpu.SetUnimplementedPolicy(processor.UnimplementedTrap)
+	cpu.Step()
+	assert.NoError(t, cpu.Err(), "STP is documented on the WDC 65C02")
+}
+
+func TestUnimplemented_Handler(t *testing.T) {
+	cpu := newCPU(processor.VariantNMOS, 0x03, 0x10, 0xE8) // SLO ($10,X); INX
+	var gotOpcode byte
+	var gotPC uint16
+	cpu.SetUnimplementedHandler(func(cpu *processor.CPU, opcode byte, pc uint16) {
+		gotOpcode, gotPC = opcode, pc
+		cpu.A = 0x99
+		cpu.PC++ // Skip the operand
+	})
+
+	cpu.Step()
+	assert.Equal(t, uint8(0x03), gotOpcode, "Opcode should be 0x03")
+	assert.Equal(t, uint16(0x8000), gotPC, "PC should be 0x8000")
+	assert.Equal(t, uint8(0x99), cpu.A, "A should be 0x99")
+
+	assert.Equal(t, "INX", cpu.Step().Operation.Name(), "Execution should continue after the operand")
+}
+
+func TestUnimplemented_Interrupt(t *testing.T) {
+	for _, cycleAccurate := range []bool{false, true} {
+		cpu := newCPU(processor.VariantNMOS, 0x02) // JAM
+		cpu.SetUnimplementedPolicy(processor.UnimplementedInterrupt)
+		assert.NoError(t, cpu.SetCycleAccurate(cycleAccurate))
+		cpu.Write16(0xFFFE, 0x9000)
+		cpu.SP = 0xFF
+
+		result := cpu.Step()
+		assert.Equal(t, uint8(7), result.Cycles, "Cycles should be 7")
+		assert.Equal(t, uint16(0x9000), cpu.PC, "PC should be 0x9000")
+		assert.False(t, cpu.Halted(), "CPU should not be halted")
+		assert.Equal(t, uint16(0x8002), cpu.Read16(0x01FE), "The return address should skip the signature byte")
+		assert.NotZero(t, cpu.Read(0x01FD)&byte(processor.B), "The B flag should be pushed")
+	}
+}
+
+func TestUnimplementedPolicy_String(t *testing.T) {
+	assert.Equal(t, "trap", processor.UnimplementedTrap.String())
+	assert.Equal(t, "UnimplementedPolicy(99)", processor.UnimplementedPolicy(99).String())
+
+	policy, err := processor.ParseUnimplementedPolicy("BRK")
+	assert.NoError(t, err)
+	assert.Equal(t, processor.UnimplementedInterrupt, policy)
+
+	_, err = processor.ParseUnimplementedPolicy("bogus")
+	assert.Error(t, err)
+}
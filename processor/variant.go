@@ -0,0 +1,79 @@
+package processor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Variant identifies which member of the 6502 family the CPU emulates.
+//
+// The members of the family share the same core instruction set but differ in the details: whether decimal mode
+// works, how the undocumented opcodes behave, and so on. The variant is chosen when the CPU is created and cannot
+// be changed afterwards.
+type Variant uint8
+
+const (
+	// Variant2A03 is the Ricoh 2A03 used in the NES. It is an NMOS 6502 with the decimal mode circuitry removed, so
+	// the D flag can still be set and cleared but ADC and SBC always perform binary arithmetic.
+	Variant2A03 Variant = iota
+
+	// VariantNMOS is the original MOS Technology 6502. ADC and SBC perform BCD arithmetic when the D flag is set,
+	// including the well known (but undocumented) behaviour of the N, V and Z flags in decimal mode.
+	VariantNMOS
+
+	// Variant65C02 is the WDC W65C02S, the CMOS 6502 still in production today. It adds new instructions (BRA,
+	// PHX/PHY/PLX/PLY, STZ, TRB/TSB, the Rockwell bit instructions RMB/SMB/BBR/BBS, and WAI/STP) and new addressing
+	// modes, and fixes the NMOS quirks: JMP ($xxFF) reads across the page boundary, interrupts clear the D flag, and
+	// the N, V and Z flags are valid after decimal arithmetic. Every unused opcode is a NOP.
+	Variant65C02
+
+	// VariantR65C02 is the Rockwell R65C02. It is the same as the WDC part but without the WAI and STP instructions.
+	VariantR65C02
+)
+
+var variantNames = map[Variant]string{
+	Variant2A03:   "2A03",
+	VariantNMOS:   "NMOS",
+	Variant65C02:  "65C02",
+	VariantR65C02: "R65C02",
+}
+
+// String returns the short name of the variant, as accepted by ParseVariant.
+func (v Variant) String() string {
+	if name, ok := variantNames[v]; ok {
+		return name
+	}
+	return fmt.Sprintf("Variant(%d)", uint8(v))
+}
+
+// ParseVariant returns the variant with the given (case-insensitive) name.
+func ParseVariant(name string) (Variant, error) {
+	for v, n := range variantNames {
+		if strings.EqualFold(n, name) {
+			return v, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown CPU variant %q", name)
+}
+
+// hasDecimalMode returns true if ADC and SBC honour the D flag on this variant.
+func (v Variant) hasDecimalMode() bool {
+	return v != Variant2A03
+}
+
+// isCMOS returns true for the CMOS members of the family (the 65C02 and its derivatives).
+func (v Variant) isCMOS() bool {
+	return v == Variant65C02 || v == VariantR65C02
+}
+
+// operations returns the opcode lookup table for this variant.
+func (v Variant) operations() *[256]Operation {
+	switch v {
+	case Variant65C02:
+		return &wdc65c02Operations
+	case VariantR65C02:
+		return &rockwell65c02Operations
+	default:
+		return &nmosOperations
+	}
+}
@@ -0,0 +1,189 @@
+// Package snapshot saves and restores the state of an emulator (save states).
+//
+// A snapshot is made up of sections, one for each component of the emulator (the CPU, the RAM, and any devices on
+// the bus). Each component implements Section, and is responsible for the format of its own section.
+//
+// The file format is little-endian throughout:
+//
+//	magic    [8]byte  "6502SNAP"
+//	version  uint16   the format version (Version)
+//	sections          repeated until the end of the file:
+//	  id      [4]byte  the section ID (for example "CPU " or "RAM ")
+//	  length  uint32   the length of the data in bytes
+//	  data    [length]byte
+//
+// Sections may appear in any order. Sections that are not asked for when loading are skipped, so a snapshot
+// taken from an emulator with more devices can still be loaded into one with fewer.
+package snapshot
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Version is the version of the snapshot format written by Save. It is bumped whenever the format of any section
+// changes.
+const Version uint16 = 1
+
+var magic = [8]byte{'6', '5', '0', '2', 'S', 'N', 'A', 'P'}
+
+// Section is implemented by each component of the emulator whose state is saved in a snapshot.
+type Section interface {
+	// SnapshotID returns the 4 character ID of the component's section. It must be unique within a snapshot.
+	SnapshotID() string
+
+	// SaveSnapshot writes the component's state to w.
+	SaveSnapshot(w io.Writer) error
+
+	// LoadSnapshot restores the component's state from r, which contains the data written by SaveSnapshot.
+	LoadSnapshot(r io.Reader) error
+}
+
+// Committer is implemented by sections that discard state derived from their old state, such as caches or a history,
+// when they are restored. They discard it in CommitSnapshot rather than LoadSnapshot, so that it survives a load that
+// is rolled back.
+type Committer interface {
+	// CommitSnapshot is called by Load once every section has been restored.
+	CommitSnapshot()
+}
+
+type header struct {
+	Magic   [8]byte
+	Version uint16
+}
+
+type sectionHeader struct {
+	ID     [4]byte
+	Length uint32
+}
+
+// Save writes a snapshot of the given sections to w.
+func Save(w io.Writer, sections ...Section) error {
+	if err := binary.Write(w, binary.LittleEndian, header{magic, Version}); err != nil {
+		return err
+	}
+	seen := map[string]bool{}
+	for _, section := range sections {
+		id := section.SnapshotID()
+		if len(id) != 4 {
+			return fmt.Errorf("snapshot section ID %q is not 4 characters", id)
+		}
+		if seen[id] {
+			return fmt.Errorf("duplicate snapshot section %q", id)
+		}
+		seen[id] = true
+
+		var data bytes.Buffer
+		if err := section.SaveSnapshot(&data); err != nil {
+			return fmt.Errorf("saving snapshot section %q: %w", id, err)
+		}
+		sh := sectionHeader{Length: uint32(data.Len())}
+		copy(sh.ID[:], id)
+		if err := binary.Write(w, binary.LittleEndian, sh); err != nil {
+			return err
+		}
+		if _, err := data.WriteTo(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Load restores the given sections from a snapshot read from r. Every section must be present in the snapshot.
+// Nothing is restored if the snapshot cannot be read or is missing a section, and if a section rejects its data, the
+// sections restored before it are put back the way they were.
+func Load(r io.Reader, sections ...Section) error {
+	var h header
+	if err := binary.Read(r, binary.LittleEndian, &h); err != nil {
+		return fmt.Errorf("reading snapshot header: %w", err)
+	}
+	if h.Magic != magic {
+		return errors.New("not a snapshot file")
+	}
+	if h.Version != Version {
+		return fmt.Errorf("unsupported snapshot version %d (expected %d)", h.Version, Version)
+	}
+
+	data := map[string][]byte{}
+	for {
+		var sh sectionHeader
+		err := binary.Read(r, binary.LittleEndian, &sh)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("reading snapshot section header: %w", err)
+		}
+		// The length is not trusted to allocate the data up front, as a corrupt file could claim up to 4GB
+		var payload bytes.Buffer
+		if n, err := payload.ReadFrom(io.LimitReader(r, int64(sh.Length))); err != nil {
+			return fmt.Errorf("reading snapshot section %q: %w", sh.ID[:], err)
+		} else if n < int64(sh.Length) {
+			return fmt.Errorf("reading snapshot section %q: %w", sh.ID[:], io.ErrUnexpectedEOF)
+		}
+		data[string(sh.ID[:])] = payload.Bytes()
+	}
+
+	for _, section := range sections {
+		if _, ok := data[section.SnapshotID()]; !ok {
+			return fmt.Errorf("snapshot has no %q section", section.SnapshotID())
+		}
+	}
+	// Save the current state of each section, to put it back if a later section is rejected
+	backups := make([]bytes.Buffer, len(sections))
+	for i, section := range sections {
+		if err := section.SaveSnapshot(&backups[i]); err != nil {
+			return fmt.Errorf("saving snapshot section %q: %w", section.SnapshotID(), err)
+		}
+	}
+	for i, section := range sections {
+		if err := section.LoadSnapshot(bytes.NewReader(data[section.SnapshotID()])); err != nil {
+			err = fmt.Errorf("loading snapshot section %q: %w", section.SnapshotID(), err)
+			for j := range sections[:i+1] {
+				if rerr := sections[j].LoadSnapshot(&backups[j]); rerr != nil {
+					err = errors.Join(err, fmt.Errorf("restoring section %q: %w", sections[j].SnapshotID(), rerr))
+				}
+			}
+			return err
+		}
+	}
+	for _, section := range sections {
+		if committer, ok := section.(Committer); ok {
+			committer.CommitSnapshot()
+		}
+	}
+	return nil
+}
+
+// SaveFile writes a snapshot of the given sections to the named file, replacing it if it exists.
+func SaveFile(name string, sections ...Section) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	if err := Save(w, sections...); err != nil {
+		f.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LoadFile restores the given sections from a snapshot in the named file.
+func LoadFile(name string, sections ...Section) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return Load(bufio.NewReader(f), sections...)
+}
@@ -0,0 +1,195 @@
+package snapshot_test
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ukdave/6502_emulator/bus"
+	"github.com/ukdave/6502_emulator/internal/cputest"
+	"github.com/ukdave/6502_emulator/processor"
+	"github.com/ukdave/6502_emulator/snapshot"
+)
+
+// program counts down X and increments memory at $10 in a loop
+var program = []byte{
+	0xA2, 0xFF, // LDX #$FF
+	0xE6, 0x10, // INC $10
+	0xCA,       // DEX
+	0xD0, 0xFB, // BNE -5
+	0x02, // JAM
+}
+
+// newMachine creates a CPU and RAM with the program loaded at 0x8000
+func newMachine(variant processor.Variant, cycleAccurate bool) (*processor.CPU, *bus.SimpleBus) {
+	cpu, ram := cputest.New(variant, 0x8000, program...)
+	ram.Write(0xFFFC, 0x00)
+	ram.Write(0xFFFD, 0x80)
+	if err := cpu.SetCycleAccurate(cycleAccurate); err != nil {
+		panic(err)
+	}
+	return cpu, ram
+}
+
+// runToHalt clocks the CPU until it halts and returns the number of cycles taken
+func runToHalt(cpu *processor.CPU) uint64 {
+	for !cpu.Halted() {
+		cpu.Clock()
+	}
+	return cpu.TotalCycles
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	for _, cycleAccurate := range []bool{false, true} {
+		cpu, ram := newMachine(processor.VariantNMOS, cycleAccurate)
+		for range 1001 { // Stop part way through an instruction
+			cpu.Clock()
+		}
+		var buf bytes.Buffer
+		assert.NoError(t, snapshot.Save(&buf, cpu, ram))
+
+		restoredCPU, restoredRAM := newMachine(processor.VariantNMOS, false)
+		restoredRAM.Write(0x0010, 0x99)
+		assert.NoError(t, snapshot.Load(&buf, restoredCPU, restoredRAM))
+		assert.Equal(t, cycleAccurate, restoredCPU.CycleAccurate(), "The execution mode should be restored")
+		assert.Equal(t, cpu.Cycles(), restoredCPU.Cycles(), "The remaining cycles should be restored")
+
+		assert.Equal(t, runToHalt(cpu), runToHalt(restoredCPU), "Both CPUs should halt on the same cycle")
+		assert.Equal(t, cpu.PC, restoredCPU.PC, "PC should match")
+		assert.Equal(t, cpu.Status, restoredCPU.Status, "Status should match")
+		assert.Equal(t, uint8(0xFF), restoredRAM.Read(0x0010), "Memory at 0x0010 should be 0xFF")
+	}
+}
+
+func TestSaveAndLoadFile(t *testing.T) {
+	cpu, ram := newMachine(processor.Variant2A03, false)
+	cpu.Step()
+	cpu.A = 0x42
+
+	name := filepath.Join(t.TempDir(), "test.snap")
+	assert.NoError(t, snapshot.SaveFile(name, cpu, ram))
+
+	restored, restoredRAM := newMachine(processor.Variant2A03, false)
+	assert.NoError(t, snapshot.LoadFile(name, restored, restoredRAM))
+	assert.Equal(t, uint8(0x42), restored.A, "A should be 0x42")
+	assert.Equal(t, uint8(0xFF), restored.X, "X should be 0xFF")
+	assert.Equal(t, uint16(0x8002), restored.PC, "PC should be 0x8002")
+	assert.Equal(t, cpu.TotalCycles, restored.TotalCycles, "TotalCycles should match")
+}
+
+func TestSaveAndLoad_BankedMemory(t *testing.T) {
+	banked, err := bus.NewBankedRAM("BANK", 0x8000, 0x1000, 0x1000)
+	assert.NoError(t, err)
+	banked.Select(1, 5)
+	banked.Write(0x1010, 0x42)
+	var buf bytes.Buffer
+	assert.NoError(t, snapshot.Save(&buf, banked))
+	saved := buf.Bytes()
+
+	restored, err := bus.NewBankedRAM("BANK", 0x8000, 0x1000, 0x1000)
+	assert.NoError(t, err)
+	assert.NoError(t, snapshot.Load(bytes.NewReader(saved), restored))
+	assert.Equal(t, 5, restored.Bank(1), "The bank in each window should be restored")
+	assert.Equal(t, uint8(0x42), restored.Read(0x1010), "The banked contents should be restored")
+	restored.Select(1, 0)
+	assert.Equal(t, uint8(0x00), restored.Read(0x1010), "Other banks should be unchanged")
+
+	different, err := bus.NewBankedRAM("BANK", 0x4000, 0x1000, 0x1000)
+	assert.NoError(t, err)
+	assert.ErrorContains(t, snapshot.Load(bytes.NewReader(saved), different), "the device has 2 and 16384")
+}
+
+func TestLoad_Errors(t *testing.T) {
+	cpu, ram := newMachine(processor.VariantNMOS, false)
+	var buf bytes.Buffer
+	assert.NoError(t, snapshot.Save(&buf, cpu))
+	saved := buf.Bytes()
+
+	err := snapshot.Load(bytes.NewReader(saved), cpu, ram)
+	assert.EqualError(t, err, `snapshot has no "RAM " section`)
+
+	err = snapshot.Load(bytes.NewReader([]byte("not a snapshot")), cpu)
+	assert.EqualError(t, err, "not a snapshot file")
+
+	future := bytes.Clone(saved)
+	future[8] = 0x99
+	err = snapshot.Load(bytes.NewReader(future), cpu)
+	assert.EqualError(t, err, "unsupported snapshot version 153 (expected 1)")
+
+	err = snapshot.Load(bytes.NewReader(saved[:len(saved)-1]), cpu)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	other, _ := newMachine(processor.Variant2A03, false)
+	err = snapshot.Load(bytes.NewReader(saved), other)
+	assert.EqualError(t, err, `loading snapshot section "CPU ": snapshot is of a NMOS CPU, not 2A03`)
+
+	huge := bytes.Clone(saved[:10+8])
+	copy(huge[10+4:], []byte{0xFF, 0xFF, 0xFF, 0xFF}) // A section claiming to be 4GB long
+	err = snapshot.Load(bytes.NewReader(append(huge, 0x00)), cpu)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestLoad_RejectedSection(t *testing.T) {
+	cpu, ram := newMachine(processor.VariantNMOS, false)
+	banked, err := bus.NewBankedRAM("BANK", 0x8000, 0x1000)
+	assert.NoError(t, err)
+	for range 100 {
+		cpu.Clock()
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, snapshot.Save(&buf, cpu, ram, banked))
+
+	restoredCPU, restoredRAM := newMachine(processor.VariantNMOS, false)
+	restoredCPU.EnableHistory(10)
+	restoredCPU.Step()
+	restoredRAM.Write(0x0010, 0x99)
+	different, err := bus.NewBankedRAM("BANK", 0x4000, 0x1000)
+	assert.NoError(t, err)
+	err = snapshot.Load(bytes.NewReader(buf.Bytes()), restoredCPU, restoredRAM, different)
+	assert.ErrorContains(t, err, `loading snapshot section "BANK"`)
+	assert.Equal(t, uint64(2), restoredCPU.TotalCycles, "The CPU should be put back the way it was")
+	assert.Equal(t, uint8(0x99), restoredRAM.Read(0x0010), "The RAM should be put back the way it was")
+	assert.Equal(t, 1, restoredCPU.HistoryLen(), "The history should be kept")
+
+	assert.NoError(t, snapshot.Load(bytes.NewReader(buf.Bytes()), restoredCPU, restoredRAM, banked))
+	assert.Equal(t, 0, restoredCPU.HistoryLen(), "The history should be discarded once the snapshot has loaded")
+}
+
+// device is a section that is saved alongside the CPU
+type device struct {
+	id    string
+	value byte
+}
+
+func (d *device) SnapshotID() string { return d.id }
+
+func (d *device) SaveSnapshot(w io.Writer) error {
+	_, err := w.Write([]byte{d.value})
+	return err
+}
+
+func (d *device) LoadSnapshot(r io.Reader) error {
+	b := make([]byte, 1)
+	_, err := io.ReadFull(r, b)
+	d.value = b[0]
+	return err
+}
+
+func TestLoad_ExtraSections(t *testing.T) {
+	cpu, _ := newMachine(processor.VariantNMOS, false)
+	var buf bytes.Buffer
+	assert.NoError(t, snapshot.Save(&buf, &device{"VIA1", 1}, cpu, &device{"VIA2", 2}))
+
+	via2 := &device{id: "VIA2"}
+	assert.NoError(t, snapshot.Load(&buf, cpu, via2), "Sections that are not asked for should be skipped")
+	assert.Equal(t, byte(2), via2.value, "The device should be restored")
+}
+
+func TestSave_Errors(t *testing.T) {
+	var buf bytes.Buffer
+	assert.EqualError(t, snapshot.Save(&buf, &device{id: "TOOLONG"}), `snapshot section ID "TOOLONG" is not 4 characters`)
+	assert.EqualError(t, snapshot.Save(&buf, &device{id: "DEV "}, &device{id: "DEV "}), `duplicate snapshot section "DEV "`)
+}
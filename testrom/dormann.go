@@ -0,0 +1,132 @@
+// Package testrom runs well known test programs for the 6502 and reports whether the CPU passed them.
+//
+// The programs themselves are not distributed with the emulator, so they are loaded from files supplied by the
+// user.
+package testrom
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ukdave/6502_emulator/bus"
+	"github.com/ukdave/6502_emulator/processor"
+)
+
+// Test describes how to run one of Klaus Dormann's 6502 test programs
+// (https://github.com/Klaus2m5/6502_65C02_functional_tests).
+//
+// The programs run until they reach a trap, which is a branch or jump to itself. Whether they passed depends on
+// which trap they stopped at or, for programs that always finish in the same place, on a value they leave in memory.
+type Test struct {
+	Name         string
+	LoadAddress  uint16 // Where the binary is loaded
+	StartAddress uint16 // Where execution starts
+
+	// SuccessAddress is the trap that is reached once every test has passed. Any other trap is a failure.
+	SuccessAddress uint16
+
+	// ErrorAddress, used when SuccessAddress is zero, holds zero if every test passed.
+	ErrorAddress uint16
+
+	// EndOpcode, if non-zero, also stops the program when it is about to be executed.
+	EndOpcode byte
+
+	// Report are the variables whose values are reported if the program fails.
+	Report []Variable
+}
+
+// Variable is a byte of memory used by a test program.
+type Variable struct {
+	Name    string
+	Address uint16
+}
+
+// DormannFunctional is 6502_functional_test.bin, which tests every documented NMOS instruction and address mode.
+// It is a 64 KB image, so it is loaded at $0000 and overwrites the vectors. This is the binary from the repository's
+// bin_files directory; if you assemble it yourself with a different configuration, copy this and change
+// SuccessAddress to the address of the success trap in your listing.
+var DormannFunctional = Test{
+	Name:           "6502_functional_test",
+	LoadAddress:    0x0000,
+	StartAddress:   0x0400,
+	SuccessAddress: 0x3469,
+	Report:         []Variable{{"test case", 0x0200}},
+}
+
+// DormannDecimal is 6502_decimal_test.bin, which checks the result and carry of ADC and SBC for every pair of
+// operands in decimal mode. It is assembled at $0200 and sets ERROR to zero if every operation was correct. It ends
+// with $DB, which is STP on the 65C02 but not an instruction that stops the NMOS 6502.
+var DormannDecimal = Test{
+	Name:         "6502_decimal_test",
+	LoadAddress:  0x0200,
+	StartAddress: 0x0200,
+	ErrorAddress: 0x000B,
+	EndOpcode:    0xDB,
+	Report:       []Variable{{"N1", 0x0000}, {"N2", 0x0001}},
+}
+
+// Result is the outcome of running a test program.
+type Result struct {
+	Test   string
+	Passed bool
+	PC     uint16 // Where the program stopped
+	Cycles uint64 // Cycles taken
+	Values []byte // Values of the test's Report variables when it stopped
+	report []Variable
+}
+
+// String describes the result, for example "6502_functional_test failed at $0A3C after 1234 cycles (test case
+// $29)".
+func (r Result) String() string {
+	outcome := "failed"
+	if r.Passed {
+		outcome = "passed"
+	}
+	s := fmt.Sprintf("%s %s at $%04X after %d cycles", r.Test, outcome, r.PC, r.Cycles)
+	if !r.Passed && len(r.Values) > 0 {
+		values := make([]string, len(r.Values))
+		for i, value := range r.Values {
+			values[i] = fmt.Sprintf("%s $%02X", r.report[i].Name, value)
+		}
+		s += " (" + strings.Join(values, ", ") + ")"
+	}
+	return s
+}
+
+// Run loads the test program into RAM and runs it on the CPU until it stops, then reports whether it passed. It
+// returns an error if the program does not fit in memory, or if it has not stopped after maxCycles cycles (0 for no
+// limit).
+func (t Test) Run(cpu *processor.CPU, ram *bus.SimpleBus, program []byte, maxCycles uint64) (Result, error) {
+	if int(t.LoadAddress)+len(program) > 0x10000 {
+		return Result{}, fmt.Errorf("%s: %d bytes do not fit in memory at $%04X", t.Name, len(program), t.LoadAddress)
+	}
+	for i, b := range program {
+		ram.Write(t.LoadAddress+uint16(i), b)
+	}
+	cpu.PC = t.StartAddress
+
+	// A trap is an instruction that leaves PC where it was
+	last := -1
+	stopped := func(cpu *processor.CPU) bool {
+		if int(cpu.PC) == last || (t.EndOpcode != 0 && ram.Read(cpu.PC) == t.EndOpcode) {
+			return true
+		}
+		last = int(cpu.PC)
+		return false
+	}
+	cycles := cpu.RunUntil(stopped, maxCycles)
+	if cpu.HaltReason() == processor.HaltNone && !stopped(cpu) {
+		return Result{}, fmt.Errorf("%s: still running at $%04X after %d cycles", t.Name, cpu.PC, cycles)
+	}
+
+	result := Result{Test: t.Name, PC: cpu.PC, Cycles: cycles, report: t.Report}
+	if t.SuccessAddress != 0 {
+		result.Passed = cpu.PC == t.SuccessAddress
+	} else {
+		result.Passed = ram.Read(t.ErrorAddress) == 0
+	}
+	for _, v := range t.Report {
+		result.Values = append(result.Values, ram.Read(v.Address))
+	}
+	return result, nil
+}
@@ -0,0 +1,98 @@
+package testrom_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ukdave/6502_emulator/internal/cputest"
+	"github.com/ukdave/6502_emulator/processor"
+	"github.com/ukdave/6502_emulator/testrom"
+)
+
+// trapTest is a small program in the style of the functional test: it stores a test number, then traps at $0407 if
+// A isn't 1 or at $040A if it is
+var trapTest = testrom.Test{
+	Name:           "trap",
+	LoadAddress:    0x0400,
+	StartAddress:   0x0400,
+	SuccessAddress: 0x040A,
+	Report:         []testrom.Variable{{"test case", 0x0200}},
+}
+
+func trapProgram(a byte) []byte {
+	return []byte{
+		0xA9, a, //          LDA #a
+		0x8D, 0x00, 0x02, // STA $0200
+		0xC9, 0x01, //       CMP #$01
+		0xD0, 0xFE, //       BNE *
+		0xEA,             // NOP
+		0x4C, 0x0A, 0x04, // JMP *
+	}
+}
+
+func TestRun_Passed(t *testing.T) {
+	cpu, ram := cputest.New(processor.VariantNMOS, 0x0000)
+	result, err := trapTest.Run(cpu, ram, trapProgram(0x01), 1000)
+	assert.NoError(t, err)
+	assert.True(t, result.Passed, "The program should pass")
+	assert.Equal(t, uint16(0x040A), result.PC, "The program should stop at the success trap")
+	assert.Equal(t, "trap passed at $040A after 15 cycles", result.String())
+}
+
+func TestRun_Failed(t *testing.T) {
+	cpu, ram := cputest.New(processor.VariantNMOS, 0x0000)
+	result, err := trapTest.Run(cpu, ram, trapProgram(0x29), 1000)
+	assert.NoError(t, err)
+	assert.False(t, result.Passed, "The program should fail")
+	assert.Equal(t, []byte{0x29}, result.Values, "The test case should be reported")
+	assert.Equal(t, "trap failed at $0407 after 11 cycles (test case $29)", result.String())
+}
+
+func TestRun_ErrorAddress(t *testing.T) {
+	test := testrom.Test{Name: "error", LoadAddress: 0x0200, StartAddress: 0x0200, ErrorAddress: 0x000B, EndOpcode: 0xDB}
+	for _, errorValue := range []byte{0x00, 0x01} {
+		cpu, ram := cputest.New(processor.VariantNMOS, 0x0000)
+		program := []byte{0xA9, errorValue, 0x85, 0x0B, 0xDB} // LDA #errorValue; STA $0B; end of test
+		result, err := test.Run(cpu, ram, program, 1000)
+		assert.NoError(t, err)
+		assert.Equal(t, errorValue == 0, result.Passed, "The program should pass if ERROR is 0")
+		assert.Equal(t, uint16(0x0204), result.PC, "The program should stop at the end opcode")
+	}
+}
+
+func TestRun_Errors(t *testing.T) {
+	cpu, ram := cputest.New(processor.VariantNMOS, 0x0000)
+	_, err := trapTest.Run(cpu, ram, make([]byte, 0xFC01), 1000)
+	assert.EqualError(t, err, "trap: 64513 bytes do not fit in memory at $0400")
+
+	cpu, ram = cputest.New(processor.VariantNMOS, 0x0000)
+	_, err = trapTest.Run(cpu, ram, []byte{0xE8, 0x4C, 0x00, 0x04}, 1000) // INX; JMP $0400
+	assert.EqualError(t, err, "trap: still running at $0400 after 1000 cycles")
+}
+
+// Klaus Dormann's test programs aren't part of this repository. Put 6502_functional_test.bin and
+// 6502_decimal_test.bin in testdata, or point DORMANN_TESTS at the directory holding them, to run them.
+func TestDormann(t *testing.T) {
+	dir := os.Getenv("DORMANN_TESTS")
+	if dir == "" {
+		dir = "testdata"
+	}
+	for _, test := range []testrom.Test{testrom.DormannFunctional, testrom.DormannDecimal} {
+		t.Run(test.Name, func(t *testing.T) {
+			program, err := os.ReadFile(filepath.Join(dir, test.Name+".bin"))
+			if os.IsNotExist(err) {
+				t.Skipf("%s.bin not found in %s", test.Name, dir)
+			}
+			assert.NoError(t, err)
+
+			cpu, ram := cputest.New(processor.VariantNMOS, 0x0000)
+			cpu.SetJIT(true)
+			result, err := test.Run(cpu, ram, program, 200_000_000)
+			assert.NoError(t, err)
+			assert.True(t, result.Passed, result.String())
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package testrom
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ukdave/6502_emulator/bus"
+)
+
+// INES is a NES cartridge image in the iNES format (https://www.nesdev.org/wiki/INES).
+type INES struct {
+	PRG    []byte // Program ROM, in 16 KB units
+	CHR    []byte // Character ROM, in 8 KB units
+	Mapper uint8
+}
+
+var inesMagic = []byte{'N', 'E', 'S', 0x1A}
+
+// ParseINES parses an iNES image.
+func ParseINES(data []byte) (*INES, error) {
+	if len(data) < 16 || !bytes.Equal(data[:4], inesMagic) {
+		return nil, fmt.Errorf("not an iNES image")
+	}
+	prgSize, chrSize := int(data[4])*0x4000, int(data[5])*0x2000
+	flags6, flags7 := data[6], data[7]
+
+	offset := 16
+	if flags6&0x04 != 0 {
+		offset += 512 // Skip the trainer
+	}
+	if len(data) < offset+prgSize+chrSize {
+		return nil, fmt.Errorf("iNES image is %d bytes, expected at least %d", len(data), offset+prgSize+chrSize)
+	}
+	return &INES{
+		PRG:    data[offset : offset+prgSize],
+		CHR:    data[offset+prgSize : offset+prgSize+chrSize],
+		Mapper: flags6>>4 | flags7&0xF0,
+	}, nil
+}
+
+// LoadNROM copies the program ROM of an NROM (mapper 0) cartridge into RAM at $8000. A 16 KB ROM is mirrored at
+// $C000, as it is on the NES.
+func (c *INES) LoadNROM(ram *bus.SimpleBus) error {
+	if c.Mapper != 0 {
+		return fmt.Errorf("mapper %d is not supported, only NROM (mapper 0)", c.Mapper)
+	}
+	if len(c.PRG) != 0x4000 && len(c.PRG) != 0x8000 {
+		return fmt.Errorf("NROM program ROM must be 16 KB or 32 KB, not %d bytes", len(c.PRG))
+	}
+	for addr := 0x8000; addr <= 0xFFFF; addr++ {
+		ram.Write(uint16(addr), c.PRG[(addr-0x8000)%len(c.PRG)])
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package testrom
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/ukdave/6502_emulator/bus"
+	"github.com/ukdave/6502_emulator/processor"
+)
+
+// nestest (https://www.nesdev.org/wiki/Emulator_tests) exercises every documented 6502 instruction and most of the
+// undocumented ones. Started at $C000 rather than at its reset vector it runs in automation mode, without needing a
+// PPU, and nestest.log records the state of the CPU before each instruction it executes. Comparing a trace in the
+// same format against that log finds the first instruction that the emulator gets wrong.
+
+// NestestStart is where nestest starts in automation mode.
+const NestestStart = 0xC000
+
+// LoadNestest loads nestest.nes into RAM. The APU and I/O registers, which nestest.log shows reading as $FF, are set
+// to $FF.
+func LoadNestest(ram *bus.SimpleBus, data []byte) error {
+	cartridge, err := ParseINES(data)
+	if err != nil {
+		return err
+	}
+	if err := cartridge.LoadNROM(ram); err != nil {
+		return err
+	}
+	for addr := uint16(0x4000); addr <= 0x401F; addr++ {
+		ram.Write(addr, 0xFF)
+	}
+	return nil
+}
+
+// nestestNames are the mnemonics that nestest.log spells differently.
+var nestestNames = map[string]string{"ISC": "ISB"}
+
+// NestestTrace returns the line of nestest.log for the instruction at PC, for example
+//
+//	C000  4C F5 C5  JMP $C5F5                       A:00 X:00 Y:00 P:24 SP:FD CYC:7
+//
+// The log's PPU column is left out, as there is no PPU to report on.
+func NestestTrace(cpu *processor.CPU) string {
+	op := cpu.DisassembleOperation(cpu.PC)
+	info := op.Instruction
+
+	bytes := make([]string, len(op.Bytes))
+	for i, b := range op.Bytes {
+		bytes[i] = fmt.Sprintf("%02X", b)
+	}
+	marker := " "
+	if info.Undocumented {
+		marker = "*"
+	}
+	name := info.Mnemonic
+	if alias, ok := nestestNames[name]; ok {
+		name = alias
+	}
+
+	return fmt.Sprintf("%04X  %-9s%s%-32sA:%02X X:%02X Y:%02X P:%02X SP:%02X CYC:%d", cpu.PC,
+		strings.Join(bytes, " "), marker, name+nestestOperand(cpu, op), cpu.A, cpu.X, cpu.Y, cpu.Status, cpu.SP,
+		cpu.TotalCycles)
+}
+
+// nestestOperand formats the operand of an instruction as nestest.log does, with the effective address and the
+// value in memory before the instruction executes.
+func nestestOperand(cpu *processor.CPU, op processor.DisassembledOperation) string {
+	info := op.Instruction
+	operand, zp := op.Operand, byte(op.Operand)
+	// read16zp reads a pointer from the zero page, wrapping around within it
+	read16zp := func(addr byte) uint16 {
+		return uint16(cpu.Peek(uint16(addr))) | uint16(cpu.Peek(uint16(addr+1)))<<8
+	}
+
+	switch info.Mode {
+	case processor.ModeACC:
+		return " A"
+	case processor.ModeIMM:
+		return fmt.Sprintf(" #$%02X", zp)
+	case processor.ModeZP0:
+		return fmt.Sprintf(" $%02X = %02X", zp, cpu.Peek(uint16(zp)))
+	case processor.ModeZPX, processor.ModeZPY:
+		index, register := cpu.X, "X"
+		if info.Mode == processor.ModeZPY {
+			index, register = cpu.Y, "Y"
+		}
+		addr := zp + index
+		return fmt.Sprintf(" $%02X,%s @ %02X = %02X", zp, register, addr, cpu.Peek(uint16(addr)))
+	case processor.ModeABS:
+		if info.Mnemonic == "JMP" || info.Mnemonic == "JSR" {
+			return fmt.Sprintf(" $%04X", operand)
+		}
+		return fmt.Sprintf(" $%04X = %02X", operand, cpu.Peek(operand))
+	case processor.ModeABX, processor.ModeABY:
+		index, register := cpu.X, "X"
+		if info.Mode == processor.ModeABY {
+			index, register = cpu.Y, "Y"
+		}
+		addr := operand + uint16(index)
+		return fmt.Sprintf(" $%04X,%s @ %04X = %02X", operand, register, addr, cpu.Peek(addr))
+	case processor.ModeIND:
+		// The NMOS 6502 doesn't carry into the high byte of the pointer
+		target := uint16(cpu.Peek(operand)) | uint16(cpu.Peek(operand&0xFF00|uint16(byte(operand)+1)))<<8
+		return fmt.Sprintf(" ($%04X) = %04X", operand, target)
+	case processor.ModeINDX:
+		pointer := zp + cpu.X
+		addr := read16zp(pointer)
+		return fmt.Sprintf(" ($%02X,X) @ %02X = %04X = %02X", zp, pointer, addr, cpu.Peek(addr))
+	case processor.ModeINDY:
+		base := read16zp(zp)
+		addr := base + uint16(cpu.Y)
+		return fmt.Sprintf(" ($%02X),Y = %04X @ %04X = %02X", zp, base, addr, cpu.Peek(addr))
+	case processor.ModeREL:
+		return fmt.Sprintf(" $%04X", cpu.PC+2+uint16(int8(zp)))
+	}
+	return ""
+}
+
+// Divergence is the first line of a trace that differs from the golden log.
+type Divergence struct {
+	Line     int // Line number, starting from 1
+	Expected string
+	Got      string
+}
+
+// String shows the two lines one above the other, with a caret under the first character that differs.
+func (d *Divergence) String() string {
+	column := 0
+	for column < len(d.Expected) && column < len(d.Got) && d.Expected[column] == d.Got[column] {
+		column++
+	}
+	return fmt.Sprintf("line %d differs:\nexpected: %s\n     got: %s\n          %s^", d.Line, d.Expected, d.Got,
+		strings.Repeat(" ", column))
+}
+
+// NestestResult is the outcome of running nestest.
+type NestestResult struct {
+	Lines      int         // Number of instructions traced
+	Divergence *Divergence // The first line that differed from the golden log, or nil
+	Official   byte        // nestest's result code for the documented instructions ($00 if they passed)
+	Unofficial byte        // nestest's result code for the undocumented instructions ($00 if they passed)
+}
+
+// String summarises the result.
+func (r NestestResult) String() string {
+	s := fmt.Sprintf("nestest traced %d instructions, result codes $%02X (official) and $%02X (unofficial)", r.Lines,
+		r.Official, r.Unofficial)
+	if r.Divergence != nil {
+		s += "\n" + r.Divergence.String()
+	}
+	return s
+}
+
+// ppuColumn matches the PPU column of nestest.log, which NestestTrace leaves out.
+var ppuColumn = regexp.MustCompile(`PPU:\s*-?\d+,\s*-?\d+ `)
+
+// RunNestest runs nestest, which must already be loaded, in automation mode from the state that nestest.log starts
+// in. It writes the trace of each instruction to out, if it is not nil. If golden is not nil each line is compared
+// with the next line of the golden log, and nestest stops at the first line that differs or when the log ends. It
+// also stops if the CPU halts, or after maxCycles cycles (0 for no limit).
+func RunNestest(cpu *processor.CPU, out io.Writer, golden io.Reader, maxCycles uint64) (NestestResult, error) {
+	cpu.PC, cpu.SP, cpu.Status = NestestStart, 0xFD, 0x24
+	cpu.A, cpu.X, cpu.Y = 0, 0, 0
+	cpu.TotalCycles = 7 // nestest.log counts the reset sequence
+
+	var expected *bufio.Scanner
+	if golden != nil {
+		expected = bufio.NewScanner(golden)
+	}
+	result := NestestResult{}
+	for maxCycles == 0 || cpu.TotalCycles-7 < maxCycles {
+		if expected != nil && !expected.Scan() {
+			break
+		}
+		line := NestestTrace(cpu)
+		result.Lines++
+		if out != nil {
+			if _, err := fmt.Fprintln(out, line); err != nil {
+				return result, err
+			}
+		}
+		if expected != nil {
+			want := ppuColumn.ReplaceAllString(strings.TrimRight(expected.Text(), "\r"), "")
+			if line != want {
+				result.Divergence = &Divergence{Line: result.Lines, Expected: want, Got: line}
+				break
+			}
+		}
+		if cpu.Step(); cpu.Halted() {
+			break
+		}
+	}
+	if expected != nil && expected.Err() != nil {
+		return result, expected.Err()
+	}
+	result.Official, result.Unofficial = cpu.Peek(0x02), cpu.Peek(0x03)
+	return result, nil
+}
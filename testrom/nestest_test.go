@@ -0,0 +1,157 @@
+package testrom_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ukdave/6502_emulator/bus"
+	"github.com/ukdave/6502_emulator/internal/cputest"
+	"github.com/ukdave/6502_emulator/processor"
+	"github.com/ukdave/6502_emulator/testrom"
+)
+
+// goldenStart is the start of nestest.log
+const goldenStart = "" +
+	"C000  4C F5 C5  JMP $C5F5                       A:00 X:00 Y:00 P:24 SP:FD PPU:  0, 21 CYC:7\n" +
+	"C5F5  A2 00     LDX #$00                        A:00 X:00 Y:00 P:24 SP:FD PPU:  0, 30 CYC:10\n" +
+	"C5F7  86 00     STX $00 = 00                    A:00 X:00 Y:00 P:26 SP:FD PPU:  0, 36 CYC:12\n" +
+	"C5F9  86 10     STX $10 = 00                    A:00 X:00 Y:00 P:26 SP:FD PPU:  0, 45 CYC:15\n" +
+	"C5FB  86 11     STX $11 = 00                    A:00 X:00 Y:00 P:26 SP:FD PPU:  0, 54 CYC:18\n" +
+	"C5FD  20 2D C7  JSR $C72D                       A:00 X:00 Y:00 P:26 SP:FD PPU:  0, 63 CYC:21\n" +
+	"C72D  EA        NOP                             A:00 X:00 Y:00 P:26 SP:FB PPU:  0, 81 CYC:27\n" +
+	"C72E  38        SEC                             A:00 X:00 Y:00 P:26 SP:FB PPU:  0, 87 CYC:29\n" +
+	"C72F  B0 04     BCS $C735                       A:00 X:00 Y:00 P:27 SP:FB PPU:  0, 93 CYC:31\n" +
+	"C735  EA        NOP                             A:00 X:00 Y:00 P:27 SP:FB PPU:  0,102 CYC:34\n"
+
+// newINES returns an iNES image with a 16 KB program ROM holding the instructions traced by goldenStart
+func newINES() []byte {
+	image := append([]byte{'N', 'E', 'S', 0x1A, 1, 1}, make([]byte, 10+0x4000+0x2000)...)
+	prg := image[16:]
+	copy(prg[0x0000:], []byte{0x4C, 0xF5, 0xC5})                                                 // JMP $C5F5
+	copy(prg[0x05F5:], []byte{0xA2, 0x00, 0x86, 0x00, 0x86, 0x10, 0x86, 0x11, 0x20, 0x2D, 0xC7}) // LDX, STX..., JSR $C72D
+	copy(prg[0x072D:], []byte{0xEA, 0x38, 0xB0, 0x04})                                           // NOP; SEC; BCS $C735
+	copy(prg[0x0735:], []byte{0xEA})                                                             // NOP
+	return image
+}
+
+func TestParseINES(t *testing.T) {
+	image := newINES()
+	cartridge, err := testrom.ParseINES(image)
+	assert.NoError(t, err)
+	assert.Len(t, cartridge.PRG, 0x4000, "The program ROM should be 16 KB")
+	assert.Len(t, cartridge.CHR, 0x2000, "The character ROM should be 8 KB")
+	assert.Equal(t, uint8(0), cartridge.Mapper, "The mapper should be NROM")
+
+	image[6], image[7] = 0x10, 0x40
+	cartridge, err = testrom.ParseINES(image)
+	assert.NoError(t, err)
+	assert.Equal(t, uint8(0x41), cartridge.Mapper, "The mapper number should be made from both flags bytes")
+	assert.EqualError(t, cartridge.LoadNROM(bus.NewSimpleBus()), "mapper 65 is not supported, only NROM (mapper 0)")
+
+	_, err = testrom.ParseINES([]byte("NES"))
+	assert.EqualError(t, err, "not an iNES image")
+	_, err = testrom.ParseINES(image[:0x1000])
+	assert.EqualError(t, err, "iNES image is 4096 bytes, expected at least 24592")
+}
+
+func TestLoadNROM(t *testing.T) {
+	cartridge, _ := testrom.ParseINES(newINES())
+	ram := bus.NewSimpleBus()
+	assert.NoError(t, cartridge.LoadNROM(ram))
+	assert.Equal(t, byte(0x4C), ram.Read(0x8000), "The program ROM should be loaded at $8000")
+	assert.Equal(t, byte(0x4C), ram.Read(0xC000), "A 16 KB program ROM should be mirrored at $C000")
+}
+
+func TestNestestTrace(t *testing.T) {
+	tests := []struct {
+		program     []byte
+		disassembly string
+	}{
+		{[]byte{0x4A}, "LSR A"},
+		{[]byte{0xB5, 0xF0}, "LDA $F0,X @ 00 = 11"},
+		{[]byte{0xB6, 0x10}, "LDX $10,Y @ 12 = 22"},
+		{[]byte{0xBD, 0xF0, 0x02}, "LDA $02F0,X @ 0300 = 33"},
+		{[]byte{0x6C, 0xFF, 0x02}, "JMP ($02FF) = 4400"},
+		{[]byte{0xA1, 0xFF}, "LDA ($FF,X) @ 0F = 0300 = 33"},
+		{[]byte{0xB1, 0x0F}, "LDA ($0F),Y = 0300 @ 0302 = 55"},
+		{[]byte{0xD0, 0xFE}, "BNE $0600"},
+		{[]byte{0x04, 0x12}, "*NOP $12 = 22"},
+		{[]byte{0xE7, 0x12}, "*ISB $12 = 22"},
+	}
+	for _, test := range tests {
+		cpu, ram := cputest.New(processor.Variant2A03, 0x0600, test.program...)
+		cpu.X, cpu.Y = 0x10, 0x02
+		for addr, value := range map[uint16]byte{0x00: 0x11, 0x0F: 0x00, 0x10: 0x03, 0x12: 0x22, 0x0300: 0x33,
+			0x0302: 0x55, 0x02FF: 0x00, 0x0200: 0x44} {
+			ram.Write(addr, value)
+		}
+		line := testrom.NestestTrace(cpu)
+		assert.Equal(t, test.disassembly, strings.TrimSpace(line[15:48]), "The disassembly should match")
+		assert.Equal(t, "A:00 X:10 Y:02 P:24 SP:FD CYC:0", line[48:], "The registers should match")
+	}
+}
+
+func TestRunNestest(t *testing.T) {
+	ram := bus.NewSimpleBus()
+	assert.NoError(t, testrom.LoadNestest(ram, newINES()))
+	cpu := processor.NewCPUWithVariant(ram, processor.Variant2A03)
+
+	var trace bytes.Buffer
+	result, err := testrom.RunNestest(cpu, &trace, strings.NewReader(goldenStart), 0)
+	assert.NoError(t, err)
+	assert.Nil(t, result.Divergence, "The trace should match the golden log")
+	assert.Equal(t, 10, result.Lines, "Every line of the golden log should be traced")
+	assert.Equal(t, "C000  4C F5 C5  JMP $C5F5                       A:00 X:00 Y:00 P:24 SP:FD CYC:7",
+		strings.Split(trace.String(), "\n")[0], "The trace should leave out the PPU column")
+}
+
+func TestRunNestest_Divergence(t *testing.T) {
+	ram := bus.NewSimpleBus()
+	assert.NoError(t, testrom.LoadNestest(ram, newINES()))
+	cpu := processor.NewCPUWithVariant(ram, processor.Variant2A03)
+
+	golden := strings.Replace(goldenStart, "P:26 SP:FB PPU:  0, 87 CYC:29", "P:26 SP:FB PPU:  0, 87 CYC:30", 1)
+	result, err := testrom.RunNestest(cpu, nil, strings.NewReader(golden), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 8, result.Lines, "nestest should stop at the first difference")
+	assert.Equal(t, ""+
+		"line 8 differs:\n"+
+		"expected: C72E  38        SEC                             A:00 X:00 Y:00 P:26 SP:FB CYC:30\n"+
+		"     got: C72E  38        SEC                             A:00 X:00 Y:00 P:26 SP:FB CYC:29\n"+
+		strings.Repeat(" ", 88)+"^",
+		result.Divergence.String())
+}
+
+// nestest isn't part of this repository. Put nestest.nes and nestest.log (https://www.nesdev.org/wiki/Emulator_tests)
+// in testdata, or point NESTEST_DIR at the directory holding them, to run it.
+func TestNestest(t *testing.T) {
+	dir := os.Getenv("NESTEST_DIR")
+	if dir == "" {
+		dir = "testdata"
+	}
+	image, err := os.ReadFile(filepath.Join(dir, "nestest.nes"))
+	if os.IsNotExist(err) {
+		t.Skipf("nestest.nes not found in %s", dir)
+	}
+	assert.NoError(t, err)
+	golden, err := os.Open(filepath.Join(dir, "nestest.log"))
+	if os.IsNotExist(err) {
+		t.Skipf("nestest.log not found in %s", dir)
+	}
+	assert.NoError(t, err)
+	defer golden.Close()
+
+	ram := bus.NewSimpleBus()
+	assert.NoError(t, testrom.LoadNestest(ram, image))
+	cpu := processor.NewCPUWithVariant(ram, processor.Variant2A03)
+	result, err := testrom.RunNestest(cpu, nil, golden, 0)
+	assert.NoError(t, err)
+	assert.Nil(t, result.Divergence, result.String())
+	assert.Equal(t, byte(0), result.Official, "The documented instructions should pass")
+	assert.Equal(t, byte(0), result.Unofficial, "The undocumented instructions should pass")
+}
@@ -0,0 +1,166 @@
+package trace
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Format is the format that a trace is written in.
+type Format uint8
+
+const (
+	Text Format = iota // One aligned line of text per instruction
+	CSV                // Comma separated values, with a header line
+	JSON               // One JSON object per line
+)
+
+var formatNames = map[Format]string{Text: "text", CSV: "csv", JSON: "json"}
+
+// String returns the name of the format, as accepted by ParseFormat.
+func (f Format) String() string {
+	if name, ok := formatNames[f]; ok {
+		return name
+	}
+	return fmt.Sprintf("Format(%d)", uint8(f))
+}
+
+// ParseFormat returns the format with the given (case-insensitive) name.
+func ParseFormat(name string) (Format, error) {
+	for f, n := range formatNames {
+		if strings.EqualFold(n, name) {
+			return f, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown trace format %q", name)
+}
+
+// Entry is a single traced instruction. The registers are those from before the instruction executed.
+type Entry struct {
+	Cycle       uint64 // Cycles run before the instruction started
+	PC          uint16
+	Bytes       []byte // The opcode and its operands
+	Mnemonic    string
+	Disassembly string
+	A, X, Y, SP byte
+	P           byte   // Status register
+	Address     uint16 // Effective address
+	HasAddress  bool   // False for instructions without an effective address (implied, accumulator and immediate)
+}
+
+// Flags returns the status flags as letters, with a "." in place of each flag that is clear, for example
+// "N.U..IZ.".
+func (e *Entry) Flags() string {
+	flags := []byte("NVUBDIZC")
+	for i := range flags {
+		if e.P&(0x80>>i) == 0 {
+			flags[i] = '.'
+		}
+	}
+	return string(flags)
+}
+
+// hexBytes returns the instruction bytes in hex, separated by spaces.
+func (e *Entry) hexBytes() string {
+	s := make([]string, len(e.Bytes))
+	for i, b := range e.Bytes {
+		s[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(s, " ")
+}
+
+// String returns the entry as a line of the Text format, for example
+//
+//	8003  BD F0 20  LDA $20F0,X {ABX}           A:00 X:20 Y:00 SP:FD P:24 ..U..I.. CYC:2 EA:2110
+func (e *Entry) String() string {
+	s := fmt.Sprintf("%04X  %-8s  %-26s  A:%02X X:%02X Y:%02X SP:%02X P:%02X %s CYC:%d", e.PC, e.hexBytes(),
+		e.Disassembly, e.A, e.X, e.Y, e.SP, e.P, e.Flags(), e.Cycle)
+	if e.HasAddress {
+		s += fmt.Sprintf(" EA:%04X", e.Address)
+	}
+	return s
+}
+
+// csvHeader names the columns of the CSV format.
+var csvHeader = []string{"cycle", "pc", "bytes", "mnemonic", "disassembly", "a", "x", "y", "sp", "p", "flags", "address"}
+
+// csvRecord returns the entry as a record of the CSV format. Addresses and registers are in hex.
+func (e *Entry) csvRecord() []string {
+	address := ""
+	if e.HasAddress {
+		address = fmt.Sprintf("%04X", e.Address)
+	}
+	return []string{strconv.FormatUint(e.Cycle, 10), fmt.Sprintf("%04X", e.PC), e.hexBytes(), e.Mnemonic,
+		e.Disassembly, fmt.Sprintf("%02X", e.A), fmt.Sprintf("%02X", e.X), fmt.Sprintf("%02X", e.Y),
+		fmt.Sprintf("%02X", e.SP), fmt.Sprintf("%02X", e.P), e.Flags(), address}
+}
+
+// jsonEntry is an entry as written in the JSON format.
+type jsonEntry struct {
+	Cycle       uint64  `json:"cycle"`
+	PC          uint16  `json:"pc"`
+	Bytes       string  `json:"bytes"`
+	Mnemonic    string  `json:"mnemonic"`
+	Disassembly string  `json:"disassembly"`
+	A           byte    `json:"a"`
+	X           byte    `json:"x"`
+	Y           byte    `json:"y"`
+	SP          byte    `json:"sp"`
+	P           byte    `json:"p"`
+	Flags       string  `json:"flags"`
+	Address     *uint16 `json:"address,omitempty"`
+}
+
+// MarshalJSON returns the entry as an object of the JSON format. Numbers are in decimal, as JSON requires.
+func (e *Entry) MarshalJSON() ([]byte, error) {
+	j := jsonEntry{e.Cycle, e.PC, e.hexBytes(), e.Mnemonic, e.Disassembly, e.A, e.X, e.Y, e.SP, e.P, e.Flags(), nil}
+	if e.HasAddress {
+		j.Address = &e.Address
+	}
+	return json.Marshal(j)
+}
+
+// encoder writes entries in one of the formats.
+type encoder struct {
+	format Format
+	w      io.Writer
+	csv    *csv.Writer
+}
+
+func newEncoder(w io.Writer, format Format) *encoder {
+	e := &encoder{format: format, w: w}
+	if format == CSV {
+		e.csv = csv.NewWriter(w)
+		_ = e.csv.Write(csvHeader)
+	}
+	return e
+}
+
+// encode writes an entry.
+func (e *encoder) encode(entry *Entry) error {
+	switch e.format {
+	case CSV:
+		return e.csv.Write(entry.csvRecord())
+	case JSON:
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(e.w, "%s\n", line)
+		return err
+	}
+	_, err := fmt.Fprintln(e.w, entry.String())
+	return err
+}
+
+// flush writes any entries the encoder has buffered.
+func (e *encoder) flush() error {
+	if e.csv != nil {
+		e.csv.Flush()
+		return e.csv.Error()
+	}
+	return nil
+}
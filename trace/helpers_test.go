@@ -0,0 +1,12 @@
+package trace_test
+
+import (
+	"github.com/ukdave/6502_emulator/internal/cputest"
+	"github.com/ukdave/6502_emulator/processor"
+)
+
+// newCPU creates a CPU with 64KB of RAM and the given program at $8000, with the Program Counter pointing at it
+func newCPU(variant processor.Variant, program ...byte) *processor.CPU {
+	cpu, _ := cputest.New(variant, 0x8000, program...)
+	return cpu
+}
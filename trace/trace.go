@@ -0,0 +1,242 @@
+// Package trace records the instructions executed by a CPU to a file.
+//
+// A Tracer attaches to a CPU through its hooks (see processor.Hooks) and writes an Entry for each instruction, giving
+// its address, bytes and disassembly, the registers and flags before it executed, the cycle it started on and the
+// effective address it used. The trace can be limited to some addresses or instructions, started and stopped by
+// triggers, or kept in a ring buffer that is only written out when the CPU halts.
+package trace
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ukdave/6502_emulator/processor"
+)
+
+// Range is an inclusive range of addresses.
+type Range struct {
+	From, To uint16
+}
+
+// Contains returns true if the address is in the range.
+func (r Range) Contains(addr uint16) bool {
+	return addr >= r.From && addr <= r.To
+}
+
+// ParseAddress parses a hex address, with or without a "$" or "0x" prefix.
+func ParseAddress(s string) (uint16, error) {
+	digits := strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(s), "$"), "0x")
+	addr, err := strconv.ParseUint(digits, 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid address %q", s)
+	}
+	return uint16(addr), nil
+}
+
+// ParseRange parses a range of hex addresses in the form "8000-80FF", or a single address.
+func ParseRange(s string) (Range, error) {
+	from, to, found := strings.Cut(s, "-")
+	start, err := ParseAddress(from)
+	if err != nil {
+		return Range{}, err
+	}
+	end := start
+	if found {
+		if end, err = ParseAddress(to); err != nil {
+			return Range{}, err
+		}
+	}
+	if end < start {
+		return Range{}, fmt.Errorf("invalid range %q: the end is before the start", s)
+	}
+	return Range{start, end}, nil
+}
+
+// Options control what is traced.
+type Options struct {
+	Format Format
+
+	// Ranges limits the trace to instructions at addresses in one of the ranges. All addresses are traced if empty.
+	Ranges []Range
+
+	// Mnemonics limits the trace to the given (case-insensitive) instructions. All instructions are traced if empty.
+	Mnemonics []string
+
+	// StartAt, if not nil, holds tracing off until PC reaches the given address.
+	StartAt *uint16
+
+	// StopAt, if not nil, ends tracing when PC reaches the given address. That instruction is not traced.
+	StopAt *uint16
+
+	// StopAfter, if not zero, ends tracing once this many instructions have executed since it started (whether or
+	// not they passed the filters).
+	StopAfter int
+
+	// Ring, if not zero, keeps only the last Ring entries in memory. They are written out when the CPU halts (for
+	// example on a JAM, STP or trapped unimplemented opcode), or when Flush is called.
+	Ring int
+}
+
+// Tracer writes a trace of the instructions executed by a CPU.
+type Tracer struct {
+	options   Options
+	writer    *bufio.Writer
+	encoder   *encoder
+	mnemonics map[string]bool
+	hooks     *processor.Hooks
+
+	started  bool
+	stopped  bool
+	executed int     // Instructions executed since tracing started
+	entry    Entry   // The instruction being executed
+	pending  bool    // True if entry is to be written once the instruction has executed
+	ring     []Entry // Entries kept by the Ring option, oldest first from next
+	next     int
+	err      error
+}
+
+// New returns a tracer that writes to w.
+func New(w io.Writer, options Options) *Tracer {
+	t := &Tracer{options: options, writer: bufio.NewWriter(w), started: options.StartAt == nil}
+	t.encoder = newEncoder(t.writer, options.Format)
+	if len(options.Mnemonics) > 0 {
+		t.mnemonics = map[string]bool{}
+		for _, m := range options.Mnemonics {
+			t.mnemonics[strings.ToUpper(m)] = true
+		}
+	}
+	t.hooks = &processor.Hooks{BeforeInstruction: t.beforeInstruction, AfterInstruction: t.afterInstruction}
+	return t
+}
+
+// Attach starts tracing the CPU.
+func (t *Tracer) Attach(cpu *processor.CPU) {
+	cpu.AddHooks(t.hooks)
+}
+
+// Detach stops tracing the CPU. It does not flush the trace.
+func (t *Tracer) Detach(cpu *processor.CPU) {
+	cpu.RemoveHooks(t.hooks)
+}
+
+// Flush writes out the entries held by the Ring option and anything buffered, and returns the first error that
+// occurred while writing the trace.
+func (t *Tracer) Flush() error {
+	t.dumpRing()
+	if err := t.encoder.flush(); err != nil && t.err == nil {
+		t.err = err
+	}
+	if err := t.writer.Flush(); err != nil && t.err == nil {
+		t.err = err
+	}
+	return t.err
+}
+
+// Active returns true if instructions are being traced: the start trigger has been reached and the stop trigger
+// has not.
+func (t *Tracer) Active() bool {
+	return t.started && !t.stopped
+}
+
+// beforeInstruction checks the triggers and filters, and records the state before the instruction executes.
+func (t *Tracer) beforeInstruction(cpu *processor.CPU, pc uint16, opcode byte) {
+	if t.stopped {
+		return
+	}
+	if !t.started {
+		if pc != *t.options.StartAt {
+			return
+		}
+		t.started = true
+	}
+	if t.options.StopAt != nil && pc == *t.options.StopAt {
+		t.stopped = true
+		return
+	}
+
+	t.executed++
+	info := cpu.InstructionSet().Opcode(opcode)
+	if !t.traced(pc, info.Mnemonic) {
+		return
+	}
+	op := cpu.DisassembleOperation(pc)
+	t.entry = Entry{
+		Cycle:       cpu.TotalCycles - 1, // The opcode fetch has been counted
+		PC:          pc,
+		Bytes:       op.Bytes,
+		Mnemonic:    info.Mnemonic,
+		Disassembly: op.Disassembly,
+		A:           cpu.A,
+		X:           cpu.X,
+		Y:           cpu.Y,
+		SP:          cpu.SP,
+		P:           cpu.Status,
+	}
+	t.pending = true
+}
+
+// afterInstruction adds the effective address to the entry and writes it, writes out the ring buffer if the CPU has
+// halted, and checks the StopAfter trigger.
+func (t *Tracer) afterInstruction(cpu *processor.CPU, pc uint16, opcode byte) {
+	if t.pending {
+		t.pending = false
+		switch cpu.InstructionSet().Opcode(opcode).Mode {
+		case processor.ModeIMP, processor.ModeACC, processor.ModeIMM:
+		default:
+			t.entry.Address, t.entry.HasAddress = cpu.EffectiveAddress(), true
+		}
+		t.write(t.entry)
+	}
+	if cpu.Halted() {
+		t.dumpRing()
+	}
+	if t.Active() && t.options.StopAfter != 0 && t.executed >= t.options.StopAfter {
+		t.stopped = true
+	}
+}
+
+// traced returns true if an instruction passes the filters.
+func (t *Tracer) traced(pc uint16, mnemonic string) bool {
+	if t.mnemonics != nil && !t.mnemonics[mnemonic] {
+		return false
+	}
+	if len(t.options.Ranges) == 0 {
+		return true
+	}
+	for _, r := range t.options.Ranges {
+		if r.Contains(pc) {
+			return true
+		}
+	}
+	return false
+}
+
+// write writes an entry, or keeps it in the ring buffer.
+func (t *Tracer) write(entry Entry) {
+	if t.options.Ring > 0 {
+		if len(t.ring) < t.options.Ring {
+			t.ring = append(t.ring, entry)
+		} else {
+			t.ring[t.next] = entry
+			t.next = (t.next + 1) % t.options.Ring
+		}
+		return
+	}
+	if err := t.encoder.encode(&entry); err != nil && t.err == nil {
+		t.err = err
+	}
+}
+
+// dumpRing writes out the entries in the ring buffer, oldest first, and empties it.
+func (t *Tracer) dumpRing() {
+	entries := append(t.ring[t.next:], t.ring[:t.next]...)
+	t.ring, t.next = nil, 0
+	for i := range entries {
+		if err := t.encoder.encode(&entries[i]); err != nil && t.err == nil {
+			t.err = err
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package trace_test
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ukdave/6502_emulator/processor"
+	"github.com/ukdave/6502_emulator/trace"
+)
+
+// program stores 3 to $10 and $11, then halts
+var program = []byte{
+	0xA9, 0x03, //       8000 LDA #$03
+	0xA2, 0x01, //       8002 LDX #$01
+	0x95, 0x10, //       8004 STA $10,X
+	0xCA,       //       8006 DEX
+	0x10, 0xFB, //       8007 BPL $8004
+	0x02, //             8009 JAM
+}
+
+// run traces the program until it halts and returns the trace
+func run(t *testing.T, cpu *processor.CPU, options trace.Options) string {
+	var out bytes.Buffer
+	tracer := trace.New(&out, options)
+	tracer.Attach(cpu)
+	cpu.RunUntil(func(*processor.CPU) bool { return false }, 1000)
+	assert.NoError(t, tracer.Flush())
+	return out.String()
+}
+
+func TestTracer_Text(t *testing.T) {
+	expected := "" +
+		"8000  A9 03     LDA #$03 {IMM}              A:00 X:00 Y:00 SP:FD P:24 ..U..I.. CYC:0\n" +
+		"8002  A2 01     LDX #$01 {IMM}              A:03 X:00 Y:00 SP:FD P:24 ..U..I.. CYC:2\n" +
+		"8004  95 10     STA $10,X {ZPX}             A:03 X:01 Y:00 SP:FD P:24 ..U..I.. CYC:4 EA:0011\n" +
+		"8006  CA        DEX {IMP}                   A:03 X:01 Y:00 SP:FD P:24 ..U..I.. CYC:8\n" +
+		"8007  10 FB     BPL $FB [$8004] {REL}       A:03 X:00 Y:00 SP:FD P:26 ..U..IZ. CYC:10 EA:8004\n" +
+		"8004  95 10     STA $10,X {ZPX}             A:03 X:00 Y:00 SP:FD P:26 ..U..IZ. CYC:13 EA:0010\n" +
+		"8006  CA        DEX {IMP}                   A:03 X:00 Y:00 SP:FD P:26 ..U..IZ. CYC:17\n" +
+		"8007  10 FB     BPL $FB [$8004] {REL}       A:03 X:FF Y:00 SP:FD P:A4 N.U..I.. CYC:19 EA:8004\n" +
+		"8009  02        JAM {IMP}                   A:03 X:FF Y:00 SP:FD P:A4 N.U..I.. CYC:21\n"
+	assert.Equal(t, expected, run(t, newCPU(processor.VariantNMOS, program...), trace.Options{}))
+
+	cpu := newCPU(processor.VariantNMOS, program...)
+	assert.NoError(t, cpu.SetCycleAccurate(true))
+	assert.Equal(t, expected, run(t, cpu, trace.Options{}), "Cycle accurate mode should give the same trace")
+}
+
+func TestTracer_CSV(t *testing.T) {
+	lines := strings.Split(run(t, newCPU(processor.VariantNMOS, program...), trace.Options{Format: trace.CSV}), "\n")
+	assert.Equal(t, "cycle,pc,bytes,mnemonic,disassembly,a,x,y,sp,p,flags,address", lines[0])
+	assert.Equal(t, "0,8000,A9 03,LDA,LDA #$03 {IMM},00,00,00,FD,24,..U..I..,", lines[1])
+	assert.Equal(t, "4,8004,95 10,STA,\"STA $10,X {ZPX}\",03,01,00,FD,24,..U..I..,0011", lines[3])
+}
+
+func TestTracer_JSON(t *testing.T) {
+	lines := strings.Split(run(t, newCPU(processor.VariantNMOS, program...), trace.Options{Format: trace.JSON}), "\n")
+	assert.Equal(t, `{"cycle":0,"pc":32768,"bytes":"A9 03","mnemonic":"LDA","disassembly":"LDA #$03 {IMM}",`+
+		`"a":0,"x":0,"y":0,"sp":253,"p":36,"flags":"..U..I.."}`, lines[0])
+	assert.Equal(t, `{"cycle":4,"pc":32772,"bytes":"95 10","mnemonic":"STA","disassembly":"STA $10,X {ZPX}",`+
+		`"a":3,"x":1,"y":0,"sp":253,"p":36,"flags":"..U..I..","address":17}`, lines[2])
+}
+
+// pcs returns the address at the start of each line of a text trace
+func pcs(trace string) []string {
+	var addresses []string
+	for line := range strings.Lines(trace) {
+		addresses = append(addresses, line[:4])
+	}
+	return addresses
+}
+
+func TestTracer_Filters(t *testing.T) {
+	out := run(t, newCPU(processor.VariantNMOS, program...), trace.Options{Ranges: []trace.Range{{From: 0x8004, To: 0x8006}}})
+	assert.Equal(t, []string{"8004", "8006", "8004", "8006"}, pcs(out), "Only the range should be traced")
+
+	out = run(t, newCPU(processor.VariantNMOS, program...), trace.Options{Mnemonics: []string{"dex", "JAM"}})
+	assert.Equal(t, []string{"8006", "8006", "8009"}, pcs(out), "Only the mnemonics should be traced")
+}
+
+func TestTracer_Triggers(t *testing.T) {
+	start, stop := uint16(0x8004), uint16(0x8009)
+	out := run(t, newCPU(processor.VariantNMOS, program...), trace.Options{StartAt: &start, StopAfter: 3})
+	assert.Equal(t, []string{"8004", "8006", "8007"}, pcs(out), "Tracing should start at $8004 and stop after 3")
+
+	out = run(t, newCPU(processor.VariantNMOS, program...), trace.Options{StartAt: &start, StopAt: &stop, Mnemonics: []string{"STA"}})
+	assert.Equal(t, []string{"8004", "8004"}, pcs(out), "Tracing should stop at $8009")
+}
+
+func TestTracer_Ring(t *testing.T) {
+	cpu := newCPU(processor.VariantNMOS, program...)
+	var out bytes.Buffer
+	tracer := trace.New(&out, trace.Options{Ring: 3})
+	tracer.Attach(cpu)
+	cpu.RunCycles(8)
+	assert.Empty(t, out.String(), "Nothing should be written until the CPU halts")
+
+	cpu.RunUntil(func(*processor.CPU) bool { return false }, 1000)
+	assert.NoError(t, tracer.Flush())
+	assert.Equal(t, []string{"8006", "8007", "8009"}, pcs(out.String()), "The last 3 instructions should be written")
+}
+
+func TestTracer_Detach(t *testing.T) {
+	cpu := newCPU(processor.VariantNMOS, program...)
+	var out bytes.Buffer
+	tracer := trace.New(&out, trace.Options{})
+	tracer.Attach(cpu)
+	cpu.Step()
+	tracer.Detach(cpu)
+	cpu.Step()
+	assert.NoError(t, tracer.Flush())
+	assert.Equal(t, []string{"8000"}, pcs(out.String()), "Only the instruction run while attached should be traced")
+}
+
+func TestTracer_Accesses(t *testing.T) {
+	for _, cycleAccurate := range []bool{false, true} {
+		plain, traced := newCPU(processor.VariantNMOS, program...), newCPU(processor.VariantNMOS, program...)
+		assert.NoError(t, plain.SetCycleAccurate(cycleAccurate))
+		assert.NoError(t, traced.SetCycleAccurate(cycleAccurate))
+		trace.New(&bytes.Buffer{}, trace.Options{}).Attach(traced)
+		for i := 0; i < 8; i++ {
+			expected := slices.Clone(plain.Step().Accesses)
+			assert.Equal(t, expected, traced.Step().Accesses,
+				"Tracing should not add bus accesses (cycle accurate: %t, step %d)", cycleAccurate, i)
+		}
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	r, err := trace.ParseRange("$8000-0x80ff")
+	assert.NoError(t, err)
+	assert.Equal(t, trace.Range{From: 0x8000, To: 0x80FF}, r)
+	assert.True(t, r.Contains(0x80FF), "The range should include its end")
+
+	r, err = trace.ParseRange("C000")
+	assert.NoError(t, err)
+	assert.Equal(t, trace.Range{From: 0xC000, To: 0xC000}, r)
+
+	_, err = trace.ParseRange("8000-7FFF")
+	assert.EqualError(t, err, `invalid range "8000-7FFF": the end is before the start`)
+	_, err = trace.ParseRange("8000-xyz")
+	assert.EqualError(t, err, `invalid address "xyz"`)
+}
+
+func TestParseFormat(t *testing.T) {
+	format, err := trace.ParseFormat("JSON")
+	assert.NoError(t, err)
+	assert.Equal(t, trace.JSON, format)
+	assert.Equal(t, "csv", trace.CSV.String())
+
+	_, err = trace.ParseFormat("xml")
+	assert.EqualError(t, err, `unknown trace format "xml"`)
+}
@@ -1,8 +1,11 @@
 package tui
 
 import (
+	"fmt"
 	"time"
 
+	"github.com/ukdave/6502_emulator/snapshot"
+
 	tea "charm.land/bubbletea/v2"
 )
 
@@ -12,16 +15,67 @@ func (m *Model) updateDimensions(width, height int) {
 	m.help.SetWidth(width)
 }
 
+// step runs the current instruction, advancing the devices with it.
 func (m *Model) step() {
 	m.updateMemoryTracking()
-	for {
-		m.cpu.Clock()
-		if m.cpu.Cycles() == 0 {
-			break
-		}
+	m.machine.Step()
+}
+
+// stepBack undoes the most recent instruction.
+func (m *Model) stepBack() {
+	m.updateMemoryTracking()
+	m.message = ""
+	if !m.cpu.StepBack() {
+		m.message = "No history"
+	}
+}
+
+// rewind undoes the instructions run in the last rewindCycles cycles.
+func (m *Model) rewind() {
+	m.updateMemoryTracking()
+	m.message = ""
+	if m.cpu.RewindCycles(rewindCycles) == 0 {
+		m.message = "No history"
+	}
+}
+
+// toggleIRQ asserts or releases the IRQ line. The line is level-triggered, so while it is asserted the CPU keeps
+// taking the interrupt whenever interrupts are enabled.
+func (m *Model) toggleIRQ() {
+	m.irqAsserted = !m.irqAsserted
+	if m.irqAsserted {
+		m.cpu.AssertIRQ(irqSource)
+	} else {
+		m.cpu.ReleaseIRQ(irqSource)
 	}
 }
 
+// pulseNMI asserts and releases the NMI line, signalling a single non-maskable interrupt.
+func (m *Model) pulseNMI() {
+	m.cpu.SetNMI(true)
+	m.cpu.SetNMI(false)
+}
+
+// saveSnapshot saves the state of the emulator to the snapshot file.
+func (m *Model) saveSnapshot() {
+	if err := snapshot.SaveFile(m.snapshotPath, m.snapshotSections...); err != nil {
+		m.message = fmt.Sprintf("Save failed: %v", err)
+		return
+	}
+	m.message = "Saved " + m.snapshotPath
+}
+
+// loadSnapshot restores the state of the emulator from the snapshot file.
+func (m *Model) loadSnapshot() {
+	if err := snapshot.LoadFile(m.snapshotPath, m.snapshotSections...); err != nil {
+		m.message = fmt.Sprintf("Load failed: %v", err)
+		return
+	}
+	m.irqAsserted = m.cpu.IRQAsserted()
+	m.updateMemoryTracking()
+	m.message = "Loaded " + m.snapshotPath
+}
+
 func (m *Model) run() tea.Cmd {
 	return func() tea.Msg {
 		if m.running {
@@ -29,11 +83,10 @@ func (m *Model) run() tea.Cmd {
 		} else {
 			m.running = true
 			for {
-				pcBefore := m.cpu.PC
 				m.step()
 				m.runUpdateChan <- runUpdateMsg{}
 				time.Sleep(time.Duration(m.runDelayMillis) * time.Millisecond)
-				if !m.running || m.cpu.PC == 0x0000 || m.cpu.PC == pcBefore {
+				if !m.running || m.machine.Finished() {
 					break
 				}
 			}
@@ -50,8 +103,13 @@ func (m *Model) waitForRunUpdateMsg() tea.Cmd {
 	}
 }
 
+// peek returns the byte at an address without the side effects a read can have on devices.
+func (m *Model) peek(addr uint16) byte {
+	return m.cpu.Peek(addr)
+}
+
 func (m *Model) updateMemoryTracking() {
 	for i := uint32(0); i < 65536; i++ {
-		m.previousMemory[i] = m.cpu.Read(uint16(i))
+		m.previousMemory[i] = m.peek(uint16(i))
 	}
 }
@@ -4,12 +4,16 @@ import "charm.land/bubbles/v2/key"
 
 // keyMap defines a set of keybindings. To work for help it must satisfy key.Map.
 type keyMap struct {
-	Step  key.Binding
-	Run   key.Binding
-	Reset key.Binding
-	IRQ   key.Binding
-	NMI   key.Binding
-	Quit  key.Binding
+	Step   key.Binding
+	Back   key.Binding
+	Rewind key.Binding
+	Run    key.Binding
+	Reset  key.Binding
+	IRQ    key.Binding
+	NMI    key.Binding
+	Save   key.Binding
+	Load   key.Binding
+	Quit   key.Binding
 }
 
 var keys = keyMap{
@@ -17,6 +21,14 @@ var keys = keyMap{
 		key.WithKeys("space", "enter"),
 		key.WithHelp("space/enter", "Step"),
 	),
+	Back: key.NewBinding(
+		key.WithKeys("b"),
+		key.WithHelp("b", "Back"),
+	),
+	Rewind: key.NewBinding(
+		key.WithKeys("w"),
+		key.WithHelp("w", "Rewind"),
+	),
 	Run: key.NewBinding(
 		key.WithKeys("e"),
 		key.WithHelp("e", "Run/Stop"),
@@ -27,12 +39,20 @@ var keys = keyMap{
 	),
 	IRQ: key.NewBinding(
 		key.WithKeys("i"),
-		key.WithHelp("i", "IRQ"),
+		key.WithHelp("i", "IRQ on/off"),
 	),
 	NMI: key.NewBinding(
 		key.WithKeys("n"),
 		key.WithHelp("n", "NMI"),
 	),
+	Save: key.NewBinding(
+		key.WithKeys("s"),
+		key.WithHelp("s", "Save"),
+	),
+	Load: key.NewBinding(
+		key.WithKeys("l"),
+		key.WithHelp("l", "Load"),
+	),
 	Quit: key.NewBinding(
 		key.WithKeys("q", "esc", "ctrl+c"),
 		key.WithHelp("q", "Quit"),
@@ -41,7 +61,7 @@ var keys = keyMap{
 
 // ShortHelp returns keybindings to be shown in the mini help view. It's part of the key.Map interface.
 func (k keyMap) ShortHelp() []key.Binding {
-	return []key.Binding{k.Step, k.Run, k.Reset, k.IRQ, k.NMI, k.Quit}
+	return []key.Binding{k.Step, k.Back, k.Rewind, k.Run, k.Reset, k.IRQ, k.NMI, k.Save, k.Load, k.Quit}
 }
 
 // FullHelp returns keybindings for the expanded help view. It's part of the key.Map interface.
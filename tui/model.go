@@ -2,7 +2,10 @@
 package tui
 
 import (
+	"github.com/ukdave/6502_emulator/bus"
+	"github.com/ukdave/6502_emulator/machine"
 	"github.com/ukdave/6502_emulator/processor"
+	"github.com/ukdave/6502_emulator/snapshot"
 
 	"charm.land/bubbles/v2/help"
 	"charm.land/bubbles/v2/key"
@@ -12,12 +15,25 @@ import (
 
 type runUpdateMsg struct{}
 
+// irqSource identifies the TUI's IRQ button to the CPU
+const irqSource processor.IRQSource = 0
+
+// rewindCycles is the number of cycles rewound by the rewind key
+const rewindCycles = 100
+
 type Model struct {
+	machine        *machine.Machine // Runs the CPU and its devices together
 	cpu            *processor.CPU
-	previousMemory [65536]byte // Track previous memory state to detect changes
+	mappedBus      *bus.MappedBus // The CPU's bus, if it is mapped, for showing banks
+	previousMemory [65536]byte    // Track previous memory state to detect changes
+
+	snapshotPath     string             // The file written and read by the save and load keys
+	snapshotSections []snapshot.Section // The state saved in a snapshot
+	message          string             // The result of the last save or load
 
 	runDelayMillis int
 	running        bool
+	irqAsserted    bool // The IRQ button is held down
 	runUpdateChan  chan runUpdateMsg
 
 	width  int
@@ -34,9 +50,14 @@ type Model struct {
 	helpStyle               lipgloss.Style
 }
 
-func NewModel(cpu *processor.CPU, runDelayMillis int) *Model {
+// NewModel creates the TUI model for a machine. The save and load keys write and read a snapshot of the given
+// sections (which should include the CPU) to snapshotPath.
+func NewModel(mach *machine.Machine, runDelayMillis int, snapshotPath string, sections ...snapshot.Section) *Model {
 	m := &Model{
-		cpu:                     cpu,
+		machine:                 mach,
+		cpu:                     mach.CPU,
+		snapshotPath:            snapshotPath,
+		snapshotSections:        sections,
 		runDelayMillis:          runDelayMillis,
 		runUpdateChan:           make(chan runUpdateMsg),
 		keys:                    keys,
@@ -53,6 +74,12 @@ func NewModel(cpu *processor.CPU, runDelayMillis int) *Model {
 	return m
 }
 
+// SetMappedBus tells the TUI that the CPU's bus is a MappedBus. The memory view then lists the windows of any banked
+// memory on the bus with the bank mapped into each one.
+func (m *Model) SetMappedBus(mappedBus *bus.MappedBus) {
+	m.mappedBus = mappedBus
+}
+
 func (m *Model) Init() tea.Cmd {
 	return tea.Batch(
 		m.waitForRunUpdateMsg(),
@@ -67,15 +94,23 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		switch {
 		case key.Matches(msg, m.keys.Step):
 			m.step()
+		case key.Matches(msg, m.keys.Back):
+			m.stepBack()
+		case key.Matches(msg, m.keys.Rewind):
+			m.rewind()
 		case key.Matches(msg, m.keys.Run):
 			return m, m.run()
 		case key.Matches(msg, m.keys.IRQ):
-			m.cpu.IRQ()
+			m.toggleIRQ()
 		case key.Matches(msg, m.keys.NMI):
-			m.cpu.NMI()
+			m.pulseNMI()
 		case key.Matches(msg, m.keys.Reset):
 			m.cpu.Reset()
-			m.updateMemoryTracking()
+			m.step() // Run the reset sequence
+		case key.Matches(msg, m.keys.Save):
+			m.saveSnapshot()
+		case key.Matches(msg, m.keys.Load):
+			m.loadSnapshot()
 		case key.Matches(msg, m.keys.Quit):
 			return m, tea.Quit
 		}
@@ -90,7 +125,7 @@ func (m *Model) View() tea.View {
 	statusPanelHeight := 12 + m.boxStyle.GetVerticalFrameSize()
 
 	help := m.helpStyle.
-		Render(m.help.View(m.keys))
+		Render(m.help.View(m.keys) + "  " + m.message)
 
 	status := m.boxStyle.
 		Width(rightColWidth).
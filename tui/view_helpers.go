@@ -1,14 +1,42 @@
 package tui
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
+	"github.com/ukdave/6502_emulator/bus"
+	"github.com/ukdave/6502_emulator/processor"
+
 	"charm.land/lipgloss/v2"
 )
 
 func (m *Model) memoryView() string {
-	return m.renderMemoryPage(uint16(0x0000)) + "\n\n" + m.renderMemoryPage(m.cpu.ResetVector())
+	view := m.renderMemoryPage(uint16(0x0000)) + "\n\n" + m.renderMemoryPage(m.cpu.ResetVector())
+	if banks := m.banksView(); banks != "" {
+		view += "\n\n" + banks
+	}
+	return view
+}
+
+// banksView lists the windows of the banked memory on the mapped bus, with the bank shown in each one.
+func (m *Model) banksView() string {
+	if m.mappedBus == nil {
+		return ""
+	}
+	var lines []string
+	for _, mapping := range m.mappedBus.Mappings() {
+		banked, ok := mapping.Device.(*bus.BankedMemory)
+		if !ok {
+			continue
+		}
+		for _, w := range banked.Windows() {
+			start := mapping.Start + w.Offset
+			lines = append(lines, fmt.Sprintf("%-8s $%04X-$%04X  bank %d of %d", mapping.Name, start,
+				start+uint16(w.Size-1), w.Bank, w.Banks))
+		}
+	}
+	return strings.Join(lines, "\n")
 }
 
 func (m *Model) renderMemoryPage(startAddress uint16) string {
@@ -19,7 +47,7 @@ func (m *Model) renderMemoryPage(startAddress uint16) string {
 		pageStr += fmt.Sprintf("$%04X: ", i)
 		for j := uint16(0); j < 16; j++ {
 			addr := i + j
-			currentValue := m.cpu.Read(addr)
+			currentValue := m.peek(addr)
 			hexStr := fmt.Sprintf("%02X", currentValue)
 
 			if m.previousMemory[addr] != currentValue {
@@ -42,14 +70,27 @@ func (m *Model) renderMemoryPage(startAddress uint16) string {
 
 func (m *Model) statusView() string {
 	running := ""
+	var opcodeErr *processor.UnimplementedOpcodeError
+	var faultErr *processor.FaultError
+	var accessErr *bus.AccessError
 	if m.running {
 		running = m.runningStyle.Render("*** RUNNING ***")
+	} else if errors.As(m.cpu.Err(), &opcodeErr) {
+		running = m.runningStyle.Render(fmt.Sprintf("TRAP: OPCODE $%02X", opcodeErr.Opcode))
+	} else if errors.As(m.cpu.Err(), &faultErr) && errors.As(faultErr, &accessErr) && accessErr.Write {
+		running = m.runningStyle.Render(fmt.Sprintf("FAULT: $%04X WROTE $%04X", faultErr.PC, accessErr.Address))
+	} else if reason := m.cpu.HaltReason(); reason != processor.HaltNone {
+		running = m.runningStyle.Render(strings.ToUpper(reason.String()))
+	}
+	irq := "off"
+	if m.cpu.IRQAsserted() {
+		irq = "on"
 	}
 	return m.statusFlags() +
 		fmt.Sprintf("PC:  $%04X       Cycles:  %d\n", m.cpu.PC, m.cpu.TotalCycles) +
 		fmt.Sprintf("A:   $%02X  %-5s  %s\n", m.cpu.A, fmt.Sprintf("[%d]", m.cpu.A), running) +
-		fmt.Sprintf("X:   $%02X  [%d]\n", m.cpu.X, m.cpu.X) +
-		fmt.Sprintf("Y:   $%02X  [%d]\n", m.cpu.Y, m.cpu.Y) +
+		fmt.Sprintf("X:   $%02X  %-5s  CPU:  %s\n", m.cpu.X, fmt.Sprintf("[%d]", m.cpu.X), m.cpu.Variant()) +
+		fmt.Sprintf("Y:   $%02X  %-5s  IRQ:  %s\n", m.cpu.Y, fmt.Sprintf("[%d]", m.cpu.Y), irq) +
 		fmt.Sprintf("SP:  $%02X\n\n", m.cpu.SP) +
 		fmt.Sprintf("Reset Vector:  $%04X\n", m.cpu.ResetVector()) +
 		fmt.Sprintf("NMI Vector:    $%04X\n", m.cpu.NMIVector()) +
@@ -0,0 +1,169 @@
+package via
+
+// Tick advances the VIA by one cycle of its φ2 clock, which is the CPU's clock. It makes VIA a machine.Clockable.
+//
+// Timer 1 counts down from the value written to it, and times out 1.5 cycles after passing zero, so a timer started
+// with N interrupts N+2 ticks later (the half cycle is rounded up, as ticks fall at the end of a cycle). In
+// free-running mode it then reloads from its latch, giving an interrupt every N+2 cycles. Timer 2 behaves in the same
+// way as a one-shot timer, or counts pulses on PB6 (see SetPortB).
+func (v *VIA) Tick() {
+	s := &v.state
+	if s.CA2Pulse {
+		s.CA2Pulse = false
+		v.updateCA2(true)
+	}
+	if s.CB2Pulse {
+		s.CB2Pulse = false
+		v.updateCB2(true)
+	}
+	v.tickT1()
+	v.tickT2()
+	if mode := v.shiftMode(); s.SRRunning && (mode == 0x02 || mode == 0x06) {
+		v.shiftClock()
+	}
+}
+
+// tickT1 counts timer 1 down, and handles it timing out.
+func (v *VIA) tickT1() {
+	s := &v.state
+	switch {
+	case s.T1Reload:
+		s.T1Reload = false
+		s.T1Counter = s.T1Latch
+	case s.T1Counter != 0:
+		s.T1Counter--
+	default:
+		s.T1Counter = 0xFFFF
+		freeRunning := s.ACR&0x40 != 0
+		if freeRunning {
+			s.T1Reload = true
+		}
+		if !s.T1Armed {
+			return
+		}
+		v.setFlags(IntT1)
+		if freeRunning {
+			s.PB7 = !s.PB7
+		} else {
+			s.T1Armed = false
+			s.PB7 = true
+		}
+		if s.ACR&0x80 != 0 {
+			v.updatePortB()
+		}
+	}
+}
+
+// tickT2 counts timer 2 down, unless it is counting pulses. In the shift register modes that use it as the shift
+// clock, only its low byte counts, reloading from the latch each time it passes zero, and it does not interrupt.
+func (v *VIA) tickT2() {
+	s := &v.state
+	if s.ACR&0x20 != 0 {
+		return
+	}
+	if mode := v.shiftMode(); mode == 0x01 || mode == 0x04 || mode == 0x05 {
+		switch {
+		case s.T2Reload:
+			s.T2Reload = false
+			s.T2Counter = s.T2Counter&0xFF00 | uint16(s.T2LatchLow)
+		case s.T2Counter&0xFF != 0:
+			s.T2Counter--
+		default:
+			s.T2Reload = true
+			if s.SRRunning {
+				v.shiftClock()
+			}
+		}
+		return
+	}
+	switch {
+	case s.T2Reload:
+		s.T2Reload = false
+	case s.T2Counter != 0:
+		s.T2Counter--
+	default:
+		s.T2Counter = 0xFFFF
+		if s.T2Armed {
+			s.T2Armed = false
+			v.setFlags(IntT2)
+		}
+	}
+}
+
+// shiftMode returns the shift register mode, bits 2-4 of the ACR:
+//
+//	0  disabled
+//	1  shift in at the rate of timer 2
+//	2  shift in at the rate of φ2
+//	3  shift in on the CB1 clock
+//	4  shift out continuously at the rate of timer 2
+//	5  shift out at the rate of timer 2
+//	6  shift out at the rate of φ2
+//	7  shift out on the CB1 clock
+func (v *VIA) shiftMode() byte {
+	return v.state.ACR >> 2 & 0x07
+}
+
+// shiftClockOutput returns true if the shift register drives CB1 as its clock output.
+func (v *VIA) shiftClockOutput() bool {
+	mode := v.shiftMode()
+	return mode != 0x00 && mode&0x03 != 0x03
+}
+
+// startShift starts the shift register shifting 8 bits, when the SR register is read or written.
+func (v *VIA) startShift() {
+	if v.shiftMode() != 0x00 {
+		v.state.SRRunning = true
+		v.state.SRCount = 0
+	}
+}
+
+// updateShiftLines sets CB1 and CB2 for a new shift register mode.
+func (v *VIA) updateShiftLines() {
+	if v.shiftMode() == 0x00 {
+		v.state.SRRunning = false
+	}
+	if v.shiftClockOutput() {
+		v.updateCB1(true) // The shift clock idles high
+	}
+	if v.shiftMode()&0x04 != 0 {
+		v.updateCB2(v.state.SR&0x80 != 0)
+	}
+}
+
+// shiftClock toggles the shift clock on CB1 in the internally clocked modes, and shifts a bit on the new edge.
+func (v *VIA) shiftClock() {
+	level := !v.state.CB1
+	v.updateCB1(level)
+	v.shiftEdge(level)
+}
+
+// shiftEdge shifts a bit on an edge of the shift clock: out on a falling edge, with the outgoing bit recirculated
+// into bit 0, or in from CB2 on a rising edge. Eight rising edges complete the shift, which flags the SR interrupt
+// (except when shifting out continuously, which never stops).
+func (v *VIA) shiftEdge(rising bool) {
+	s := &v.state
+	if !s.SRRunning {
+		return
+	}
+	out := v.shiftMode()&0x04 != 0
+	if !rising {
+		if out {
+			bit := s.SR >> 7
+			s.SR = s.SR<<1 | bit
+			v.updateCB2(bit != 0)
+		}
+		return
+	}
+	if !out {
+		s.SR <<= 1
+		if s.CB2 {
+			s.SR |= 0x01
+		}
+	}
+	s.SRCount++
+	if s.SRCount == 8 && v.shiftMode() != 0x04 {
+		s.SRRunning = false
+		v.setFlags(IntSR)
+	}
+}
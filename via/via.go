@@ -0,0 +1,557 @@
+// Package via emulates the MOS 6522 Versatile Interface Adapter (VIA).
+//
+// A VIA is a device for a MappedBus (see bus.MappedBus) with 16 registers, mirrored across whatever range it is
+// mapped to, and a clock input that must be ticked once every CPU cycle (see machine.Machine). It provides two 8-bit
+// ports (A and B) whose pins can each be an input or an output, the CA1/CA2 and CB1/CB2 control lines used for
+// handshaking and interrupts, two 16-bit timers, a shift register and an IRQ output.
+//
+// Other devices attach to the pins from Go: SetPortA, SetPortB and the SetCA1-style methods drive the input pins,
+// and the OnPortA, OnPortB and OnCA2-style callbacks report changes to the output pins.
+package via
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/ukdave/6502_emulator/processor"
+)
+
+// The VIA's registers, by their offset from the start of its mapping.
+const (
+	RegORB  = 0x0 // Output register B (reads input register B)
+	RegORA  = 0x1 // Output register A (reads input register A), with handshaking
+	RegDDRB = 0x2 // Data direction register B (1 for an output pin)
+	RegDDRA = 0x3 // Data direction register A
+	RegT1CL = 0x4 // Timer 1 counter low byte (writes set the latch)
+	RegT1CH = 0x5 // Timer 1 counter high byte (writes start the timer)
+	RegT1LL = 0x6 // Timer 1 latch low byte
+	RegT1LH = 0x7 // Timer 1 latch high byte
+	RegT2CL = 0x8 // Timer 2 counter low byte (writes set the latch)
+	RegT2CH = 0x9 // Timer 2 counter high byte (writes start the timer)
+	RegSR   = 0xA // Shift register
+	RegACR  = 0xB // Auxiliary control register
+	RegPCR  = 0xC // Peripheral control register
+	RegIFR  = 0xD // Interrupt flag register
+	RegIER  = 0xE // Interrupt enable register
+	RegORA2 = 0xF // Output register A, without handshaking
+)
+
+// The interrupt sources, as bits of the IFR and IER registers.
+const (
+	IntCA2   = 1 << 0
+	IntCA1   = 1 << 1
+	IntSR    = 1 << 2 // The shift register has shifted 8 bits
+	IntCB2   = 1 << 3
+	IntCB1   = 1 << 4
+	IntT2    = 1 << 5
+	IntT1    = 1 << 6
+	IntIRQ   = 1 << 7 // Read from IFR: any enabled interrupt is flagged. Written to IER: set (rather than clear) bits
+	intFlags = 0x7F
+)
+
+// VIA is a MOS 6522 Versatile Interface Adapter.
+type VIA struct {
+	// OnIRQ, if not nil, is called whenever the IRQ output changes, with true when it is asserted (see ConnectIRQ).
+	OnIRQ func(asserted bool)
+
+	// OnPortA and OnPortB, if not nil, are called whenever the levels of a port's output pins change, with the levels
+	// of all 8 pins.
+	OnPortA func(pins byte)
+	OnPortB func(pins byte)
+
+	// OnCA2, OnCB1 and OnCB2, if not nil, are called whenever the control lines change while the VIA drives them.
+	OnCA2 func(level bool)
+	OnCB1 func(level bool)
+	OnCB2 func(level bool)
+
+	id    string
+	state state
+}
+
+// state is the complete state of a VIA, as saved in a snapshot.
+type state struct {
+	ORA, ORB, DDRA, DDRB byte
+	InputA, InputB       byte // The levels driven onto the ports by other devices
+	LatchA, LatchB       byte // The input latches, loaded on an active CA1/CB1 transition
+	PinsA, PinsB         byte // The last levels reported to OnPortA and OnPortB
+
+	T1Counter, T1Latch uint16
+	T1Reload           bool // The next tick reloads timer 1 from its latch rather than counting down
+	T1Armed            bool // Timer 1 interrupts when it next times out
+	PB7                bool // The level of PB7 when timer 1 drives it
+
+	T2Counter  uint16
+	T2LatchLow byte
+	T2Reload   bool // The next tick starts timer 2 (or reloads its low byte as the shift clock) rather than counting
+	T2Armed    bool
+
+	SR        byte
+	SRCount   uint8 // The number of bits shifted since the shift register was started
+	SRRunning bool
+
+	ACR, PCR, IFR, IER byte
+
+	CA1, CA2, CB1, CB2 bool // The levels of the control lines
+	CA2Pulse, CB2Pulse bool // A pulse output is low, and goes high on the next tick
+	IRQ                bool
+}
+
+// New creates a VIA in its reset state. The id identifies the VIA in snapshots, and must be 4 characters, for
+// example "VIA1".
+func New(id string) (*VIA, error) {
+	if len(id) != 4 {
+		return nil, fmt.Errorf("VIA ID %q is not 4 characters", id)
+	}
+	v := &VIA{id: id}
+	v.state.InputA, v.state.InputB = 0xFF, 0xFF // The inputs are pulled up
+	v.state.CA1, v.state.CA2, v.state.CB1, v.state.CB2 = true, true, true, true
+	v.state.PB7 = true
+	v.Reset()
+	return v, nil
+}
+
+// Reset does what the VIA's RES input does: it clears all of the registers apart from the timers, their latches
+// and the shift register, which makes every pin an input and disables all interrupts.
+func (v *VIA) Reset() {
+	s := &v.state
+	s.ORA, s.ORB, s.DDRA, s.DDRB, s.ACR, s.PCR, s.IFR, s.IER = 0, 0, 0, 0, 0, 0, 0, 0
+	s.T1Armed, s.T2Armed, s.SRRunning = false, false, false
+	s.PinsA, s.PinsB = s.InputA, s.InputB
+	v.updateIRQ()
+}
+
+// ConnectIRQ wires the VIA's IRQ output to a CPU's IRQ input, as the given source.
+func (v *VIA) ConnectIRQ(cpu *processor.CPU, source processor.IRQSource) {
+	v.OnIRQ = func(asserted bool) {
+		if asserted {
+			cpu.AssertIRQ(source)
+		} else {
+			cpu.ReleaseIRQ(source)
+		}
+	}
+	v.OnIRQ(v.state.IRQ)
+}
+
+// IRQ returns true if the IRQ output is asserted.
+func (v *VIA) IRQ() bool {
+	return v.state.IRQ
+}
+
+// Read returns the value of a register, with the side effects reading it has on the VIA (for example, reading
+// T1C-L clears the timer 1 interrupt).
+func (v *VIA) Read(addr uint16) byte {
+	s := &v.state
+	reg := addr & 0x0F
+	switch reg {
+	case RegORB:
+		v.clearFlags(IntCB1 | v.dependentFlag(IntCB2, s.PCR>>5))
+	case RegORA:
+		v.clearFlags(IntCA1 | v.dependentFlag(IntCA2, s.PCR>>1))
+		v.handshakeCA2()
+	case RegT1CL:
+		v.clearFlags(IntT1)
+	case RegT2CL:
+		v.clearFlags(IntT2)
+	case RegSR:
+		v.clearFlags(IntSR)
+		v.startShift()
+	}
+	return v.Peek(reg)
+}
+
+// Peek returns the value of a register without any of the side effects of reading it, for debuggers.
+func (v *VIA) Peek(addr uint16) byte {
+	s := &v.state
+	switch addr & 0x0F {
+	case RegORB:
+		input := v.PortB()
+		if s.ACR&0x02 != 0 {
+			input = s.LatchB
+		}
+		value := s.ORB&s.DDRB | input&^s.DDRB
+		if s.ACR&0x80 != 0 {
+			value = value&0x7F | v.pb7()
+		}
+		return value
+	case RegORA, RegORA2:
+		if s.ACR&0x01 != 0 {
+			return s.LatchA
+		}
+		return v.PortA()
+	case RegDDRB:
+		return s.DDRB
+	case RegDDRA:
+		return s.DDRA
+	case RegT1CL:
+		return byte(s.T1Counter)
+	case RegT1CH:
+		return byte(s.T1Counter >> 8)
+	case RegT1LL:
+		return byte(s.T1Latch)
+	case RegT1LH:
+		return byte(s.T1Latch >> 8)
+	case RegT2CL:
+		return byte(s.T2Counter)
+	case RegT2CH:
+		return byte(s.T2Counter >> 8)
+	case RegSR:
+		return s.SR
+	case RegACR:
+		return s.ACR
+	case RegPCR:
+		return s.PCR
+	case RegIFR:
+		if s.IRQ {
+			return s.IFR | IntIRQ
+		}
+		return s.IFR
+	case RegIER:
+		return s.IER | 0x80
+	}
+	panic("unreachable")
+}
+
+// Write stores a value in a register.
+func (v *VIA) Write(addr uint16, data byte) {
+	s := &v.state
+	switch addr & 0x0F {
+	case RegORB:
+		s.ORB = data
+		v.clearFlags(IntCB1 | v.dependentFlag(IntCB2, s.PCR>>5))
+		v.handshakeCB2()
+		v.updatePortB()
+	case RegORA:
+		s.ORA = data
+		v.clearFlags(IntCA1 | v.dependentFlag(IntCA2, s.PCR>>1))
+		v.handshakeCA2()
+		v.updatePortA()
+	case RegORA2:
+		s.ORA = data
+		v.updatePortA()
+	case RegDDRB:
+		s.DDRB = data
+		v.updatePortB()
+	case RegDDRA:
+		s.DDRA = data
+		v.updatePortA()
+	case RegT1CL, RegT1LL:
+		s.T1Latch = s.T1Latch&0xFF00 | uint16(data)
+	case RegT1CH:
+		s.T1Latch = s.T1Latch&0x00FF | uint16(data)<<8
+		s.T1Counter = s.T1Latch
+		s.T1Reload, s.T1Armed = true, true
+		v.clearFlags(IntT1)
+		if s.ACR&0x80 != 0 {
+			s.PB7 = false
+			v.updatePortB()
+		}
+	case RegT1LH:
+		s.T1Latch = s.T1Latch&0x00FF | uint16(data)<<8
+		v.clearFlags(IntT1)
+	case RegT2CL:
+		s.T2LatchLow = data
+	case RegT2CH:
+		s.T2Counter = uint16(data)<<8 | uint16(s.T2LatchLow)
+		s.T2Reload, s.T2Armed = true, true
+		v.clearFlags(IntT2)
+	case RegSR:
+		s.SR = data
+		v.clearFlags(IntSR)
+		v.startShift()
+	case RegACR:
+		s.ACR = data
+		v.updatePortB()
+		v.updateShiftLines()
+	case RegPCR:
+		s.PCR = data
+		v.updateCA2(v.controlOutput(data>>1, s.CA2))
+		if v.shiftMode()&0x04 == 0 {
+			v.updateCB2(v.controlOutput(data>>5, s.CB2))
+		}
+	case RegIFR:
+		v.clearFlags(data & intFlags)
+	case RegIER:
+		if data&0x80 != 0 {
+			s.IER |= data & intFlags
+		} else {
+			s.IER &^= data & intFlags
+		}
+		v.updateIRQ()
+	}
+}
+
+// setFlags flags interrupts.
+func (v *VIA) setFlags(flags byte) {
+	v.state.IFR |= flags
+	v.updateIRQ()
+}
+
+// clearFlags clears interrupt flags.
+func (v *VIA) clearFlags(flags byte) {
+	v.state.IFR &^= flags
+	v.updateIRQ()
+}
+
+// updateIRQ asserts the IRQ output if any enabled interrupt is flagged, and releases it otherwise.
+func (v *VIA) updateIRQ() {
+	irq := v.state.IFR&v.state.IER&intFlags != 0
+	if irq != v.state.IRQ {
+		v.state.IRQ = irq
+		if v.OnIRQ != nil {
+			v.OnIRQ(irq)
+		}
+	}
+}
+
+// dependentFlag returns the flag of a CA2 or CB2 line if the line's 3 bit control mode (taken from the PCR) is an
+// input mode whose flag is cleared by accessing the port, and zero for the independent interrupt and output modes.
+func (v *VIA) dependentFlag(flag byte, mode byte) byte {
+	if mode&0x05 == 0 {
+		return flag
+	}
+	return 0
+}
+
+// PortA returns the levels of port A's pins: its output register for the output pins, and the levels driven by
+// other devices for the input pins.
+func (v *VIA) PortA() byte {
+	s := &v.state
+	return s.ORA&s.DDRA | s.InputA&^s.DDRA
+}
+
+// PortB returns the levels of port B's pins, as PortA does. PB7 is driven by timer 1 when the ACR says so.
+func (v *VIA) PortB() byte {
+	s := &v.state
+	pins := s.ORB&s.DDRB | s.InputB&^s.DDRB
+	if s.ACR&0x80 != 0 {
+		pins = pins&0x7F | v.pb7()
+	}
+	return pins
+}
+
+// pb7 returns the level timer 1 drives onto PB7, as bit 7.
+func (v *VIA) pb7() byte {
+	if v.state.PB7 {
+		return 0x80
+	}
+	return 0
+}
+
+// SetPortA sets the levels other devices drive onto port A. Only the input pins are affected.
+func (v *VIA) SetPortA(levels byte) {
+	v.state.InputA = levels
+}
+
+// SetPortB sets the levels other devices drive onto port B. Only the input pins are affected. In pulse counting
+// mode, timer 2 counts the falling edges on PB6.
+func (v *VIA) SetPortB(levels byte) {
+	s := &v.state
+	before := v.PortB()
+	s.InputB = levels
+	if s.ACR&0x20 != 0 && before&0x40 != 0 && v.PortB()&0x40 == 0 {
+		s.T2Counter--
+		if s.T2Counter == 0 && s.T2Armed {
+			s.T2Armed = false
+			v.setFlags(IntT2)
+		}
+	}
+}
+
+// updatePortA calls OnPortA if the levels of port A's pins have changed.
+func (v *VIA) updatePortA() {
+	if pins := v.PortA(); pins != v.state.PinsA {
+		v.state.PinsA = pins
+		if v.OnPortA != nil {
+			v.OnPortA(pins)
+		}
+	}
+}
+
+// updatePortB calls OnPortB if the levels of port B's pins have changed.
+func (v *VIA) updatePortB() {
+	if pins := v.PortB(); pins != v.state.PinsB {
+		v.state.PinsB = pins
+		if v.OnPortB != nil {
+			v.OnPortB(pins)
+		}
+	}
+}
+
+// CA1 returns the level of the CA1 line.
+func (v *VIA) CA1() bool {
+	return v.state.CA1
+}
+
+// CA2 returns the level of the CA2 line.
+func (v *VIA) CA2() bool {
+	return v.state.CA2
+}
+
+// CB1 returns the level of the CB1 line.
+func (v *VIA) CB1() bool {
+	return v.state.CB1
+}
+
+// CB2 returns the level of the CB2 line.
+func (v *VIA) CB2() bool {
+	return v.state.CB2
+}
+
+// SetCA1 drives the CA1 input. The active transition (chosen by the PCR) flags the CA1 interrupt, latches port A if
+// latching is enabled, and ends a CA2 handshake.
+func (v *VIA) SetCA1(level bool) {
+	s := &v.state
+	if level == s.CA1 {
+		return
+	}
+	s.CA1 = level
+	if level != (s.PCR&0x01 != 0) {
+		return
+	}
+	v.setFlags(IntCA1)
+	if s.ACR&0x01 != 0 {
+		s.LatchA = v.PortA()
+	}
+	if s.PCR>>1&0x07 == 0x04 {
+		v.updateCA2(true)
+	}
+}
+
+// SetCA2 drives the CA2 line, if the PCR makes it an input. The active transition flags the CA2 interrupt.
+func (v *VIA) SetCA2(level bool) {
+	s := &v.state
+	if s.PCR&0x08 != 0 || level == s.CA2 {
+		return
+	}
+	s.CA2 = level
+	if level == (s.PCR&0x04 != 0) {
+		v.setFlags(IntCA2)
+	}
+}
+
+// SetCB1 drives the CB1 input, unless the shift register is using it as its clock output. The active transition
+// flags the CB1 interrupt, latches port B if latching is enabled, and ends a CB2 handshake. CB1 also clocks the
+// shift register in the external clock modes.
+func (v *VIA) SetCB1(level bool) {
+	s := &v.state
+	if v.shiftClockOutput() || level == s.CB1 {
+		return
+	}
+	s.CB1 = level
+	if mode := v.shiftMode(); mode&0x03 == 0x03 {
+		v.shiftEdge(level)
+	}
+	if level != (s.PCR&0x10 != 0) {
+		return
+	}
+	v.setFlags(IntCB1)
+	if s.ACR&0x02 != 0 {
+		s.LatchB = v.PortB()
+	}
+	if s.PCR>>5&0x07 == 0x04 {
+		v.updateCB2(true)
+	}
+}
+
+// SetCB2 drives the CB2 line, if the PCR makes it an input and the shift register is not shifting out. The active
+// transition flags the CB2 interrupt. CB2 is also the data input of the shift register when it shifts in.
+func (v *VIA) SetCB2(level bool) {
+	s := &v.state
+	if s.PCR&0x80 != 0 || v.shiftMode()&0x04 != 0 || level == s.CB2 {
+		return
+	}
+	s.CB2 = level
+	if level == (s.PCR&0x40 != 0) {
+		v.setFlags(IntCB2)
+	}
+}
+
+// controlOutput returns the level of a CA2 or CB2 line in a 3 bit control mode, given its current level.
+func (v *VIA) controlOutput(mode byte, level bool) bool {
+	switch mode & 0x07 {
+	case 0x04, 0x05: // Handshake and pulse outputs idle high
+		return true
+	case 0x06: // Manual output low
+		return false
+	case 0x07: // Manual output high
+		return true
+	}
+	return level
+}
+
+// handshakeCA2 takes CA2 low when port A is accessed in the handshake and pulse output modes.
+func (v *VIA) handshakeCA2() {
+	switch v.state.PCR >> 1 & 0x07 {
+	case 0x04:
+		v.updateCA2(false)
+	case 0x05:
+		v.updateCA2(false)
+		v.state.CA2Pulse = true
+	}
+}
+
+// handshakeCB2 takes CB2 low when port B is written in the handshake and pulse output modes.
+func (v *VIA) handshakeCB2() {
+	if v.shiftMode()&0x04 != 0 {
+		return
+	}
+	switch v.state.PCR >> 5 & 0x07 {
+	case 0x04:
+		v.updateCB2(false)
+	case 0x05:
+		v.updateCB2(false)
+		v.state.CB2Pulse = true
+	}
+}
+
+// updateCA2 sets the level the VIA drives onto CA2, and calls OnCA2 if it has changed.
+func (v *VIA) updateCA2(level bool) {
+	if level != v.state.CA2 {
+		v.state.CA2 = level
+		if v.OnCA2 != nil {
+			v.OnCA2(level)
+		}
+	}
+}
+
+// updateCB1 sets the level the VIA drives onto CB1, and calls OnCB1 if it has changed.
+func (v *VIA) updateCB1(level bool) {
+	if level != v.state.CB1 {
+		v.state.CB1 = level
+		if v.OnCB1 != nil {
+			v.OnCB1(level)
+		}
+	}
+}
+
+// updateCB2 sets the level the VIA drives onto CB2, and calls OnCB2 if it has changed.
+func (v *VIA) updateCB2(level bool) {
+	if level != v.state.CB2 {
+		v.state.CB2 = level
+		if v.OnCB2 != nil {
+			v.OnCB2(level)
+		}
+	}
+}
+
+// SnapshotID returns the ID of the VIA's section in a snapshot (see the snapshot package).
+func (v *VIA) SnapshotID() string {
+	return v.id
+}
+
+// SaveSnapshot writes the state of the VIA to w.
+func (v *VIA) SaveSnapshot(w io.Writer) error {
+	return binary.Write(w, binary.LittleEndian, &v.state)
+}
+
+// LoadSnapshot restores the state of the VIA from r. The callbacks are not called: the devices attached to the VIA,
+// and the CPU's IRQ input, are expected to be restored from the same snapshot.
+func (v *VIA) LoadSnapshot(r io.Reader) error {
+	var s state
+	if err := binary.Read(r, binary.LittleEndian, &s); err != nil {
+		return err
+	}
+	v.state = s
+	return nil
+}
@@ -0,0 +1,302 @@
+package via_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ukdave/6502_emulator/bus"
+	"github.com/ukdave/6502_emulator/internal/cputest"
+	"github.com/ukdave/6502_emulator/machine"
+	"github.com/ukdave/6502_emulator/processor"
+	"github.com/ukdave/6502_emulator/snapshot"
+	"github.com/ukdave/6502_emulator/via"
+)
+
+// newVIA returns a VIA in its reset state
+func newVIA(t *testing.T) *via.VIA {
+	v, err := via.New("VIA1")
+	assert.NoError(t, err)
+	return v
+}
+
+// tick ticks the VIA n times
+func tick(v *via.VIA, n int) {
+	for range n {
+		v.Tick()
+	}
+}
+
+func TestNew(t *testing.T) {
+	_, err := via.New("VIA10")
+	assert.EqualError(t, err, `VIA ID "VIA10" is not 4 characters`)
+}
+
+func TestVIA_Timer1OneShot(t *testing.T) {
+	v := newVIA(t)
+	var irqs []bool
+	v.OnIRQ = func(asserted bool) { irqs = append(irqs, asserted) }
+	v.Write(via.RegIER, via.IntIRQ|via.IntT1)
+	v.Write(via.RegT1CL, 10)
+	v.Write(via.RegT1CH, 0)
+
+	tick(v, 11)
+	assert.False(t, v.IRQ(), "The timer should not have timed out after N+1 ticks")
+	assert.Equal(t, uint8(0x00), v.Peek(via.RegT1CL), "The counter should have reached zero")
+	v.Tick()
+	assert.True(t, v.IRQ(), "The timer should time out after N+2 ticks")
+	assert.Equal(t, uint8(via.IntIRQ|via.IntT1), v.Read(via.RegIFR))
+	assert.Equal(t, uint8(0xFF), v.Peek(via.RegT1CH), "The counter should roll over to $FFFF")
+
+	v.Read(via.RegT1CL)
+	assert.False(t, v.IRQ(), "Reading T1C-L should clear the interrupt")
+	tick(v, 0x20000)
+	assert.False(t, v.IRQ(), "A one-shot timer should only time out once")
+	assert.Equal(t, []bool{true, false}, irqs)
+}
+
+func TestVIA_Timer1FreeRunning(t *testing.T) {
+	v := newVIA(t)
+	var toggles []int
+	ticks := 0
+	v.OnPortB = func(pins byte) { toggles = append(toggles, ticks) }
+	v.Write(via.RegACR, 0xC0) // Free-running, with PB7 output
+	v.Write(via.RegT1CL, 4)
+	v.Write(via.RegT1CH, 0)
+	assert.Equal(t, uint8(0x00), v.PortB()&0x80, "Starting the timer should take PB7 low")
+
+	for ticks = 1; ticks <= 18; ticks++ {
+		v.Tick()
+	}
+	assert.Equal(t, []int{0, 6, 12, 18}, toggles, "PB7 should toggle every N+2 ticks")
+	assert.Equal(t, uint8(via.IntT1), v.Read(via.RegIFR)&via.IntT1, "The timer should have flagged its interrupt")
+}
+
+func TestVIA_Timer2(t *testing.T) {
+	v := newVIA(t)
+	v.Write(via.RegT2CL, 5)
+	v.Write(via.RegT2CH, 0)
+	tick(v, 6)
+	assert.Zero(t, v.Read(via.RegIFR)&via.IntT2, "The timer should not have timed out after N+1 ticks")
+	v.Tick()
+	assert.Equal(t, uint8(via.IntT2), v.Read(via.RegIFR)&via.IntT2, "The timer should time out after N+2 ticks")
+
+	// Counting pulses on PB6
+	v.Write(via.RegACR, 0x20)
+	v.Write(via.RegT2CL, 3)
+	v.Write(via.RegT2CH, 0)
+	for i := range 3 {
+		tick(v, 100)
+		assert.Zero(t, v.Read(via.RegIFR)&via.IntT2, "Only pulses should count (pulse %d)", i)
+		v.SetPortB(0xBF)
+		v.SetPortB(0xFF)
+	}
+	assert.Equal(t, uint8(via.IntT2), v.Read(via.RegIFR)&via.IntT2, "The timer should time out after 3 pulses")
+}
+
+func TestVIA_Ports(t *testing.T) {
+	v := newVIA(t)
+	var outputs []byte
+	v.OnPortA = func(pins byte) { outputs = append(outputs, pins) }
+	v.SetPortA(0x30)
+	assert.Equal(t, uint8(0x30), v.Read(via.RegORA), "Input pins should read the levels driven onto them")
+
+	v.Write(via.RegDDRA, 0x0F)
+	v.Write(via.RegORA, 0xA5)
+	assert.Equal(t, uint8(0x35), v.PortA(), "Output pins should take their levels from ORA")
+	assert.Equal(t, uint8(0x35), v.Read(via.RegORA2))
+	assert.Equal(t, []byte{0x30, 0x35}, outputs, "OnPortA should be called when the output pins change")
+
+	v.Write(via.RegDDRB, 0xF0)
+	v.Write(via.RegORB, 0x5A)
+	v.SetPortB(0x0C)
+	assert.Equal(t, uint8(0x5C), v.Read(via.RegORB), "Port B should read ORB for its output pins")
+
+	v.Reset()
+	assert.Equal(t, uint8(0x00), v.Read(via.RegDDRA), "Reset should make every pin an input")
+}
+
+func TestVIA_CA1AndHandshake(t *testing.T) {
+	v := newVIA(t)
+	var ca2 []bool
+	v.OnCA2 = func(level bool) { ca2 = append(ca2, level) }
+	v.Write(via.RegPCR, 0x09) // CA1 on a rising edge, CA2 handshake output
+	v.Write(via.RegACR, 0x01) // Latch port A on CA1
+	v.SetPortA(0x42)
+
+	v.SetCA1(false)
+	assert.Zero(t, v.Read(via.RegIFR), "A falling edge should not be active")
+	v.SetCA1(true)
+	assert.Equal(t, uint8(via.IntCA1), v.Read(via.RegIFR), "A rising edge should flag the CA1 interrupt")
+
+	v.SetPortA(0x00)
+	assert.Equal(t, uint8(0x42), v.Read(via.RegORA), "Port A should read the value latched by CA1")
+	assert.Zero(t, v.Read(via.RegIFR), "Reading ORA should clear the CA1 interrupt")
+	assert.False(t, v.CA2(), "Reading ORA should take CA2 low")
+
+	v.SetCA1(false)
+	v.SetCA1(true)
+	assert.Equal(t, []bool{false, true}, ca2, "CA1 should end the handshake")
+}
+
+func TestVIA_CB2Pulse(t *testing.T) {
+	v := newVIA(t)
+	var cb2 []bool
+	v.OnCB2 = func(level bool) { cb2 = append(cb2, level) }
+	v.Write(via.RegPCR, 0xA0) // CB2 pulse output
+	v.Write(via.RegORB, 0x01)
+	assert.False(t, v.CB2(), "Writing ORB should take CB2 low")
+	v.Tick()
+	assert.Equal(t, []bool{false, true}, cb2, "CB2 should go high again after one cycle")
+
+	v.Write(via.RegPCR, 0xC0) // CB2 manual output low
+	assert.False(t, v.CB2())
+}
+
+func TestVIA_InterruptRegisters(t *testing.T) {
+	v := newVIA(t)
+	v.Write(via.RegIER, via.IntIRQ|via.IntCA1|via.IntCB1)
+	assert.Equal(t, uint8(0x92), v.Read(via.RegIER), "Bit 7 should read as 1")
+	v.Write(via.RegIER, via.IntCA1)
+	assert.Equal(t, uint8(0x90), v.Read(via.RegIER), "Writing with bit 7 clear should disable the interrupts")
+
+	v.SetCA1(false) // Negative edge, the default
+	assert.Equal(t, uint8(via.IntCA1), v.Read(via.RegIFR), "A disabled interrupt is still flagged")
+	assert.False(t, v.IRQ(), "A disabled interrupt should not assert IRQ")
+	v.SetCB1(false)
+	assert.True(t, v.IRQ(), "An enabled interrupt should assert IRQ")
+	v.Write(via.RegIFR, via.IntCB1)
+	assert.False(t, v.IRQ(), "Writing a 1 to IFR should clear the flag")
+}
+
+func TestVIA_ShiftOut(t *testing.T) {
+	v := newVIA(t)
+	var bits []bool
+	v.OnCB1 = func(level bool) {
+		if level {
+			bits = append(bits, v.CB2())
+		}
+	}
+	v.Write(via.RegACR, 0x18) // Shift out at the rate of φ2
+	v.Write(via.RegSR, 0xA5)
+	tick(v, 15)
+	assert.Zero(t, v.Read(via.RegIFR), "The shift should take 16 ticks")
+	v.Tick()
+	assert.Equal(t, uint8(via.IntSR), v.Read(via.RegIFR))
+	assert.Equal(t, []bool{true, false, true, false, false, true, false, true}, bits, "The bits should be shifted out MSB first")
+	assert.Equal(t, uint8(0xA5), v.Peek(via.RegSR), "The bits should be recirculated")
+}
+
+func TestVIA_ShiftIn(t *testing.T) {
+	v := newVIA(t)
+	v.Write(via.RegACR, 0x0C) // Shift in on the CB1 clock
+	v.Read(via.RegSR)
+	for i := range 8 {
+		v.SetCB2(0x3C<<i&0x80 != 0)
+		v.SetCB1(false)
+		v.SetCB1(true)
+	}
+	assert.Equal(t, uint8(via.IntSR), v.Read(via.RegIFR)&via.IntSR, "The shift should be complete")
+	assert.Equal(t, uint8(0x3C), v.Read(via.RegSR))
+	assert.Zero(t, v.Read(via.RegIFR)&via.IntSR, "Reading SR should clear the interrupt")
+}
+
+func TestVIA_Peek(t *testing.T) {
+	v := newVIA(t)
+	v.Write(via.RegT1CL, 0)
+	v.Write(via.RegT1CH, 0)
+	tick(v, 2)
+	v.Peek(via.RegT1CL)
+	assert.Equal(t, uint8(via.IntT1), v.Peek(via.RegIFR), "Peeking should not clear the interrupt")
+	v.Read(via.RegT1CL)
+	assert.Zero(t, v.Peek(via.RegIFR), "Reading should clear the interrupt")
+}
+
+func TestVIA_Snapshot(t *testing.T) {
+	v := newVIA(t)
+	v.Write(via.RegDDRA, 0xFF)
+	v.Write(via.RegORA, 0x12)
+	v.Write(via.RegT1CL, 0x34)
+	v.Write(via.RegT1CH, 0x12)
+	v.Write(via.RegIER, 0xC0)
+	tick(v, 100)
+
+	var buf bytes.Buffer
+	assert.NoError(t, snapshot.Save(&buf, v))
+	restored := newVIA(t)
+	assert.NoError(t, snapshot.Load(&buf, restored))
+	for reg := range uint16(16) {
+		assert.Equal(t, v.Peek(reg), restored.Peek(reg), "Register %d should be restored", reg)
+	}
+	tick(v, 0x1234)
+	tick(restored, 0x1234)
+	assert.Equal(t, v.IRQ(), restored.IRQ(), "Both timers should time out together")
+}
+
+// newVIACPU returns a 65C02 running the program at $8000, with 64KB of RAM and a VIA mapped at $6000
+func newVIACPU(t *testing.T, programs map[uint16][]byte) (*processor.CPU, *bus.SimpleBus, *via.VIA) {
+	ram, mapped := bus.NewSimpleBus(), bus.NewMappedBus()
+	v := newVIA(t)
+	assert.NoError(t, mapped.Map(bus.Mapping{Name: "RAM", Start: 0x0000, End: 0xFFFF, Device: ram}))
+	assert.NoError(t, mapped.Map(bus.Mapping{Name: "VIA", Start: 0x6000, End: 0x600F, Priority: 1, Device: v}))
+	cpu := processor.NewCPUWithVariant(mapped, processor.Variant65C02)
+	for addr, program := range programs {
+		cputest.Load(cpu, ram, addr, program...)
+	}
+	cpu.PC = 0x8000
+	v.ConnectIRQ(cpu, 1)
+	return cpu, ram, v
+}
+
+// TestVIA_TimerInterrupts runs a program that counts the interrupts from timer 1 in free-running mode
+func TestVIA_TimerInterrupts(t *testing.T) {
+	cpu, ram, v := newVIACPU(t, map[uint16][]byte{
+		0x8000: {
+			0xA9, 0xC0, //       LDA #$C0
+			0x8D, 0x0E, 0x60, // STA $600E   Enable the timer 1 interrupt
+			0xA9, 0x40, //       LDA #$40
+			0x8D, 0x0B, 0x60, // STA $600B   Free-running
+			0xA9, 0x62, //       LDA #98
+			0x8D, 0x04, 0x60, // STA $6004
+			0xA9, 0x00, //       LDA #0
+			0x8D, 0x05, 0x60, // STA $6005   Start, interrupting every 100 cycles
+			0x58,             // CLI
+			0x4C, 0x15, 0x80, // JMP *
+		},
+		0x9000: {
+			0xE6, 0x10, //       INC $10
+			0x2C, 0x04, 0x60, // BIT $6004   Clear the interrupt
+			0x40, //             RTI
+		},
+		0xFFFE: {0x00, 0x90},
+	})
+	m := machine.New(cpu)
+	assert.NoError(t, m.Attach(v, machine.CPUClock))
+
+	m.RunCycles(10_000)
+	assert.InDelta(t, 99, int(ram.Read(0x10)), 1, "There should be an interrupt every 100 cycles")
+	m.RunCycles(10_000)
+	assert.InDelta(t, 199, int(ram.Read(0x10)), 1, "There should be an interrupt every 100 cycles")
+}
+
+// TestVIA_History checks that recording the CPU's writes for its history does not read the VIA's registers
+func TestVIA_History(t *testing.T) {
+	cpu, _, v := newVIACPU(t, map[uint16][]byte{
+		0x8000: {
+			0xA9, 0x10, //       LDA #$10
+			0x8D, 0x04, 0x60, // STA $6004   Set the timer 1 latch
+		},
+	})
+	cpu.EnableHistory(10)
+	v.Write(via.RegT1CL, 0)
+	v.Write(via.RegT1CH, 0)
+	tick(v, 2)
+	assert.Equal(t, uint8(via.IntT1), v.Peek(via.RegIFR)&via.IntT1)
+
+	cpu.Step()
+	cpu.Step()
+	assert.Equal(t, uint8(0x10), v.Peek(via.RegT1LL), "The latch should have been written")
+	assert.Equal(t, uint8(via.IntT1), v.Peek(via.RegIFR)&via.IntT1, "Writing the latch should not clear the interrupt")
+	assert.Equal(t, 2, cpu.HistoryLen())
+}
@@ -0,0 +1,244 @@
+package w65c816
+
+// The 65C816 has a 24-bit address space made up of 256 banks of 64KB. Addresses built from a 16-bit operand use
+// the Data Bank Register (DBR) for data and the Program Bank Register (PBR) for jumps, while direct page and stack
+// addresses are always in bank 0. Indexing an absolute address can carry into the next bank; indexing a direct page
+// or stack address wraps within bank 0.
+//
+// In emulation mode with the direct page on a page boundary (the low byte of D is zero), direct page indexing
+// wraps within the page, so zero page code written for the 6502 behaves as expected.
+
+type AddressModeFunc func(*CPU) AddressInfo
+
+type AddressInfo struct {
+	Address       uint32 // Effective 24-bit address
+	PageChanged   bool   // Indexing crossed a page boundary, or used 16-bit index registers
+	IsAccumulator bool
+	IsImmediate   bool
+	ExtraByte     bool  // The immediate operand is 16 bits, one byte longer than the operation's base size
+	ExtraCycles   uint8 // Cycles added by the addressing mode itself (a direct page not aligned to a page)
+}
+
+// operand returns the 24-bit address of the byte at the given offset from the current opcode.
+func (c *CPU) operand(offset uint16) uint32 {
+	return c.ProgramAddress(c.PC + offset)
+}
+
+// direct returns the bank 0 address of the given offset into the direct page.
+func (c *CPU) direct(offset uint16) uint16 {
+	return c.D + offset
+}
+
+// directIndexed returns the bank 0 address of the given offset into the direct page plus an index register. In
+// emulation mode with a page aligned direct page the result wraps within the page, as on the 6502.
+func (c *CPU) directIndexed(offset byte, index uint16) uint16 {
+	if c.E && c.D&0x00FF == 0 {
+		return c.D | uint16(offset+byte(index))
+	}
+	return c.D + uint16(offset) + index
+}
+
+// directPointer reads a 16-bit pointer from the direct page. In emulation mode with a page aligned direct page the
+// high byte wraps within the page.
+func (c *CPU) directPointer(addr uint16) uint16 {
+	lo := uint16(c.Read(uint32(addr)))
+	next := addr + 1
+	if c.E && c.D&0x00FF == 0 {
+		next = addr&0xFF00 | uint16(byte(addr)+1)
+	}
+	hi := uint16(c.Read(uint32(next)))
+	return hi<<8 | lo
+}
+
+// directCycles returns the extra cycle taken by direct page addressing modes when the direct page is not aligned.
+func (c *CPU) directCycles() uint8 {
+	return ternary[uint8](c.D&0x00FF != 0, 1, 0)
+}
+
+// indexed adds an index register to a 24-bit base address. PageChanged is set if a page boundary was crossed or
+// the index registers are 16-bit (which always costs an extra cycle).
+func (c *CPU) indexed(base uint32, index uint16) AddressInfo {
+	addr := (base + uint32(index)) & 0xFFFFFF
+	pageChanged := base&0xFFFF00 != addr&0xFFFF00 || !c.Index8()
+	return AddressInfo{Address: addr, PageChanged: pageChanged}
+}
+
+// IMP implements "Implied" address mode.
+// The instruction implicitly operates on internal CPU state and or registers and does not reference a memory
+// address. Instructions using this addressing mode are 1 byte long.
+func IMP(cpu *CPU) AddressInfo {
+	return AddressInfo{}
+}
+
+// ACC implements "Accumulator" address mode.
+// The operation is performed directly on the accumulator register (A) rather than on a memory location.
+func ACC(cpu *CPU) AddressInfo {
+	return AddressInfo{IsAccumulator: true}
+}
+
+// IMM implements "Immediate" address mode with an 8-bit operand, regardless of the register widths. It is used
+// by REP, SEP, and the signature byte of BRK and COP.
+func IMM(cpu *CPU) AddressInfo {
+	return AddressInfo{Address: cpu.operand(1), IsImmediate: true}
+}
+
+// IMA implements "Immediate" address mode for instructions that operate on the accumulator. The operand is 16 bits
+// when the accumulator is 16 bits wide.
+func IMA(cpu *CPU) AddressInfo {
+	return AddressInfo{Address: cpu.operand(1), IsImmediate: true, ExtraByte: !cpu.Accumulator8()}
+}
+
+// IMX implements "Immediate" address mode for instructions that operate on the X or Y register. The operand is
+// 16 bits when the index registers are 16 bits wide.
+func IMX(cpu *CPU) AddressInfo {
+	return AddressInfo{Address: cpu.operand(1), IsImmediate: true, ExtraByte: !cpu.Index8()}
+}
+
+// ABS implements "Absolute" address mode.
+// A 16-bit address is read from the instruction operands and combined with the data bank. Jump instructions use
+// only the low 16 bits and stay in the program bank.
+func ABS(cpu *CPU) AddressInfo {
+	return AddressInfo{Address: cpu.DataAddress(cpu.Read16(cpu.operand(1)))}
+}
+
+// ABX implements "Absolute with X Offset" address mode.
+func ABX(cpu *CPU) AddressInfo {
+	return cpu.indexed(cpu.DataAddress(cpu.Read16(cpu.operand(1))), cpu.X)
+}
+
+// ABY implements "Absolute with Y Offset" address mode.
+func ABY(cpu *CPU) AddressInfo {
+	return cpu.indexed(cpu.DataAddress(cpu.Read16(cpu.operand(1))), cpu.Y)
+}
+
+// ABL implements "Absolute Long" address mode.
+// A full 24-bit address is read from the instruction operands and used directly.
+func ABL(cpu *CPU) AddressInfo {
+	return AddressInfo{Address: cpu.Read24(cpu.operand(1))}
+}
+
+// ALX implements "Absolute Long with X Offset" address mode.
+// A full 24-bit address is read from the instruction operands and the X register is added to it. There is no
+// penalty for crossing a page boundary.
+func ALX(cpu *CPU) AddressInfo {
+	return AddressInfo{Address: (cpu.Read24(cpu.operand(1)) + uint32(cpu.X)) & 0xFFFFFF}
+}
+
+// DIR implements "Direct Page" address mode, the 65C816 equivalent of zero page addressing.
+// The 8-bit operand is an offset into the direct page, which starts at the address in the D register.
+func DIR(cpu *CPU) AddressInfo {
+	addr := cpu.direct(uint16(cpu.Read(cpu.operand(1))))
+	return AddressInfo{Address: uint32(addr), ExtraCycles: cpu.directCycles()}
+}
+
+// DRX implements "Direct Page with X Offset" address mode.
+func DRX(cpu *CPU) AddressInfo {
+	addr := cpu.directIndexed(cpu.Read(cpu.operand(1)), cpu.X)
+	return AddressInfo{Address: uint32(addr), ExtraCycles: cpu.directCycles()}
+}
+
+// DRY implements "Direct Page with Y Offset" address mode.
+func DRY(cpu *CPU) AddressInfo {
+	addr := cpu.directIndexed(cpu.Read(cpu.operand(1)), cpu.Y)
+	return AddressInfo{Address: uint32(addr), ExtraCycles: cpu.directCycles()}
+}
+
+// DIN implements "Direct Page Indirect" address mode.
+// A 16-bit pointer is read from the direct page and combined with the data bank to form the final address.
+func DIN(cpu *CPU) AddressInfo {
+	ptr := cpu.directPointer(cpu.direct(uint16(cpu.Read(cpu.operand(1)))))
+	return AddressInfo{Address: cpu.DataAddress(ptr), ExtraCycles: cpu.directCycles()}
+}
+
+// DNX implements "Direct Page Indexed Indirect X" address mode, the equivalent of the 6502 (zp,X) mode.
+func DNX(cpu *CPU) AddressInfo {
+	ptr := cpu.directPointer(cpu.directIndexed(cpu.Read(cpu.operand(1)), cpu.X))
+	return AddressInfo{Address: cpu.DataAddress(ptr), ExtraCycles: cpu.directCycles()}
+}
+
+// DNY implements "Direct Page Indirect Indexed Y" address mode, the equivalent of the 6502 (zp),Y mode.
+func DNY(cpu *CPU) AddressInfo {
+	ptr := cpu.directPointer(cpu.direct(uint16(cpu.Read(cpu.operand(1)))))
+	info := cpu.indexed(cpu.DataAddress(ptr), cpu.Y)
+	info.ExtraCycles = cpu.directCycles()
+	return info
+}
+
+// DLN implements "Direct Page Indirect Long" address mode.
+// A full 24-bit pointer is read from the direct page and used as the final address.
+func DLN(cpu *CPU) AddressInfo {
+	addr := cpu.Read24(uint32(cpu.direct(uint16(cpu.Read(cpu.operand(1))))))
+	return AddressInfo{Address: addr, ExtraCycles: cpu.directCycles()}
+}
+
+// DLY implements "Direct Page Indirect Long Indexed Y" address mode.
+// A full 24-bit pointer is read from the direct page and the Y register is added to it. There is no penalty for
+// crossing a page boundary.
+func DLY(cpu *CPU) AddressInfo {
+	addr := cpu.Read24(uint32(cpu.direct(uint16(cpu.Read(cpu.operand(1))))))
+	return AddressInfo{Address: (addr + uint32(cpu.Y)) & 0xFFFFFF, ExtraCycles: cpu.directCycles()}
+}
+
+// SRL implements "Stack Relative" address mode.
+// The 8-bit operand is added to the stack pointer to form an address in bank 0. It gives direct access to
+// parameters and local variables on the stack.
+func SRL(cpu *CPU) AddressInfo {
+	return AddressInfo{Address: uint32(cpu.SP + uint16(cpu.Read(cpu.operand(1))))}
+}
+
+// SRY implements "Stack Relative Indirect Indexed Y" address mode.
+// A 16-bit pointer is read from the stack (as in SRL), combined with the data bank, and the Y register is added.
+func SRY(cpu *CPU) AddressInfo {
+	ptr := cpu.Read16(uint32(cpu.SP + uint16(cpu.Read(cpu.operand(1)))))
+	return AddressInfo{Address: (cpu.DataAddress(ptr) + uint32(cpu.Y)) & 0xFFFFFF}
+}
+
+// REL implements "Relative" address mode.
+// The operand is a signed 8-bit offset relative to the next instruction, used by the branch instructions. The
+// target is always within the program bank. Crossing a page boundary only costs an extra cycle in emulation mode.
+func REL(cpu *CPU) AddressInfo {
+	offset := int8(cpu.Read(cpu.operand(1)))
+	baseAddr := cpu.PC + 2
+	addr := baseAddr + uint16(offset)
+	pageChanged := cpu.E && pagesDiffer(baseAddr, addr)
+	return AddressInfo{Address: cpu.ProgramAddress(addr), PageChanged: pageChanged}
+}
+
+// RLL implements "Relative Long" address mode.
+// The operand is a signed 16-bit offset relative to the next instruction, used by BRL and PER. The target wraps
+// within the program bank.
+func RLL(cpu *CPU) AddressInfo {
+	offset := cpu.Read16(cpu.operand(1))
+	return AddressInfo{Address: cpu.ProgramAddress(cpu.PC + 3 + offset)}
+}
+
+// AIN implements "Absolute Indirect" address mode, used by JMP (abs).
+// A 16-bit pointer is read from the instruction operands and used to fetch the 16-bit target address from bank 0.
+// Unlike the NMOS 6502 there is no page wrapping bug.
+func AIN(cpu *CPU) AddressInfo {
+	ptr := uint32(cpu.Read16(cpu.operand(1)))
+	return AddressInfo{Address: cpu.ProgramAddress(cpu.Read16(ptr))}
+}
+
+// AIL implements "Absolute Indirect Long" address mode, used by JML [abs].
+// A 16-bit pointer is read from the instruction operands and used to fetch a full 24-bit target address from
+// bank 0.
+func AIL(cpu *CPU) AddressInfo {
+	ptr := uint32(cpu.Read16(cpu.operand(1)))
+	return AddressInfo{Address: cpu.Read24(ptr)}
+}
+
+// AIX implements "Absolute Indexed Indirect" address mode, used by JMP (abs,X) and JSR (abs,X).
+// The X register is added to a 16-bit base address and the result is used to fetch the target address from the
+// program bank.
+func AIX(cpu *CPU) AddressInfo {
+	ptr := cpu.Read16(cpu.operand(1)) + cpu.X
+	return AddressInfo{Address: cpu.ProgramAddress(cpu.Read16(cpu.ProgramAddress(ptr)))}
+}
+
+// BLK implements "Block Move" address mode, used by MVN and MVP.
+// The instruction has two operands: the destination bank followed by the source bank. Address points at the first
+// operand.
+func BLK(cpu *CPU) AddressInfo {
+	return AddressInfo{Address: cpu.operand(1)}
+}
@@ -0,0 +1,235 @@
+package w65c816_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/ukdave/6502_emulator/bus"
+	"github.com/ukdave/6502_emulator/w65c816"
+)
+
+type AddressModesSuite struct {
+	suite.Suite
+	bus bus.LongBus
+	cpu *w65c816.CPU
+}
+
+func TestAddressModesSuite(t *testing.T) {
+	suite.Run(t, new(AddressModesSuite))
+}
+
+func (suite *AddressModesSuite) SetupTest() {
+	suite.bus = bus.NewSimpleLongBus()
+	suite.cpu = w65c816.NewCPU(suite.bus)
+	suite.cpu.PBR = 0x01
+	suite.cpu.PC = 0x8000
+}
+
+// operands writes the instruction operands after the opcode at 0x018000
+func (suite *AddressModesSuite) operands(operands ...byte) {
+	for i, b := range operands {
+		suite.bus.WriteLong(0x018001+uint32(i), b)
+	}
+}
+
+func (suite *AddressModesSuite) TestIMA() {
+	result := w65c816.IMA(suite.cpu)
+	assert.Equal(suite.T(), uint32(0x018001), result.Address, "Expected address to be 0x018001")
+	assert.False(suite.T(), result.ExtraByte, "Expected an 8-bit operand")
+
+	suite.cpu.SetEmulation(false)
+	suite.cpu.SetFlag(w65c816.M, false)
+	result = w65c816.IMA(suite.cpu)
+	assert.True(suite.T(), result.ExtraByte, "Expected a 16-bit operand")
+}
+
+func (suite *AddressModesSuite) TestABS_UsesDataBank() {
+	suite.operands(0x34, 0x12)
+	suite.cpu.DBR = 0x7E
+
+	result := w65c816.ABS(suite.cpu)
+
+	assert.Equal(suite.T(), uint32(0x7E1234), result.Address, "Expected address to be 0x7E1234")
+}
+
+func (suite *AddressModesSuite) TestABX_CarriesIntoNextBank() {
+	suite.operands(0xFF, 0xFF)
+	suite.cpu.DBR = 0x7E
+	suite.cpu.X = 0x02
+
+	result := w65c816.ABX(suite.cpu)
+
+	assert.Equal(suite.T(), uint32(0x7F0001), result.Address, "Expected address to be 0x7F0001")
+	assert.True(suite.T(), result.PageChanged, "Expected page to change")
+}
+
+func (suite *AddressModesSuite) TestABX_16BitIndexAlwaysPenalised() {
+	suite.operands(0x00, 0x20)
+	suite.cpu.SetEmulation(false)
+	suite.cpu.SetFlag(w65c816.X, false)
+	suite.cpu.X = 0x01
+
+	result := w65c816.ABX(suite.cpu)
+
+	assert.Equal(suite.T(), uint32(0x002001), result.Address, "Expected address to be 0x002001")
+	assert.True(suite.T(), result.PageChanged, "Expected the extra cycle with 16-bit index registers")
+}
+
+func (suite *AddressModesSuite) TestABL() {
+	suite.operands(0x56, 0x34, 0x12)
+
+	result := w65c816.ABL(suite.cpu)
+
+	assert.Equal(suite.T(), uint32(0x123456), result.Address, "Expected address to be 0x123456")
+}
+
+func (suite *AddressModesSuite) TestALX() {
+	suite.operands(0xFF, 0xFF, 0x12)
+	suite.cpu.X = 0x01
+
+	result := w65c816.ALX(suite.cpu)
+
+	assert.Equal(suite.T(), uint32(0x130000), result.Address, "Expected address to be 0x130000")
+}
+
+func (suite *AddressModesSuite) TestDIR() {
+	suite.operands(0x10)
+	suite.cpu.D = 0x2001
+
+	result := w65c816.DIR(suite.cpu)
+
+	assert.Equal(suite.T(), uint32(0x002011), result.Address, "Expected address to be 0x002011")
+	assert.Equal(suite.T(), uint8(1), result.ExtraCycles, "Expected an extra cycle for an unaligned direct page")
+}
+
+func (suite *AddressModesSuite) TestDRX_EmulationWrapsInPage() {
+	suite.operands(0xF0)
+	suite.cpu.D = 0x0200
+	suite.cpu.X = 0x20
+
+	result := w65c816.DRX(suite.cpu)
+
+	assert.Equal(suite.T(), uint32(0x000210), result.Address, "Expected address to wrap within the direct page")
+	assert.Equal(suite.T(), uint8(0), result.ExtraCycles, "Expected no extra cycle for an aligned direct page")
+}
+
+func (suite *AddressModesSuite) TestDRX_NativeDoesNotWrap() {
+	suite.operands(0xF0)
+	suite.cpu.SetEmulation(false)
+	suite.cpu.D = 0x0200
+	suite.cpu.X = 0x20
+
+	result := w65c816.DRX(suite.cpu)
+
+	assert.Equal(suite.T(), uint32(0x000310), result.Address, "Expected address to be 0x000310")
+}
+
+func (suite *AddressModesSuite) TestDNY() {
+	suite.operands(0x10)
+	suite.cpu.DBR = 0x02
+	suite.cpu.Y = 0x10
+	suite.bus.WriteLong(0x0010, 0xF8)
+	suite.bus.WriteLong(0x0011, 0x30)
+
+	result := w65c816.DNY(suite.cpu)
+
+	assert.Equal(suite.T(), uint32(0x023108), result.Address, "Expected address to be 0x023108")
+	assert.True(suite.T(), result.PageChanged, "Expected page to change")
+}
+
+func (suite *AddressModesSuite) TestDLN() {
+	suite.operands(0x10)
+	suite.bus.WriteLong(0x0010, 0x56)
+	suite.bus.WriteLong(0x0011, 0x34)
+	suite.bus.WriteLong(0x0012, 0x12)
+
+	result := w65c816.DLN(suite.cpu)
+
+	assert.Equal(suite.T(), uint32(0x123456), result.Address, "Expected address to be 0x123456")
+}
+
+func (suite *AddressModesSuite) TestDLY() {
+	suite.operands(0x10)
+	suite.cpu.Y = 0x04
+	suite.bus.WriteLong(0x0010, 0xFE)
+	suite.bus.WriteLong(0x0011, 0xFF)
+	suite.bus.WriteLong(0x0012, 0x12)
+
+	result := w65c816.DLY(suite.cpu)
+
+	assert.Equal(suite.T(), uint32(0x130002), result.Address, "Expected address to be 0x130002")
+}
+
+func (suite *AddressModesSuite) TestSRL() {
+	suite.operands(0x03)
+	suite.cpu.SP = 0x01F0
+
+	result := w65c816.SRL(suite.cpu)
+
+	assert.Equal(suite.T(), uint32(0x0001F3), result.Address, "Expected address to be 0x0001F3")
+}
+
+func (suite *AddressModesSuite) TestSRY() {
+	suite.operands(0x01)
+	suite.cpu.SP = 0x01F0
+	suite.cpu.DBR = 0x02
+	suite.cpu.Y = 0x05
+	suite.bus.WriteLong(0x01F1, 0x00)
+	suite.bus.WriteLong(0x01F2, 0x40)
+
+	result := w65c816.SRY(suite.cpu)
+
+	assert.Equal(suite.T(), uint32(0x024005), result.Address, "Expected address to be 0x024005")
+}
+
+func (suite *AddressModesSuite) TestREL() {
+	suite.operands(0xFE) // -2
+
+	result := w65c816.REL(suite.cpu)
+
+	assert.Equal(suite.T(), uint32(0x018000), result.Address, "Expected address to be 0x018000")
+	assert.False(suite.T(), result.PageChanged, "Expected page not to change")
+}
+
+func (suite *AddressModesSuite) TestRLL() {
+	suite.operands(0x00, 0x80) // -32768
+
+	result := w65c816.RLL(suite.cpu)
+
+	assert.Equal(suite.T(), uint32(0x010003), result.Address, "Expected address to be 0x010003")
+}
+
+func (suite *AddressModesSuite) TestAIN_ReadsPointerFromBank0() {
+	suite.operands(0x00, 0x30)
+	suite.bus.WriteLong(0x003000, 0x34)
+	suite.bus.WriteLong(0x003001, 0x12)
+	suite.bus.WriteLong(0x013000, 0xFF) // Not used: the pointer is always in bank 0
+
+	result := w65c816.AIN(suite.cpu)
+
+	assert.Equal(suite.T(), uint32(0x011234), result.Address, "Expected address to be 0x011234")
+}
+
+func (suite *AddressModesSuite) TestAIX_ReadsPointerFromProgramBank() {
+	suite.operands(0x00, 0x30)
+	suite.cpu.X = 0x02
+	suite.bus.WriteLong(0x013002, 0x34)
+	suite.bus.WriteLong(0x013003, 0x12)
+
+	result := w65c816.AIX(suite.cpu)
+
+	assert.Equal(suite.T(), uint32(0x011234), result.Address, "Expected address to be 0x011234")
+}
+
+func (suite *AddressModesSuite) TestAIL() {
+	suite.operands(0x00, 0x30)
+	suite.bus.WriteLong(0x003000, 0x56)
+	suite.bus.WriteLong(0x003001, 0x34)
+	suite.bus.WriteLong(0x003002, 0x12)
+
+	result := w65c816.AIL(suite.cpu)
+
+	assert.Equal(suite.T(), uint32(0x123456), result.Address, "Expected address to be 0x123456")
+}
@@ -0,0 +1,360 @@
+// Package w65c816 implements a WDC 65C816 CPU core, the 16-bit successor to the 6502 used in the SNES and the
+// Apple IIgs. It sits alongside the processor package and follows the same structure: a CPU with exported
+// registers, a table of operations, and one function per instruction and per addressing mode.
+//
+// The 65C816 extends the accumulator and index registers to 16 bits, adds a movable direct page and a data bank
+// register, and widens the address space to 24 bits (16MB) split into 256 banks of 64KB. The CPU interacts with
+// the rest of the system through a bus.LongBus; bus.NewWrappedBus can be used to run it against an existing 16-bit
+// bus.
+//
+// After a reset the CPU is in emulation mode (E = 1), where it behaves like a 65C02 so that existing 6502 programs
+// run unmodified. Executing CLC followed by XCE switches it into native mode, and the REP and SEP instructions then
+// select 8-bit or 16-bit accumulator (M flag) and index registers (X flag).
+package w65c816
+
+import (
+	"github.com/ukdave/6502_emulator/bus"
+)
+
+type Flag byte
+
+// The status register stores 8 flags, which are enumerated here.
+// In emulation mode bits 4 and 5 behave as they do on the 6502 (Break and Unused). In native mode they select the
+// width of the index registers and the accumulator respectively.
+const (
+	C Flag = (1 << 0) // Carry Bit
+	Z Flag = (1 << 1) // Zero
+	I Flag = (1 << 2) // Disable Interrupts
+	D Flag = (1 << 3) // Decimal Mode
+	X Flag = (1 << 4) // Index Register Select (native mode): 1 = 8-bit, 0 = 16-bit
+	M Flag = (1 << 5) // Memory/Accumulator Select (native mode): 1 = 8-bit, 0 = 16-bit
+	V Flag = (1 << 6) // Overflow
+	N Flag = (1 << 7) // Negative
+
+	B Flag = X // Break (emulation mode)
+	U Flag = M // Unused (emulation mode)
+)
+
+// The interrupt vectors. Native mode has its own set, and a separate vector for BRK.
+const (
+	vectorCOPNative   = 0xFFE4
+	vectorBRKNative   = 0xFFE6
+	vectorNMINative   = 0xFFEA
+	vectorIRQNative   = 0xFFEE
+	vectorCOPEmulated = 0xFFF4
+	vectorNMIEmulated = 0xFFFA
+	vectorReset       = 0xFFFC
+	vectorIRQEmulated = 0xFFFE
+)
+
+type CPU struct {
+	bus bus.LongBus
+
+	// CPU Core registers, exported for ease of access by external inspectors.
+	A      uint16 // Accumulator Register (C). When the accumulator is 8-bit the high byte (B) is preserved.
+	X      uint16 // X Register. The high byte is always zero when the index registers are 8-bit.
+	Y      uint16 // Y Register. The high byte is always zero when the index registers are 8-bit.
+	SP     uint16 // Stack Pointer (always in bank 0; confined to page 1 in emulation mode)
+	D      uint16 // Direct Page Register (the 65C816 equivalent of the 6502 zero page, always in bank 0)
+	DBR    byte   // Data Bank Register (the bank used by absolute addressing modes)
+	PBR    byte   // Program Bank Register (the bank the program is executing from)
+	PC     uint16 // Program Counter (within the program bank)
+	Status byte   // Status Register
+	E      bool   // Emulation Mode flag, swapped with the carry flag by XCE
+
+	TotalCycles uint64 // Total number of cycles executed
+	cycles      uint8
+
+	halted  bool // Set by a STP instruction; cleared by Reset
+	waiting bool // Set by a WAI instruction; cleared by an interrupt or Reset
+}
+
+// NewCPU creates a new CPU instance connected to the given bus. The CPU starts in emulation mode.
+func NewCPU(bus bus.LongBus) *CPU {
+	c := &CPU{bus: bus}
+	c.Reset()
+	return c
+}
+
+// Reset resets the CPU to its initial powerup state.
+//
+// The CPU is placed in emulation mode with 8-bit registers, the direct page and both bank registers are set to
+// zero, and execution starts at the address in the (bank 0) reset vector.
+func (c *CPU) Reset() {
+	c.A = 0x0000
+	c.X = 0x0000
+	c.Y = 0x0000
+	c.SP = 0x01FD
+	c.D = 0x0000
+	c.DBR = 0x00
+	c.PBR = 0x00
+	c.E = true
+	c.Status = 0x34 // Clear all flags except M, X and I
+	c.PC = c.ResetVector()
+	c.TotalCycles = 0
+	c.cycles = 0
+	c.halted = false
+	c.waiting = false
+}
+
+// Halted returns true if the CPU has stopped executing instructions after a STP instruction. A halted CPU still
+// counts clock cycles but does nothing else until it is reset.
+func (c *CPU) Halted() bool {
+	return c.halted
+}
+
+// Waiting returns true if the CPU is paused by a WAI instruction. It resumes when an interrupt is signalled.
+func (c *CPU) Waiting() bool {
+	return c.waiting
+}
+
+// ResetVector returns the 16-bit address read from the reset vector ($00FFFC–$00FFFD), which is loaded into the
+// program counter on reset.
+func (c *CPU) ResetVector() uint16 {
+	return c.Read16(vectorReset)
+}
+
+// IRQVector returns the 16-bit address read from the IRQ vector for the current mode, which is loaded into the
+// program counter on an IRQ. In emulation mode BRK shares this vector.
+func (c *CPU) IRQVector() uint16 {
+	return c.Read16(ternary[uint32](c.E, vectorIRQEmulated, vectorIRQNative))
+}
+
+// NMIVector returns the 16-bit address read from the NMI vector for the current mode, which is loaded into the
+// program counter on a non-maskable interrupt.
+func (c *CPU) NMIVector() uint16 {
+	return c.Read16(ternary[uint32](c.E, vectorNMIEmulated, vectorNMINative))
+}
+
+// Clock advances the CPU by a single clock cycle.
+//
+// As with the processor package, each instruction is executed atomically on its first cycle and the remaining
+// cycles are counted down by subsequent calls.
+func (c *CPU) Clock() {
+	c.TotalCycles++
+	if c.cycles > 0 {
+		c.cycles--
+		return
+	}
+	if c.halted || c.waiting {
+		return
+	}
+
+	opcode := c.Read(c.ProgramAddress(c.PC))
+	op := operations[opcode]
+
+	// Get the address information/operand using the appropriate address mode for this operation.
+	addressInfo := op.AddressMode(c)
+
+	// Increment the Program Counter (PC) by the size of this operation. Immediate operands are one byte longer when
+	// the register they are loaded into is 16 bits wide. The PC wraps within the program bank.
+	c.PC += uint16(op.Size)
+	if addressInfo.ExtraByte {
+		c.PC++
+	}
+
+	// Get the starting number of cycles for this operation. Direct page addressing takes an extra cycle when the
+	// low byte of the direct page register is not zero. Instructions add their own cycles for 16-bit data.
+	c.cycles = op.Cycles + addressInfo.ExtraCycles
+
+	// Perform operation
+	extraCycle := op.Instruction(c, addressInfo)
+
+	// Indexed addressing modes take an extra cycle when a page boundary is crossed (or always, when the index
+	// registers are 16-bit), but only for instructions which allow it.
+	if extraCycle && addressInfo.PageChanged {
+		c.cycles++
+	}
+
+	// Decrement the number of cycles remaining for this instruction
+	c.cycles--
+}
+
+// IRQ performs an Interrupt Request (IRQ) sequence.
+// In native mode the Program Bank Register is pushed as well as the Program Counter and status flags.
+func (c *CPU) IRQ() {
+	// An IRQ always wakes the CPU from WAI, even if the interrupt itself is masked
+	c.waiting = false
+
+	// Only run if the Disable Interrupts flag is clear
+	if !c.GetFlag(I) {
+		c.interrupt(c.IRQVector(), false)
+		c.cycles += 7
+	}
+}
+
+// NMI performs a Non-Maskable Interrupt (NMI) sequence.
+func (c *CPU) NMI() {
+	c.waiting = false
+	c.interrupt(c.NMIVector(), false)
+	c.cycles += 7
+}
+
+// interrupt pushes the return state onto the stack and jumps to the given handler in bank 0. The break argument
+// sets the B flag in the status pushed in emulation mode (it has no meaning in native mode).
+func (c *CPU) interrupt(vector uint16, brk bool) {
+	if !c.E {
+		c.Push(c.PBR)
+		c.cycles++
+	}
+	c.Push16(c.PC)
+	if c.E {
+		c.Push(ternary(brk, c.Status|byte(B), c.Status&^byte(B)))
+	} else {
+		c.Push(c.Status)
+	}
+	c.SetFlag(I, true)
+	c.SetFlag(D, false)
+	c.PBR = 0x00
+	c.PC = vector
+}
+
+// ProgramAddress returns the 24-bit address of the given offset within the program bank.
+func (c *CPU) ProgramAddress(addr uint16) uint32 {
+	return uint32(c.PBR)<<16 | uint32(addr)
+}
+
+// DataAddress returns the 24-bit address of the given offset within the data bank.
+func (c *CPU) DataAddress(addr uint16) uint32 {
+	return uint32(c.DBR)<<16 | uint32(addr)
+}
+
+// Read reads an 8-bit value from the bus at the specified 24-bit address.
+func (c *CPU) Read(addr uint32) byte {
+	return c.bus.ReadLong(addr & 0xFFFFFF)
+}
+
+// Read16 reads a 16-bit value from the bus at the specified 24-bit address.
+// The value is assumed to be stored least significant byte first (little endian).
+func (c *CPU) Read16(addr uint32) uint16 {
+	lo := uint16(c.Read(addr))
+	hi := uint16(c.Read(addr + 1))
+	return (hi << 8) | lo
+}
+
+// Read24 reads a 24-bit value (a long address) from the bus at the specified 24-bit address.
+func (c *CPU) Read24(addr uint32) uint32 {
+	return uint32(c.Read16(addr)) | uint32(c.Read(addr+2))<<16
+}
+
+// Write writes an 8-bit value to the bus at the specified 24-bit address.
+func (c *CPU) Write(addr uint32, data byte) {
+	c.bus.WriteLong(addr&0xFFFFFF, data)
+}
+
+// Write16 writes a 16-bit value to the bus at the specified 24-bit address.
+// The value is written least significant byte first (little endian).
+func (c *CPU) Write16(addr uint32, data uint16) {
+	c.Write(addr, uint8(data&0xFF))
+	c.Write(addr+1, uint8(data>>8))
+}
+
+// GetFlag returns the value of a specific bit of the status register.
+func (c *CPU) GetFlag(flag Flag) bool {
+	return (c.Status & byte(flag)) > 0
+}
+
+// SetFlag sets or clears a specific bit of the status register.
+//
+// In emulation mode the M and X flags cannot be cleared, and whenever the X flag is set the high bytes of the index
+// registers are cleared, just as they are on the real chip.
+func (c *CPU) SetFlag(flag Flag, value bool) {
+	if value {
+		c.Status |= byte(flag)
+	} else {
+		c.Status &= ^byte(flag)
+	}
+	c.updateModes()
+}
+
+// SetStatus replaces the whole status register, subject to the same rules as SetFlag.
+func (c *CPU) SetStatus(value byte) {
+	c.Status = value
+	c.updateModes()
+}
+
+// SetEmulation enters or leaves emulation mode. Entering emulation mode forces 8-bit registers and moves the stack
+// back into page 1.
+func (c *CPU) SetEmulation(value bool) {
+	c.E = value
+	c.updateModes()
+}
+
+// updateModes enforces the invariants between E, the M and X flags, and the registers.
+func (c *CPU) updateModes() {
+	if c.E {
+		c.Status |= byte(M | X)
+		c.SP = 0x0100 | c.SP&0x00FF
+	}
+	if c.Status&byte(X) != 0 {
+		c.X &= 0x00FF
+		c.Y &= 0x00FF
+	}
+}
+
+// Accumulator8 returns true if the accumulator (and memory operations) are 8 bits wide.
+func (c *CPU) Accumulator8() bool {
+	return c.E || c.GetFlag(M)
+}
+
+// Index8 returns true if the X and Y registers are 8 bits wide.
+func (c *CPU) Index8() bool {
+	return c.E || c.GetFlag(X)
+}
+
+// setZN sets both the Zero and Negative flags based on a value that is 8 bits wide if narrow is true, or 16 bits
+// wide otherwise.
+func (c *CPU) setZN(value uint16, narrow bool) {
+	if narrow {
+		c.SetFlag(Z, value&0x00FF == 0)
+		c.SetFlag(N, value&0x0080 != 0)
+	} else {
+		c.SetFlag(Z, value == 0)
+		c.SetFlag(N, value&0x8000 != 0)
+	}
+}
+
+func (c *CPU) addBranchCycles(addressInfo AddressInfo) {
+	c.cycles++
+	if addressInfo.PageChanged {
+		c.cycles++
+	}
+}
+
+// Cycles returns the number of remaining cycles (or clock ticks) required to complete the current instruction.
+func (c *CPU) Cycles() uint8 {
+	return c.cycles
+}
+
+// Push pushes an 8-bit value onto the stack. The stack is always in bank 0; in emulation mode it wraps within
+// page 1.
+func (c *CPU) Push(value uint8) {
+	c.Write(uint32(c.SP), value)
+	c.SP--
+	if c.E {
+		c.SP = 0x0100 | c.SP&0x00FF
+	}
+}
+
+// Push16 pushes a 16-bit value onto the stack, most significant byte first.
+func (c *CPU) Push16(value uint16) {
+	c.Push(uint8(value >> 8))
+	c.Push(uint8(value & 0xFF))
+}
+
+// Pop pops an 8-bit value off the stack.
+func (c *CPU) Pop() uint8 {
+	c.SP++
+	if c.E {
+		c.SP = 0x0100 | c.SP&0x00FF
+	}
+	return c.Read(uint32(c.SP))
+}
+
+// Pop16 pops a 16-bit value off the stack.
+// The value is assumed to be stored least significant byte first (little endian).
+func (c *CPU) Pop16() uint16 {
+	lo := uint16(c.Pop())
+	hi := uint16(c.Pop())
+	return hi<<8 | lo
+}
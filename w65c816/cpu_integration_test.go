@@ -0,0 +1,98 @@
+package w65c816_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ukdave/6502_emulator/bus"
+	"github.com/ukdave/6502_emulator/w65c816"
+)
+
+// run clocks the CPU until the Program Counter equals 0x0000 (the IRQ vector of an empty memory, reached via BRK at
+// the end of each program) or the clock cycle limit is reached. It returns the number of clock cycles executed.
+func run(cpu *w65c816.CPU) int {
+	totalClockCycles := 0
+	clockCycleLimit := 1000
+	for {
+		for {
+			cpu.Clock()
+			totalClockCycles++
+			if cpu.Cycles() == 0 || totalClockCycles > clockCycleLimit {
+				break
+			}
+		}
+		if cpu.PC == 0x0000 || totalClockCycles > clockCycleLimit {
+			return totalClockCycles
+		}
+	}
+}
+
+// TestIntegration_EmulationMode runs the same program as the processor package integration test against a
+// SimpleBus, to check that 6502 code behaves identically in emulation mode (including cycle counts).
+func TestIntegration_EmulationMode(t *testing.T) {
+	simple := bus.NewSimpleBus()
+	simple.Write(0xFFFC, 0x00)
+	simple.Write(0xFFFD, 0x80)
+
+	// This program will multiply 10 (0x0A) by 3 (0x03) using repeated addition and store the result at 0x0002
+	bytes := []byte{
+		0xA2, 0x0A, //         LDX #$0A
+		0x8E, 0x00, 0x00, //   STX $0000
+		0xA2, 0x03, //         LDX #$03
+		0x8E, 0x01, 0x00, //   STX $0001
+		0xAC, 0x00, 0x00, //   LDY $0000
+		0xA9, 0x00, //         LDA #$00
+		0x18,             //   CLC
+		0x6D, 0x01, 0x00, //   ADC $0001
+		0x88,       //         DEY
+		0xD0, 0xFA, //         BNE $8010
+		0x8D, 0x02, 0x00, //   STA $0002
+		0xEA, //               NOP
+		0xEA, //               NOP
+		0xEA, //               NOP
+	}
+	for i, b := range bytes {
+		simple.Write(0x8000+uint16(i), b)
+	}
+
+	cpu := w65c816.NewCPU(bus.NewWrappedBus(simple))
+	totalClockCycles := run(cpu)
+
+	assert.Equal(t, uint16(0x0000), cpu.PC, "Expected Program Counter to be 0x0000")
+	assert.Equal(t, 126, totalClockCycles, "Expected totalClockCycles to be 126")
+	assert.Equal(t, uint8(0x1E), simple.Read(0x0002), "Expected program result (at 0x0002) to be 0x1E")
+}
+
+// TestIntegration_NativeMode switches to native mode and adds two 16-bit numbers, one of which is in another bank.
+func TestIntegration_NativeMode(t *testing.T) {
+	bus := bus.NewSimpleLongBus()
+	bus.WriteLong(0xFFFC, 0x00)
+	bus.WriteLong(0xFFFD, 0x80)
+	bus.WriteLong(0xFFE6, 0x00) // Native mode BRK vector
+	bus.WriteLong(0xFFE7, 0x00)
+	bus.WriteLong(0x021000, 0x34)
+	bus.WriteLong(0x021001, 0x12)
+
+	bytes := []byte{
+		0x18,       // CLC
+		0xFB,       // XCE          ; enter native mode
+		0xC2, 0x30, // REP #$30     ; 16-bit A, X and Y
+		0xA9, 0xCD, 0xAB, // LDA #$ABCD
+		0x18,                   // CLC
+		0x6F, 0x00, 0x10, 0x02, // ADC $021000
+		0x8D, 0x00, 0x20, // STA $2000
+		0x00, 0x00, // BRK
+	}
+	for i, b := range bytes {
+		bus.WriteLong(0x8000+uint32(i), b)
+	}
+
+	cpu := w65c816.NewCPU(bus)
+	run(cpu)
+
+	assert.False(t, cpu.E, "Expected CPU to be in native mode")
+	assert.Equal(t, uint16(0xBE01), cpu.A, "Expected Accumulator to be 0xBE01")
+	assert.Equal(t, uint8(0x01), bus.ReadLong(0x2000), "Expected result low byte at 0x2000")
+	assert.Equal(t, uint8(0xBE), bus.ReadLong(0x2001), "Expected result high byte at 0x2001")
+}
@@ -0,0 +1,96 @@
+package w65c816_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ukdave/6502_emulator/bus"
+	"github.com/ukdave/6502_emulator/w65c816"
+)
+
+func TestNewCPU(t *testing.T) {
+	// Write starting value (0x1234) for PC to 0xFFFC
+	bus := bus.NewSimpleLongBus()
+	bus.WriteLong(0xFFFC, 0x34)
+	bus.WriteLong(0xFFFD, 0x12)
+
+	cpu := w65c816.NewCPU(bus)
+
+	assert.True(t, cpu.E, "CPU should start in emulation mode")
+	assert.True(t, cpu.Accumulator8(), "Accumulator should be 8-bit")
+	assert.True(t, cpu.Index8(), "Index registers should be 8-bit")
+	assert.Equal(t, uint16(0x01FD), cpu.SP, "Stack Pointer should be 0x01FD")
+	assert.Equal(t, uint16(0x0000), cpu.D, "Direct Page Register should be 0")
+	assert.Equal(t, uint8(0x00), cpu.DBR, "Data Bank Register should be 0")
+	assert.Equal(t, uint8(0x00), cpu.PBR, "Program Bank Register should be 0")
+	assert.Equal(t, uint16(0x1234), cpu.PC, "Program Counter should be 0x1234")
+	assert.Equal(t, uint8(0b00110100), cpu.Status, "Status Flags should be 0b00110100")
+}
+
+func TestEmulationModeForcesNarrowRegisters(t *testing.T) {
+	cpu := w65c816.NewCPU(bus.NewSimpleLongBus())
+
+	// Enter native mode with 16-bit registers
+	cpu.SetEmulation(false)
+	cpu.SetStatus(0x00)
+	cpu.X = 0x1234
+	cpu.Y = 0x5678
+	cpu.SP = 0x1FF0
+	assert.False(t, cpu.Accumulator8(), "Accumulator should be 16-bit")
+	assert.False(t, cpu.Index8(), "Index registers should be 16-bit")
+
+	// Returning to emulation mode truncates the index registers and the stack pointer
+	cpu.SetEmulation(true)
+	assert.True(t, cpu.GetFlag(w65c816.M), "M flag should be set")
+	assert.True(t, cpu.GetFlag(w65c816.X), "X flag should be set")
+	assert.Equal(t, uint16(0x0034), cpu.X, "X Register should be 0x0034")
+	assert.Equal(t, uint16(0x0078), cpu.Y, "Y Register should be 0x0078")
+	assert.Equal(t, uint16(0x01F0), cpu.SP, "Stack Pointer should be 0x01F0")
+
+	// The M and X flags cannot be cleared in emulation mode
+	cpu.SetStatus(0x00)
+	assert.Equal(t, uint8(0b00110000), cpu.Status, "Status Flags should be 0b00110000")
+}
+
+func TestIRQ_Native(t *testing.T) {
+	bus := bus.NewSimpleLongBus()
+	bus.WriteLong(0xFFEE, 0x00)
+	bus.WriteLong(0xFFEF, 0x90)
+
+	cpu := w65c816.NewCPU(bus)
+	cpu.SetEmulation(false)
+	cpu.SP = 0x1FFF
+	cpu.PBR = 0x12
+	cpu.PC = 0x3456
+	cpu.SetFlag(w65c816.I, false)
+	cpu.SetFlag(w65c816.D, true)
+
+	cpu.IRQ()
+
+	assert.Equal(t, uint16(0x9000), cpu.PC, "Program Counter should be 0x9000")
+	assert.Equal(t, uint8(0x00), cpu.PBR, "Program Bank Register should be 0")
+	assert.True(t, cpu.GetFlag(w65c816.I), "Disable Interrupt flag should be set")
+	assert.False(t, cpu.GetFlag(w65c816.D), "Decimal flag should be cleared")
+	assert.Equal(t, uint8(0x12), bus.ReadLong(0x1FFF), "Expected PBR to be pushed first")
+	assert.Equal(t, uint8(0x34), bus.ReadLong(0x1FFE), "Expected PC high byte on the stack")
+	assert.Equal(t, uint8(0x56), bus.ReadLong(0x1FFD), "Expected PC low byte on the stack")
+	assert.Equal(t, uint16(0x1FFB), cpu.SP, "Stack Pointer should be 0x1FFB")
+	assert.Equal(t, uint8(8), cpu.Cycles(), "Expected 8 cycles")
+}
+
+func TestNMI_Emulation(t *testing.T) {
+	bus := bus.NewSimpleLongBus()
+	bus.WriteLong(0xFFFA, 0x00)
+	bus.WriteLong(0xFFFB, 0xA0)
+
+	cpu := w65c816.NewCPU(bus)
+	cpu.PC = 0x3456
+
+	cpu.NMI()
+
+	assert.Equal(t, uint16(0xA000), cpu.PC, "Program Counter should be 0xA000")
+	assert.Equal(t, uint16(0x01FA), cpu.SP, "Stack Pointer should be 0x01FA")
+	assert.Equal(t, uint8(0b00100100), bus.ReadLong(0x01FB), "Expected the B flag to be clear in the pushed status")
+	assert.Equal(t, uint8(7), cpu.Cycles(), "Expected 7 cycles")
+}
@@ -0,0 +1,1063 @@
+package w65c816
+
+// The 65C816 implements 92 instructions: those of the 65C02 (without the Rockwell bit instructions) plus the new
+// 16-bit, bank and block move instructions.
+//
+// Access:     LDA, STA, LDX, STX, LDY, STY, STZ
+// Transfer:   TAX, TXA, TAY, TYA, TXY, TYX, TCD, TDC, TCS, TSC, XBA
+// Arithmetic: ADC, SBC, INC, DEC, INX, DEX, INY, DEY
+// Shift:      ASL, LSR, ROL, ROR
+// Bitwise:    AND, ORA, EOR, BIT, TSB, TRB
+// Compare:    CMP, CPX, CPY
+// Branch:     BCC, BCS, BEQ, BNE, BPL, BMI, BVC, BVS, BRA, BRL
+// Jump:       JMP, JML, JSR, JSL, RTS, RTL, BRK, COP, RTI
+// Stack:      PHA, PLA, PHX, PLX, PHY, PLY, PHB, PLB, PHD, PLD, PHK, PHP, PLP, PEA, PEI, PER, TXS, TSX
+// Block Move: MVN, MVP
+// Flags:      CLC, SEC, CLI, SEI, CLD, SED, CLV, REP, SEP, XCE
+// Other:      NOP, WDM, WAI, STP
+//
+// https://www.westerndesigncenter.com/wdc/documentation/w65c816s.pdf
+//
+// Most instructions operate on 8 or 16 bits of data depending on the M flag (accumulator and memory) or the X flag
+// (index registers). Each 16-bit memory access takes one extra clock cycle, which the instructions add themselves.
+// As in the processor package, an instruction returns true if it allows the extra cycle taken when an indexed
+// addressing mode crosses a page boundary.
+
+type InstructionFunc func(*CPU, AddressInfo) bool
+
+// widthM returns the mask and sign bit for the current accumulator width.
+func (c *CPU) widthM() (mask uint16, sign uint16) {
+	if c.Accumulator8() {
+		return 0x00FF, 0x0080
+	}
+	return 0xFFFF, 0x8000
+}
+
+// setA sets the accumulator. When it is 8-bit only the low byte is changed; the high byte (B) is preserved.
+func (c *CPU) setA(value uint16) {
+	if c.Accumulator8() {
+		c.A = c.A&0xFF00 | value&0x00FF
+	} else {
+		c.A = value
+	}
+}
+
+// setX sets the X register, truncating the value to 8 bits if the index registers are 8-bit.
+func (c *CPU) setX(value uint16) {
+	c.X = ternary(c.Index8(), value&0x00FF, value)
+}
+
+// setY sets the Y register, truncating the value to 8 bits if the index registers are 8-bit.
+func (c *CPU) setY(value uint16) {
+	c.Y = ternary(c.Index8(), value&0x00FF, value)
+}
+
+// readM reads an accumulator sized operand from memory (or from the accumulator itself).
+func (c *CPU) readM(addressInfo AddressInfo) uint16 {
+	mask, _ := c.widthM()
+	if addressInfo.IsAccumulator {
+		return c.A & mask
+	}
+	if c.Accumulator8() {
+		return uint16(c.Read(addressInfo.Address))
+	}
+	c.cycles++
+	return c.Read16(addressInfo.Address)
+}
+
+// writeM writes an accumulator sized operand to memory (or to the accumulator itself).
+func (c *CPU) writeM(addressInfo AddressInfo, value uint16) {
+	if addressInfo.IsAccumulator {
+		c.setA(value)
+		return
+	}
+	if c.Accumulator8() {
+		c.Write(addressInfo.Address, byte(value))
+		return
+	}
+	c.cycles++
+	c.Write16(addressInfo.Address, value)
+}
+
+// readX reads an index register sized operand from memory.
+func (c *CPU) readX(addressInfo AddressInfo) uint16 {
+	if c.Index8() {
+		return uint16(c.Read(addressInfo.Address))
+	}
+	c.cycles++
+	return c.Read16(addressInfo.Address)
+}
+
+// writeX writes an index register sized operand to memory.
+func (c *CPU) writeX(addressInfo AddressInfo, value uint16) {
+	if c.Index8() {
+		c.Write(addressInfo.Address, byte(value))
+		return
+	}
+	c.cycles++
+	c.Write16(addressInfo.Address, value)
+}
+
+//
+// Load/Store Instructions
+//
+
+// LDA - Load Accumulator
+// Function:  A = memory
+// Flags Out: Z, N
+func LDA(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.setA(cpu.readM(addressInfo))
+	cpu.setZN(cpu.A, cpu.Accumulator8())
+	return true
+}
+
+// STA - Store Accumulator
+// Function: memory = A
+func STA(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.writeM(addressInfo, cpu.A)
+	return false
+}
+
+// LDX - Load X Register
+// Function:  X = memory
+// Flags Out: Z, N
+func LDX(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.setX(cpu.readX(addressInfo))
+	cpu.setZN(cpu.X, cpu.Index8())
+	return true
+}
+
+// STX - Store X Register
+// Function: memory = X
+func STX(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.writeX(addressInfo, cpu.X)
+	return false
+}
+
+// LDY - Load Y Register
+// Function:  Y = memory
+// Flags Out: Z, N
+func LDY(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.setY(cpu.readX(addressInfo))
+	cpu.setZN(cpu.Y, cpu.Index8())
+	return true
+}
+
+// STY - Store Y Register
+// Function: memory = Y
+func STY(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.writeX(addressInfo, cpu.Y)
+	return false
+}
+
+// STZ - Store Zero
+// Function: memory = 0
+func STZ(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.writeM(addressInfo, 0)
+	return false
+}
+
+//
+// Register Transfer Instructions
+//
+// The width of a transfer is the width of the destination register.
+//
+
+// TAX - Transfer Accumulator to X
+// Function:  X = A
+// Flags Out: Z, N
+func TAX(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.setX(cpu.A)
+	cpu.setZN(cpu.X, cpu.Index8())
+	return false
+}
+
+// TAY - Transfer Accumulator to Y
+// Function:  Y = A
+// Flags Out: Z, N
+func TAY(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.setY(cpu.A)
+	cpu.setZN(cpu.Y, cpu.Index8())
+	return false
+}
+
+// TXA - Transfer X to Accumulator
+// Function:  A = X
+// Flags Out: Z, N
+func TXA(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.setA(cpu.X)
+	cpu.setZN(cpu.A, cpu.Accumulator8())
+	return false
+}
+
+// TYA - Transfer Y to Accumulator
+// Function:  A = Y
+// Flags Out: Z, N
+func TYA(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.setA(cpu.Y)
+	cpu.setZN(cpu.A, cpu.Accumulator8())
+	return false
+}
+
+// TXY - Transfer X to Y
+// Function:  Y = X
+// Flags Out: Z, N
+func TXY(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.setY(cpu.X)
+	cpu.setZN(cpu.Y, cpu.Index8())
+	return false
+}
+
+// TYX - Transfer Y to X
+// Function:  X = Y
+// Flags Out: Z, N
+func TYX(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.setX(cpu.Y)
+	cpu.setZN(cpu.X, cpu.Index8())
+	return false
+}
+
+// TCD - Transfer 16-bit Accumulator to Direct Page Register
+// Function:  D = C
+// Flags Out: Z, N
+func TCD(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.D = cpu.A
+	cpu.setZN(cpu.D, false)
+	return false
+}
+
+// TDC - Transfer Direct Page Register to 16-bit Accumulator
+// Function:  C = D
+// Flags Out: Z, N
+func TDC(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.A = cpu.D
+	cpu.setZN(cpu.A, false)
+	return false
+}
+
+// TCS - Transfer 16-bit Accumulator to Stack Pointer
+// Function: SP = C
+//
+// In emulation mode only the low byte is transferred, keeping the stack in page 1.
+func TCS(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.SP = ternary(cpu.E, 0x0100|cpu.A&0x00FF, cpu.A)
+	return false
+}
+
+// TSC - Transfer Stack Pointer to 16-bit Accumulator
+// Function:  C = SP
+// Flags Out: Z, N
+func TSC(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.A = cpu.SP
+	cpu.setZN(cpu.A, false)
+	return false
+}
+
+// XBA - Exchange the B and A Accumulators (the high and low bytes of the accumulator)
+// Function:  A = A << 8 | A >> 8
+// Flags Out: Z, N (from the new low byte)
+func XBA(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.A = cpu.A<<8 | cpu.A>>8
+	cpu.setZN(cpu.A, true)
+	return false
+}
+
+//
+// Arithmetic Instructions
+//
+
+// ADC - Add with Carry
+// Function:  A = A + memory + C
+// Flags Out: C, Z, V, N
+//
+// When the D flag is set the operands are treated as 2 (or 4) digit BCD numbers. Unlike the NMOS 6502 all the
+// flags are valid after a decimal operation.
+func ADC(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.add(cpu.readM(addressInfo))
+	return true
+}
+
+// SBC - Subtract with Carry
+// Function:  A = A - memory - !C
+// Flags Out: C, Z, V, N
+//
+// As with ADC, decimal arithmetic is used when the D flag is set.
+func SBC(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.subtract(cpu.readM(addressInfo))
+	return true
+}
+
+// INC - Increment Memory (or the Accumulator)
+// Function:  memory = memory + 1
+// Flags Out: Z, N
+func INC(cpu *CPU, addressInfo AddressInfo) bool {
+	value := cpu.readM(addressInfo) + 1
+	cpu.writeM(addressInfo, value)
+	cpu.setZN(value, cpu.Accumulator8())
+	return false
+}
+
+// DEC - Decrement Memory (or the Accumulator)
+// Function:  memory = memory - 1
+// Flags Out: Z, N
+func DEC(cpu *CPU, addressInfo AddressInfo) bool {
+	value := cpu.readM(addressInfo) - 1
+	cpu.writeM(addressInfo, value)
+	cpu.setZN(value, cpu.Accumulator8())
+	return false
+}
+
+// INX - Increment X Register
+// Function:  X = X + 1
+// Flags Out: Z, N
+func INX(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.setX(cpu.X + 1)
+	cpu.setZN(cpu.X, cpu.Index8())
+	return false
+}
+
+// DEX - Decrement X Register
+// Function:  X = X - 1
+// Flags Out: Z, N
+func DEX(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.setX(cpu.X - 1)
+	cpu.setZN(cpu.X, cpu.Index8())
+	return false
+}
+
+// INY - Increment Y Register
+// Function:  Y = Y + 1
+// Flags Out: Z, N
+func INY(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.setY(cpu.Y + 1)
+	cpu.setZN(cpu.Y, cpu.Index8())
+	return false
+}
+
+// DEY - Decrement Y Register
+// Function:  Y = Y - 1
+// Flags Out: Z, N
+func DEY(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.setY(cpu.Y - 1)
+	cpu.setZN(cpu.Y, cpu.Index8())
+	return false
+}
+
+// add adds value and the carry flag to the accumulator, using BCD arithmetic when the D flag is set.
+func (c *CPU) add(value uint16) {
+	mask, sign := c.widthM()
+	a := uint32(c.A & mask)
+	v := uint32(value & mask)
+	carry := ternary(c.GetFlag(C), uint32(1), uint32(0))
+
+	// unadjusted is the result before the top digit is decimal adjusted; V is taken from it
+	var result, unadjusted uint32
+	if c.GetFlag(D) {
+		digits := ternary(c.Accumulator8(), 2, 4)
+		for i := range digits {
+			shift := uint(i * 4)
+			digit := (a>>shift)&0x0F + (v>>shift)&0x0F + carry
+			if i == digits-1 {
+				unadjusted = result | digit<<shift
+			}
+			if digit > 0x09 {
+				digit += 0x06
+			}
+			carry = ternary(digit > 0x0F, uint32(1), uint32(0))
+			result |= (digit & 0x0F) << shift
+		}
+		result |= carry << (digits * 4)
+	} else {
+		result = a + v + carry
+		unadjusted = result
+	}
+
+	c.SetFlag(C, result > uint32(mask))
+	c.SetFlag(V, ^(a^v)&(a^unadjusted)&uint32(sign) != 0)
+	c.setA(uint16(result))
+	c.setZN(uint16(result), c.Accumulator8())
+}
+
+// subtract subtracts value and the inverted carry flag from the accumulator, using BCD arithmetic when the D flag
+// is set.
+func (c *CPU) subtract(value uint16) {
+	if !c.GetFlag(D) {
+		// Binary subtraction is the same as adding the ones' complement
+		c.add(^value)
+		return
+	}
+
+	mask, sign := c.widthM()
+	a := int(c.A & mask)
+	v := int(value & mask)
+	borrow := ternary(c.GetFlag(C), 0, 1)
+
+	// V is set exactly as it would be for a binary subtraction
+	binary := a - v - borrow
+	c.SetFlag(V, (a^v)&(a^binary)&int(sign) != 0)
+
+	var result int
+	for i := range ternary(c.Accumulator8(), 2, 4) {
+		shift := uint(i * 4)
+		digit := (a>>shift)&0x0F - (v>>shift)&0x0F - borrow
+		borrow = 0
+		if digit < 0 {
+			digit += 10
+			borrow = 1
+		}
+		result |= (digit & 0x0F) << shift
+	}
+
+	c.SetFlag(C, borrow == 0)
+	c.setA(uint16(result))
+	c.setZN(uint16(result), c.Accumulator8())
+}
+
+//
+// Shift Instructions
+//
+
+// ASL - Arithmetic Shift Left
+// Function:  memory = memory << 1 (or A = A << 1)
+// Flags Out: C, Z, N
+func ASL(cpu *CPU, addressInfo AddressInfo) bool {
+	_, sign := cpu.widthM()
+	value := cpu.readM(addressInfo)
+	cpu.SetFlag(C, value&sign != 0)
+	value <<= 1
+	cpu.writeM(addressInfo, value)
+	cpu.setZN(value, cpu.Accumulator8())
+	return false
+}
+
+// LSR - Logical Shift Right
+// Function:  memory = memory >> 1 (or A = A >> 1)
+// Flags Out: C, Z, N
+func LSR(cpu *CPU, addressInfo AddressInfo) bool {
+	value := cpu.readM(addressInfo)
+	cpu.SetFlag(C, value&0x0001 != 0)
+	value >>= 1
+	cpu.writeM(addressInfo, value)
+	cpu.setZN(value, cpu.Accumulator8())
+	return false
+}
+
+// ROL - Rotate Left
+// Function:  memory = memory << 1 through C (or A = A << 1 through C)
+// Flags Out: C, Z, N
+func ROL(cpu *CPU, addressInfo AddressInfo) bool {
+	_, sign := cpu.widthM()
+	c := ternary(cpu.GetFlag(C), uint16(1), uint16(0))
+	value := cpu.readM(addressInfo)
+	cpu.SetFlag(C, value&sign != 0)
+	value = value<<1 | c
+	cpu.writeM(addressInfo, value)
+	cpu.setZN(value, cpu.Accumulator8())
+	return false
+}
+
+// ROR - Rotate Right
+// Function:  memory = memory >> 1 through C (or A = A >> 1 through C)
+// Flags Out: C, Z, N
+func ROR(cpu *CPU, addressInfo AddressInfo) bool {
+	_, sign := cpu.widthM()
+	c := ternary(cpu.GetFlag(C), sign, uint16(0))
+	value := cpu.readM(addressInfo)
+	cpu.SetFlag(C, value&0x0001 != 0)
+	value = value>>1 | c
+	cpu.writeM(addressInfo, value)
+	cpu.setZN(value, cpu.Accumulator8())
+	return false
+}
+
+//
+// Bitwise Instructions
+//
+
+// AND - Bitwise Logic AND
+// Function:  A = A & memory
+// Flags Out: Z, N
+func AND(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.setA(cpu.A & cpu.readM(addressInfo))
+	cpu.setZN(cpu.A, cpu.Accumulator8())
+	return true
+}
+
+// ORA - Bitwise Logic OR
+// Function:  A = A | memory
+// Flags Out: Z, N
+func ORA(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.setA(cpu.A | cpu.readM(addressInfo))
+	cpu.setZN(cpu.A, cpu.Accumulator8())
+	return true
+}
+
+// EOR - Bitwise Logic XOR
+// Function:  A = A ^ memory
+// Flags Out: Z, N
+func EOR(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.setA(cpu.A ^ cpu.readM(addressInfo))
+	cpu.setZN(cpu.A, cpu.Accumulator8())
+	return true
+}
+
+// BIT - Test Bits in Memory with Accumulator
+// Function:  A & memory
+// Flags Out: Z, V, N
+//
+// N and V are copied from the top two bits of the operand, except in immediate mode where only Z is affected.
+func BIT(cpu *CPU, addressInfo AddressInfo) bool {
+	mask, sign := cpu.widthM()
+	value := cpu.readM(addressInfo)
+	cpu.SetFlag(Z, cpu.A&value&mask == 0)
+	if !addressInfo.IsImmediate {
+		cpu.SetFlag(N, value&sign != 0)
+		cpu.SetFlag(V, value&(sign>>1) != 0)
+	}
+	return true
+}
+
+// TSB - Test and Set Memory Bits
+// Function:  memory = memory | A
+// Flags Out: Z (from A & memory)
+func TSB(cpu *CPU, addressInfo AddressInfo) bool {
+	mask, _ := cpu.widthM()
+	value := cpu.readM(addressInfo)
+	cpu.SetFlag(Z, cpu.A&value&mask == 0)
+	cpu.writeM(addressInfo, value|cpu.A)
+	return false
+}
+
+// TRB - Test and Reset Memory Bits
+// Function:  memory = memory & ^A
+// Flags Out: Z (from A & memory)
+func TRB(cpu *CPU, addressInfo AddressInfo) bool {
+	mask, _ := cpu.widthM()
+	value := cpu.readM(addressInfo)
+	cpu.SetFlag(Z, cpu.A&value&mask == 0)
+	cpu.writeM(addressInfo, value&^cpu.A)
+	return false
+}
+
+// compare sets the flags for a comparison of a register with a value of the given width.
+func (c *CPU) compare(register uint16, value uint16, narrow bool) {
+	mask := ternary(narrow, uint16(0x00FF), uint16(0xFFFF))
+	register &= mask
+	value &= mask
+	c.SetFlag(C, register >= value)
+	c.setZN(register-value, narrow)
+}
+
+// CMP - Compare Accumulator
+// Function:  A - memory
+// Flags Out: C, Z, N
+func CMP(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.compare(cpu.A, cpu.readM(addressInfo), cpu.Accumulator8())
+	return true
+}
+
+// CPX - Compare X Register
+// Function:  X - memory
+// Flags Out: C, Z, N
+func CPX(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.compare(cpu.X, cpu.readX(addressInfo), cpu.Index8())
+	return false
+}
+
+// CPY - Compare Y Register
+// Function:  Y - memory
+// Flags Out: C, Z, N
+func CPY(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.compare(cpu.Y, cpu.readX(addressInfo), cpu.Index8())
+	return false
+}
+
+//
+// Branch Instructions
+//
+
+// BCC - Branch if Carry Clear
+func BCC(cpu *CPU, addressInfo AddressInfo) bool {
+	if !cpu.GetFlag(C) {
+		cpu.addBranchCycles(addressInfo)
+		cpu.PC = uint16(addressInfo.Address)
+	}
+	return false
+}
+
+// BCS - Branch if Carry Set
+func BCS(cpu *CPU, addressInfo AddressInfo) bool {
+	if cpu.GetFlag(C) {
+		cpu.addBranchCycles(addressInfo)
+		cpu.PC = uint16(addressInfo.Address)
+	}
+	return false
+}
+
+// BEQ - Branch if Equal
+func BEQ(cpu *CPU, addressInfo AddressInfo) bool {
+	if cpu.GetFlag(Z) {
+		cpu.addBranchCycles(addressInfo)
+		cpu.PC = uint16(addressInfo.Address)
+	}
+	return false
+}
+
+// BNE - Branch if Not Equal
+func BNE(cpu *CPU, addressInfo AddressInfo) bool {
+	if !cpu.GetFlag(Z) {
+		cpu.addBranchCycles(addressInfo)
+		cpu.PC = uint16(addressInfo.Address)
+	}
+	return false
+}
+
+// BPL - Branch if Positive
+func BPL(cpu *CPU, addressInfo AddressInfo) bool {
+	if !cpu.GetFlag(N) {
+		cpu.addBranchCycles(addressInfo)
+		cpu.PC = uint16(addressInfo.Address)
+	}
+	return false
+}
+
+// BMI - Branch if Negative
+func BMI(cpu *CPU, addressInfo AddressInfo) bool {
+	if cpu.GetFlag(N) {
+		cpu.addBranchCycles(addressInfo)
+		cpu.PC = uint16(addressInfo.Address)
+	}
+	return false
+}
+
+// BVC - Branch if Overflow Clear
+func BVC(cpu *CPU, addressInfo AddressInfo) bool {
+	if !cpu.GetFlag(V) {
+		cpu.addBranchCycles(addressInfo)
+		cpu.PC = uint16(addressInfo.Address)
+	}
+	return false
+}
+
+// BVS - Branch if Overflow Set
+func BVS(cpu *CPU, addressInfo AddressInfo) bool {
+	if cpu.GetFlag(V) {
+		cpu.addBranchCycles(addressInfo)
+		cpu.PC = uint16(addressInfo.Address)
+	}
+	return false
+}
+
+// BRA - Branch Always
+func BRA(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.addBranchCycles(addressInfo)
+	cpu.PC = uint16(addressInfo.Address)
+	return false
+}
+
+// BRL - Branch Always Long
+// The 16-bit offset can reach anywhere in the program bank. It always takes 4 cycles.
+func BRL(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.PC = uint16(addressInfo.Address)
+	return false
+}
+
+//
+// Jump Instructions
+//
+
+// JMP - Jump (within the program bank)
+// Function = PC = memory
+func JMP(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.PC = uint16(addressInfo.Address)
+	return false
+}
+
+// JML - Jump Long
+// Function = PBR:PC = memory
+func JML(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.PBR = byte(addressInfo.Address >> 16)
+	cpu.PC = uint16(addressInfo.Address)
+	return false
+}
+
+// JSR - Jump to Subroutine (within the program bank)
+// Function:
+//
+//	push PC - 1 to stack
+//	PC = memory
+func JSR(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.Push16(cpu.PC - 1)
+	cpu.PC = uint16(addressInfo.Address)
+	return false
+}
+
+// JSL - Jump to Subroutine Long
+// Function:
+//
+//	push PBR to stack
+//	push PC - 1 to stack
+//	PBR:PC = memory
+func JSL(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.Push(cpu.PBR)
+	cpu.Push16(cpu.PC - 1)
+	cpu.PBR = byte(addressInfo.Address >> 16)
+	cpu.PC = uint16(addressInfo.Address)
+	return false
+}
+
+// RTS - Return from Subroutine
+// Function:
+//
+//	pull PC from stack
+//	PC = PC + 1
+func RTS(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.PC = cpu.Pop16() + 1
+	return false
+}
+
+// RTL - Return from Subroutine Long
+// Function:
+//
+//	pull PC from stack
+//	pull PBR from stack
+//	PC = PC + 1
+func RTL(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.PC = cpu.Pop16() + 1
+	cpu.PBR = cpu.Pop()
+	return false
+}
+
+// BRK - Software Break
+// Function:
+//
+//	push PBR to stack (native mode only)
+//	push PC + 2 to stack
+//	push status flags to stack
+//	PBR:PC = ($00FFE6) in native mode, or ($00FFFE) in emulation mode
+func BRK(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.interrupt(cpu.Read16(ternary[uint32](cpu.E, vectorIRQEmulated, vectorBRKNative)), true)
+	return false
+}
+
+// COP - Co-Processor Enable
+// A software interrupt like BRK, with its own vector. The signature byte is free for the handler to use.
+func COP(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.interrupt(cpu.Read16(ternary[uint32](cpu.E, vectorCOPEmulated, vectorCOPNative)), false)
+	return false
+}
+
+// RTI - Return from Interrupt
+// Function:
+//
+//	pull status flags from stack
+//	pull PC from stack
+//	pull PBR from stack (native mode only)
+func RTI(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.SetStatus(cpu.Pop())
+	cpu.PC = cpu.Pop16()
+	if !cpu.E {
+		cpu.PBR = cpu.Pop()
+		cpu.cycles++
+	}
+	return false
+}
+
+//
+// Stack Instructions
+//
+
+// PHA - Push Accumulator
+func PHA(cpu *CPU, addressInfo AddressInfo) bool {
+	if cpu.Accumulator8() {
+		cpu.Push(byte(cpu.A))
+	} else {
+		cpu.Push16(cpu.A)
+		cpu.cycles++
+	}
+	return false
+}
+
+// PLA - Pull Accumulator
+// Flags Out: Z, N
+func PLA(cpu *CPU, addressInfo AddressInfo) bool {
+	if cpu.Accumulator8() {
+		cpu.setA(uint16(cpu.Pop()))
+	} else {
+		cpu.A = cpu.Pop16()
+		cpu.cycles++
+	}
+	cpu.setZN(cpu.A, cpu.Accumulator8())
+	return false
+}
+
+// PHX - Push X Register
+func PHX(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.pushIndex(cpu.X)
+	return false
+}
+
+// PLX - Pull X Register
+// Flags Out: Z, N
+func PLX(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.X = cpu.popIndex()
+	cpu.setZN(cpu.X, cpu.Index8())
+	return false
+}
+
+// PHY - Push Y Register
+func PHY(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.pushIndex(cpu.Y)
+	return false
+}
+
+// PLY - Pull Y Register
+// Flags Out: Z, N
+func PLY(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.Y = cpu.popIndex()
+	cpu.setZN(cpu.Y, cpu.Index8())
+	return false
+}
+
+// pushIndex pushes an index register sized value onto the stack.
+func (c *CPU) pushIndex(value uint16) {
+	if c.Index8() {
+		c.Push(byte(value))
+	} else {
+		c.Push16(value)
+		c.cycles++
+	}
+}
+
+// popIndex pops an index register sized value off the stack.
+func (c *CPU) popIndex() uint16 {
+	if c.Index8() {
+		return uint16(c.Pop())
+	}
+	c.cycles++
+	return c.Pop16()
+}
+
+// PHB - Push Data Bank Register
+func PHB(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.Push(cpu.DBR)
+	return false
+}
+
+// PLB - Pull Data Bank Register
+// Flags Out: Z, N
+func PLB(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.DBR = cpu.Pop()
+	cpu.setZN(uint16(cpu.DBR), true)
+	return false
+}
+
+// PHD - Push Direct Page Register
+func PHD(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.Push16(cpu.D)
+	return false
+}
+
+// PLD - Pull Direct Page Register
+// Flags Out: Z, N
+func PLD(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.D = cpu.Pop16()
+	cpu.setZN(cpu.D, false)
+	return false
+}
+
+// PHK - Push Program Bank Register
+func PHK(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.Push(cpu.PBR)
+	return false
+}
+
+// PHP - Push Processor Status
+func PHP(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.Push(cpu.Status)
+	return false
+}
+
+// PLP - Pull Processor Status
+// In emulation mode the M and X (B and U) bits are always read back as 1.
+func PLP(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.SetStatus(cpu.Pop())
+	return false
+}
+
+// PEA - Push Effective Absolute Address
+// Pushes the 16-bit operand itself (not the value at that address).
+func PEA(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.Push16(uint16(addressInfo.Address))
+	return false
+}
+
+// PEI - Push Effective Indirect Address
+// Pushes the 16-bit value stored at the direct page address.
+func PEI(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.Push16(cpu.Read16(addressInfo.Address))
+	return false
+}
+
+// PER - Push Effective PC Relative Address
+// Pushes the address formed by adding the 16-bit operand to the address of the next instruction.
+func PER(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.Push16(uint16(addressInfo.Address))
+	return false
+}
+
+// TXS - Transfer X to Stack Pointer
+// In emulation mode only the low byte is transferred, keeping the stack in page 1.
+func TXS(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.SP = ternary(cpu.E, 0x0100|cpu.X&0x00FF, cpu.X)
+	return false
+}
+
+// TSX - Transfer Stack Pointer to X
+// Flags Out: Z, N
+func TSX(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.setX(cpu.SP)
+	cpu.setZN(cpu.X, cpu.Index8())
+	return false
+}
+
+//
+// Block Move Instructions
+//
+// MVN and MVP copy C + 1 bytes (the full 16-bit accumulator, regardless of the M flag) from the source bank at X
+// to the destination bank at Y. Each execution moves a single byte in 7 cycles and then, unless the count has run
+// out, moves the PC back to the start of the instruction so that it executes again. This means interrupts can be
+// serviced in the middle of a long move.
+//
+
+// MVN - Block Move Negative (X and Y are incremented; used when the destination is below the source)
+func MVN(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.blockMove(addressInfo, 1)
+	return false
+}
+
+// MVP - Block Move Positive (X and Y are decremented; used when the destination is above the source)
+func MVP(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.blockMove(addressInfo, 0xFFFF)
+	return false
+}
+
+// blockMove moves a single byte for MVN or MVP and adds step to the X and Y registers.
+func (c *CPU) blockMove(addressInfo AddressInfo, step uint16) {
+	destBank := c.Read(addressInfo.Address)
+	sourceBank := c.Read(addressInfo.Address + 1)
+	c.DBR = destBank
+
+	value := c.Read(uint32(sourceBank)<<16 | uint32(c.X))
+	c.Write(uint32(destBank)<<16|uint32(c.Y), value)
+	c.setX(c.X + step)
+	c.setY(c.Y + step)
+
+	c.A--
+	if c.A != 0xFFFF {
+		c.PC -= 3
+	}
+}
+
+//
+// Status Flag Instructions
+//
+
+// CLC - Clear Carry Flag
+func CLC(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.SetFlag(C, false)
+	return false
+}
+
+// SEC - Set Carry Flag
+func SEC(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.SetFlag(C, true)
+	return false
+}
+
+// CLI - Clear Interrupt Disable Flag
+func CLI(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.SetFlag(I, false)
+	return false
+}
+
+// SEI - Set Interrupt Disable Flag
+func SEI(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.SetFlag(I, true)
+	return false
+}
+
+// CLD - Clear Decimal Flag
+func CLD(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.SetFlag(D, false)
+	return false
+}
+
+// SED - Set Decimal Flag
+func SED(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.SetFlag(D, true)
+	return false
+}
+
+// CLV - Clear Overflow Flag
+func CLV(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.SetFlag(V, false)
+	return false
+}
+
+// REP - Reset Processor Status Bits
+// Function: Status = Status & ^memory
+//
+// Clears every status flag whose bit is set in the operand. In emulation mode the M and X flags stay set.
+func REP(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.SetStatus(cpu.Status &^ cpu.Read(addressInfo.Address))
+	return false
+}
+
+// SEP - Set Processor Status Bits
+// Function: Status = Status | memory
+//
+// Sets every status flag whose bit is set in the operand. Setting the X flag clears the high bytes of X and Y.
+func SEP(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.SetStatus(cpu.Status | cpu.Read(addressInfo.Address))
+	return false
+}
+
+// XCE - Exchange Carry and Emulation Flags
+// This is the only way to switch between emulation and native mode: CLC; XCE enters native mode, and SEC; XCE
+// returns to emulation mode.
+func XCE(cpu *CPU, addressInfo AddressInfo) bool {
+	carry := cpu.GetFlag(C)
+	cpu.SetFlag(C, cpu.E)
+	cpu.SetEmulation(carry)
+	return false
+}
+
+//
+// Other Instructions
+//
+
+// NOP - No Operation
+func NOP(cpu *CPU, addressInfo AddressInfo) bool {
+	return false
+}
+
+// WDM - Reserved for future expansion (William D. Mensch, Jr.). A two byte NOP.
+func WDM(cpu *CPU, addressInfo AddressInfo) bool {
+	return false
+}
+
+// WAI - Wait for Interrupt
+// The processor stops until an IRQ or NMI is signalled.
+func WAI(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.waiting = true
+	return false
+}
+
+// STP - Stop the Clock
+// The processor stops until it is reset. The Program Counter is left pointing at the STP opcode.
+func STP(cpu *CPU, addressInfo AddressInfo) bool {
+	cpu.PC--
+	cpu.halted = true
+	return false
+}
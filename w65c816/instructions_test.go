@@ -0,0 +1,490 @@
+package w65c816_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/ukdave/6502_emulator/bus"
+	"github.com/ukdave/6502_emulator/w65c816"
+)
+
+type InstructionsSuite struct {
+	suite.Suite
+	bus bus.LongBus
+	cpu *w65c816.CPU
+}
+
+func TestInstructionsSuite(t *testing.T) {
+	suite.Run(t, new(InstructionsSuite))
+}
+
+func (suite *InstructionsSuite) SetupTest() {
+	suite.bus = bus.NewSimpleLongBus()
+	suite.cpu = w65c816.NewCPU(suite.bus)
+}
+
+// native switches the CPU to native mode with 16-bit accumulator and index registers
+func (suite *InstructionsSuite) native() {
+	suite.cpu.SetEmulation(false)
+	suite.cpu.SetFlag(w65c816.M, false)
+	suite.cpu.SetFlag(w65c816.X, false)
+}
+
+// load writes a program at 0x8000 and points the Program Counter at it
+func (suite *InstructionsSuite) load(program ...byte) {
+	for i, b := range program {
+		suite.bus.WriteLong(0x8000+uint32(i), b)
+	}
+	suite.cpu.PC = 0x8000
+}
+
+//
+// Load/Store Instructions
+//
+
+func (suite *InstructionsSuite) TestLDA_8Bit() {
+	suite.cpu.A = 0x1234
+	suite.bus.WriteLong(0x2000, 0x80)
+
+	extraCycle := w65c816.LDA(suite.cpu, w65c816.AddressInfo{Address: 0x2000})
+
+	assert.Equal(suite.T(), uint16(0x1280), suite.cpu.A, "Accumulator should be 0x1280 (high byte preserved)")
+	assert.True(suite.T(), suite.cpu.GetFlag(w65c816.N), "Negative flag should be set")
+	assert.True(suite.T(), extraCycle, "Expected extraCycle to be true")
+}
+
+func (suite *InstructionsSuite) TestLDA_16Bit() {
+	suite.native()
+	suite.bus.WriteLong(0x2000, 0x00)
+	suite.bus.WriteLong(0x2001, 0x80)
+
+	w65c816.LDA(suite.cpu, w65c816.AddressInfo{Address: 0x2000})
+
+	assert.Equal(suite.T(), uint16(0x8000), suite.cpu.A, "Accumulator should be 0x8000")
+	assert.True(suite.T(), suite.cpu.GetFlag(w65c816.N), "Negative flag should be set")
+	assert.False(suite.T(), suite.cpu.GetFlag(w65c816.Z), "Zero flag should be false")
+	assert.Equal(suite.T(), uint8(1), suite.cpu.Cycles(), "Expected an extra cycle for the 16-bit read")
+}
+
+func (suite *InstructionsSuite) TestLDA_ImmediateSize() {
+	// LDA #$1234 is 3 bytes long and takes 3 cycles when the accumulator is 16-bit
+	suite.native()
+	suite.load(0xA9, 0x34, 0x12)
+
+	suite.cpu.Clock()
+
+	assert.Equal(suite.T(), uint16(0x1234), suite.cpu.A, "Accumulator should be 0x1234")
+	assert.Equal(suite.T(), uint16(0x8003), suite.cpu.PC, "Program Counter should be 0x8003")
+	assert.Equal(suite.T(), uint8(2), suite.cpu.Cycles(), "Expected 2 cycles remaining")
+}
+
+func (suite *InstructionsSuite) TestSTA_16Bit() {
+	suite.native()
+	suite.cpu.A = 0xBEEF
+
+	w65c816.STA(suite.cpu, w65c816.AddressInfo{Address: 0x7E2000})
+
+	assert.Equal(suite.T(), uint8(0xEF), suite.bus.ReadLong(0x7E2000), "Memory at 0x7E2000 should be 0xEF")
+	assert.Equal(suite.T(), uint8(0xBE), suite.bus.ReadLong(0x7E2001), "Memory at 0x7E2001 should be 0xBE")
+}
+
+func (suite *InstructionsSuite) TestLDX_16Bit() {
+	suite.native()
+	suite.bus.WriteLong(0x2000, 0x00)
+	suite.bus.WriteLong(0x2001, 0x01)
+
+	w65c816.LDX(suite.cpu, w65c816.AddressInfo{Address: 0x2000})
+
+	assert.Equal(suite.T(), uint16(0x0100), suite.cpu.X, "X Register should be 0x0100")
+}
+
+//
+// Register Transfer Instructions
+//
+
+func (suite *InstructionsSuite) TestTAX_WidthOfDestination() {
+	// With an 8-bit accumulator and 16-bit index registers all 16 bits of C are transferred
+	suite.native()
+	suite.cpu.SetFlag(w65c816.M, true)
+	suite.cpu.A = 0x1234
+
+	w65c816.TAX(suite.cpu, w65c816.AddressInfo{})
+
+	assert.Equal(suite.T(), uint16(0x1234), suite.cpu.X, "X Register should be 0x1234")
+}
+
+func (suite *InstructionsSuite) TestTCD_TDC() {
+	suite.cpu.A = 0x1234
+
+	w65c816.TCD(suite.cpu, w65c816.AddressInfo{})
+	assert.Equal(suite.T(), uint16(0x1234), suite.cpu.D, "Direct Page Register should be 0x1234")
+
+	suite.cpu.A = 0x0000
+	w65c816.TDC(suite.cpu, w65c816.AddressInfo{})
+	assert.Equal(suite.T(), uint16(0x1234), suite.cpu.A, "Accumulator should be 0x1234")
+}
+
+func (suite *InstructionsSuite) TestTCS_Emulation() {
+	suite.cpu.A = 0x1234
+
+	w65c816.TCS(suite.cpu, w65c816.AddressInfo{})
+
+	assert.Equal(suite.T(), uint16(0x0134), suite.cpu.SP, "Stack Pointer should stay in page 1")
+}
+
+func (suite *InstructionsSuite) TestXBA() {
+	suite.cpu.A = 0x80FF
+
+	w65c816.XBA(suite.cpu, w65c816.AddressInfo{})
+
+	assert.Equal(suite.T(), uint16(0xFF80), suite.cpu.A, "Accumulator should be 0xFF80")
+	assert.True(suite.T(), suite.cpu.GetFlag(w65c816.N), "Negative flag should be set")
+}
+
+//
+// Arithmetic Instructions
+//
+
+func (suite *InstructionsSuite) TestADC_16Bit() {
+	suite.native()
+	suite.cpu.A = 0x7FFF
+	suite.cpu.SetFlag(w65c816.C, false)
+	suite.bus.WriteLong(0x2000, 0x01)
+	suite.bus.WriteLong(0x2001, 0x00)
+
+	w65c816.ADC(suite.cpu, w65c816.AddressInfo{Address: 0x2000})
+
+	assert.Equal(suite.T(), uint16(0x8000), suite.cpu.A, "Accumulator should be 0x8000")
+	assert.True(suite.T(), suite.cpu.GetFlag(w65c816.V), "Overflow flag should be set")
+	assert.True(suite.T(), suite.cpu.GetFlag(w65c816.N), "Negative flag should be set")
+	assert.False(suite.T(), suite.cpu.GetFlag(w65c816.C), "Carry flag should be false")
+}
+
+func (suite *InstructionsSuite) TestADC_Decimal16Bit() {
+	suite.native()
+	suite.cpu.A = 0x9999
+	suite.cpu.SetFlag(w65c816.C, false)
+	suite.cpu.SetFlag(w65c816.D, true)
+	suite.bus.WriteLong(0x2000, 0x01)
+	suite.bus.WriteLong(0x2001, 0x00)
+
+	w65c816.ADC(suite.cpu, w65c816.AddressInfo{Address: 0x2000})
+
+	assert.Equal(suite.T(), uint16(0x0000), suite.cpu.A, "Accumulator should be 0x0000")
+	assert.True(suite.T(), suite.cpu.GetFlag(w65c816.C), "Carry flag should be set")
+	assert.True(suite.T(), suite.cpu.GetFlag(w65c816.Z), "Zero flag should be set")
+}
+
+func (suite *InstructionsSuite) TestSBC_Decimal16Bit() {
+	suite.native()
+	suite.cpu.A = 0x1000
+	suite.cpu.SetFlag(w65c816.C, true)
+	suite.cpu.SetFlag(w65c816.D, true)
+	suite.bus.WriteLong(0x2000, 0x01)
+	suite.bus.WriteLong(0x2001, 0x00)
+
+	w65c816.SBC(suite.cpu, w65c816.AddressInfo{Address: 0x2000})
+
+	assert.Equal(suite.T(), uint16(0x0999), suite.cpu.A, "Accumulator should be 0x0999")
+	assert.True(suite.T(), suite.cpu.GetFlag(w65c816.C), "Carry flag should be set (no borrow)")
+}
+
+func (suite *InstructionsSuite) TestSBC_Binary8Bit() {
+	suite.cpu.A = 0x1200
+	suite.cpu.SetFlag(w65c816.C, true)
+	suite.bus.WriteLong(0x2000, 0x01)
+
+	w65c816.SBC(suite.cpu, w65c816.AddressInfo{Address: 0x2000})
+
+	assert.Equal(suite.T(), uint16(0x12FF), suite.cpu.A, "Accumulator should be 0x12FF")
+	assert.False(suite.T(), suite.cpu.GetFlag(w65c816.C), "Carry flag should be false (borrow)")
+	assert.True(suite.T(), suite.cpu.GetFlag(w65c816.N), "Negative flag should be set")
+}
+
+func (suite *InstructionsSuite) TestINC_16BitMemory() {
+	suite.native()
+	suite.bus.WriteLong(0x2000, 0xFF)
+	suite.bus.WriteLong(0x2001, 0x00)
+
+	w65c816.INC(suite.cpu, w65c816.AddressInfo{Address: 0x2000})
+
+	assert.Equal(suite.T(), uint8(0x00), suite.bus.ReadLong(0x2000), "Memory at 0x2000 should be 0x00")
+	assert.Equal(suite.T(), uint8(0x01), suite.bus.ReadLong(0x2001), "Memory at 0x2001 should be 0x01")
+	assert.Equal(suite.T(), uint8(2), suite.cpu.Cycles(), "Expected two extra cycles for a 16-bit read-modify-write")
+}
+
+func (suite *InstructionsSuite) TestINX_8BitWraps() {
+	suite.cpu.X = 0x00FF
+
+	w65c816.INX(suite.cpu, w65c816.AddressInfo{})
+
+	assert.Equal(suite.T(), uint16(0x0000), suite.cpu.X, "X Register should be 0x0000")
+	assert.True(suite.T(), suite.cpu.GetFlag(w65c816.Z), "Zero flag should be set")
+}
+
+//
+// Shift Instructions
+//
+
+func (suite *InstructionsSuite) TestASL_16BitAccumulator() {
+	suite.native()
+	suite.cpu.A = 0x8001
+
+	w65c816.ASL(suite.cpu, w65c816.AddressInfo{IsAccumulator: true})
+
+	assert.Equal(suite.T(), uint16(0x0002), suite.cpu.A, "Accumulator should be 0x0002")
+	assert.True(suite.T(), suite.cpu.GetFlag(w65c816.C), "Carry flag should be set")
+}
+
+func (suite *InstructionsSuite) TestROR_8BitAccumulator() {
+	suite.cpu.A = 0x1201
+	suite.cpu.SetFlag(w65c816.C, true)
+
+	w65c816.ROR(suite.cpu, w65c816.AddressInfo{IsAccumulator: true})
+
+	assert.Equal(suite.T(), uint16(0x1280), suite.cpu.A, "Accumulator should be 0x1280")
+	assert.True(suite.T(), suite.cpu.GetFlag(w65c816.C), "Carry flag should be set")
+}
+
+//
+// Bitwise Instructions
+//
+
+func (suite *InstructionsSuite) TestBIT_16Bit() {
+	suite.native()
+	suite.cpu.A = 0x0001
+	suite.bus.WriteLong(0x2000, 0x00)
+	suite.bus.WriteLong(0x2001, 0xC0)
+
+	w65c816.BIT(suite.cpu, w65c816.AddressInfo{Address: 0x2000})
+
+	assert.True(suite.T(), suite.cpu.GetFlag(w65c816.Z), "Zero flag should be set")
+	assert.True(suite.T(), suite.cpu.GetFlag(w65c816.N), "Negative flag should be set")
+	assert.True(suite.T(), suite.cpu.GetFlag(w65c816.V), "Overflow flag should be set")
+}
+
+func (suite *InstructionsSuite) TestCMP_16Bit() {
+	suite.native()
+	suite.cpu.A = 0x1000
+	suite.bus.WriteLong(0x2000, 0x00)
+	suite.bus.WriteLong(0x2001, 0x20)
+
+	w65c816.CMP(suite.cpu, w65c816.AddressInfo{Address: 0x2000})
+
+	assert.False(suite.T(), suite.cpu.GetFlag(w65c816.C), "Carry flag should be false")
+	assert.True(suite.T(), suite.cpu.GetFlag(w65c816.N), "Negative flag should be set")
+}
+
+//
+// Jump Instructions
+//
+
+func (suite *InstructionsSuite) TestJSL_RTL() {
+	suite.cpu.SetEmulation(false)
+	suite.cpu.SP = 0x1FFF
+	suite.cpu.PBR = 0x01
+	suite.cpu.PC = 0x8004
+
+	w65c816.JSL(suite.cpu, w65c816.AddressInfo{Address: 0x123456})
+
+	assert.Equal(suite.T(), uint8(0x12), suite.cpu.PBR, "Program Bank Register should be 0x12")
+	assert.Equal(suite.T(), uint16(0x3456), suite.cpu.PC, "Program Counter should be 0x3456")
+	assert.Equal(suite.T(), uint8(0x01), suite.bus.ReadLong(0x1FFF), "Expected the program bank to be pushed")
+
+	w65c816.RTL(suite.cpu, w65c816.AddressInfo{})
+
+	assert.Equal(suite.T(), uint8(0x01), suite.cpu.PBR, "Program Bank Register should be 0x01")
+	assert.Equal(suite.T(), uint16(0x8004), suite.cpu.PC, "Program Counter should be 0x8004")
+	assert.Equal(suite.T(), uint16(0x1FFF), suite.cpu.SP, "Stack Pointer should be 0x1FFF")
+}
+
+func (suite *InstructionsSuite) TestJMP_StaysInProgramBank() {
+	suite.cpu.PBR = 0x05
+	suite.cpu.DBR = 0x7E
+
+	w65c816.JMP(suite.cpu, w65c816.AddressInfo{Address: 0x7E1234})
+
+	assert.Equal(suite.T(), uint8(0x05), suite.cpu.PBR, "Program Bank Register should be unchanged")
+	assert.Equal(suite.T(), uint16(0x1234), suite.cpu.PC, "Program Counter should be 0x1234")
+}
+
+func (suite *InstructionsSuite) TestBRK_Native() {
+	suite.bus.WriteLong(0xFFE6, 0x00)
+	suite.bus.WriteLong(0xFFE7, 0xC0)
+	suite.cpu.SetEmulation(false)
+	suite.cpu.PBR = 0x02
+
+	w65c816.BRK(suite.cpu, w65c816.AddressInfo{})
+
+	assert.Equal(suite.T(), uint16(0xC000), suite.cpu.PC, "Program Counter should be 0xC000")
+	assert.Equal(suite.T(), uint8(0x00), suite.cpu.PBR, "Program Bank Register should be 0")
+}
+
+//
+// Stack Instructions
+//
+
+func (suite *InstructionsSuite) TestPHA_PLA_16Bit() {
+	suite.native()
+	suite.cpu.A = 0x1234
+
+	w65c816.PHA(suite.cpu, w65c816.AddressInfo{})
+	assert.Equal(suite.T(), uint16(0x01FB), suite.cpu.SP, "Stack Pointer should be 0x01FB")
+
+	suite.cpu.A = 0x0000
+	w65c816.PLA(suite.cpu, w65c816.AddressInfo{})
+	assert.Equal(suite.T(), uint16(0x1234), suite.cpu.A, "Accumulator should be 0x1234")
+	assert.Equal(suite.T(), uint16(0x01FD), suite.cpu.SP, "Stack Pointer should be 0x01FD")
+}
+
+func (suite *InstructionsSuite) TestPHB_PLB() {
+	suite.cpu.DBR = 0x80
+
+	w65c816.PHB(suite.cpu, w65c816.AddressInfo{})
+	suite.cpu.DBR = 0x00
+	w65c816.PLB(suite.cpu, w65c816.AddressInfo{})
+
+	assert.Equal(suite.T(), uint8(0x80), suite.cpu.DBR, "Data Bank Register should be 0x80")
+	assert.True(suite.T(), suite.cpu.GetFlag(w65c816.N), "Negative flag should be set")
+}
+
+func (suite *InstructionsSuite) TestPEA() {
+	w65c816.PEA(suite.cpu, w65c816.AddressInfo{Address: 0x7E1234})
+
+	assert.Equal(suite.T(), uint16(0x1234), suite.cpu.Read16(0x01FC), "Expected 0x1234 on the stack")
+}
+
+func (suite *InstructionsSuite) TestPEI() {
+	suite.bus.WriteLong(0x0010, 0x78)
+	suite.bus.WriteLong(0x0011, 0x56)
+
+	w65c816.PEI(suite.cpu, w65c816.AddressInfo{Address: 0x0010})
+
+	assert.Equal(suite.T(), uint16(0x5678), suite.cpu.Read16(0x01FC), "Expected 0x5678 on the stack")
+}
+
+func (suite *InstructionsSuite) TestPLP_Emulation() {
+	suite.cpu.Push(0x00)
+
+	w65c816.PLP(suite.cpu, w65c816.AddressInfo{})
+
+	assert.Equal(suite.T(), uint8(0b00110000), suite.cpu.Status, "M and X should remain set in emulation mode")
+}
+
+//
+// Block Move Instructions
+//
+
+func (suite *InstructionsSuite) TestMVN() {
+	suite.native()
+	// MVN $7E,$01 (destination bank first)
+	suite.load(0x54, 0x7E, 0x01)
+	suite.bus.WriteLong(0x011000, 0xAA)
+	suite.bus.WriteLong(0x011001, 0xBB)
+	suite.bus.WriteLong(0x011002, 0xCC)
+	suite.cpu.A = 0x0002 // Move 3 bytes
+	suite.cpu.X = 0x1000
+	suite.cpu.Y = 0x2000
+
+	for suite.cpu.PC == 0x8000 {
+		suite.cpu.Clock()
+	}
+	for suite.cpu.Cycles() > 0 {
+		suite.cpu.Clock()
+	}
+
+	assert.Equal(suite.T(), uint8(0xAA), suite.bus.ReadLong(0x7E2000), "Memory at 0x7E2000 should be 0xAA")
+	assert.Equal(suite.T(), uint8(0xBB), suite.bus.ReadLong(0x7E2001), "Memory at 0x7E2001 should be 0xBB")
+	assert.Equal(suite.T(), uint8(0xCC), suite.bus.ReadLong(0x7E2002), "Memory at 0x7E2002 should be 0xCC")
+	assert.Equal(suite.T(), uint16(0xFFFF), suite.cpu.A, "Accumulator should be 0xFFFF")
+	assert.Equal(suite.T(), uint16(0x1003), suite.cpu.X, "X Register should be 0x1003")
+	assert.Equal(suite.T(), uint16(0x2003), suite.cpu.Y, "Y Register should be 0x2003")
+	assert.Equal(suite.T(), uint8(0x7E), suite.cpu.DBR, "Data Bank Register should be 0x7E")
+	assert.Equal(suite.T(), uint64(21), suite.cpu.TotalCycles, "Expected 7 cycles per byte")
+}
+
+func (suite *InstructionsSuite) TestMVP() {
+	suite.native()
+	suite.cpu.A = 0x0001 // Move 2 bytes
+	suite.cpu.X = 0x1001
+	suite.cpu.Y = 0x1002
+	suite.bus.WriteLong(0x1000, 0x11)
+	suite.bus.WriteLong(0x1001, 0x22)
+	suite.bus.WriteLong(0x9001, 0x00) // Destination bank
+	suite.bus.WriteLong(0x9002, 0x00) // Source bank
+	suite.cpu.PC = 0x9003
+
+	w65c816.MVP(suite.cpu, w65c816.AddressInfo{Address: 0x9001})
+	assert.Equal(suite.T(), uint16(0x9000), suite.cpu.PC, "Expected the instruction to repeat")
+
+	// The last byte leaves A at 0xFFFF and the PC pointing at the next instruction
+	suite.cpu.PC = 0x9003
+	w65c816.MVP(suite.cpu, w65c816.AddressInfo{Address: 0x9001})
+	assert.Equal(suite.T(), uint16(0x9003), suite.cpu.PC, "Expected the move to be complete")
+	assert.Equal(suite.T(), uint16(0xFFFF), suite.cpu.A, "Accumulator should be 0xFFFF")
+	assert.Equal(suite.T(), uint8(0x22), suite.bus.ReadLong(0x1002), "Memory at 0x1002 should be 0x22")
+	assert.Equal(suite.T(), uint8(0x11), suite.bus.ReadLong(0x1001), "Memory at 0x1001 should be 0x11")
+}
+
+//
+// Status Flag Instructions
+//
+
+func (suite *InstructionsSuite) TestREP_SEP() {
+	suite.cpu.SetEmulation(false)
+	suite.cpu.X = 0x0012
+	suite.bus.WriteLong(0x9000, 0x30)
+
+	w65c816.REP(suite.cpu, w65c816.AddressInfo{Address: 0x9000})
+	assert.False(suite.T(), suite.cpu.Accumulator8(), "Accumulator should be 16-bit")
+	assert.False(suite.T(), suite.cpu.Index8(), "Index registers should be 16-bit")
+
+	suite.cpu.X = 0x1234
+	w65c816.SEP(suite.cpu, w65c816.AddressInfo{Address: 0x9000})
+	assert.True(suite.T(), suite.cpu.Accumulator8(), "Accumulator should be 8-bit")
+	assert.Equal(suite.T(), uint16(0x0034), suite.cpu.X, "Setting the X flag should clear the high byte of X")
+}
+
+func (suite *InstructionsSuite) TestXCE() {
+	suite.cpu.SetFlag(w65c816.C, false)
+
+	w65c816.XCE(suite.cpu, w65c816.AddressInfo{})
+	assert.False(suite.T(), suite.cpu.E, "CPU should be in native mode")
+	assert.True(suite.T(), suite.cpu.GetFlag(w65c816.C), "Carry should hold the old emulation flag")
+
+	w65c816.XCE(suite.cpu, w65c816.AddressInfo{})
+	assert.True(suite.T(), suite.cpu.E, "CPU should be in emulation mode")
+	assert.False(suite.T(), suite.cpu.GetFlag(w65c816.C), "Carry should hold the old emulation flag")
+}
+
+//
+// Other Instructions
+//
+
+func (suite *InstructionsSuite) TestSTP() {
+	suite.load(0xDB)
+
+	for range 10 {
+		suite.cpu.Clock()
+	}
+
+	assert.True(suite.T(), suite.cpu.Halted(), "CPU should be halted")
+	assert.Equal(suite.T(), uint16(0x8000), suite.cpu.PC, "Expected PC to point at STP")
+}
+
+func (suite *InstructionsSuite) TestWAI() {
+	suite.load(0xCB, 0xE8) // WAI; INX
+	suite.cpu.SetFlag(w65c816.I, true)
+
+	for range 10 {
+		suite.cpu.Clock()
+	}
+	assert.True(suite.T(), suite.cpu.Waiting(), "CPU should be waiting")
+
+	suite.cpu.IRQ()
+	suite.cpu.Clock()
+	assert.False(suite.T(), suite.cpu.Waiting(), "CPU should no longer be waiting")
+	assert.Equal(suite.T(), uint16(0x0001), suite.cpu.X, "Expected INX to have been executed")
+}
@@ -0,0 +1,35 @@
+package w65c816
+
+type Operation struct {
+	Instruction InstructionFunc
+	AddressMode AddressModeFunc
+	Size        uint8 // Size in bytes, assuming 8-bit immediate operands
+	Cycles      uint8 // Base cycle count, assuming 8-bit registers and a page aligned direct page
+}
+
+// operations is the lookup table for all 65C816 instructions. Unlike the 6502 every one of the 256 opcodes is a
+// documented instruction. It is 16x16 entries, arranged so that the bottom 4 bits of the opcode choose the column,
+// and the top 4 bits choose the row.
+var operations = [256]Operation{
+	{BRK, IMM, 2, 7}, {ORA, DNX, 2, 6}, {COP, IMM, 2, 7}, {ORA, SRL, 2, 4}, {TSB, DIR, 2, 5}, {ORA, DIR, 2, 3}, {ASL, DIR, 2, 5}, {ORA, DLN, 2, 6}, {PHP, IMP, 1, 3}, {ORA, IMA, 2, 2}, {ASL, ACC, 1, 2}, {PHD, IMP, 1, 4}, {TSB, ABS, 3, 6}, {ORA, ABS, 3, 4}, {ASL, ABS, 3, 6}, {ORA, ABL, 4, 5},
+	{BPL, REL, 2, 2}, {ORA, DNY, 2, 5}, {ORA, DIN, 2, 5}, {ORA, SRY, 2, 7}, {TRB, DIR, 2, 5}, {ORA, DRX, 2, 4}, {ASL, DRX, 2, 6}, {ORA, DLY, 2, 6}, {CLC, IMP, 1, 2}, {ORA, ABY, 3, 4}, {INC, ACC, 1, 2}, {TCS, IMP, 1, 2}, {TRB, ABS, 3, 6}, {ORA, ABX, 3, 4}, {ASL, ABX, 3, 7}, {ORA, ALX, 4, 5},
+	{JSR, ABS, 3, 6}, {AND, DNX, 2, 6}, {JSL, ABL, 4, 8}, {AND, SRL, 2, 4}, {BIT, DIR, 2, 3}, {AND, DIR, 2, 3}, {ROL, DIR, 2, 5}, {AND, DLN, 2, 6}, {PLP, IMP, 1, 4}, {AND, IMA, 2, 2}, {ROL, ACC, 1, 2}, {PLD, IMP, 1, 5}, {BIT, ABS, 3, 4}, {AND, ABS, 3, 4}, {ROL, ABS, 3, 6}, {AND, ABL, 4, 5},
+	{BMI, REL, 2, 2}, {AND, DNY, 2, 5}, {AND, DIN, 2, 5}, {AND, SRY, 2, 7}, {BIT, DRX, 2, 4}, {AND, DRX, 2, 4}, {ROL, DRX, 2, 6}, {AND, DLY, 2, 6}, {SEC, IMP, 1, 2}, {AND, ABY, 3, 4}, {DEC, ACC, 1, 2}, {TSC, IMP, 1, 2}, {BIT, ABX, 3, 4}, {AND, ABX, 3, 4}, {ROL, ABX, 3, 7}, {AND, ALX, 4, 5},
+	{RTI, IMP, 1, 6}, {EOR, DNX, 2, 6}, {WDM, IMM, 2, 2}, {EOR, SRL, 2, 4}, {MVP, BLK, 3, 7}, {EOR, DIR, 2, 3}, {LSR, DIR, 2, 5}, {EOR, DLN, 2, 6}, {PHA, IMP, 1, 3}, {EOR, IMA, 2, 2}, {LSR, ACC, 1, 2}, {PHK, IMP, 1, 3}, {JMP, ABS, 3, 3}, {EOR, ABS, 3, 4}, {LSR, ABS, 3, 6}, {EOR, ABL, 4, 5},
+	{BVC, REL, 2, 2}, {EOR, DNY, 2, 5}, {EOR, DIN, 2, 5}, {EOR, SRY, 2, 7}, {MVN, BLK, 3, 7}, {EOR, DRX, 2, 4}, {LSR, DRX, 2, 6}, {EOR, DLY, 2, 6}, {CLI, IMP, 1, 2}, {EOR, ABY, 3, 4}, {PHY, IMP, 1, 3}, {TCD, IMP, 1, 2}, {JML, ABL, 4, 4}, {EOR, ABX, 3, 4}, {LSR, ABX, 3, 7}, {EOR, ALX, 4, 5},
+	{RTS, IMP, 1, 6}, {ADC, DNX, 2, 6}, {PER, RLL, 3, 6}, {ADC, SRL, 2, 4}, {STZ, DIR, 2, 3}, {ADC, DIR, 2, 3}, {ROR, DIR, 2, 5}, {ADC, DLN, 2, 6}, {PLA, IMP, 1, 4}, {ADC, IMA, 2, 2}, {ROR, ACC, 1, 2}, {RTL, IMP, 1, 6}, {JMP, AIN, 3, 5}, {ADC, ABS, 3, 4}, {ROR, ABS, 3, 6}, {ADC, ABL, 4, 5},
+	{BVS, REL, 2, 2}, {ADC, DNY, 2, 5}, {ADC, DIN, 2, 5}, {ADC, SRY, 2, 7}, {STZ, DRX, 2, 4}, {ADC, DRX, 2, 4}, {ROR, DRX, 2, 6}, {ADC, DLY, 2, 6}, {SEI, IMP, 1, 2}, {ADC, ABY, 3, 4}, {PLY, IMP, 1, 4}, {TDC, IMP, 1, 2}, {JMP, AIX, 3, 6}, {ADC, ABX, 3, 4}, {ROR, ABX, 3, 7}, {ADC, ALX, 4, 5},
+	{BRA, REL, 2, 2}, {STA, DNX, 2, 6}, {BRL, RLL, 3, 4}, {STA, SRL, 2, 4}, {STY, DIR, 2, 3}, {STA, DIR, 2, 3}, {STX, DIR, 2, 3}, {STA, DLN, 2, 6}, {DEY, IMP, 1, 2}, {BIT, IMA, 2, 2}, {TXA, IMP, 1, 2}, {PHB, IMP, 1, 3}, {STY, ABS, 3, 4}, {STA, ABS, 3, 4}, {STX, ABS, 3, 4}, {STA, ABL, 4, 5},
+	{BCC, REL, 2, 2}, {STA, DNY, 2, 6}, {STA, DIN, 2, 5}, {STA, SRY, 2, 7}, {STY, DRX, 2, 4}, {STA, DRX, 2, 4}, {STX, DRY, 2, 4}, {STA, DLY, 2, 6}, {TYA, IMP, 1, 2}, {STA, ABY, 3, 5}, {TXS, IMP, 1, 2}, {TXY, IMP, 1, 2}, {STZ, ABS, 3, 4}, {STA, ABX, 3, 5}, {STZ, ABX, 3, 5}, {STA, ALX, 4, 5},
+	{LDY, IMX, 2, 2}, {LDA, DNX, 2, 6}, {LDX, IMX, 2, 2}, {LDA, SRL, 2, 4}, {LDY, DIR, 2, 3}, {LDA, DIR, 2, 3}, {LDX, DIR, 2, 3}, {LDA, DLN, 2, 6}, {TAY, IMP, 1, 2}, {LDA, IMA, 2, 2}, {TAX, IMP, 1, 2}, {PLB, IMP, 1, 4}, {LDY, ABS, 3, 4}, {LDA, ABS, 3, 4}, {LDX, ABS, 3, 4}, {LDA, ABL, 4, 5},
+	{BCS, REL, 2, 2}, {LDA, DNY, 2, 5}, {LDA, DIN, 2, 5}, {LDA, SRY, 2, 7}, {LDY, DRX, 2, 4}, {LDA, DRX, 2, 4}, {LDX, DRY, 2, 4}, {LDA, DLY, 2, 6}, {CLV, IMP, 1, 2}, {LDA, ABY, 3, 4}, {TSX, IMP, 1, 2}, {TYX, IMP, 1, 2}, {LDY, ABX, 3, 4}, {LDA, ABX, 3, 4}, {LDX, ABY, 3, 4}, {LDA, ALX, 4, 5},
+	{CPY, IMX, 2, 2}, {CMP, DNX, 2, 6}, {REP, IMM, 2, 3}, {CMP, SRL, 2, 4}, {CPY, DIR, 2, 3}, {CMP, DIR, 2, 3}, {DEC, DIR, 2, 5}, {CMP, DLN, 2, 6}, {INY, IMP, 1, 2}, {CMP, IMA, 2, 2}, {DEX, IMP, 1, 2}, {WAI, IMP, 1, 3}, {CPY, ABS, 3, 4}, {CMP, ABS, 3, 4}, {DEC, ABS, 3, 6}, {CMP, ABL, 4, 5},
+	{BNE, REL, 2, 2}, {CMP, DNY, 2, 5}, {CMP, DIN, 2, 5}, {CMP, SRY, 2, 7}, {PEI, DIR, 2, 6}, {CMP, DRX, 2, 4}, {DEC, DRX, 2, 6}, {CMP, DLY, 2, 6}, {CLD, IMP, 1, 2}, {CMP, ABY, 3, 4}, {PHX, IMP, 1, 3}, {STP, IMP, 1, 3}, {JML, AIL, 3, 6}, {CMP, ABX, 3, 4}, {DEC, ABX, 3, 7}, {CMP, ALX, 4, 5},
+	{CPX, IMX, 2, 2}, {SBC, DNX, 2, 6}, {SEP, IMM, 2, 3}, {SBC, SRL, 2, 4}, {CPX, DIR, 2, 3}, {SBC, DIR, 2, 3}, {INC, DIR, 2, 5}, {SBC, DLN, 2, 6}, {INX, IMP, 1, 2}, {SBC, IMA, 2, 2}, {NOP, IMP, 1, 2}, {XBA, IMP, 1, 3}, {CPX, ABS, 3, 4}, {SBC, ABS, 3, 4}, {INC, ABS, 3, 6}, {SBC, ABL, 4, 5},
+	{BEQ, REL, 2, 2}, {SBC, DNY, 2, 5}, {SBC, DIN, 2, 5}, {SBC, SRY, 2, 7}, {PEA, ABS, 3, 5}, {SBC, DRX, 2, 4}, {INC, DRX, 2, 6}, {SBC, DLY, 2, 6}, {SED, IMP, 1, 2}, {SBC, ABY, 3, 4}, {PLX, IMP, 1, 4}, {XCE, IMP, 1, 2}, {JSR, AIX, 3, 8}, {SBC, ABX, 3, 4}, {INC, ABX, 3, 7}, {SBC, ALX, 4, 5},
+}
+
+// GetOperation returns the operation for the given opcode.
+func GetOperation(opcode byte) Operation {
+	return operations[opcode]
+}
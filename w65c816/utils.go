@@ -0,0 +1,13 @@
+package w65c816
+
+// pagesDiffer returns true if the two addresses reference different pages
+func pagesDiffer(a, b uint16) bool {
+	return a&0xFF00 != b&0xFF00
+}
+
+func ternary[T any](cond bool, vtrue, vfalse T) T {
+	if cond {
+		return vtrue
+	}
+	return vfalse
+}